@@ -0,0 +1,122 @@
+package envsync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ageIntro starts a file encrypted with age in its binary format.
+	ageIntro = "age-encryption.org/"
+
+	// ageExtension is the extension of files encrypted with age, left out to tell their codec, e.g. env.yaml.age.
+	ageExtension = ".age"
+)
+
+// isAge reports whether b is encrypted with age, in its binary or armored format.
+func isAge(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(ageIntro)) || bytes.HasPrefix(bytes.TrimSpace(b), []byte(armor.Header))
+}
+
+// isAgeFile reports whether the file at name is encrypted with age.
+// A file that can't be read isn't.
+func isAgeFile(name string) bool {
+	file, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, len(armor.Header)+64)
+	n, _ := io.ReadFull(file, head)
+	return isAge(head[:n])
+}
+
+// decryptAge returns b, the content of the file at name, decrypted with the identities in the files of AgeIdentities.
+func (s *Syncer) decryptAge(name string, b []byte) ([]byte, error) {
+	if len(s.AgeIdentities) == 0 {
+		return nil, fmt.Errorf("%s is encrypted with age, but no identity is set to decrypt it", name)
+	}
+	var identities []age.Identity
+	for _, f := range s.AgeIdentities {
+		ids, err := readAgeIdentities(f)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, ids...)
+	}
+
+	var r io.Reader = bytes.NewReader(b)
+	if !bytes.HasPrefix(b, []byte(ageIntro)) {
+		r = armor.NewReader(r)
+	}
+	plain, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't decrypt %s with age", name))
+	}
+	return ioutil.ReadAll(plain)
+}
+
+// readAgeIdentities returns the identities in the file at name, e.g. one written by age-keygen.
+func readAgeIdentities(name string) ([]age.Identity, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't open age identity file")
+	}
+	defer file.Close()
+	ids, err := age.ParseIdentities(file)
+	return ids, errors.Wrap(err, fmt.Sprintf("couldn't read age identities in %s", name))
+}
+
+// encryptAge returns plain encrypted with age for AgeRecipients, in the armored format, which is text.
+func (s *Syncer) encryptAge(plain []byte) ([]byte, error) {
+	recipients, err := age.ParseRecipients(strings.NewReader(strings.Join(s.AgeRecipients, "\n")))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid age recipient")
+	}
+
+	var b bytes.Buffer
+	aw := armor.NewWriter(&b)
+	w, err := age.Encrypt(aw, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeAgeFile replaces the content of the file at name with doc encrypted with age for AgeRecipients.
+func (s *Syncer) writeAgeFile(name string, doc *Document) error {
+	name, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	var plain bytes.Buffer
+	if err := doc.write(&plain); err != nil {
+		return err
+	}
+	encrypted, err := s.encryptAge(plain.Bytes())
+	if err != nil {
+		return err
+	}
+	return replaceFile(name, 0644, func(w io.Writer) error {
+		_, err := w.Write(encrypted)
+		return err
+	})
+}
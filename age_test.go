@@ -0,0 +1,98 @@
+package envsync_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Sync_Age(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+	dir := makeTree(t, map[string]string{
+		"keys.txt":   "# created: 2024-01-01T00:00:00Z\n" + identity.String() + "\n",
+		"env.sample": "DB_PASSWORD=hunter2\n",
+		".env.age":   "",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, "env.sample"), filepath.Join(dir, ".env.age")
+	recipients, identities := []string{identity.Recipient().String()}, []string{filepath.Join(dir, "keys.txt")}
+
+	syncer := envsync.New(envsync.WithAge(recipients, identities))
+	res, err := syncer.SyncWithResult(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD"}, res.Added)
+	b, _ := ioutil.ReadFile(target)
+	assert.True(t, strings.HasPrefix(string(b), "-----BEGIN AGE ENCRYPTED FILE-----\n"))
+	assert.NotContains(t, string(b), "hunter2")
+
+	// an encrypted target is compared once it is decrypted
+	diff, err := envsync.New(envsync.WithAge(nil, identities)).Diff(source, target)
+	assert.Nil(t, err)
+	assert.True(t, diff.Equal())
+
+	_, err = envsync.New().Diff(source, target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), ".env.age is encrypted with age, but no identity is set to decrypt it")
+
+	// the snapshot of the source, in plain text, isn't kept next to it
+	_, err = envsync.New(envsync.WithAge(recipients, identities), envsync.WithMerge(envsync.MergeThreeWay)).SyncWithResult(source, target)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, ".envsync", ".env.age.base"))
+	assert.True(t, os.IsNotExist(err))
+
+	// it is never written back in plain text
+	ioutil.WriteFile(source, []byte("DB_PASSWORD=hunter2\nDB_USER=admin\n"), 0644)
+	_, err = envsync.New(envsync.WithAge(nil, identities)).SyncWithResult(source, target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no recipient is set to encrypt it for")
+	after, _ := ioutil.ReadFile(target)
+	assert.Equal(t, b, after)
+
+	_, err = envsync.New(envsync.WithAge([]string{"age1invalid"}, identities)).SyncWithResult(source, target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid age recipient")
+}
+
+func TestSyncer_Init_Age(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.Nil(t, err)
+	dir := makeTree(t, map[string]string{
+		"keys.txt":   identity.String() + "\n",
+		"env.sample": "# envsync:secret\nSESSION_KEY=\nDB_PASSWORD=hunter2\n",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, "env.sample"), filepath.Join(dir, ".env.age")
+	recipients, identities := []string{identity.Recipient().String()}, []string{filepath.Join(dir, "keys.txt")}
+
+	res, err := envsync.New(envsync.WithAge(recipients, identities), envsync.WithBackup()).Init(context.Background(), source, target)
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadFile(target)
+	assert.True(t, strings.HasPrefix(string(b), "-----BEGIN AGE ENCRYPTED FILE-----\n"))
+	assert.NotContains(t, string(b), "hunter2")
+	assert.NotContains(t, string(b), res.Env["SESSION_KEY"])
+
+	diff, err := envsync.New(envsync.WithAge(nil, identities)).Diff(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"SESSION_KEY"}, diff.Changed)
+
+	// it is never created in plain text
+	os.Remove(target)
+	_, err = envsync.New().Init(context.Background(), source, target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no recipient is set to encrypt it for")
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCodecOf_Age(t *testing.T) {
+	assert.Equal(t, envsync.CodecYAML, envsync.CodecOf("env.yaml.age"))
+	assert.Equal(t, envsync.CodecDotenv, envsync.CodecOf(".env.age"))
+}
@@ -0,0 +1,142 @@
+package envsync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// annotationPrefix starts an annotation in a comment, e.g. # envsync:secret.
+const annotationPrefix = "envsync:"
+
+// Annotations recognized in the comments directly above a key-value in source.
+const (
+	// annotationRequired marks a key that needs a non-empty value in target.
+	// It may be written as # required too.
+	annotationRequired = "required"
+
+	// annotationGenerate marks a key that is given a random value when it is added to target,
+	// e.g. # envsync:generate hex32.
+	annotationGenerate = "generate"
+
+	// annotationType declares the type of the value of a key, e.g. # envsync:type int.
+	annotationType = "type"
+
+	// annotationPattern declares a regular expression the value of a key must match,
+	// e.g. # envsync:pattern ^postgres://.
+	annotationPattern = "pattern"
+
+	// annotationRequiredIf makes a key required when a condition on another key holds,
+	// e.g. # envsync:required-if FEATURE_X=true.
+	annotationRequiredIf = "required-if"
+
+	// annotationEnum declares the values a key may have, e.g. # envsync:enum debug,info,warn,error.
+	annotationEnum = "enum"
+
+	// annotationAlias declares the deprecated names of a key, e.g. # envsync:alias DB_URL,DATABASE.
+	// A deprecated key in target is renamed to the key.
+	annotationAlias = "alias"
+
+	// annotationSecret marks a key that is given a random value when target is initialized,
+	// the same as # envsync:generate hex32.
+	annotationSecret = "secret"
+)
+
+// annotations returns the annotations in the comments directly above the key-value holding key,
+// mapped to their argument, e.g. # envsync:generate hex32 gives {"generate": "hex32"}.
+func (d *Document) annotations(key string) map[string]string {
+	annotations := make(map[string]string)
+	i := d.index(key)
+	if i < 0 {
+		return annotations
+	}
+
+	for _, c := range d.comments(i) {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c), "#"))
+		if text == annotationRequired {
+			annotations[annotationRequired] = ""
+			continue
+		}
+		if !strings.HasPrefix(text, annotationPrefix) {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(text, annotationPrefix), " ", 2)
+		arg := ""
+		if len(fields) == 2 {
+			arg = strings.TrimSpace(fields[1])
+		}
+		annotations[fields[0]] = arg
+	}
+	return annotations
+}
+
+// requiredKeys returns the keys in env annotated as required in d.
+func (d *Document) requiredKeys(env map[string]string) []string {
+	var keys []string
+	for _, k := range sortedKeys(env) {
+		if _, ok := d.annotations(k)[annotationRequired]; ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// checkRequired returns ErrRequiredEmpty if a key of src annotated as required is empty in env.
+// The keys of unknown are the ones whose value in env isn't known, e.g. the ones of a write-only target,
+// and are taken as set.
+func checkRequired(src *Document, sMap map[string]string, env Env, unknown map[string]string) error {
+	var empty []string
+	for _, k := range src.requiredKeys(sMap) {
+		if _, ok := unknown[k]; !ok && env[k] == "" {
+			empty = append(empty, k)
+		}
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+	return errors.Wrap(ErrRequiredEmpty, strings.Join(empty, ", "))
+}
+
+// Schema returns the schema declared by the annotations in the document:
+// keys annotated with # required are required, # envsync:type declares the type of a key,
+// # envsync:pattern the regular expression its value must match,
+// # envsync:enum the comma-separated values it may have,
+// and # envsync:required-if the comma-separated conditions making it required, e.g. FEATURE_X=true.
+// Every key in the document is in the schema, so it can be used to validate an actual env against a sample env.
+func (d *Document) Schema() (*Schema, error) {
+	schema := &Schema{Keys: make(map[string]*KeySchema)}
+	for _, k := range d.Keys() {
+		annotations := d.annotations(k)
+		ks := &KeySchema{}
+		_, ks.Required = annotations[annotationRequired]
+		if typ, ok := annotations[annotationType]; ok {
+			var err error
+			if ks.Type, err = valueType(typ); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("invalid annotation of %s", k))
+			}
+		}
+		if pattern, ok := annotations[annotationPattern]; ok {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("invalid annotation of %s", k))
+			}
+			ks.Pattern = pattern
+		}
+		if conds, ok := annotations[annotationRequiredIf]; ok {
+			for _, c := range strings.Split(conds, ",") {
+				ks.RequiredIf = append(ks.RequiredIf, strings.TrimSpace(c))
+			}
+			if err := checkConditions(ks.RequiredIf); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("invalid annotation of %s", k))
+			}
+		}
+		if enum, ok := annotations[annotationEnum]; ok {
+			for _, v := range strings.Split(enum, ",") {
+				ks.Enum = append(ks.Enum, strings.TrimSpace(v))
+			}
+		}
+		schema.Keys[k] = ks
+	}
+	return schema, nil
+}
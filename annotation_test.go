@@ -0,0 +1,34 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Required(t *testing.T) {
+	src := "# required\nAPI_KEY=\n# the database\n# envsync:required\nDB_URL=postgres://localhost\nDEBUG=\n"
+
+	var buf bytes.Buffer
+	_, err := envsync.New().SyncReaders(strings.NewReader(src), strings.NewReader("DB_URL=\n"), &buf)
+	assert.True(t, errors.Is(err, envsync.ErrRequiredEmpty))
+	assert.Contains(t, err.Error(), "API_KEY, DB_URL")
+	assert.Empty(t, buf.String())
+
+	_, err = envsync.New().SyncReaders(strings.NewReader(src), strings.NewReader("API_KEY=abc\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "API_KEY=abc\nDB_URL=postgres://localhost\nDEBUG=\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_RequiredExcluded(t *testing.T) {
+	src := "# required\nAPI_KEY=\nDEBUG=\n"
+
+	var buf bytes.Buffer
+	_, err := envsync.New(envsync.WithExclude("API_KEY")).SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "DEBUG=\n", buf.String())
+}
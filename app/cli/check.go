@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+// exit codes of the check command
+const (
+	checkDrift = 1
+	checkError = 2
+)
+
+var checkCommand = cli.Command{
+	Name:  "check",
+	Usage: "fail if actual env is missing env from sample env",
+	Description: "check doesn't modify anything. It exits with status 0 when actual env is in sync,\n" +
+		"   1 when env is missing (or extra with --extra), and 2 when the files can't be read.",
+	Flags: append([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "extra, e",
+			Usage: "also fail if actual env has env that doesn't exist in sample env",
+		},
+	}, append(fileFlags, compareFlags...)...),
+	Action: checkAction,
+}
+
+func checkAction(c *cli.Context) error {
+	if _, err := applyConfig(c); err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	syncer, err := newSyncer(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	if syncer.Codecs, err = fileCodecs(c); err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	diff, err := syncer.Diff(c.String("source"), c.String("target"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	extra := diff.OnlyInTarget
+	if !c.Bool("extra") {
+		extra = nil
+	}
+	failed := len(diff.OnlyInSource)+len(extra) > 0
+
+	if c.Bool("json") {
+		if err := envsync.WriteJSON(os.Stdout, diff); err != nil {
+			return cli.NewExitError(err.Error(), checkError)
+		}
+		if failed {
+			return cli.NewExitError("", checkDrift)
+		}
+		return nil
+	}
+
+	if !failed {
+		fmt.Println("target is in sync with source")
+		return nil
+	}
+
+	color := useColor(c)
+	for _, k := range diff.OnlyInSource {
+		fmt.Println(paint("Missing env:", colorRed, color), k)
+	}
+	for _, k := range extra {
+		fmt.Println(paint("Extra env:", colorYellow, color), k)
+	}
+	return cli.NewExitError("", checkDrift)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// ANSI escapes coloring the output
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+var colorFlag = cli.StringFlag{
+	Name:  "color",
+	Usage: "color the output always, never, or auto: only when stdout is a terminal and NO_COLOR isn't set",
+	Value: "auto",
+}
+
+// checkColor returns an error if the --color flag isn't auto, always, or never.
+func checkColor(c *cli.Context) error {
+	switch c.String("color") {
+	case "", "auto", "always", "never":
+		return nil
+	}
+	return fmt.Errorf("unknown color mode: %s", c.String("color"))
+}
+
+// useColor tells whether the output is colored following the --color flag.
+// By default it is when stdout is a terminal and the NO_COLOR environment variable isn't set, see https://no-color.org.
+func useColor(c *cli.Context) bool {
+	switch c.String("color") {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the ANSI escape code if color is set.
+func paint(s, code string, color bool) string {
+	if !color {
+		return s
+	}
+	return code + s + colorReset
+}
+
+// printPatch prints patch, coloring removed lines in red, added lines in green, and hunk headers in cyan if color is set.
+func printPatch(patch string, color bool) {
+	for _, l := range strings.SplitAfter(patch, "\n") {
+		if l == "" {
+			continue
+		}
+		code := ""
+		switch {
+		case strings.HasPrefix(l, "---") || strings.HasPrefix(l, "+++"):
+			code = colorBold
+		case strings.HasPrefix(l, "@@"):
+			code = colorCyan
+		case strings.HasPrefix(l, "-"):
+			code = colorRed
+		case strings.HasPrefix(l, "+"):
+			code = colorGreen
+		}
+		if code == "" {
+			fmt.Print(l)
+			continue
+		}
+		fmt.Println(paint(strings.TrimSuffix(l, "\n"), code, color))
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultConfig is the config file read when --config isn't set, if it exists.
+const defaultConfig = ".envsync.yaml"
+
+var configFlag = cli.StringFlag{
+	Name:  "config",
+	Usage: "read the files to sync and default flag values from the config file",
+	Value: defaultConfig,
+}
+
+// config is a per-project config file, e.g.
+//
+//	pairs:
+//	  - source: env.sample
+//	    target: .env
+//	  - source: api/env.sample
+//	    target: api/.env
+//	merge: three-way
+//	exclude: [LOCAL_*]
+//	enable: [secret]
+//
+// Pairs are the files sync synchronizes when neither --source nor --target is set.
+// Any other key is the name of a flag, giving its value when the flag isn't set on the command line.
+// Keys naming a flag the command doesn't have are ignored, so a single file serves every command.
+type config struct {
+	Pairs []configPair `yaml:"pairs"`
+}
+
+type configPair struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+// applyConfig reads the config file and sets the flags of c it gives a value to, unless they are already set.
+// A missing config file is only an error when --config is set.
+func applyConfig(c *cli.Context) (*config, error) {
+	name := c.String("config")
+	if name == "" {
+		return &config{}, nil
+	}
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) && !c.IsSet("config") {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", name, err)
+	}
+	var flags map[string]interface{}
+	if err := yaml.Unmarshal(b, &flags); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", name, err)
+	}
+	for _, f := range sortedFlags(flags) {
+		if f == "pairs" || f == "config" || !hasFlag(c, f) || c.IsSet(f) {
+			continue
+		}
+		for _, v := range configValues(flags[f]) {
+			if err := c.Set(f, v); err != nil {
+				return nil, fmt.Errorf("invalid %s in %s: %v", f, name, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// hasFlag tells whether the command run with c has the flag name.
+func hasFlag(c *cli.Context, name string) bool {
+	flags := c.Command.Flags
+	if c.Command.Name == "" {
+		flags = c.App.Flags
+	}
+	for _, f := range flags {
+		for _, n := range strings.Split(f.GetName(), ",") {
+			if strings.TrimSpace(n) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configValues returns the flag values v gives: one for each item of a list,
+// one KEY=value for each item of a map, e.g. for --rename, and v itself otherwise.
+func configValues(v interface{}) []string {
+	switch v := v.(type) {
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			values[i] = fmt.Sprint(item)
+		}
+		return values
+	case map[interface{}]interface{}:
+		var values []string
+		for k, item := range v {
+			values = append(values, fmt.Sprintf("%v=%v", k, item))
+		}
+		sort.Strings(values)
+		return values
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+func sortedFlags(flags map[string]interface{}) []string {
+	names := make([]string, 0, len(flags))
+	for f := range flags {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	return names
+}
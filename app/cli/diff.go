@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+// exit codes of the diff command with --exit-code
+const (
+	diffDifferent = 1
+	diffError     = 2
+)
+
+var diffCommand = cli.Command{
+	Name:  "diff",
+	Usage: "show env that differs between sample env and actual env",
+	Description: "diff doesn't modify anything. With --exit-code, it exits like git diff --exit-code:\n" +
+		"   with status 0 when sample env and actual env have the same env, 1 when they differ, and 2 when they can't be read.",
+	Flags: append(append(fileFlags, compareFlags...),
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "print the differences as a table, a unified diff, or json (default: \"table\")",
+		},
+		cli.BoolFlag{
+			Name:  "exit-code",
+			Usage: "exit with status 1 when sample env and actual env differ, and 2 on errors",
+		},
+		patchFlag,
+	),
+	Action: diffAction,
+}
+
+func diffAction(c *cli.Context) error {
+	fail := func(err error) error {
+		if c.Bool("exit-code") {
+			return cli.NewExitError(err.Error(), diffError)
+		}
+		fmt.Println(err.Error())
+		return err
+	}
+	if _, err := applyConfig(c); err != nil {
+		return fail(err)
+	}
+	output, err := diffOutput(c)
+	if err != nil {
+		return fail(err)
+	}
+	syncer, err := newSyncer(c)
+	if err != nil {
+		return fail(err)
+	}
+	if syncer.Codecs, err = fileCodecs(c); err != nil {
+		return fail(err)
+	}
+	syncer.Patch = output == "unified"
+
+	diff, err := syncer.Diff(c.String("source"), c.String("target"))
+	if err != nil {
+		return fail(err)
+	}
+	color := useColor(c)
+	switch {
+	case output == "json":
+		if err := envsync.WriteJSON(os.Stdout, diff); err != nil {
+			return fail(err)
+		}
+	case diff.Equal():
+		fmt.Println("source and target have the same env")
+	case output == "unified":
+		printPatch(diff.Patch, color)
+	default:
+		printDiffTable(diff, color)
+	}
+
+	if c.Bool("exit-code") && !diff.Equal() {
+		return cli.NewExitError("", diffDifferent)
+	}
+	return nil
+}
+
+// diffOutput returns the output of the diff command: table, unified, or json.
+// --json and --patch are the same as --output json and --output unified.
+func diffOutput(c *cli.Context) (string, error) {
+	output := c.String("output")
+	switch {
+	case output == "" && c.Bool("json"):
+		return "json", nil
+	case output == "" && c.Bool("patch"):
+		return "unified", nil
+	case output == "":
+		return "table", nil
+	case output == "table" || output == "unified" || output == "json":
+		return output, nil
+	}
+	return "", fmt.Errorf("unknown output %q, expected table, unified, or json", output)
+}
+
+// printDiffTable prints the keys of diff with their values in sample env and in actual env,
+// each row marked by + if the key is only in sample env, - if it is only in actual env, and ~ if its value changed.
+func printDiffTable(diff *envsync.DiffResult, color bool) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  KEY\tSAMPLE\tACTUAL")
+	for _, rows := range []struct {
+		mark string
+		keys []string
+	}{{"+", diff.OnlyInSource}, {"-", diff.OnlyInTarget}, {"~", diff.Changed}} {
+		for _, k := range rows.keys {
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", rows.mark, k, tableValue(diff.Source[k]), tableValue(diff.Target[k]))
+		}
+	}
+	w.Flush()
+
+	colors := map[byte]string{'+': colorGreen, '-': colorRed, '~': colorYellow}
+	for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		l = strings.TrimRight(l, " ")
+		if code, ok := colors[l[0]]; ok {
+			l = paint(l, code, color)
+		}
+		fmt.Println(l)
+	}
+}
+
+// tableValue returns v on a single line, so it fits in a cell of a table.
+func tableValue(v string) string {
+	return strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(v)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+var fmtCommand = cli.Command{
+	Name:  "fmt",
+	Usage: "format actual env: key names, quotes, order, whitespace, and line endings",
+	Description: "fmt renames keys that don't follow the naming convention, e.g. db-host to DB_HOST, keeping their values and comments.\n" +
+		"   A key is left as it is if its new name is already declared.\n" +
+		"   It also trims whitespace, collapses blank lines, writes line endings as \\n, and quotes and orders env following --quote and --layout.\n" +
+		"   With --check, it exits with status 1 when actual env isn't formatted, without modifying it.",
+	Flags: []cli.Flag{
+		targetFlag,
+		cli.StringFlag{
+			Name:  "naming-pattern",
+			Usage: "set the regular expression keys must match (default: \"" + envsync.UpperSnakeCase + "\")",
+		},
+		cli.StringFlag{
+			Name:  "quote",
+			Usage: "quote values by keep, minimal, double, or single (default: \"keep\")",
+		},
+		cli.StringFlag{
+			Name:  "layout",
+			Usage: "order env by keep, sorted, or grouped: sorted in the groups of --group-by (default: \"keep\")",
+		},
+		cli.StringFlag{
+			Name:  "group-by",
+			Usage: "group env of --layout grouped by prefix, explicit groups of --group, the sections of sample env, or none (default: \"prefix\")",
+		},
+		cli.IntFlag{
+			Name:  "group-depth",
+			Usage: "set the number of words of the prefix grouped env share, e.g. 2 for AWS_S3_*",
+			Value: 1,
+		},
+		cli.StringSliceFlag{
+			Name:  "group",
+			Usage: "declare a group of --group-by explicit, e.g. \"Database=DB_*,DATABASE_*\", can be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "no-group-comments",
+			Usage: "separate the groups of --layout grouped by a blank line only, without a # GROUP comment",
+		},
+		cli.StringFlag{
+			Name:  "source, s",
+			Usage: "set sample env, whose sections are the groups of --group-by source",
+			Value: "env.sample",
+		},
+		cli.BoolFlag{
+			Name:  "check",
+			Usage: "print the changes as a unified diff and fail if actual env isn't formatted, without writing it",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run, d",
+			Usage: "show keys that would be renamed without writing actual env",
+		},
+		cli.BoolFlag{
+			Name:  "backup, b",
+			Usage: "copy actual env to a new backup in .envsync/backups before modifying it",
+		},
+		sourceFormatFlag,
+		targetFormatFlag,
+		colorFlag,
+		maskFlag,
+		showSecretsFlag,
+		configFlag,
+	},
+	Action: fmtAction,
+}
+
+func fmtAction(c *cli.Context) error {
+	if _, err := applyConfig(c); err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	if err := checkColor(c); err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	st, err := parseStyle(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	cs, err := codecs(c, configPair{Source: c.String("source"), Target: c.String("target")})
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	syncer := &envsync.Syncer{
+		Codecs:      cs,
+		DryRun:      c.Bool("dry-run") || c.Bool("check"),
+		Backup:      c.Bool("backup"),
+		Patch:       c.Bool("check"),
+		Style:       st,
+		Mask:        c.StringSlice("mask"),
+		ShowSecrets: c.Bool("show-secrets"),
+	}
+	rule := envsync.NamingRule{Pattern: c.String("naming-pattern")}
+
+	target := c.String("target")
+	res, err := syncer.Format(context.Background(), target, rule)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	if c.Bool("check") {
+		if !res.Changed {
+			fmt.Println(target, "is formatted")
+			return nil
+		}
+		fmt.Println(target, "isn't formatted")
+		printPatch(res.Patch, useColor(c))
+		return cli.NewExitError("", checkDrift)
+	}
+
+	msg := "Env renamed:"
+	if syncer.DryRun {
+		msg = "Env would be renamed:"
+	}
+	for _, k := range res.Fixed {
+		fmt.Println(msg, k)
+	}
+	switch {
+	case !res.Changed:
+		fmt.Println("target is already formatted")
+	case syncer.DryRun:
+		fmt.Println("dry run finished, target is left unchanged")
+	default:
+		fmt.Println("target is successfully formatted")
+	}
+	return nil
+}
+
+// parseStyle returns the style given by the --quote and --layout flags, with the grouping of parseGrouping.
+func parseStyle(c *cli.Context) (envsync.Style, error) {
+	var st envsync.Style
+	var err error
+	if c.String("quote") != "" {
+		if st.Quoting, err = envsync.ParseQuoting(c.String("quote")); err != nil {
+			return st, err
+		}
+	}
+	if c.String("layout") != "" {
+		if st.Layout, err = envsync.ParseLayout(c.String("layout")); err != nil {
+			return st, err
+		}
+	}
+	err = parseGrouping(c, &st)
+	return st, err
+}
+
+// parseGrouping sets the grouping of st given by the --group-by, --group-depth, --no-group-comments, and --group flags,
+// reading the source to group keys like it with --group-by source.
+func parseGrouping(c *cli.Context, st *envsync.Style) error {
+	if c.String("group-by") != "" {
+		var err error
+		if st.Grouping, err = envsync.ParseGrouping(c.String("group-by")); err != nil {
+			return err
+		}
+	}
+	st.PrefixDepth = c.Int("group-depth")
+	st.NoHeadings = c.Bool("no-group-comments")
+	for _, g := range c.StringSlice("group") {
+		sp := strings.SplitN(g, "=", 2)
+		if len(sp) != 2 || sp[0] == "" || sp[1] == "" {
+			return fmt.Errorf("invalid group %q, expected NAME=PATTERN,PATTERN", g)
+		}
+		st.Groups = append(st.Groups, envsync.Group{Name: sp[0], Keys: strings.Split(sp[1], ",")})
+	}
+	if st.Grouping == envsync.GroupingSource {
+		doc, err := readDocument(c, "source")
+		if err != nil {
+			return err
+		}
+		st.Source = doc
+	}
+	return nil
+}
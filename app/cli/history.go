@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+var historyCommand = cli.Command{
+	Name:   "history",
+	Usage:  "list the backups of actual env, from the most recent",
+	Flags:  []cli.Flag{targetFlag},
+	Action: historyAction,
+}
+
+var rollbackCommand = cli.Command{
+	Name:  "rollback",
+	Usage: "restore actual env from one of its backups",
+	Flags: []cli.Flag{
+		targetFlag,
+		cli.IntFlag{
+			Name:  "n",
+			Usage: "restore the nth most recent backup as numbered by the history command",
+			Value: 1,
+		},
+	},
+	Action: rollbackAction,
+}
+
+func historyAction(c *cli.Context) error {
+	syncer := &envsync.Syncer{}
+	backups, err := syncer.History(c.String("target"))
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	if len(backups) == 0 {
+		fmt.Println("no backup found")
+		return nil
+	}
+	for i, b := range backups {
+		fmt.Printf("%d\t%s\t%s\n", i+1, b.Time.Local().Format("2006-01-02 15:04:05"), b.Path)
+	}
+	return nil
+}
+
+func rollbackAction(c *cli.Context) error {
+	syncer := &envsync.Syncer{}
+	target := c.String("target")
+	if err := syncer.Rollback(target, c.Int("n")); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	fmt.Println(target, "is successfully restored")
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+var initCommand = cli.Command{
+	Name:  "init",
+	Usage: "create actual env from sample env when it doesn't exist",
+	Description: "init copies sample env to actual env. Env annotated with \"# envsync:secret\" is given a random value.\n" +
+		"   With --interactive, it asks for the value of env annotated with \"# envsync:required\".",
+	Flags: append([]cli.Flag{
+		cli.BoolFlag{
+			Name:  "interactive",
+			Usage: "ask for the value of required env, enter accepts the sample value and \"-\" skips it",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run, d",
+			Usage: "show env that would be added without creating actual env",
+		},
+	}, fileFlags...),
+	Action: initAction,
+}
+
+func initAction(c *cli.Context) error {
+	syncer, err := newSyncer(c)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	if syncer.Codecs, err = fileCodecs(c); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	res, err := syncer.Init(context.Background(), c.String("source"), c.String("target"))
+	printResult("", syncer, res, err, useColor(c))
+	return err
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+// defaultLintRules are the rules run unless they are disabled.
+// The secret rule is left out since actual env is expected to hold real secrets.
+var defaultLintRules = []string{"duplicate", "naming", "placeholder", "schema"}
+
+var lintCommand = cli.Command{
+	Name:  "lint",
+	Usage: "check actual env for duplicate keys, naming, placeholders, and schema violations",
+	Description: "lint doesn't modify anything. It exits with status 0 when no rule reports an error, warnings aside,\n" +
+		"   1 when one does, and 2 when the files can't be read.\n" +
+		"   The rules are duplicate, naming, placeholder, schema, and secret. All of them but secret run by default.\n" +
+		"   The schema rule uses the annotations in sample env with --source, or the schema file if it exists.",
+	Flags: []cli.Flag{
+		targetFlag,
+		cli.StringFlag{
+			Name:  "source, s",
+			Usage: "use the annotations in sample env as schema and to find required env",
+		},
+		cli.StringFlag{
+			Name:  "schema",
+			Usage: "set schema file, in YAML or JSON",
+			Value: envsync.DefaultSchemaFile,
+		},
+		cli.StringSliceFlag{
+			Name:  "enable, e",
+			Usage: "run the rule in addition to the default ones, can be repeated",
+		},
+		cli.StringSliceFlag{
+			Name:  "disable, D",
+			Usage: "don't run the rule, can be repeated",
+		},
+		cli.StringFlag{
+			Name:  "naming-pattern",
+			Usage: "set the regular expression keys must match (default: \"" + envsync.UpperSnakeCase + "\")",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the violations as a JSON array",
+		},
+		sourceFormatFlag,
+		targetFormatFlag,
+		maskFlag,
+		showSecretsFlag,
+		configFlag,
+	},
+	Action: lintAction,
+}
+
+// lintViolation is how a violation is printed with --json.
+type lintViolation struct {
+	File     string `json:"file"`
+	Rule     string `json:"rule"`
+	Key      string `json:"key"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+func lintAction(c *cli.Context) error {
+	if _, err := applyConfig(c); err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	rules, err := lintRules(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	target := c.String("target")
+	doc, err := readDocument(c, "target")
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	var source *envsync.Document
+	if c.String("source") != "" {
+		if source, err = readDocument(c, "source"); err != nil {
+			return cli.NewExitError(err.Error(), checkError)
+		}
+	}
+
+	masker, err := newMasker(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	violations := masker.Violations(envsync.Lint(doc, source, rules...), doc.Env())
+	failed := false
+	for _, v := range violations {
+		failed = failed || !v.Warning
+	}
+
+	if c.Bool("json") {
+		out := make([]lintViolation, len(violations))
+		for i, v := range violations {
+			severity := "error"
+			if v.Warning {
+				severity = "warning"
+			}
+			out[i] = lintViolation{File: target, Rule: v.Rule, Key: v.Key, Line: v.Line, Message: v.Message, Severity: severity}
+		}
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return cli.NewExitError(err.Error(), checkError)
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, v := range violations {
+			fmt.Printf("%s: %s\n", target, v)
+		}
+		if !failed {
+			fmt.Println(target, "has no lint error")
+		}
+	}
+
+	if failed {
+		return cli.NewExitError("", checkDrift)
+	}
+	return nil
+}
+
+// lintRules returns the rules enabled by the flags, in the order of their names.
+func lintRules(c *cli.Context) ([]envsync.LintRule, error) {
+	enabled := make(map[string]bool)
+	for _, name := range defaultLintRules {
+		enabled[name] = true
+	}
+	for _, name := range c.StringSlice("enable") {
+		enabled[name] = true
+	}
+	for _, name := range c.StringSlice("disable") {
+		enabled[name] = false
+	}
+
+	var names []string
+	for name, ok := range enabled {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var rules []envsync.LintRule
+	for _, name := range names {
+		switch name {
+		case "duplicate":
+			rules = append(rules, envsync.DuplicateRule{})
+		case "naming":
+			rules = append(rules, envsync.NamingRule{Pattern: c.String("naming-pattern")})
+		case "placeholder":
+			rules = append(rules, envsync.PlaceholderRule{})
+		case "secret":
+			rules = append(rules, envsync.SecretRule{})
+		case "schema":
+			schema, err := lintSchema(c)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, envsync.SchemaRule{Schema: schema})
+		default:
+			return nil, fmt.Errorf("unknown lint rule: %s, the rules are duplicate, naming, placeholder, schema, and secret", name)
+		}
+	}
+	return rules, nil
+}
+
+// lintSchema returns the schema of the schema rule, or nil if there is none:
+// the default schema file is optional, unlike one given with --schema.
+func lintSchema(c *cli.Context) (*envsync.Schema, error) {
+	if c.String("source") == "" && !c.IsSet("schema") {
+		if _, err := os.Stat(c.String("schema")); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	return loadSchema(c)
+}
+
+// readDocument parses the env file set by flag, e.g. target,
+// with the codec set by its format flag, e.g. --target-format, or the codec of its extension.
+func readDocument(c *cli.Context, flag string) (*envsync.Document, error) {
+	name := c.String(flag)
+	codec := envsync.CodecOf(name)
+	if format := c.String(flag + "-format"); format != "" {
+		var err error
+		if codec, err = envsync.ParseCodec(format); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc, err := envsync.DecodeDocument(file, codec)
+	if perr, ok := err.(*envsync.ParseError); ok {
+		perr.File = name
+	}
+	return doc, err
+}
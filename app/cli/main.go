@@ -1,22 +1,18 @@
 package main
 
 import (
-	"fmt"
 	"os"
+	"time"
 
 	"github.com/bukalapak/envsync"
 	"github.com/urfave/cli"
 )
 
 func main() {
-	var source string
-	var target string
-	syncer := &envsync.Syncer{}
-
 	app := cli.NewApp()
 	app.Name = "envsync"
 	app.Usage = "synchronize sample env and actual env file"
-	app.UsageText = "envsync -s [sample env] -t [actual env]"
+	app.UsageText = "envsync [command] -s [sample env] -t [actual env]"
 	app.Version = envsync.VERSION
 	app.Copyright = "Bukalapak™ © 2018"
 	app.Authors = []cli.Author{
@@ -24,28 +20,183 @@ func main() {
 			Name: "PT. Bukalapak.com",
 		},
 	}
-	app.Flags = []cli.Flag{
-		cli.StringFlag{
-			Name:        "source, s",
-			Usage:       "set sample env",
-			Value:       "env.sample",
-			Destination: &source,
-		},
-		cli.StringFlag{
-			Name:        "target, t",
-			Usage:       "set actual env",
-			Value:       ".env",
-			Destination: &target,
-		},
+	// without a command, envsync synchronizes like it always did
+	app.Flags = syncFlags
+	app.Action = syncAction
+	app.Commands = []cli.Command{
+		syncCommand,
+		initCommand,
+		diffCommand,
+		checkCommand,
+		validateCommand,
+		fmtCommand,
+		scanCommand,
+		lintCommand,
+		treeCommand,
+		watchCommand,
+		historyCommand,
+		rollbackCommand,
+		undoCommand,
 	}
-	app.Action = func(c *cli.Context) error {
-		err := syncer.Sync(source, target)
-		if err == nil {
-			fmt.Println("source and target are successfully synchronized")
-		} else {
-			fmt.Println(err.Error())
+	if err := app.Run(os.Args); err != nil {
+		os.Exit(1)
+	}
+}
+
+var sourceFlag = cli.StringFlag{
+	Name:  "source, s",
+	Usage: "set sample env",
+	Value: "env.sample",
+}
+
+var targetFlag = cli.StringFlag{
+	Name:  "target, t",
+	Usage: "set actual env",
+	Value: ".env",
+}
+
+var fileFlags = []cli.Flag{
+	sourceFlag,
+	targetFlag,
+	sourceFormatFlag,
+	targetFormatFlag,
+}
+
+// sourceFormatFlag and targetFormatFlag set the codec of files whose extension doesn't tell it.
+var sourceFormatFlag = cli.StringFlag{
+	Name:  "source-format",
+	Usage: "read sample env as dotenv, yaml, json, toml, properties, ini, systemd, tfvars, or shell instead of following its extension",
+}
+
+var targetFormatFlag = cli.StringFlag{
+	Name:  "target-format",
+	Usage: "read and write actual env as dotenv, yaml, json, toml, properties, ini, systemd, tfvars, or shell instead of following its extension",
+}
+
+// codecs returns the codecs of the files of pairs set by --source-format and --target-format.
+func codecs(c *cli.Context, pairs ...configPair) (map[string]envsync.Codec, error) {
+	res := make(map[string]envsync.Codec)
+	for _, flag := range []string{"source-format", "target-format"} {
+		if c.String(flag) == "" {
+			continue
+		}
+		codec, err := envsync.ParseCodec(c.String(flag))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pairs {
+			name := p.Source
+			if flag == "target-format" {
+				name = p.Target
+			}
+			res[name] = codec
 		}
-		return err
 	}
-	app.Run(os.Args)
+	return res, nil
+}
+
+// fileCodecs returns the codecs of the files of the --source and --target flags of fileFlags,
+// set by --source-format and --target-format.
+func fileCodecs(c *cli.Context) (map[string]envsync.Codec, error) {
+	return codecs(c, configPair{Source: c.String("source"), Target: c.String("target")})
+}
+
+// compareFlags configure how env is compared.
+var compareFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "include, i",
+		Usage: "only compare keys matching the pattern, e.g. \"FEATURE_*\" or \"/^FEATURE_/\", can be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude, x",
+		Usage: "ignore keys matching the pattern, can be repeated",
+	},
+	cli.BoolFlag{
+		Name:  "expand",
+		Usage: "expand ${KEY} references in values before comparing them",
+	},
+	duplicatesFlag,
+	jsonFlag,
+	colorFlag,
+	maskFlag,
+	showSecretsFlag,
+	configFlag,
+	ignoreFileFlag,
+	ageIdentityFlag,
+	retriesFlag,
+	timeoutFlag,
+}
+
+// patchFlag makes a command print the changes to actual env as a unified diff.
+var patchFlag = cli.BoolFlag{
+	Name:  "patch",
+	Usage: "print the changes to actual env as a unified diff",
+}
+
+// jsonFlag makes a command print its result as JSON instead of text.
+var jsonFlag = cli.BoolFlag{
+	Name:  "json",
+	Usage: "print the result as JSON, for scripts",
+}
+
+// maskFlag and showSecretsFlag decide which values are replaced by **** in output.
+var maskFlag = cli.StringSliceFlag{
+	Name:  "mask",
+	Usage: "hide the values of keys matching the pattern instead of \"*_SECRET\", \"*_TOKEN\", and \"*_PASSWORD\", can be repeated",
+}
+
+var showSecretsFlag = cli.BoolFlag{
+	Name:  "show-secrets",
+	Usage: "show secret values in output instead of ****",
+}
+
+// newMasker returns the Masker hiding secret values in output, or nil with --show-secrets.
+func newMasker(c *cli.Context) (*envsync.Masker, error) {
+	if c.Bool("show-secrets") {
+		return nil, nil
+	}
+	return envsync.NewMasker(c.StringSlice("mask")...)
+}
+
+// ignoreFileFlag sets the file listing the keys and files left untouched.
+var ignoreFileFlag = cli.StringFlag{
+	Name:  "ignore-file",
+	Usage: "leave the keys and files listed in the file untouched, like .gitignore",
+	Value: envsync.DefaultIgnoreFile,
+}
+
+// loadIgnore reads the ignore file, or returns nil if the default one doesn't exist.
+func loadIgnore(c *cli.Context) (*envsync.Ignore, error) {
+	name := c.String("ignore-file")
+	if name == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(name); os.IsNotExist(err) && !c.IsSet("ignore-file") {
+		return nil, nil
+	}
+	return envsync.LoadIgnore(name)
+}
+
+// ageIdentityFlag sets the files of the identities decrypting env files encrypted with age.
+var ageIdentityFlag = cli.StringSliceFlag{
+	Name:  "age-identity",
+	Usage: "decrypt env encrypted with age with the identities in the file, e.g. written by age-keygen, can be repeated",
+}
+
+// retriesFlag and timeoutFlag make reading and writing remote env resist a flaky network.
+var retriesFlag = cli.IntFlag{
+	Name:  "retries",
+	Usage: "try reading or writing remote env again up to n times when it fails on a timeout or an unavailable server",
+	Value: 2,
+}
+
+var timeoutFlag = cli.DurationFlag{
+	Name:  "timeout",
+	Usage: "give up each attempt to read or write remote env after the duration, e.g. 30s, 0 for no timeout",
+	Value: 30 * time.Second,
+}
+
+var duplicatesFlag = cli.StringFlag{
+	Name:  "duplicates",
+	Usage: "handle keys declared more than once by keep-last, keep-first, warn, or error (default: \"keep-last\")",
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+// defaultSource is scanned when no --source is given.
+const defaultSource = "env.sample"
+
+var scanCommand = cli.Command{
+	Name:  "scan",
+	Usage: "fail if sample env holds values that look like real secrets",
+	Description: "scan flags AWS access keys, JSON Web Tokens, private keys, and other high-entropy values in sample env,\n" +
+		"   so they aren't committed as samples. It exits with status 0 when nothing is found,\n" +
+		"   1 when a secret is found, and 2 when the files can't be read.",
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "source, s",
+			Usage: "set sample env, can be repeated (default: \"" + defaultSource + "\")",
+		},
+		cli.StringSliceFlag{
+			Name:  "ignore",
+			Usage: "never flag the value of the key, can be repeated",
+		},
+	},
+	Action: scanAction,
+}
+
+func scanAction(c *cli.Context) error {
+	sources := c.StringSlice("source")
+	if len(sources) == 0 {
+		sources = []string{defaultSource}
+	}
+	rule := envsync.SecretRule{Ignore: c.StringSlice("ignore")}
+
+	found := false
+	for _, source := range sources {
+		violations, err := envsync.LintFile(source, rule)
+		if err != nil {
+			return cli.NewExitError(err.Error(), checkError)
+		}
+		for _, v := range violations {
+			fmt.Printf("%s: %s\n", source, v)
+			found = true
+		}
+	}
+	if found {
+		return cli.NewExitError("", checkDrift)
+	}
+
+	fmt.Println("no secret found in sample env")
+	return nil
+}
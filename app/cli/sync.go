@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+// defaultTarget is used when no --target is given.
+// It isn't the flag value since a repeated flag would append to it.
+const defaultTarget = ".env"
+
+var syncFlags = append([]cli.Flag{
+	sourceFlag,
+	cli.StringSliceFlag{
+		Name:  "target, t",
+		Usage: "set actual env, can be repeated to sync into several actual envs (default: \"" + defaultTarget + "\")",
+	},
+	sourceFormatFlag,
+	targetFormatFlag,
+	cli.StringSliceFlag{
+		Name:  "layer, l",
+		Usage: "merge another sample env over the sample env, can be repeated",
+	},
+	cli.BoolFlag{
+		Name:  "interactive",
+		Usage: "ask for the value of each env added, enter accepts the sample value and \"-\" skips it",
+	},
+}, syncOptionFlags...)
+
+// syncOptionFlags configure the Syncer made by newSyncer.
+var syncOptionFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "dry-run, d",
+		Usage: "show env that would be added without writing target",
+	},
+	cli.BoolFlag{
+		Name:  "prune, p",
+		Usage: "remove env in actual env that doesn't exist in sample env",
+	},
+	cli.BoolFlag{
+		Name:  "overwrite, o",
+		Usage: "replace actual env value with sample env value for keys in both",
+	},
+	cli.StringFlag{
+		Name:  "merge",
+		Usage: "resolve env with different values by keep-target, keep-source, prompt, fail, or three-way (default: \"keep-target\")",
+	},
+	cli.StringFlag{
+		Name:  "overwrite-pattern",
+		Usage: "limit --overwrite and --merge to keys matching the pattern, e.g. \"FEATURE_*\"",
+	},
+	cli.StringFlag{
+		Name:  "order",
+		Usage: "place added env in alphabetical, source, or none order",
+		Value: "alphabetical",
+	},
+	cli.StringSliceFlag{
+		Name:  "include, i",
+		Usage: "only sync keys matching the pattern, e.g. \"FEATURE_*\" or \"/^FEATURE_/\", can be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "exclude, x",
+		Usage: "leave keys matching the pattern untouched, can be repeated",
+	},
+	cli.StringSliceFlag{
+		Name:  "rename",
+		Usage: "rename the deprecated key in actual env, e.g. \"DB_URL=DATABASE_URL\", can be repeated",
+	},
+	cli.StringFlag{
+		Name:  "strict",
+		Usage: "warn about or error on env in actual env that doesn't exist in sample env (default: \"off\")",
+	},
+	cli.StringFlag{
+		Name:  "placeholder",
+		Usage: "add env with an empty sample value as empty, marker (<CHANGE_ME>), prompt, or fail (default: \"empty\")",
+	},
+	cli.BoolFlag{
+		Name:  "template",
+		Usage: "evaluate {{ }} templates in sample env values when they are added, e.g. {{uuid}} or {{randAlphaNum 24}}",
+	},
+	cli.BoolFlag{
+		Name:  "expand",
+		Usage: "expand ${KEY} references in values before comparing them, values are written unexpanded",
+	},
+	cli.BoolFlag{
+		Name:  "inline-comments",
+		Usage: "treat \" #\" after an unquoted value as the start of a comment",
+	},
+	duplicatesFlag,
+	cli.BoolFlag{
+		Name:  "lint",
+		Usage: "warn about placeholder values left in actual env, e.g. changeme or <your-key-here>",
+	},
+	cli.BoolFlag{
+		Name:  "backup, b",
+		Usage: "copy actual env to a new backup in .envsync/backups before modifying it",
+	},
+	cli.BoolFlag{
+		Name:  "managed",
+		Usage: "sync only the env between the # envsync:start and # envsync:end comments of actual env",
+	},
+	cli.StringFlag{
+		Name:  "header",
+		Usage: "keep the comment at the top of actual env, e.g. \"managed by envsync, do not edit above this line\"",
+	},
+	cli.StringFlag{
+		Name:  "footer",
+		Usage: "keep the comment at the bottom of actual env, below added env",
+	},
+	cli.StringSliceFlag{
+		Name:  "age-recipient",
+		Usage: "write actual env encrypted with age for the recipient, e.g. age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p, can be repeated",
+	},
+	ageIdentityFlag,
+	retriesFlag,
+	timeoutFlag,
+	jsonFlag,
+	patchFlag,
+	colorFlag,
+	maskFlag,
+	showSecretsFlag,
+	configFlag,
+	ignoreFileFlag,
+}
+
+var syncCommand = cli.Command{
+	Name:   "sync",
+	Usage:  "add env in sample env that doesn't exist in actual env",
+	Flags:  syncFlags,
+	Action: syncAction,
+}
+
+func newSyncer(c *cli.Context) (*envsync.Syncer, error) {
+	if err := checkColor(c); err != nil {
+		return nil, err
+	}
+
+	s := &envsync.Syncer{
+		DryRun:           c.Bool("dry-run"),
+		Prune:            c.Bool("prune"),
+		Overwrite:        c.Bool("overwrite"),
+		OverwritePattern: c.String("overwrite-pattern"),
+		Backup:           c.Bool("backup"),
+		Include:          c.StringSlice("include"),
+		Exclude:          c.StringSlice("exclude"),
+		InlineComments:   c.Bool("inline-comments"),
+		Expand:           c.Bool("expand"),
+		Template:         c.Bool("template"),
+		Patch:            c.Bool("patch"),
+		Managed:          c.Bool("managed"),
+		Header:           c.String("header"),
+		Footer:           c.String("footer"),
+		Mask:             c.StringSlice("mask"),
+		ShowSecrets:      c.Bool("show-secrets"),
+		AgeRecipients:    c.StringSlice("age-recipient"),
+		AgeIdentities:    c.StringSlice("age-identity"),
+		Retries:          c.Int("retries"),
+		Timeout:          c.Duration("timeout"),
+	}
+	if err := parseStrategies(c, s); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if s.Renames, err = parseRenames(c); err != nil {
+		return nil, err
+	}
+	if s.Ignore, err = loadIgnore(c); err != nil {
+		return nil, err
+	}
+	if c.Bool("lint") {
+		s.Lint = append(s.Lint, envsync.PlaceholderRule{})
+	}
+
+	// stdout is kept for the result in JSON
+	var out io.Writer = os.Stdout
+	if c.Bool("json") {
+		out = os.Stderr
+	}
+	if c.Bool("interactive") || s.Placeholder == envsync.PlaceholderPrompt || s.Merge == envsync.MergePrompt {
+		s.Prompter = envsync.NewPrompter(os.Stdin, out)
+	}
+	s.Logger = warningLogger{w: out, prefixes: []string{"Duplicate env:", "Env deprecated:", "Retrying"}}
+	return s, nil
+}
+
+// parseStrategies sets the strategies of s given by the --order, --placeholder, --strict, --duplicates, and --merge flags.
+func parseStrategies(c *cli.Context, s *envsync.Syncer) error {
+	var err error
+	if c.String("order") != "" {
+		if s.Order, err = envsync.ParseOrder(c.String("order")); err != nil {
+			return err
+		}
+	}
+	if c.String("placeholder") != "" {
+		if s.Placeholder, err = envsync.ParsePlaceholder(c.String("placeholder")); err != nil {
+			return err
+		}
+	}
+	if c.String("strict") != "" {
+		if s.Strict, err = envsync.ParseStrict(c.String("strict")); err != nil {
+			return err
+		}
+	}
+	if c.String("duplicates") != "" {
+		if s.Duplicates, err = envsync.ParseDuplicate(c.String("duplicates")); err != nil {
+			return err
+		}
+	}
+	if c.String("merge") != "" {
+		if s.Merge, err = envsync.ParseMergeStrategy(c.String("merge")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRenames returns the keys renamed by the --rename flags, given as OLD_KEY=NEW_KEY.
+func parseRenames(c *cli.Context) (map[string]string, error) {
+	renames := make(map[string]string)
+	for _, r := range c.StringSlice("rename") {
+		sp := strings.SplitN(r, "=", 2)
+		if len(sp) != 2 || sp[0] == "" || sp[1] == "" {
+			return nil, fmt.Errorf("invalid rename %q, expected OLD_KEY=NEW_KEY", r)
+		}
+		renames[sp[0]] = sp[1]
+	}
+	return renames, nil
+}
+
+// warningLogger prints the messages starting with any of prefixes and drops the others,
+// since printResult reports the changes.
+type warningLogger struct {
+	w        io.Writer
+	prefixes []string
+}
+
+func (l warningLogger) Printf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	for _, p := range l.prefixes {
+		if strings.HasPrefix(msg, p) {
+			fmt.Fprintln(l.w, msg)
+			return
+		}
+	}
+}
+
+func syncAction(c *cli.Context) error {
+	cfg, err := applyConfig(c)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	syncer, err := newSyncer(c)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	// the pairs of the config file are synchronized unless the files are given on the command line
+	pairs := cfg.Pairs
+	if len(pairs) == 0 || c.IsSet("source") || c.IsSet("target") {
+		targets := c.StringSlice("target")
+		if len(targets) == 0 {
+			targets = []string{defaultTarget}
+		}
+		pairs = nil
+		for _, target := range targets {
+			pairs = append(pairs, configPair{Source: c.String("source"), Target: target})
+		}
+	}
+	if syncer.Codecs, err = codecs(c, pairs...); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	var failed error
+	var results []envsync.TargetResult
+	for _, p := range pairs {
+		prefix := ""
+		if len(pairs) > 1 {
+			prefix = p.Target + ": "
+		}
+
+		sources := append([]string{p.Source}, c.StringSlice("layer")...)
+		res, err := syncer.SyncLayered(context.Background(), sources, p.Target)
+		if err != nil {
+			failed = err
+		}
+		if c.Bool("json") {
+			results = append(results, envsync.TargetResult{Source: p.Source, Target: p.Target, Result: res, Err: err})
+			continue
+		}
+		printResult(prefix, syncer, res, err, useColor(c))
+	}
+	if c.Bool("json") {
+		if err := envsync.WriteJSON(os.Stdout, results); err != nil {
+			return err
+		}
+	}
+	return failed
+}
+
+// printResult prints the changes syncer made in res, or err, each line starting with prefix.
+// Keys are colored by change if color is set, and so is the patch in res if there is one.
+func printResult(prefix string, syncer *envsync.Syncer, res *envsync.SyncResult, err error, color bool) {
+	if err != nil {
+		fmt.Println(prefix + err.Error())
+		return
+	}
+
+	addMsg, pruneMsg, overwriteMsg, renameMsg := "New env added:", "Env removed:", "Env overwritten:", "Deprecated env renamed:"
+	dryRun := syncer.DryRun
+	if dryRun {
+		addMsg, pruneMsg, overwriteMsg, renameMsg = "Env would be added:", "Env would be removed:", "Env would be overwritten:", "Deprecated env would be renamed:"
+	}
+	for _, k := range envsync.Env(res.Renamed).Keys() {
+		fmt.Println(prefix+paint(renameMsg, colorCyan, color), k, "to", res.Renamed[k])
+	}
+	for _, k := range res.Added {
+		fmt.Println(prefix+paint(addMsg, colorGreen, color), k)
+	}
+	for _, k := range res.Pruned {
+		fmt.Println(prefix+paint(pruneMsg, colorRed, color), k)
+	}
+	for _, k := range res.Overwritten {
+		fmt.Println(prefix+paint(overwriteMsg, colorYellow, color), k)
+	}
+	if syncer.Strict == envsync.StrictWarn {
+		for _, k := range res.Extra {
+			fmt.Println(prefix+paint("Env not in source:", colorYellow, color), k)
+		}
+	}
+	for _, v := range res.Issues {
+		fmt.Println(prefix + v.String())
+	}
+	printPatch(res.Patch, color)
+
+	if dryRun {
+		fmt.Println(prefix + "dry run finished, target is left unchanged")
+	} else {
+		fmt.Println(prefix + "source and target are successfully synchronized")
+	}
+}
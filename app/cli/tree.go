@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+var treeCommand = cli.Command{
+	Name:  "tree",
+	Usage: "sync every sample env in a directory tree into the actual env next to it",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "dir",
+			Usage: "set the directory to walk",
+			Value: ".",
+		},
+		cli.StringFlag{
+			Name:  "glob, g",
+			Usage: "sync the sample envs matching the pattern instead of walking, e.g. \"services/*/.env.example\"",
+		},
+		cli.StringFlag{
+			Name:  "source-name",
+			Usage: "set the file name of sample envs",
+			Value: ".env.example",
+		},
+		cli.StringFlag{
+			Name:  "target-name",
+			Usage: "set the file name of actual envs",
+			Value: ".env",
+		},
+	}, syncOptionFlags...),
+	Action: treeAction,
+}
+
+func treeAction(c *cli.Context) error {
+	if _, err := applyConfig(c); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	syncer, err := newSyncer(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	var results []envsync.TargetResult
+	if c.String("glob") != "" {
+		results, err = syncer.SyncGlob(context.Background(), c.String("glob"), c.String("target-name"))
+	} else {
+		results, err = syncer.SyncTree(context.Background(), c.String("dir"), c.String("source-name"), c.String("target-name"))
+	}
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	var failed error
+	for _, r := range results {
+		if r.Err != nil {
+			failed = r.Err
+		}
+	}
+	if c.Bool("json") {
+		if results == nil {
+			results = []envsync.TargetResult{}
+		}
+		if err := envsync.WriteJSON(os.Stdout, results); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		return failed
+	}
+
+	if len(results) == 0 {
+		fmt.Println("no sample env found")
+	}
+	for _, r := range results {
+		printResult(r.Target+": ", syncer, r.Result, r.Err, useColor(c))
+	}
+	return failed
+}
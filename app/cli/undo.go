@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+var undoCommand = cli.Command{
+	Name:  "undo",
+	Usage: "restore actual env as it was before the most recent sync",
+	Description: "undo restores the most recent backup of actual env, so it only works for syncs run with --backup.\n" +
+		"   It asks for confirmation unless --yes is given.",
+	Flags: []cli.Flag{
+		targetFlag,
+		cli.BoolFlag{
+			Name:  "yes, y",
+			Usage: "restore without asking for confirmation",
+		},
+	},
+	Action: undoAction,
+}
+
+func undoAction(c *cli.Context) error {
+	syncer := &envsync.Syncer{}
+	target := c.String("target")
+	backups, err := syncer.History(target)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	if len(backups) == 0 {
+		err := fmt.Errorf("no backup of %s found, sync with --backup to be able to undo", target)
+		fmt.Println(err.Error())
+		return err
+	}
+
+	if !c.Bool("yes") {
+		fmt.Printf("restore %s as it was on %s? [y/N] ", target, backups[0].Time.Local().Format("2006-01-02 15:04:05"))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Println(target, "is left unchanged")
+			return nil
+		}
+	}
+
+	if err := syncer.Rollback(target, 1); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	fmt.Println(target, "is successfully restored")
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+var validateCommand = cli.Command{
+	Name:  "validate",
+	Usage: "check actual env against a schema",
+	Description: "validate doesn't modify anything. It exits with status 0 when actual env satisfies the schema,\n" +
+		"   1 when it doesn't, and 2 when the files can't be read.\n" +
+		"   With --source, the schema is declared by the annotations in sample env, e.g. \"# required\" or \"# envsync:type int\".",
+	Flags: []cli.Flag{
+		targetFlag,
+		cli.StringFlag{
+			Name:  "source, s",
+			Usage: "use the annotations in sample env as schema instead of a schema file",
+		},
+		cli.StringFlag{
+			Name:  "schema",
+			Usage: "set schema file, in YAML or JSON",
+			Value: envsync.DefaultSchemaFile,
+		},
+		sourceFormatFlag,
+		targetFormatFlag,
+		maskFlag,
+		showSecretsFlag,
+	},
+	Action: validateAction,
+}
+
+func validateAction(c *cli.Context) error {
+	schema, err := loadSchema(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	masker, err := newMasker(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+
+	target := c.String("target")
+	doc, err := readDocument(c, "target")
+	if err != nil {
+		return cli.NewExitError(err.Error(), checkError)
+	}
+	violations := masker.Violations(envsync.SchemaRule{Schema: schema}.Check(doc, nil), doc.Env())
+
+	failed := false
+	for _, v := range violations {
+		fmt.Printf("%s: %s\n", target, v)
+		failed = failed || !v.Warning
+	}
+	if failed {
+		return cli.NewExitError("", checkDrift)
+	}
+
+	fmt.Println(target, "satisfies the schema")
+	return nil
+}
+
+// loadSchema reads the schema file, or the annotations of the sample env if one is given.
+func loadSchema(c *cli.Context) (*envsync.Schema, error) {
+	if c.String("source") == "" {
+		return envsync.LoadSchema(c.String("schema"))
+	}
+
+	doc, err := readDocument(c, "source")
+	if err != nil {
+		return nil, err
+	}
+	return doc.Schema()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/bukalapak/envsync"
+	"github.com/urfave/cli"
+)
+
+var watchCommand = cli.Command{
+	Name:  "watch",
+	Usage: "sync actual env every time sample env changes",
+	Flags: append([]cli.Flag{
+		cli.DurationFlag{
+			Name:  "debounce",
+			Usage: "wait for sample env to stop changing for the duration before syncing",
+			Value: 200 * time.Millisecond,
+		},
+	}, append(fileFlags, syncOptionFlags...)...),
+	Action: watchAction,
+}
+
+func watchAction(c *cli.Context) error {
+	if _, err := applyConfig(c); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	syncer, err := newSyncer(c)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	if syncer.Codecs, err = fileCodecs(c); err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	source, target := c.String("source"), c.String("target")
+	fmt.Printf("watching %s, press Ctrl+C to stop\n", source)
+	err = syncer.Watch(ctx, source, target, c.Duration("debounce"), func(res *envsync.SyncResult, err error) {
+		printResult("", syncer, res, err, useColor(c))
+	})
+	if err == context.Canceled {
+		return nil
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	return err
+}
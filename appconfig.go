@@ -0,0 +1,171 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const appConfigAPIVersion = "1.0"
+
+// appConfigNoLabel is the label filter of the key-values without a label.
+const appConfigNoLabel = "\x00"
+
+// appConfigTarget is an Azure App Configuration store as a Target, e.g. appconfig://my-store for https://my-store.azconfig.io:
+// each key is a key-value of the store. The label query parameter limits the store to the key-values of a label,
+// e.g. ?label=prod, rather than the ones without a label, and the prefix query parameter to the keys starting with it,
+// e.g. ?prefix=myapp: for myapp:DB_HOST.
+//
+// Feature flags and Key Vault references aren't plain values, so they are left out.
+// It is authenticated with an Azure AD token like Key Vault.
+type appConfigTarget struct {
+	name   string
+	store  string
+	label  string
+	prefix string
+	client *restClient
+	env    Env
+}
+
+func openAppConfig(u *url.URL) (Target, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no store in %s", u)
+	}
+	q := u.Query()
+	store := q.Get("endpoint")
+	if store == "" {
+		store = "https://" + u.Host + ".azconfig.io"
+	}
+	store = strings.TrimRight(store, "/")
+	label := q.Get("label")
+	if strings.ContainsAny(label, "*,") {
+		return nil, fmt.Errorf("%s isn't a single label", label)
+	}
+
+	client := newAzureClient(store)
+	client.errorOf = func(status int, body []byte) error {
+		var e struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		}
+		if json.Unmarshal(body, &e) != nil || e.Title == "" {
+			return nil
+		}
+		if e.Detail != "" {
+			e.Title += ": " + e.Detail
+		}
+		return &httpError{status: status, message: e.Title}
+	}
+	return &appConfigTarget{name: u.String(), store: store, label: label, prefix: q.Get("prefix"), client: client}, nil
+}
+
+func (t *appConfigTarget) Name() string {
+	return t.name
+}
+
+func (t *appConfigTarget) Load(ctx context.Context) (*Document, error) {
+	label := t.label
+	if label == "" {
+		label = appConfigNoLabel
+	}
+	q := url.Values{"key": {t.prefix + "*"}, "label": {label}, "api-version": {appConfigAPIVersion}}
+	env := Env{}
+	next := "/kv?" + q.Encode()
+	for next != "" {
+		var out struct {
+			Items []struct {
+				Key         string `json:"key"`
+				Value       string `json:"value"`
+				ContentType string `json:"content_type"`
+			} `json:"items"`
+			NextLink string `json:"@nextLink"`
+		}
+		if err := t.send(ctx, http.MethodGet, next, nil, &out); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't list the key-values of %s", t.store))
+		}
+		for _, kv := range out.Items {
+			if strings.HasPrefix(kv.Key, ".appconfig.") || strings.HasPrefix(kv.ContentType, "application/vnd.microsoft.appconfig.") {
+				continue
+			}
+			env[strings.TrimPrefix(kv.Key, t.prefix)] = kv.Value
+		}
+		next = out.NextLink
+	}
+	t.env = env
+	return envDocument(env), nil
+}
+
+// Store sets the key-values whose value is added or changed since the target is loaded,
+// and deletes the ones of the keys that aren't in doc anymore.
+func (t *appConfigTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		if err := t.send(ctx, http.MethodPut, t.path(k), map[string]string{"value": env[k]}, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set key-value %s", t.prefix+k))
+		}
+		t.env[k] = env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := t.send(ctx, http.MethodDelete, t.path(k), nil, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't delete key-value %s", t.prefix+k))
+		}
+		delete(t.env, k)
+	}
+	return nil
+}
+
+// path returns the path of the key-value of key, in the label of the target.
+func (t *appConfigTarget) path(key string) string {
+	q := url.Values{"api-version": {appConfigAPIVersion}}
+	if t.label != "" {
+		q.Set("label", t.label)
+	}
+	return "/kv/" + url.PathEscape(t.prefix+key) + "?" + q.Encode()
+}
+
+// send is like the do of restClient, with path relative to the store unless it is a URL,
+// and with the media types of key-values App Configuration expects rather than JSON.
+func (t *appConfigTarget) send(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	u := path
+	if !strings.Contains(path, "://") {
+		u = t.store + path
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.microsoft.appconfig.kvset+json, application/vnd.microsoft.appconfig.kv+json, application/problem+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/vnd.microsoft.appconfig.kv+json")
+	}
+	_, b, err := t.client.send(ctx, req)
+	if err != nil || out == nil || len(b) == 0 {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
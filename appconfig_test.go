@@ -0,0 +1,130 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAppConfig serves the key-value API of App Configuration from memory, a key-value per page,
+// and the token endpoint of Azure AD.
+type fakeAppConfig struct {
+	url    string
+	mu     sync.Mutex
+	values map[string]string // by label and key, e.g. prod/myapp:DB_HOST
+}
+
+func (f *fakeAppConfig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+		if r.FormValue("client_secret") != "s3cret" || r.FormValue("scope") != f.url+"/.default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("api-version") != "1.0" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	label := q.Get("label")
+	if r.URL.Path == "/kv" {
+		if label == "\x00" {
+			label = ""
+		}
+		prefix := strings.TrimSuffix(q.Get("key"), "*")
+		var keys []string
+		for k := range f.values {
+			if strings.HasPrefix(k, label+"/"+prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		out := map[string]interface{}{"items": []interface{}{}}
+		after := q.Get("after")
+		for i, k := range keys {
+			if k <= after {
+				continue
+			}
+			item := map[string]string{"key": strings.SplitN(k, "/", 2)[1], "value": f.values[k], "label": label}
+			if strings.HasSuffix(k, "VAULT") {
+				item["content_type"] = "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"
+			}
+			out["items"] = []interface{}{item}
+			if i < len(keys)-1 {
+				q.Set("after", k)
+				out["@nextLink"] = "/kv?" + q.Encode()
+			}
+			break
+		}
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+
+	key := label + "/" + strings.TrimPrefix(r.URL.Path, "/kv/")
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("Content-Type") != "application/vnd.microsoft.appconfig.kv+json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		f.values[key] = in["value"]
+		w.Write([]byte("{}"))
+	case http.MethodDelete:
+		delete(f.values, key)
+		w.Write([]byte("{}"))
+	}
+}
+
+func TestSyncer_Sync_AppConfig(t *testing.T) {
+	ac := &fakeAppConfig{values: map[string]string{
+		"prod/myapp:DB_HOST": "db.example.com",
+		"prod/myapp:OLD":     "x",
+		"prod/myapp:VAULT":   `{"uri":"https://my-vault.vault.azure.net/secrets/password"}`,
+		"prod/other:TOKEN":   "t0ken",
+		"/myapp:DB_HOST":     "localhost",
+	}}
+	server := httptest.NewServer(ac)
+	defer server.Close()
+	ac.url = server.URL
+	defer setenv(map[string]string{"AZURE_TENANT_ID": "tenant", "AZURE_CLIENT_ID": "envsync", "AZURE_CLIENT_SECRET": "s3cret", "AZURE_AUTHORITY_HOST": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "appconfig://my-store?label=prod&prefix=myapp:&endpoint="+server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{
+		"prod/myapp:DB_HOST": "db.example.com",
+		"prod/myapp:DB_PORT": "5432",
+		"prod/myapp:VAULT":   `{"uri":"https://my-vault.vault.azure.net/secrets/password"}`,
+		"prod/other:TOKEN":   "t0ken",
+		"/myapp:DB_HOST":     "localhost",
+	}, ac.values)
+
+	// without a label, only the key-values without one are synchronized
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "appconfig://my-store?prefix=myapp:&endpoint="+server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, "5432", ac.values["/myapp:DB_PORT"])
+}
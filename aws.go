@@ -0,0 +1,273 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// awsCredentials are the keys requests to AWS are signed with.
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// awsClient calls the APIs of AWS services in a region, signing requests with Signature Version 4.
+type awsClient struct {
+	region   string
+	creds    awsCredentials
+	endpoint string // overrides the endpoint of every service if set, e.g. for LocalStack
+	http     *http.Client
+}
+
+// newAWSClient returns a client configured like the AWS CLI:
+// credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN,
+// or from the profile named by AWS_PROFILE, or default, in ~/.aws/credentials,
+// and the region is the region query parameter of u, AWS_REGION, AWS_DEFAULT_REGION, or the one of the profile in ~/.aws/config.
+// The endpoint query parameter of u, or AWS_ENDPOINT_URL, replaces the endpoints of AWS.
+func newAWSClient(u *url.URL) (*awsClient, error) {
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	home, _ := os.UserHomeDir()
+
+	c := &awsClient{
+		region:   u.Query().Get("region"),
+		endpoint: u.Query().Get("endpoint"),
+		creds: awsCredentials{
+			accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		http: http.DefaultClient,
+	}
+	if c.endpoint == "" {
+		c.endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+
+	if c.creds.accessKey == "" {
+		name := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+		if name == "" {
+			name = filepath.Join(home, ".aws", "credentials")
+		}
+		env, err := awsProfile(name, profile)
+		if err != nil {
+			return nil, err
+		}
+		c.creds = awsCredentials{accessKey: env["AWS_ACCESS_KEY_ID"], secretKey: env["AWS_SECRET_ACCESS_KEY"], sessionToken: env["AWS_SESSION_TOKEN"]}
+	}
+	if c.creds.accessKey == "" || c.creds.secretKey == "" {
+		return nil, errors.New("no AWS credentials found")
+	}
+
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if c.region == "" {
+			c.region = os.Getenv(name)
+		}
+	}
+	if c.region == "" {
+		name := os.Getenv("AWS_CONFIG_FILE")
+		if name == "" {
+			name = filepath.Join(home, ".aws", "config")
+		}
+		section := "profile " + profile
+		if profile == "default" {
+			section = profile
+		}
+		env, err := awsProfile(name, section)
+		if err != nil {
+			return nil, err
+		}
+		c.region = env["REGION"]
+	}
+	if c.region == "" {
+		return nil, errors.New("no AWS region set")
+	}
+	return c, nil
+}
+
+// awsProfile returns the settings of section in the AWS config or credentials file at name, their keys upper-cased,
+// e.g. AWS_ACCESS_KEY_ID for aws_access_key_id. A missing file has no setting.
+func awsProfile(name, section string) (Env, error) {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return Env{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	doc, err := decodeINI(file)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s", name))
+	}
+	prefix := iniKey(section, "")
+	env := Env{}
+	for k, v := range doc.Env() {
+		if strings.HasPrefix(k, prefix) {
+			env[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	return env, nil
+}
+
+// call calls action of the JSON API of service, e.g. AmazonSSM.PutParameter of ssm, decoding its response into out.
+func (c *awsClient) call(ctx context.Context, service, action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := c.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, c.region)
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", action)
+	c.sign(req, body, service, time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(b, &e)
+		if i := strings.LastIndex(e.Type, "#"); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return &awsError{code: e.Type, message: e.Message, status: resp.StatusCode}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+// send signs req, whose body is body, for service and sends it, returning the header and the body of the response,
+// e.g. for the REST API of S3. A response whose status isn't 2xx is an *awsError,
+// with the code and message of its XML error document if it has one.
+func (c *awsClient) send(ctx context.Context, req *http.Request, body []byte, service string) (http.Header, []byte, error) {
+	req = req.WithContext(ctx)
+	c.sign(req, body, service, time.Now())
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		xml.Unmarshal(b, &e)
+		return nil, nil, &awsError{code: e.Code, message: e.Message, status: resp.StatusCode}
+	}
+	return resp.Header, b, nil
+}
+
+// awsError is an error returned by an AWS API, e.g. ParameterNotFound.
+type awsError struct {
+	code    string
+	message string
+	status  int
+}
+
+func (e *awsError) Error() string {
+	if e.code == "" {
+		return fmt.Sprintf("AWS responded %d %s", e.status, http.StatusText(e.status))
+	}
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+// sign signs req, whose body is body, for service with Signature Version 4 at t.
+func (c *awsClient) sign(req *http.Request, body []byte, service string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	canonicalRequest := strings.Join([]string{req.Method, path, query, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := strings.Join([]string{date, c.region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + c.creds.secretKey)
+	for _, s := range []string{date, c.region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.creds.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
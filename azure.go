@@ -0,0 +1,85 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	azureAuthorityHost = "https://login.microsoftonline.com"
+	azureIMDSURL       = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// azureError is an error returned by an Azure API, e.g. SecretNotFound.
+type azureError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	status  int
+}
+
+func (e *azureError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// newAzureClient returns a client of an Azure API authenticated with an Azure AD token of resource, e.g. https://vault.azure.net.
+func newAzureClient(resource string) *restClient {
+	var tokens tokenCache
+	return &restClient{
+		auth: bearer(func(ctx context.Context) (string, error) {
+			token, err := tokens.get(func() (string, time.Duration, error) {
+				return azureAccessToken(ctx, resource)
+			})
+			return token, errors.Wrap(err, "couldn't get Azure credentials")
+		}),
+		errorOf: func(status int, body []byte) error {
+			var e struct {
+				Error *azureError `json:"error"`
+			}
+			if json.Unmarshal(body, &e) != nil || e.Error == nil || e.Error.Code == "" {
+				return nil
+			}
+			e.Error.status = status
+			return e.Error
+		},
+	}
+}
+
+// azureAccessToken returns an Azure AD token of resource, and the time it is valid for,
+// found like the DefaultAzureCredential of the Azure SDK: the service principal of AZURE_TENANT_ID, AZURE_CLIENT_ID,
+// and AZURE_CLIENT_SECRET, the login of the Azure CLI, or the managed identity of the machine.
+func azureAccessToken(ctx context.Context, resource string) (string, time.Duration, error) {
+	if os.Getenv("AZURE_CLIENT_SECRET") != "" {
+		authority := os.Getenv("AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = azureAuthorityHost
+		}
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {os.Getenv("AZURE_CLIENT_ID")},
+			"client_secret": {os.Getenv("AZURE_CLIENT_SECRET")},
+			"scope":         {resource + "/.default"},
+		}
+		u := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimRight(authority, "/"), os.Getenv("AZURE_TENANT_ID"))
+		return requestToken(ctx, u, form, nil)
+	}
+
+	var cli struct {
+		AccessToken string `json:"accessToken"`
+	}
+	out, err := exec.CommandContext(ctx, "az", "account", "get-access-token", "--resource", resource, "-o", "json").Output()
+	if err == nil && json.Unmarshal(out, &cli) == nil && cli.AccessToken != "" {
+		// the token is asked again after a while rather than parsing the local time the CLI gives as expiresOn
+		return cli.AccessToken, 5 * time.Minute, nil
+	}
+
+	u := azureIMDSURL + "?" + url.Values{"api-version": {"2018-02-01"}, "resource": {resource}}.Encode()
+	return requestToken(ctx, u, nil, map[string]string{"Metadata": "true"})
+}
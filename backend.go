@@ -0,0 +1,139 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Backend opens the store at u as a Target, e.g. the parameters under /myapp/prod/ for ssm:///myapp/prod/.
+// The store is only reached once the target is loaded or stored.
+type Backend func(u *url.URL) (Target, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{
+		"appconfig":      openAppConfig,
+		"consul":         openConsul,
+		"docker":         openDocker,
+		"doppler":        openDoppler,
+		"env":            openEnviron,
+		"etcd":           openEtcd,
+		"gcpsm":          openGCPSecrets,
+		"git":            openGit,
+		"github":         openGitHub,
+		"gitlab":         openGitLab,
+		"gs":             openGCS,
+		"heroku":         openHeroku,
+		"http":           openHTTP,
+		"https":          openHTTP,
+		"infisical":      openInfisical,
+		"k8s":            openKube,
+		"keyring":        openKeyring,
+		"keyvault":       openKeyVault,
+		"op":             openOnePassword,
+		"redis":          openRedis,
+		"rediss":         openRedis,
+		"s3":             openS3,
+		"secretsmanager": openSecretsManager,
+		"ssm":            openSSM,
+		"vercel":         openVercel,
+	}
+)
+
+// RegisterBackend adds the backend of the URLs with scheme, e.g. "vault" for vault://secret/myapp.
+// Once registered, such a URL can be given as source or target to Sync and the other methods of Syncer in place of a file.
+// It is an error to register a scheme twice.
+func RegisterBackend(scheme string, b Backend) error {
+	if scheme == "" || b == nil {
+		return fmt.Errorf("invalid backend: %q", scheme)
+	}
+
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, ok := backends[scheme]; ok {
+		return fmt.Errorf("backend already registered: %s", scheme)
+	}
+	backends[scheme] = b
+	return nil
+}
+
+// fileFormat is how a backend holding an env file, e.g. an object of a bucket, decodes it:
+// with the codec of the extension of its name unless Codecs sets another, and the parse options of the Syncer opening it.
+type fileFormat struct {
+	codec Codec
+	opts  parseOptions
+}
+
+func (f *fileFormat) format() *fileFormat {
+	return f
+}
+
+// fileBackend is a Target holding an env file, which embeds its fileFormat.
+type fileBackend interface {
+	format() *fileFormat
+}
+
+// backendOf returns the backend of name if it is a URL whose scheme is registered, or nil.
+func backendOf(name string) Backend {
+	i := strings.Index(name, "://")
+	if i <= 0 {
+		return nil
+	}
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	return backends[strings.ToLower(name[:i])]
+}
+
+// open returns name as a Target: the store of its backend if it is the URL of one, or the env file at name.
+// kind is either "source" or "target".
+// A fileBackend is read like an env file, with the codec set in Codecs and the parse options of s.
+// Other backends hold key-values rather than a file, so a codec can't be set for them.
+func (s *Syncer) open(name, kind string) (Target, error) {
+	b := backendOf(name)
+	if b == nil {
+		return &fileProvider{s: s, name: name, kind: kind}, nil
+	}
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("invalid %s URL", kind))
+	}
+	t, err := b(u)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't open %s", kind))
+	}
+
+	codec, ok := s.Codecs[name]
+	if f, isFile := t.(fileBackend); isFile {
+		format := f.format()
+		if ok {
+			format.codec = codec
+		}
+		format.opts = s.parseOptions()
+	} else if ok {
+		return nil, fmt.Errorf("couldn't open %s: %s holds key-values rather than a file, so its format can't be set", kind, t.Name())
+	}
+	return t, nil
+}
+
+// load reads the document of name, the URL of a backend or an env file.
+func (s *Syncer) load(ctx context.Context, name, kind string) (*Document, error) {
+	t, err := s.open(name, kind)
+	if err != nil {
+		return nil, err
+	}
+	return s.loadProvider(ctx, t)
+}
+
+// envDocument returns a document holding env, its keys in alphabetical order, e.g. as loaded from a backend.
+func envDocument(env Env) *Document {
+	doc := &Document{}
+	for _, k := range env.Keys() {
+		doc.Append(Entry{Key: k, Value: env[k]})
+	}
+	return doc
+}
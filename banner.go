@@ -0,0 +1,73 @@
+package envsync
+
+import "strings"
+
+// bannerLines returns the comment lines of banner, each prefixed with # unless it already is.
+func bannerLines(banner string) []string {
+	if banner == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(banner, "\n"), "\n")
+	for i, l := range lines {
+		if !strings.HasPrefix(l, "#") {
+			lines[i] = "# " + l
+		}
+	}
+	return lines
+}
+
+// hasLines reports whether the lines of doc from index i are comment lines holding banner.
+func (d *Document) hasLines(i int, banner []string) bool {
+	if i < 0 || i+len(banner) > len(d.lines) {
+		return false
+	}
+	for j, b := range banner {
+		if l := d.lines[i+j]; l.kind != commentLine || l.raw != b {
+			return false
+		}
+	}
+	return true
+}
+
+// hasBanner reports whether doc starts with Header and ends with Footer.
+func (s *Syncer) hasBanner(doc *Document) bool {
+	header, footer := bannerLines(s.Header), bannerLines(s.Footer)
+	return doc.hasLines(0, header) && doc.hasLines(len(doc.lines)-len(footer), footer)
+}
+
+// removeFooter removes Footer from the end of doc, with the blank line preceding it,
+// so key-values appended to doc are placed above it once writeBanner adds it back.
+func (s *Syncer) removeFooter(doc *Document) {
+	footer := bannerLines(s.Footer)
+	if len(footer) == 0 || !doc.hasLines(len(doc.lines)-len(footer), footer) {
+		return
+	}
+	doc.lines = doc.lines[:len(doc.lines)-len(footer)]
+	if n := len(doc.lines); n > 0 && doc.lines[n-1].kind == blankLine {
+		doc.lines = doc.lines[:n-1]
+	}
+}
+
+// writeBanner adds Header at the top of doc and Footer at the bottom, each separated by a blank line,
+// unless doc already starts or ends with them.
+func (s *Syncer) writeBanner(doc *Document) {
+	if header := bannerLines(s.Header); len(header) > 0 && !doc.hasLines(0, header) {
+		lines := make([]*line, 0, len(header)+1+len(doc.lines))
+		for _, h := range header {
+			lines = append(lines, &line{kind: commentLine, raw: h})
+		}
+		if len(doc.lines) > 0 && doc.lines[0].kind != blankLine {
+			lines = append(lines, &line{kind: blankLine})
+		}
+		doc.lines = append(lines, doc.lines...)
+	}
+
+	if footer := bannerLines(s.Footer); len(footer) > 0 && !doc.hasLines(len(doc.lines)-len(footer), footer) {
+		if n := len(doc.lines); n > 0 && doc.lines[n-1].kind != blankLine {
+			doc.lines = append(doc.lines, &line{kind: blankLine})
+		}
+		for _, f := range footer {
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: f})
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package envsync_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Banner(t *testing.T) {
+	src := "HOST=localhost\nPORT=8080\n"
+
+	var buf bytes.Buffer
+	syncer := envsync.New(envsync.WithBanner("managed by envsync\ndo not edit above this line", "# end of envsync"))
+	_, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader("PORT=80\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "# managed by envsync\n# do not edit above this line\n\nPORT=80\nHOST=localhost\n\n# end of envsync\n", buf.String())
+
+	// added env is placed above the footer, and the banner isn't repeated
+	synced := buf.String()
+	buf.Reset()
+	res, err := syncer.SyncReaders(strings.NewReader(src+"DEBUG=false\n"), strings.NewReader(synced), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+	assert.Equal(t, "# managed by envsync\n# do not edit above this line\n\nPORT=80\nHOST=localhost\nDEBUG=false\n\n# end of envsync\n", buf.String())
+
+	synced = buf.String()
+	buf.Reset()
+	_, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(synced), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, synced, buf.String())
+
+	// a target in sync is changed to add a missing banner
+	syncer = envsync.New(envsync.WithBanner("", "end"), envsync.WithDryRun(), envsync.WithPatch())
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(src), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "--- a/target\n+++ b/target\n@@ -1,2 +1,4 @@\n HOST=localhost\n PORT=8080\n+\n+# end\n", res.Patch)
+}
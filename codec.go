@@ -0,0 +1,215 @@
+package envsync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Codec is the syntax of an env file.
+// Whatever the codec, an env file is read into a Document and written back in the same syntax.
+type Codec int
+
+const (
+	// CodecDotenv is the KEY=VALUE syntax of .env files.
+	// This is the default.
+	CodecDotenv Codec = iota
+
+	// CodecYAML is a flat YAML mapping, e.g. KEY: value,
+	// or a mapping with only an environment key holding one, or a list of KEY=VALUE items as in docker-compose.
+	// In a Kubernetes ConfigMap or Secret manifest, the key-values are the ones of its data block, and the rest of the manifest is kept.
+	// The values in the data block of a Secret are base64-encoded, and the ones in its stringData block aren't.
+	// Comments in a YAML file aren't read, but the comments of the Document are written.
+	// So a managed section or a banner can't be kept in it, see ErrCommentsNotKept.
+	CodecYAML
+
+	// CodecJSON is a flat JSON object, e.g. {"KEY": "value"}.
+	// Numbers and booleans are read as they are written, and written back the same way until their value changes.
+	// An empty file is an empty object. JSON has no comments, so a managed section or a banner can't be kept in it.
+	CodecJSON
+
+	// CodecTOML is a flat TOML table, e.g. KEY = "value".
+	// Comments and the lines that aren't changed are kept, like in CodecDotenv.
+	CodecTOML
+
+	// CodecProperties is a Java .properties file, e.g. key=value, key: value, or key value.
+	// Comments and the lines that aren't changed are kept, and escapes such as \uXXXX are read.
+	CodecProperties
+
+	// CodecINI is an INI file whose sections are key prefixes, e.g. host = x in [database] is DATABASE_HOST=x.
+	// Comments and the lines that aren't changed are kept. See the rules of iniKey and encodeINI.
+	CodecINI
+
+	// CodecSystemd is a systemd EnvironmentFile, e.g. KEY="value", read the way systemd reads it:
+	// ';' starts comments too, a backslash ends continued lines, and values are never expanded.
+	// It has no extension, so it is only used when it is asked for.
+	CodecSystemd
+
+	// CodecTFVars is a Terraform .tfvars file, e.g. region = "eu-west-1".
+	// Only string, number, and boolean variables are supported, and lists, maps, and objects are ErrComplexValue.
+	// Comments and the lines that aren't changed are kept.
+	CodecTFVars
+
+	// CodecShell is a shell script assigning variables, e.g. export KEY="value" in a .envrc file for direnv.
+	// Values are read like the shell does, without expanding them, and other lines are kept as they are.
+	// A changed key-value keeps its export keyword and its quotes.
+	CodecShell
+)
+
+var codecNames = map[Codec]string{
+	CodecDotenv:     "dotenv",
+	CodecYAML:       "yaml",
+	CodecJSON:       "json",
+	CodecTOML:       "toml",
+	CodecProperties: "properties",
+	CodecINI:        "ini",
+	CodecSystemd:    "systemd",
+	CodecTFVars:     "tfvars",
+	CodecShell:      "shell",
+}
+
+// codecExtensions maps the extensions of files to the codec they are read with.
+var codecExtensions = map[string]Codec{
+	".env":        CodecDotenv,
+	".yaml":       CodecYAML,
+	".yml":        CodecYAML,
+	".json":       CodecJSON,
+	".toml":       CodecTOML,
+	".properties": CodecProperties,
+	".ini":        CodecINI,
+	".tfvars":     CodecTFVars,
+	".sh":         CodecShell,
+	".envrc":      CodecShell,
+}
+
+// String returns the name of the codec.
+func (c Codec) String() string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Codec(%d)", int(c))
+}
+
+// ParseCodec returns the codec with the given name.
+// Valid names are "dotenv", "yaml", "json", "toml", "properties", "ini", "systemd", "tfvars", "shell",
+// and the names of the formats added with RegisterFormat.
+func ParseCodec(name string) (Codec, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for c, n := range codecNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return CodecDotenv, fmt.Errorf("unknown codec: %s", name)
+}
+
+// CodecOf returns the codec of the file at name, following its extension, e.g. CodecYAML for .yml.
+// Files with any other extension, such as .env or env.sample, are CodecDotenv.
+// The .age extension of files encrypted with age is left out, e.g. env.yaml.age is CodecYAML.
+func CodecOf(name string) Codec {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	if strings.EqualFold(filepath.Ext(name), ageExtension) {
+		name = name[:len(name)-len(ageExtension)]
+	}
+	if c, ok := codecExtensions[strings.ToLower(filepath.Ext(name))]; ok {
+		return c
+	}
+	return CodecDotenv
+}
+
+// DecodeDocument reads an env file written with codec from r.
+func DecodeDocument(r io.Reader, codec Codec) (*Document, error) {
+	return decodeDocument(r, codec, parseOptions{})
+}
+
+func decodeDocument(r io.Reader, codec Codec, opts parseOptions) (*Document, error) {
+	switch codec {
+	case CodecDotenv:
+		return parseDocument(r, opts)
+	case CodecYAML:
+		return decodeYAML(r)
+	case CodecJSON:
+		return decodeJSON(r)
+	case CodecTOML:
+		return decodeTOML(r)
+	case CodecProperties:
+		return decodeProperties(r)
+	case CodecINI:
+		return decodeINI(r)
+	case CodecSystemd:
+		return decodeSystemd(r)
+	case CodecTFVars:
+		return decodeTFVars(r)
+	case CodecShell:
+		return decodeShell(r)
+	}
+	if custom := customCodec(codec); custom != nil {
+		return decodeCustom(r, codec, custom)
+	}
+	return nil, fmt.Errorf("unknown codec: %s", codec)
+}
+
+// encode writes d to w with its codec.
+func (d *Document) encode(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	switch d.codec {
+	case CodecYAML:
+		d.encodeYAML(&buf)
+	case CodecJSON:
+		d.encodeJSON(&buf)
+	case CodecTOML:
+		d.encodeTOML(&buf)
+	case CodecProperties:
+		d.encodeProperties(&buf)
+	case CodecINI:
+		d.encodeINI(&buf)
+	case CodecSystemd:
+		d.encodeSystemd(&buf)
+	case CodecTFVars:
+		d.encodeTFVars(&buf)
+	case CodecShell:
+		d.encodeShell(&buf)
+	default:
+		custom := customCodec(d.codec)
+		if custom == nil {
+			return 0, fmt.Errorf("unknown codec: %s", d.codec)
+		}
+		if err := d.encodeCustom(&buf, custom); err != nil {
+			return 0, err
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// recode returns a copy of d to be written with codec.
+// Key-values are encoded again rather than written as they are read, comments are rewritten as # comments,
+// and the lines codec can't hold, e.g. the sections of an INI file, are dropped.
+func (d *Document) recode(codec Codec) *Document {
+	if d.codec == codec {
+		return d.clone()
+	}
+	c := &Document{codec: codec}
+	for _, l := range d.lines {
+		switch l.kind {
+		case entryLine:
+			e := l.clone()
+			e.raw = ""
+			c.lines = append(c.lines, e)
+		case commentLine:
+			text := strings.TrimSpace(l.raw)
+			if !strings.HasPrefix(text, "#") {
+				text = strings.TrimSpace("# " + strings.TrimLeft(text, ";!/*"))
+			}
+			c.lines = append(c.lines, &line{kind: commentLine, raw: text})
+		case blankLine:
+			c.lines = append(c.lines, &line{kind: blankLine})
+		}
+	}
+	return c
+}
@@ -0,0 +1,347 @@
+package envsync_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecOf(t *testing.T) {
+	assert.Equal(t, envsync.CodecDotenv, envsync.CodecOf(".env"))
+	assert.Equal(t, envsync.CodecDotenv, envsync.CodecOf("env.sample"))
+	assert.Equal(t, envsync.CodecYAML, envsync.CodecOf("config/env.yaml"))
+	assert.Equal(t, envsync.CodecYAML, envsync.CodecOf("docker-compose.YML"))
+
+	c, err := envsync.ParseCodec("yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.CodecYAML, c)
+	assert.Equal(t, "yaml", c.String())
+	_, err = envsync.ParseCodec("xml")
+	assert.NotNil(t, err)
+}
+
+func TestDecodeDocument_YAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		env  envsync.Env
+		out  string
+	}{
+		{
+			name: "flat",
+			in:   "HOST: localhost\nPORT: 8080\nGREETING: \"hello world\"\n",
+			env:  envsync.Env{"HOST": "localhost", "PORT": "8080", "GREETING": "hello world"},
+			out:  "HOST: localhost\nPORT: 8080\nGREETING: \"hello world\"\n",
+		},
+		{
+			name: "environment mapping",
+			in:   "environment:\n  HOST: localhost\n  EMPTY:\n",
+			env:  envsync.Env{"HOST": "localhost", "EMPTY": ""},
+			out:  "environment:\n  HOST: localhost\n  EMPTY: \"\"\n",
+		},
+		{
+			name: "environment list",
+			in:   "environment:\n  - HOST=localhost\n  - URL=http://x?a=b c\n",
+			env:  envsync.Env{"HOST": "localhost", "URL": "http://x?a=b c"},
+			out:  "environment:\n  - HOST=localhost\n  - \"URL=http://x?a=b c\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		doc, err := envsync.DecodeDocument(strings.NewReader(tt.in), envsync.CodecYAML)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.env, doc.Env(), tt.name)
+		assert.Equal(t, tt.out, doc.String(), tt.name)
+	}
+}
+
+func TestDocument_WriteTo_YAML(t *testing.T) {
+	doc, err := envsync.DecodeDocument(strings.NewReader("environment: []\n"), envsync.CodecYAML)
+	assert.Nil(t, err)
+	assert.Equal(t, "environment: []\n", doc.String())
+
+	doc.Append(envsync.Entry{Key: "NAME", Value: "null", Comments: []string{"# the name"}})
+	doc.Set("EMPTY", "")
+	var buf bytes.Buffer
+	_, err = doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "environment:\n  # the name\n  - NAME=null\n  - EMPTY=\n", buf.String())
+
+	doc, err = envsync.DecodeDocument(strings.NewReader("A: x\n"), envsync.CodecYAML)
+	assert.Nil(t, err)
+	doc.Set("NAME", "null")
+	doc.Set("MULTI", "a\nb")
+	assert.Equal(t, "A: x\nNAME: \"null\"\nMULTI: \"a\\nb\"\n", doc.String())
+}
+
+func TestSyncer_Sync_YAML(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\nPORT=8080\n",
+		"env.yml":    "environment:\n  PORT: 80\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := &envsync.Syncer{}
+	err := syncer.Sync(filepath.Join(dir, "env.sample"), filepath.Join(dir, "env.yml"))
+	assert.Nil(t, err)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "env.yml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "environment:\n  PORT: 80\n  HOST: localhost\n", string(b))
+}
+
+func TestDecodeDocument_JSON(t *testing.T) {
+	in := "{\"HOST\": \"localhost\", \"PORT\": 8080, \"DEBUG\": false, \"NAME\": null, \"URL\": \"http://x?a=b&c=d\"}"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "PORT": "8080", "DEBUG": "false", "NAME": "", "URL": "http://x?a=b&c=d"}, doc.Env())
+	assert.Equal(t, "{\n  \"HOST\": \"localhost\",\n  \"PORT\": 8080,\n  \"DEBUG\": false,\n  \"NAME\": \"\",\n  \"URL\": \"http://x?a=b&c=d\"\n}\n", doc.String())
+
+	// a changed number is written as a string, and comments are dropped
+	doc.Set("PORT", "80")
+	doc.Append(envsync.Entry{Key: "NEW", Value: "1", Comments: []string{"# new"}})
+	doc.Delete("URL")
+	assert.Equal(t, "{\n  \"HOST\": \"localhost\",\n  \"PORT\": \"80\",\n  \"DEBUG\": false,\n  \"NAME\": \"\",\n  \"NEW\": \"1\"\n}\n", doc.String())
+
+	for _, in := range []string{"{}", "", " \n"} {
+		doc, err = envsync.DecodeDocument(strings.NewReader(in), envsync.CodecJSON)
+		assert.Nil(t, err, in)
+		assert.Equal(t, "{}\n", doc.String(), in)
+	}
+
+	for _, in := range []string{"[]", "{\"DB\": {\"HOST\": \"x\"}}", "{\"A\": \"x\""} {
+		_, err = envsync.DecodeDocument(strings.NewReader(in), envsync.CodecJSON)
+		assert.NotNil(t, err, in)
+	}
+}
+
+func TestSyncer_Sync_Codecs(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\nPORT=8080\n",
+		"env.out":    "{\"PORT\": 80}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "env.out")
+	syncer := envsync.New(envsync.WithCodec(target, envsync.CodecJSON))
+	err := syncer.Sync(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+
+	b, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"PORT\": 80,\n  \"HOST\": \"localhost\"\n}\n", string(b))
+}
+
+func TestSyncer_Sync_Convert(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"app.properties": "! the host\nhost: localhost\nport = 8080\n",
+		".env":           "port=80\n",
+	})
+	defer os.RemoveAll(dir)
+
+	// key-values of another format are written the way of target, not copied as they are read
+	target := filepath.Join(dir, ".env")
+	err := envsync.New().Sync(filepath.Join(dir, "app.properties"), target)
+	assert.Nil(t, err)
+
+	b, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "port=80\nhost=localhost\n", string(b))
+
+	target = filepath.Join(dir, "env.json")
+	_, err = envsync.New().Init(context.Background(), filepath.Join(dir, "app.properties"), target)
+	assert.Nil(t, err)
+
+	b, err = ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"host\": \"localhost\",\n  \"port\": \"8080\"\n}\n", string(b))
+}
+
+func TestDecodeDocument_TOML(t *testing.T) {
+	in := "# the app\nHOST = \"localhost\" # the host\nPORT=8080\n\n\"MY KEY\" = 'C:\\path'\nDEBUG = true\nSTART = 1979-05-27 07:32:00Z\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecTOML)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "PORT": "8080", "MY KEY": `C:\path`, "DEBUG": "true", "START": "1979-05-27 07:32:00Z"}, doc.Env())
+	assert.Equal(t, in, doc.String())
+
+	doc.Set("PORT", "80")
+	doc.Set("HOST", "a \"b\"\n")
+	doc.Append(envsync.Entry{Key: "NEW", Value: "x", InlineComment: " # new"})
+	assert.Equal(t, "# the app\nHOST = \"a \\\"b\\\"\\n\" # the host\nPORT = \"80\"\n\n\"MY KEY\" = 'C:\\path'\nDEBUG = true\nSTART = 1979-05-27 07:32:00Z\nNEW = \"x\" # new\n", doc.String())
+
+	for in, reason := range map[string]error{
+		"[database]\n":      envsync.ErrUnsupportedSyntax,
+		"HOSTS = [\"a\"]\n": envsync.ErrUnsupportedSyntax,
+		"db.host = \"x\"\n": envsync.ErrUnsupportedSyntax,
+		"KEY = \"\"\"\nx\n": envsync.ErrUnsupportedSyntax,
+		"HOST \"x\"\n":      envsync.ErrMissingSeparator,
+		"HOST = \"x\n":      envsync.ErrUnterminatedQuote,
+		"HOST = \"x\" y\n":  envsync.ErrUnexpectedText,
+	} {
+		_, err = envsync.DecodeDocument(strings.NewReader(in), envsync.CodecTOML)
+		perr, ok := err.(*envsync.ParseError)
+		if assert.True(t, ok, in) {
+			assert.Equal(t, reason, perr.Err, in)
+			assert.Equal(t, 1, perr.Line, in)
+		}
+	}
+}
+
+func TestDecodeDocument_Properties(t *testing.T) {
+	in := "# the app\n! legacy comment\ndb.host = localhost\ndb.port:5432\ngreeting Hello, \\\n    World\nname=Caf\\u00e9 \\ud83d\\ude00\npath=C\\:\\\\tmp\\ttab\nempty\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecProperties)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{
+		"db.host":  "localhost",
+		"db.port":  "5432",
+		"greeting": "Hello, World",
+		"name":     "Café 😀",
+		"path":     "C:\\tmp\ttab",
+		"empty":    "",
+	}, doc.Env())
+	assert.Equal(t, in, doc.String())
+
+	doc.Set("greeting", "Hi\nthere")
+	doc.Set("name", "Zoë")
+	doc.Append(envsync.Entry{Key: "my key", Value: " leading=space"})
+	assert.Equal(t, "# the app\n! legacy comment\ndb.host = localhost\ndb.port:5432\ngreeting=Hi\\nthere\nname=Zo\\u00eb\npath=C\\:\\\\tmp\\ttab\nempty\nmy\\ key=\\ leading=space\n", doc.String())
+
+	_, err = envsync.DecodeDocument(strings.NewReader("# ok\nkey=\\u12\n"), envsync.CodecProperties)
+	perr, ok := err.(*envsync.ParseError)
+	if assert.True(t, ok) {
+		assert.Equal(t, envsync.ErrInvalidEscape, perr.Err)
+		assert.Equal(t, 2, perr.Line)
+	}
+}
+
+func TestDecodeDocument_INI(t *testing.T) {
+	in := "; global\ndebug = true\n\n[database]\nhost = localhost\nport: 5432\n\n[my-app.cache]\nttl=\"1 h\"\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecINI)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"DEBUG": "true", "DATABASE_HOST": "localhost", "DATABASE_PORT": "5432", "MY_APP_CACHE_TTL": "1 h"}, doc.Env())
+	assert.Equal(t, in, doc.String())
+
+	// added key-values are written in the section of their prefix, or before any section
+	doc.Set("DATABASE_PORT", "5433")
+	doc.Set("DATABASE_USER", "app")
+	doc.Set("MY_APP_CACHE_SIZE", "10")
+	doc.Set("REDIS_URL", "redis://localhost")
+	out := "; global\ndebug = true\nREDIS_URL = redis://localhost\n\n[database]\nhost = localhost\nport = 5433\nuser = app\n\n[my-app.cache]\nttl=\"1 h\"\nsize = 10\n"
+	assert.Equal(t, out, doc.String())
+
+	doc, err = envsync.DecodeDocument(strings.NewReader(out), envsync.CodecINI)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{
+		"DEBUG":             "true",
+		"REDIS_URL":         "redis://localhost",
+		"DATABASE_HOST":     "localhost",
+		"DATABASE_PORT":     "5433",
+		"DATABASE_USER":     "app",
+		"MY_APP_CACHE_TTL":  "1 h",
+		"MY_APP_CACHE_SIZE": "10",
+	}, doc.Env())
+
+	for in, reason := range map[string]error{
+		"[database\n": envsync.ErrUnsupportedSyntax,
+		"host\n":      envsync.ErrMissingSeparator,
+		"host=\"x\n":  envsync.ErrUnterminatedQuote,
+	} {
+		_, err = envsync.DecodeDocument(strings.NewReader(in), envsync.CodecINI)
+		perr, ok := err.(*envsync.ParseError)
+		if assert.True(t, ok, in) {
+			assert.Equal(t, reason, perr.Err, in)
+		}
+	}
+}
+
+func TestDecodeDocument_Systemd(t *testing.T) {
+	in := "; managed by ops\n# app\nHOST = localhost  \nGREETING=\"Hello \\\"world\\\" \\d $HOME\"\nPATTERN='C:\\'\nLONG=one \\\ntwo\nMULTI=\"a\nb\"\nHASH=a#b\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecSystemd)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{
+		"HOST":     "localhost",
+		"GREETING": `Hello "world" \d $HOME`,
+		"PATTERN":  `C:\`,
+		"LONG":     "one two",
+		"MULTI":    "a\nb",
+		"HASH":     "a#b",
+	}, doc.Env())
+	assert.Equal(t, in, doc.String())
+
+	doc.Set("HOST", "example.com")
+	doc.Set("PATTERN", "it's")
+	doc.Set("MULTI", "$x\ny")
+	doc.Append(envsync.Entry{Key: "NEW", Value: " padded ", Export: true})
+	assert.Equal(t, "; managed by ops\n# app\nHOST=example.com\nGREETING=\"Hello \\\"world\\\" \\d $HOME\"\nPATTERN=\"it's\"\nLONG=one \\\ntwo\nMULTI=\"\\$x\ny\"\nHASH=a#b\nNEW=\" padded \"\n", doc.String())
+
+	doc, err = envsync.DecodeDocument(strings.NewReader("A=x\\"), envsync.CodecSystemd)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"A": "x"}, doc.Env())
+
+	_, err = envsync.DecodeDocument(strings.NewReader("A=\"x\n"), envsync.CodecSystemd)
+	perr, ok := err.(*envsync.ParseError)
+	if assert.True(t, ok) {
+		assert.Equal(t, envsync.ErrUnterminatedQuote, perr.Err)
+	}
+}
+
+func TestDecodeDocument_TFVars(t *testing.T) {
+	in := "# region\nregion   = \"eu-west-1\" // primary\ninstances = 3\n/* legacy\n   settings */\ndebug = false\nowner = null\nprompt = \"$${USER} says \\\"hi\\\"\\n\"\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecTFVars)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"region": "eu-west-1", "instances": "3", "debug": "false", "owner": "", "prompt": "${USER} says \"hi\"\n"}, doc.Env())
+	assert.Equal(t, in, doc.String())
+
+	doc.Set("instances", "5")
+	doc.Set("owner", "ops %{team}")
+	assert.Equal(t, "# region\nregion   = \"eu-west-1\" // primary\ninstances = \"5\"\n/* legacy\n   settings */\ndebug = false\nowner = \"ops %%{team}\"\nprompt = \"$${USER} says \\\"hi\\\"\\n\"\n", doc.String())
+
+	for in, reason := range map[string]error{
+		"zones = [\"a\", \"b\"]\n":        envsync.ErrComplexValue,
+		"tags = {\n  team = \"ops\"\n}\n": envsync.ErrComplexValue,
+		"name = \"${var.x}\"\n":           envsync.ErrUnsupportedSyntax,
+		"script = <<EOT\n":                envsync.ErrUnsupportedSyntax,
+		"name = \"x\n":                    envsync.ErrUnterminatedQuote,
+		"name\n":                          envsync.ErrMissingSeparator,
+	} {
+		_, err = envsync.DecodeDocument(strings.NewReader(in), envsync.CodecTFVars)
+		perr, ok := err.(*envsync.ParseError)
+		if assert.True(t, ok, in) {
+			assert.Equal(t, reason, perr.Err, in)
+		}
+	}
+}
+
+func TestDecodeDocument_Shell(t *testing.T) {
+	in := "#!/usr/bin/env bash\nsource_up\nexport HOST=localhost # the host\nexport GREETING=\"Hello \\\"$USER\\\" \\d\"\nPATTERN='a\\b'\nexport LONG=one\\\ntwo\nPATH_add bin\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecShell)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "GREETING": `Hello "$USER" \d`, "PATTERN": `a\b`, "LONG": "onetwo"}, doc.Env())
+	assert.Equal(t, []string{"HOST", "GREETING", "PATTERN", "LONG"}, doc.Keys())
+	assert.Equal(t, in, doc.String())
+
+	// changed key-values keep their export keyword and quotes
+	doc.Set("HOST", "example.com")
+	doc.Set("GREETING", "it's $HOME")
+	doc.Set("PATTERN", "x y")
+	doc.Append(envsync.Entry{Key: "NEW", Value: "a b", Export: true})
+	assert.Equal(t, "#!/usr/bin/env bash\nsource_up\nexport HOST=example.com # the host\nexport GREETING=\"it's \\$HOME\"\nPATTERN='x y'\nexport LONG=one\\\ntwo\nPATH_add bin\nexport NEW='a b'\n", doc.String())
+
+	for in, reason := range map[string]error{
+		"export A=\"x\n": envsync.ErrUnterminatedQuote,
+		"A=x y\n":        envsync.ErrUnexpectedText,
+	} {
+		_, err = envsync.DecodeDocument(strings.NewReader(in), envsync.CodecShell)
+		perr, ok := err.(*envsync.ParseError)
+		if assert.True(t, ok, in) {
+			assert.Equal(t, reason, perr.Err, in)
+		}
+	}
+}
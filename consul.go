@@ -0,0 +1,189 @@
+package envsync
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	consulAddr = "127.0.0.1:8500"
+
+	// consulTxnOps is the number of operations Consul allows in a transaction.
+	consulTxnOps = 64
+)
+
+// consulTarget is a prefix of the KV store of Consul as a Target, e.g. consul:///myapp/config/:
+// each key is the KV key under the prefix, e.g. myapp/config/DB_HOST. Keys in folders under the prefix are left out.
+// The agent is the host of the URL, e.g. consul://consul.example.com:8500/myapp/config/, or else CONSUL_HTTP_ADDR,
+// and the ACL token is read from CONSUL_HTTP_TOKEN. The dc query parameter sets the datacenter.
+//
+// Changes are written with check-and-set transactions, so that a key changed meanwhile fails the synchronization
+// rather than being overwritten.
+type consulTarget struct {
+	name   string
+	prefix string
+	dc     string
+	client *restClient
+
+	// indexes are the modify indexes of the keys as they are loaded, and env their values.
+	indexes map[string]uint64
+	env     Env
+}
+
+func openConsul(u *url.URL) (Target, error) {
+	addr := u.Host
+	if addr == "" {
+		addr = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if addr == "" {
+		addr = consulAddr
+	}
+	if !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if ssl, _ := strconv.ParseBool(os.Getenv("CONSUL_HTTP_SSL")); ssl {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+	prefix := strings.TrimLeft(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	token := os.Getenv("CONSUL_HTTP_TOKEN")
+	return &consulTarget{
+		name:   u.String(),
+		prefix: prefix,
+		dc:     u.Query().Get("dc"),
+		client: &restClient{
+			base: addr,
+			auth: func(_ context.Context, req *http.Request) error {
+				if token != "" {
+					req.Header.Set("X-Consul-Token", token)
+				}
+				return nil
+			},
+			errorOf: func(status int, body []byte) error {
+				var e struct {
+					Errors []struct {
+						What string
+					}
+				}
+				if json.Unmarshal(body, &e) != nil || len(e.Errors) == 0 {
+					return nil
+				}
+				var msgs []string
+				for _, err := range e.Errors {
+					msgs = append(msgs, err.What)
+				}
+				return &httpError{status: status, message: strings.Join(msgs, ", ")}
+			},
+		},
+	}, nil
+}
+
+func (t *consulTarget) Name() string {
+	return t.name
+}
+
+func (t *consulTarget) Load(ctx context.Context) (*Document, error) {
+	q := t.query()
+	q.Set("recurse", "true")
+	var pairs []struct {
+		Key         string
+		Value       []byte
+		ModifyIndex uint64
+	}
+	err := t.client.do(ctx, http.MethodGet, "/v1/kv/"+t.prefix+"?"+q.Encode(), nil, &pairs)
+	// a prefix without keys isn't found
+	if err != nil && !isHTTPStatus(err, http.StatusNotFound) {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read the keys under %s", t.prefix))
+	}
+
+	t.indexes = make(map[string]uint64)
+	t.env = Env{}
+	for _, p := range pairs {
+		key := strings.TrimPrefix(p.Key, t.prefix)
+		if key == "" || strings.Contains(key, "/") {
+			continue
+		}
+		t.indexes[key] = p.ModifyIndex
+		t.env[key] = string(p.Value)
+	}
+	return envDocument(t.env), nil
+}
+
+// Store sets the keys whose value is added or changed since the target is loaded,
+// and deletes the keys that aren't in doc anymore, in transactions of up to 64 operations.
+func (t *consulTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	var ops []map[string]interface{}
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		// a check-and-set with index 0 only sets a key that doesn't exist
+		ops = append(ops, t.op("cas", k, base64.StdEncoding.EncodeToString([]byte(env[k]))))
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; !ok {
+			ops = append(ops, t.op("delete-cas", k, ""))
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > consulTxnOps {
+			n = consulTxnOps
+		}
+		if err := t.txn(ctx, ops[:n]); err != nil {
+			return err
+		}
+		ops = ops[n:]
+	}
+	// the target is loaded again for the modify indexes of the keys just written
+	_, err := t.Load(ctx)
+	return err
+}
+
+// op returns a KV operation of a transaction on key, checked against the modify index it is loaded with.
+func (t *consulTarget) op(verb, key, value string) map[string]interface{} {
+	kv := map[string]interface{}{"Verb": verb, "Key": t.prefix + key, "Index": t.indexes[key]}
+	if value != "" {
+		kv["Value"] = value
+	}
+	return map[string]interface{}{"KV": kv}
+}
+
+// txn runs ops in a transaction, failing with the errors Consul reports if any of them fails.
+func (t *consulTarget) txn(ctx context.Context, ops []map[string]interface{}) error {
+	err := t.client.do(ctx, http.MethodPut, "/v1/txn?"+t.query().Encode(), ops, nil)
+	return errors.Wrap(err, fmt.Sprintf("couldn't write the keys under %s", t.prefix))
+}
+
+// query returns the datacenter of the target as a query parameter, if it is set.
+func (t *consulTarget) query() url.Values {
+	q := url.Values{}
+	if t.dc != "" {
+		q.Set("dc", t.dc)
+	}
+	return q
+}
@@ -0,0 +1,120 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConsul serves the KV and transaction APIs of Consul from memory.
+type fakeConsul struct {
+	mu      sync.Mutex
+	kv      map[string]string
+	indexes map[string]uint64
+	index   uint64
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("X-Consul-Token") != "consul-token" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("ACL not found"))
+		return
+	}
+
+	if r.URL.Path == "/v1/txn" {
+		var ops []struct {
+			KV struct {
+				Verb  string
+				Key   string
+				Value []byte
+				Index uint64
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&ops)
+		for _, op := range ops {
+			if op.KV.Index != f.indexes[op.KV.Key] {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"Errors": [{"OpIndex": 0, "What": "failed to set key \"` + op.KV.Key + `\", index is stale"}]}`))
+				return
+			}
+		}
+		for _, op := range ops {
+			f.index++
+			if op.KV.Verb == "delete-cas" {
+				delete(f.kv, op.KV.Key)
+				delete(f.indexes, op.KV.Key)
+				continue
+			}
+			f.kv[op.KV.Key], f.indexes[op.KV.Key] = string(op.KV.Value), f.index
+		}
+		w.Write([]byte(`{"Results": [], "Errors": null}`))
+		return
+	}
+
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	var keys []string
+	for k := range f.kv {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sort.Strings(keys)
+	var pairs []map[string]interface{}
+	for _, k := range keys {
+		pairs = append(pairs, map[string]interface{}{"Key": k, "Value": []byte(f.kv[k]), "ModifyIndex": f.indexes[k]})
+	}
+	json.NewEncoder(w).Encode(pairs)
+}
+
+func TestSyncer_Sync_Consul(t *testing.T) {
+	consul := &fakeConsul{
+		index:   3,
+		kv:      map[string]string{"myapp/config/DB_HOST": "db.example.com", "myapp/config/OLD": "x", "myapp/config/nested/KEY": "y"},
+		indexes: map[string]uint64{"myapp/config/DB_HOST": 1, "myapp/config/OLD": 2, "myapp/config/nested/KEY": 3},
+	}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+	defer setenv(map[string]string{"CONSUL_HTTP_ADDR": server.URL, "CONSUL_HTTP_TOKEN": "consul-token"})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "consul:///myapp/config")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{"myapp/config/DB_HOST": "db.example.com", "myapp/config/DB_PORT": "5432", "myapp/config/nested/KEY": "y"}, consul.kv)
+
+	// an empty prefix is seeded from the sample
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "consul:///other/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT"}, res.Added)
+	assert.Equal(t, "localhost", consul.kv["other/DB_HOST"])
+
+	diff, err := syncer.Diff(filepath.Join(dir, "env.sample"), "consul:///myapp/config/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST"}, diff.Changed)
+
+	defer setenv(map[string]string{"CONSUL_HTTP_TOKEN": "invalid"})()
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "consul:///myapp/config")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "403 Forbidden: ACL not found")
+}
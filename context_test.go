@@ -0,0 +1,43 @@
+package envsync_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncContext_Canceled(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := syncer.SyncContext(ctx, "testdata/env.success", "testdata/env.success")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestSyncer_SyncReadersContext_Canceled(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReadersContext(ctx, strings.NewReader("FOO=bar\n"), strings.NewReader(""), &buf)
+	assert.NotNil(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestSyncer_DiffContext_Canceled(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := syncer.DiffContext(ctx, "testdata/env.success", "testdata/env.success")
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,123 @@
+package envsync
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// DiffResult describes how source and target differ.
+type DiffResult struct {
+	// OnlyInSource holds the keys in source that aren't in target.
+	OnlyInSource []string `json:"only_in_source"`
+
+	// OnlyInTarget holds the keys in target that aren't in source.
+	OnlyInTarget []string `json:"only_in_target"`
+
+	// Changed holds the keys in both source and target with different values.
+	Changed []string `json:"changed"`
+
+	// Source and Target hold the values of the keys that differ, in source and in target,
+	// e.g. to show them side by side. The value of a key matching Mask is **** unless ShowSecrets is set.
+	Source Env `json:"source,omitempty"`
+	Target Env `json:"target,omitempty"`
+
+	// Patch holds the differences as a unified diff from target to source, one key-value per line in alphabetical order,
+	// with the same masked values. It is only set if Syncer.Patch is.
+	Patch string `json:"patch,omitempty"`
+}
+
+// Equal reports whether source and target have the same key-values.
+func (d *DiffResult) Equal() bool {
+	return len(d.OnlyInSource)+len(d.OnlyInTarget)+len(d.Changed) == 0
+}
+
+// Diff compares the env files in source and target without modifying any of them.
+// Only keys matching Include and Exclude are compared.
+func (s *Syncer) Diff(source, target string) (*DiffResult, error) {
+	return s.DiffContext(context.Background(), source, target)
+}
+
+// DiffContext works like Diff and stops as soon as ctx is done.
+func (s *Syncer) DiffContext(ctx context.Context, source, target string) (*DiffResult, error) {
+	sDoc, err := s.load(ctx, source, "source")
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := s.open(target, "target")
+	if err != nil {
+		return nil, err
+	}
+	s = s.maskSecrets(dst)
+	tDoc, err := s.loadProvider(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := s.keyFilter()
+	if err != nil {
+		return nil, err
+	}
+	sEnv, err := s.env(sDoc)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't expand source")
+	}
+	tEnv, err := s.env(tDoc)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't expand target")
+	}
+	sMap, tMap := filter.apply(sEnv), filter.apply(tEnv)
+	if _, ok := dst.(writeOnlyTarget); ok {
+		// the values of target are unknown, so the keys in both are taken as equal
+		for k := range tMap {
+			if v, found := sMap[k]; found {
+				tMap[k] = v
+			}
+		}
+	}
+	diff := s.diffEnv(sMap, tMap)
+
+	masker, err := s.masker()
+	if err != nil {
+		return nil, err
+	}
+	diff.Source, diff.Target = maskedValues(masker, sMap, diff), maskedValues(masker, tMap, diff)
+	if s.Patch {
+		diff.Patch = masker.Diff(dst.Name(), envDocument(tMap), envDocument(sMap))
+	}
+	return diff, nil
+}
+
+// maskedValues returns the values env holds for the keys that differ in diff, each secret one replaced by ****.
+func maskedValues(masker *Masker, env map[string]string, diff *DiffResult) Env {
+	res := Env{}
+	for _, keys := range [][]string{diff.OnlyInSource, diff.OnlyInTarget, diff.Changed} {
+		for _, k := range keys {
+			v, ok := env[k]
+			if !ok {
+				continue
+			}
+			if masker.Secret(k) {
+				v = maskedValue
+			}
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func (s *Syncer) diffEnv(sMap, tMap map[string]string) *DiffResult {
+	changed := make(map[string]string)
+	for k, v := range sMap {
+		if tv, found := tMap[k]; found && tv != v {
+			changed[k] = v
+		}
+	}
+
+	return &DiffResult{
+		OnlyInSource: sortedKeys(s.additionalEnv(sMap, tMap)),
+		OnlyInTarget: sortedKeys(s.additionalEnv(tMap, sMap)),
+		Changed:      sortedKeys(changed),
+	}
+}
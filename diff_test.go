@@ -0,0 +1,68 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Diff(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	result := "testdata/env.result.diff"
+	ioutil.WriteFile(result, []byte("HOME=production\nFOO=bar\nRETIRED=true\n"), 0644)
+	defer exec.Command("rm", "-rf", result).Run()
+
+	diff, err := syncer.Diff("testdata/env.success", result)
+	assert.Nil(t, err)
+	assert.False(t, diff.Equal())
+	assert.Equal(t, []string{"ABC", "ROULETTE_BASIC_PASSWORD", "ROULETTE_BASIC_USER", "ROULETTE_HOST"}, diff.OnlyInSource)
+	assert.Equal(t, []string{"RETIRED"}, diff.OnlyInTarget)
+	assert.Equal(t, []string{"HOME"}, diff.Changed)
+
+	content, _ := ioutil.ReadFile(result)
+	assert.Equal(t, "HOME=production\nFOO=bar\nRETIRED=true\n", string(content))
+}
+
+func TestSyncer_Diff_Equal(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	diff, err := syncer.Diff("testdata/env.success", "testdata/env.success")
+	assert.Nil(t, err)
+	assert.True(t, diff.Equal())
+}
+
+func TestSyncer_Diff_ErrorOpenFile(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	_, err := syncer.Diff("testdata/env.empty", "testdata/env.success")
+	assert.NotNil(t, err)
+
+	_, err = syncer.Diff("testdata/env.success", "testdata/env.empty")
+	assert.NotNil(t, err)
+}
+
+func TestSyncer_Diff_Values(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example": "HOST=localhost\nPORT=8080\nAPI_TOKEN=\n",
+		".env":         "HOST=db.example.com\nAPI_TOKEN=s3cret\nRETIRED=true\n",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, ".env.example"), filepath.Join(dir, ".env")
+
+	diff, err := envsync.New(envsync.WithPatch()).Diff(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "PORT": "8080", "API_TOKEN": "****"}, diff.Source)
+	assert.Equal(t, envsync.Env{"HOST": "db.example.com", "RETIRED": "true", "API_TOKEN": "****"}, diff.Target)
+	assert.Equal(t, "--- a/"+target+"\n+++ b/"+target+"\n@@ -1,3 +1,3 @@\n-API_TOKEN=****\n-HOST=db.example.com\n-RETIRED=true\n+API_TOKEN=****\n+HOST=localhost\n+PORT=8080\n", diff.Patch)
+
+	diff, err = envsync.New(envsync.WithShowSecrets()).Diff(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cret", diff.Target["API_TOKEN"])
+	assert.Empty(t, diff.Patch)
+}
@@ -0,0 +1,130 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const dockerHost = "unix:///var/run/docker.sock"
+
+// dockerSource is the environment of a container as a Source, e.g. docker://my-app-1, read from the Docker Engine API,
+// to compare the env a container really runs with to a sample.
+// The daemon is found like the docker command does: at DOCKER_HOST, e.g. tcp://docker.example.com:2376,
+// with TLS if DOCKER_TLS_VERIFY is set and the certificates in DOCKER_CERT_PATH, or at /var/run/docker.sock.
+// The env of a container can't be changed once it is created, so it isn't a target.
+type dockerSource struct {
+	name      string
+	container string
+	client    *restClient
+}
+
+func openDocker(u *url.URL) (Target, error) {
+	container := strings.Trim(u.Host+u.Path, "/")
+	if container == "" {
+		return nil, fmt.Errorf("no container in %s", u)
+	}
+	client, err := newDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	return &dockerSource{name: u.String(), container: container, client: client}, nil
+}
+
+// newDockerClient returns a client of the Docker Engine API of the daemon at DOCKER_HOST.
+func newDockerClient() (*restClient, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = dockerHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid DOCKER_HOST")
+	}
+
+	client := &restClient{
+		errorOf: func(status int, body []byte) error {
+			var e struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &e) != nil || e.Message == "" {
+				return nil
+			}
+			return &httpError{status: status, message: e.Message}
+		},
+	}
+	switch u.Scheme {
+	case "unix":
+		// the host of requests is ignored, every connection is to the socket
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", u.Path)
+		}
+		client.base = "http://docker"
+		client.http = &http.Client{Transport: transport}
+	case "tcp", "http", "https":
+		client.base = "http://" + u.Host
+		if os.Getenv("DOCKER_TLS_VERIFY") != "" || u.Scheme == "https" {
+			dir := os.Getenv("DOCKER_CERT_PATH")
+			if dir == "" {
+				home, _ := os.UserHomeDir()
+				dir = filepath.Join(home, ".docker")
+			}
+			files := make(map[string][]byte)
+			for _, name := range []string{"ca.pem", "cert.pem", "key.pem"} {
+				b, err := ioutil.ReadFile(filepath.Join(dir, name))
+				if err != nil && !os.IsNotExist(err) {
+					return nil, err
+				}
+				files[name] = b
+			}
+			if client.http, err = tlsClient(files["ca.pem"], files["cert.pem"], files["key.pem"], false); err != nil {
+				return nil, err
+			}
+			client.base = "https://" + u.Host
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DOCKER_HOST %s, expected unix:// or tcp://", host)
+	}
+	return client, nil
+}
+
+func (t *dockerSource) Name() string {
+	return t.name
+}
+
+func (t *dockerSource) Load(ctx context.Context) (*Document, error) {
+	var out struct {
+		Config struct {
+			Env []string `json:"Env"`
+		} `json:"Config"`
+	}
+	if err := t.client.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(t.container)+"/json", nil, &out); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't inspect container %s", t.container))
+	}
+	env := Env{}
+	for _, kv := range out.Config.Env {
+		// a variable without a value, e.g. FOO, is declared empty
+		sp := strings.SplitN(kv, "=", 2)
+		if len(sp) == 1 {
+			sp = append(sp, "")
+		}
+		env[sp[0]] = sp[1]
+	}
+	return envDocument(env), nil
+}
+
+// Store fails, since the env of a container can't be changed.
+func (t *dockerSource) Store(ctx context.Context, doc *Document) error {
+	return fmt.Errorf("%s can only be a source, the env of a container can't be changed", t.name)
+}
@@ -0,0 +1,57 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDocker serves the inspection of the container my-app-1.
+func fakeDocker(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/containers/my-app-1/json" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "No such container: other"}`))
+		return
+	}
+	w.Write([]byte(`{"Id": "4fa6e0f0c678", "Config": {"Env": ["PATH=/usr/local/bin:/usr/bin", "PORT=9090", "EMPTY"]}}`))
+}
+
+func TestSyncer_Sync_Docker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake daemon listens on a unix socket")
+	}
+	dir := makeTree(t, map[string]string{"env.sample": "PORT=8080\nHOST=localhost\n", ".env": "PORT=8080\n"})
+	defer os.RemoveAll(dir)
+
+	// the daemon listens on a socket, as it does by default
+	l, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+	assert.Nil(t, err)
+	server := &httptest.Server{Listener: l, Config: &http.Server{Handler: http.HandlerFunc(fakeDocker)}}
+	server.Start()
+	defer server.Close()
+	defer setenv(map[string]string{"DOCKER_HOST": "unix://" + filepath.Join(dir, "docker.sock")})()
+
+	diff, err := envsync.New(envsync.WithExclude("PATH")).Diff(filepath.Join(dir, "env.sample"), "docker://my-app-1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST"}, diff.OnlyInSource)
+	assert.Equal(t, []string{"EMPTY"}, diff.OnlyInTarget)
+	assert.Equal(t, []string{"PORT"}, diff.Changed)
+
+	res, err := envsync.New(envsync.WithOverwrite("")).SyncWithResult("docker://my-app-1", filepath.Join(dir, ".env"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Overwritten)
+	b, _ := ioutil.ReadFile(filepath.Join(dir, ".env"))
+	assert.Contains(t, string(b), "PORT=9090\n")
+
+	_, err = envsync.New().Diff(filepath.Join(dir, "env.sample"), "docker://other")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't inspect container other: 404 Not Found: No such container: other")
+}
@@ -0,0 +1,428 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type lineKind int
+
+const (
+	blankLine lineKind = iota
+	commentLine
+	entryLine
+
+	// sectionLine is a [section] header of an INI file, its key holding the name of the section.
+	sectionLine
+
+	// rawLine is a line a codec doesn't read, e.g. a command in a shell script, written back as it is.
+	rawLine
+)
+
+const exportPrefix = "export "
+
+// line is a single line of an env file.
+// value is unquoted and quote is the quote it is wrapped in.
+// export tells whether the line is prefixed with export, as in shell-sourced env files.
+// comment is the inline comment following the value, including its preceding whitespace.
+// raw keeps the line as it is read, so untouched lines are written back verbatim.
+type line struct {
+	kind    lineKind
+	key     string
+	value   string
+	quote   byte
+	export  bool
+	comment string
+	raw     string
+}
+
+func (l *line) String() string {
+	if l.kind == entryLine && l.raw == "" {
+		prefix := ""
+		if l.export {
+			prefix = exportPrefix
+		}
+		// an inline comment after an unquoted value is only read as a comment with InlineComments
+		quote := l.quote
+		if l.comment != "" && quote == noQuote {
+			quote = doubleQuote
+		}
+		return fmt.Sprintf("%s%s%s%s%s", prefix, l.key, separator, encodeValue(l.value, quote), l.comment)
+	}
+	return l.raw
+}
+
+func (l *line) clone() *line {
+	c := *l
+	return &c
+}
+
+// Document models an env file line by line.
+// It keeps comments, blank lines, and the order of key-values,
+// so an env file can be modified and written back without losing anything.
+// Lines that aren't modified are written back exactly as they are read.
+//
+// The zero value is an empty document ready to use.
+type Document struct {
+	lines []*line
+
+	// codec is the syntax the document is read and written with, and yaml the layout of a CodecYAML document.
+	codec Codec
+	yaml  yamlLayout
+}
+
+// Entry is a key-value in a Document.
+type Entry struct {
+	Key   string
+	Value string
+
+	// Export tells whether the key-value is prefixed with export.
+	Export bool
+
+	// Comments are the comment lines directly above the key-value, with no blank line in between.
+	// They are kept as they are written, including the leading '#'.
+	Comments []string
+
+	// InlineComment is the comment following the value on the same line, including the preceding whitespace.
+	InlineComment string
+}
+
+// ParseDocument reads an env file from r.
+// It follows the same format as Parse.
+func ParseDocument(r io.Reader) (*Document, error) {
+	return parseDocument(r, parseOptions{})
+}
+
+// Get returns the value of key.
+// If key is declared more than once, the last one wins.
+func (d *Document) Get(key string) (string, bool) {
+	if l := d.line(key); l != nil {
+		return l.value, true
+	}
+	return "", false
+}
+
+// Set sets the value of every key-value holding key, keeping their quotes, export prefix, and comments.
+// If there is none, a new key-value is appended.
+func (d *Document) Set(key, value string) {
+	found := false
+	for _, l := range d.lines {
+		if l.kind == entryLine && l.key == key {
+			l.value, l.raw = value, ""
+			found = true
+		}
+	}
+	if !found {
+		d.lines = append(d.lines, d.added(&line{kind: entryLine, key: key, value: value}))
+	}
+}
+
+// Append appends e to the document, preceded by its comments.
+// Any existing key-value holding the same key is kept; use Delete first to replace it.
+func (d *Document) Append(e Entry) {
+	for _, c := range e.Comments {
+		d.lines = append(d.lines, &line{kind: commentLine, raw: c})
+	}
+	d.lines = append(d.lines, &line{kind: entryLine, key: e.Key, value: e.Value, export: e.Export, comment: e.InlineComment})
+}
+
+// Delete removes every key-value holding key and reports whether there was any.
+// Comments above the key-value are kept, since they may describe a group of key-values.
+func (d *Document) Delete(key string) bool {
+	found := d.index(key) >= 0
+	d.remove(key)
+	return found
+}
+
+// Rename renames every key-value holding key to newKey, keeping their value, quotes, export prefix, and comments,
+// and reports whether there was any.
+func (d *Document) Rename(key, newKey string) bool {
+	found := false
+	for _, l := range d.lines {
+		if l.kind == entryLine && l.key == key {
+			l.key, l.raw = newKey, ""
+			found = true
+		}
+	}
+	return found
+}
+
+// Iterate calls fn for each key-value in the order they are declared, until fn returns false.
+func (d *Document) Iterate(fn func(Entry) bool) {
+	for i, l := range d.lines {
+		if l.kind != entryLine {
+			continue
+		}
+		e := Entry{Key: l.key, Value: l.value, Export: l.export, Comments: d.comments(i), InlineComment: l.comment}
+		if !fn(e) {
+			return
+		}
+	}
+}
+
+// Keys returns the keys in the order they are first declared.
+func (d *Document) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, l := range d.lines {
+		if l.kind == entryLine && !seen[l.key] {
+			keys = append(keys, l.key)
+			seen[l.key] = true
+		}
+	}
+	return keys
+}
+
+// Env returns the key-values in the document.
+// If a key is declared more than once, the last one wins.
+func (d *Document) Env() Env {
+	res := make(Env)
+	for _, l := range d.lines {
+		if l.kind == entryLine {
+			res[l.key] = l.value
+		}
+	}
+	return res
+}
+
+// WriteTo writes the document to w as an env file, in the syntax of the codec it is read with.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if d.codec != CodecDotenv {
+		return d.encode(w)
+	}
+	var n int64
+	for _, l := range d.lines {
+		written, err := fmt.Fprintln(w, l)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// clone returns a copy of the document that can be modified independently.
+func (d *Document) clone() *Document {
+	c := &Document{lines: make([]*line, len(d.lines)), codec: d.codec, yaml: d.yaml}
+	for i, l := range d.lines {
+		c.lines[i] = l.clone()
+	}
+	return c
+}
+
+// comments returns the comment lines directly above the line at index i.
+func (d *Document) comments(i int) []string {
+	start := i
+	for start > 0 && d.lines[start-1].kind == commentLine {
+		start--
+	}
+	var comments []string
+	for _, l := range d.lines[start:i] {
+		comments = append(comments, l.raw)
+	}
+	return comments
+}
+
+// parseOptions describes how an env file is parsed.
+type parseOptions struct {
+	// inlineComments makes whitespace followed by '#' in an unquoted value start a comment.
+	inlineComments bool
+
+	// duplicates describes what happens to a key declared more than once.
+	duplicates Duplicate
+
+	// logger receives the warnings of DuplicateWarn.
+	logger Logger
+}
+
+func parseDocument(r io.Reader, opts parseOptions) (*Document, error) {
+	doc := &Document{}
+	// declared maps each key to the line it is first declared on
+	declared := make(map[string]int)
+
+	sc := bufio.NewScanner(r)
+	sc.Split(bufio.ScanLines)
+
+	n := 0
+	for sc.Scan() {
+		n++
+		start, text := n, sc.Text()
+		switch {
+		case text == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+		case strings.HasPrefix(text, "#"):
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+		default:
+			sp := strings.SplitN(text, separator, splitNumber)
+			if len(sp) != splitNumber {
+				return doc, &ParseError{Line: start, Text: text, Err: ErrMissingSeparator}
+			}
+			// a quoted value may span multiple lines
+			value, quote, comment, err := decodeValue(sp[1], opts.inlineComments)
+			for err == ErrUnterminatedQuote && sc.Scan() {
+				n++
+				text += "\n" + sc.Text()
+				sp[1] += "\n" + sc.Text()
+				value, quote, comment, err = decodeValue(sp[1], opts.inlineComments)
+			}
+			if err != nil {
+				return doc, &ParseError{Line: start, Text: text, Err: err}
+			}
+			key, export := sp[0], false
+			if strings.HasPrefix(key, exportPrefix) {
+				key, export = strings.TrimSpace(strings.TrimPrefix(key, exportPrefix)), true
+			}
+			l := &line{
+				kind:    entryLine,
+				key:     key,
+				value:   value,
+				quote:   quote,
+				export:  export,
+				comment: comment,
+				raw:     text,
+			}
+			if first, found := declared[key]; found {
+				keep, err := opts.duplicates.check(l, start, first, opts.logger)
+				if err != nil {
+					return doc, err
+				}
+				if !keep {
+					continue
+				}
+			} else {
+				declared[key] = start
+			}
+			doc.lines = append(doc.lines, l)
+		}
+	}
+
+	return doc, sc.Err()
+}
+
+// lineNumber returns the number of the line in the written document where the last key-value holding key starts,
+// or 0 if there is none.
+func (d *Document) lineNumber(key string) int {
+	i := d.index(key)
+	if i < 0 {
+		return 0
+	}
+	n := 1
+	for _, l := range d.lines[:i] {
+		n += strings.Count(l.String(), "\n") + 1
+	}
+	return n
+}
+
+// lineNumbers returns the numbers of the lines in the written document where each key-value holding key starts.
+func (d *Document) lineNumbers(key string) []int {
+	var numbers []int
+	n := 1
+	for _, l := range d.lines {
+		if l.kind == entryLine && l.key == key {
+			numbers = append(numbers, n)
+		}
+		n += strings.Count(l.String(), "\n") + 1
+	}
+	return numbers
+}
+
+// line returns the last line holding key, or nil if there is none.
+func (d *Document) line(key string) *line {
+	if i := d.index(key); i >= 0 {
+		return d.lines[i]
+	}
+	return nil
+}
+
+// index returns the index of the last line holding key, or -1 if there is none.
+func (d *Document) index(key string) int {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].kind == entryLine && d.lines[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// exported tells whether every key-value in the document is prefixed with export.
+func (d *Document) exported() bool {
+	found := false
+	for _, l := range d.lines {
+		if l.kind == entryLine {
+			if !l.export {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// adopt returns a copy of l that is prefixed with export when export is set.
+func adopt(l *line, export bool) *line {
+	c := l.clone()
+	if c.export != export {
+		c.export, c.raw = export, ""
+	}
+	return c
+}
+
+// added returns a copy of l to be added to the document.
+// The copy is prefixed with export if every key-value in the document is.
+func (d *Document) added(l *line) *line {
+	if d.exported() {
+		return adopt(l, true)
+	}
+	return l.clone()
+}
+
+// insert adds a copy of l at index i.
+// The copy is prefixed with export if every key-value in the document is.
+func (d *Document) insert(l *line, i int) {
+	c := d.added(l)
+	d.lines = append(d.lines, nil)
+	copy(d.lines[i+1:], d.lines[i:])
+	d.lines[i] = c
+}
+
+// put replaces every line holding the key of l with a copy of l, keeping their export prefix,
+// or appends a copy of l if there is none.
+// An appended copy is prefixed with export if every key-value in the document is.
+func (d *Document) put(l *line) {
+	found := false
+	for i, dl := range d.lines {
+		if dl.kind == entryLine && dl.key == l.key {
+			d.lines[i] = adopt(l, dl.export)
+			found = true
+		}
+	}
+	if !found {
+		d.lines = append(d.lines, d.added(l))
+	}
+}
+
+// remove deletes every line holding key.
+func (d *Document) remove(key string) {
+	lines := d.lines[:0]
+	for _, l := range d.lines {
+		if l.kind != entryLine || l.key != key {
+			lines = append(lines, l)
+		}
+	}
+	d.lines = lines
+}
+
+// String returns the document as an env file.
+func (d *Document) String() string {
+	var buf bytes.Buffer
+	d.write(&buf)
+	return buf.String()
+}
+
+func (d *Document) write(w io.Writer) error {
+	_, err := d.WriteTo(w)
+	return err
+}
@@ -0,0 +1,123 @@
+package envsync_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleDocument = `# database
+# url of the primary database
+DATABASE_URL="postgres://localhost/app" # primary
+
+export PORT=8080
+DEBUG='true'
+`
+
+func TestParseDocument_RoundTrip(t *testing.T) {
+	doc, err := envsync.ParseDocument(strings.NewReader(sampleDocument))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	_, err = doc.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, sampleDocument, buf.String())
+}
+
+func TestDocument_Get(t *testing.T) {
+	doc, _ := envsync.ParseDocument(strings.NewReader(sampleDocument))
+
+	v, ok := doc.Get("DATABASE_URL")
+	assert.True(t, ok)
+	assert.Equal(t, "postgres://localhost/app", v)
+
+	_, ok = doc.Get("MISSING")
+	assert.False(t, ok)
+}
+
+func TestDocument_Set(t *testing.T) {
+	doc, _ := envsync.ParseDocument(strings.NewReader(sampleDocument))
+
+	doc.Set("DEBUG", "false")
+	doc.Set("PORT", "80")
+	doc.Set("LOG_LEVEL", "info warn")
+
+	var buf bytes.Buffer
+	doc.WriteTo(&buf)
+	expected := `# database
+# url of the primary database
+DATABASE_URL="postgres://localhost/app" # primary
+
+export PORT=80
+DEBUG='false'
+LOG_LEVEL=info warn
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestDocument_Delete(t *testing.T) {
+	doc, _ := envsync.ParseDocument(strings.NewReader(sampleDocument))
+
+	assert.True(t, doc.Delete("DATABASE_URL"))
+	assert.False(t, doc.Delete("DATABASE_URL"))
+	assert.Equal(t, []string{"PORT", "DEBUG"}, doc.Keys())
+
+	var buf bytes.Buffer
+	doc.WriteTo(&buf)
+	assert.Equal(t, "# database\n# url of the primary database\n\nexport PORT=8080\nDEBUG='true'\n", buf.String())
+}
+
+func TestDocument_Rename(t *testing.T) {
+	doc, _ := envsync.ParseDocument(strings.NewReader(sampleDocument))
+
+	assert.True(t, doc.Rename("PORT", "HTTP_PORT"))
+	assert.False(t, doc.Rename("PORT", "HTTP_PORT"))
+	assert.Equal(t, []string{"DATABASE_URL", "HTTP_PORT", "DEBUG"}, doc.Keys())
+
+	var buf bytes.Buffer
+	doc.WriteTo(&buf)
+	assert.Contains(t, buf.String(), "\nexport HTTP_PORT=8080\n")
+}
+
+func TestDocument_Iterate(t *testing.T) {
+	doc, _ := envsync.ParseDocument(strings.NewReader(sampleDocument))
+
+	var entries []envsync.Entry
+	doc.Iterate(func(e envsync.Entry) bool {
+		entries = append(entries, e)
+		return true
+	})
+
+	assert.Len(t, entries, 3)
+	assert.Equal(t, envsync.Entry{
+		Key:           "DATABASE_URL",
+		Value:         "postgres://localhost/app",
+		Comments:      []string{"# database", "# url of the primary database"},
+		InlineComment: " # primary",
+	}, entries[0])
+	assert.Equal(t, envsync.Entry{Key: "PORT", Value: "8080", Export: true}, entries[1])
+
+	count := 0
+	doc.Iterate(func(e envsync.Entry) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestDocument_Append(t *testing.T) {
+	doc := &envsync.Document{}
+	doc.Append(envsync.Entry{Key: "TOKEN", Value: "a b#c", Comments: []string{"# api token"}, InlineComment: " # required"})
+	doc.Append(envsync.Entry{Key: "PATH", Value: "/bin", Export: true})
+
+	var buf bytes.Buffer
+	doc.WriteTo(&buf)
+	assert.Equal(t, "# api token\nTOKEN=\"a b#c\" # required\nexport PATH=/bin\n", buf.String())
+	assert.Equal(t, envsync.Env{"TOKEN": "a b#c", "PATH": "/bin"}, doc.Env())
+
+	parsed, _ := envsync.Parse(&buf)
+	assert.Equal(t, doc.Env(), parsed)
+}
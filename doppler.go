@@ -0,0 +1,136 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const dopplerAPIHost = "https://api.doppler.com"
+
+// dopplerComputed are the secrets Doppler adds to every config, which can't be changed.
+var dopplerComputed = map[string]bool{"DOPPLER_PROJECT": true, "DOPPLER_CONFIG": true, "DOPPLER_ENVIRONMENT": true}
+
+// dopplerTarget is a config of a Doppler project as a Target, e.g. doppler://my-project/dev,
+// authenticated with the token in DOPPLER_TOKEN. A service token is scoped to a config,
+// so doppler:// is enough with one.
+//
+// Values are read and written raw: a reference to another secret, e.g. ${DB_HOST},
+// is synchronized as it is written rather than as the value it refers to.
+type dopplerTarget struct {
+	name    string
+	project string
+	config  string
+	client  *restClient
+	env     Env
+}
+
+func openDoppler(u *url.URL) (Target, error) {
+	token := os.Getenv("DOPPLER_TOKEN")
+	if token == "" {
+		return nil, errors.New("DOPPLER_TOKEN isn't set")
+	}
+	host := u.Query().Get("endpoint")
+	if host == "" {
+		host = os.Getenv("DOPPLER_API_HOST")
+	}
+	if host == "" {
+		host = dopplerAPIHost
+	}
+	return &dopplerTarget{
+		name:    u.String(),
+		project: u.Host,
+		config:  strings.Trim(u.Path, "/"),
+		client: &restClient{
+			base: host,
+			auth: bearer(func(context.Context) (string, error) {
+				return token, nil
+			}),
+			errorOf: func(status int, body []byte) error {
+				var e struct {
+					Messages []string `json:"messages"`
+				}
+				if json.Unmarshal(body, &e) != nil || len(e.Messages) == 0 {
+					return nil
+				}
+				return &httpError{status: status, message: strings.Join(e.Messages, ", ")}
+			},
+		},
+	}, nil
+}
+
+func (t *dopplerTarget) Name() string {
+	return t.name
+}
+
+func (t *dopplerTarget) Load(ctx context.Context) (*Document, error) {
+	var out struct {
+		Secrets map[string]struct {
+			Raw string `json:"raw"`
+		} `json:"secrets"`
+	}
+	if err := t.client.do(ctx, http.MethodGet, "/v3/configs/config/secrets?"+t.query().Encode(), nil, &out); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read the secrets of %s", t.name))
+	}
+	t.env = Env{}
+	for k, s := range out.Secrets {
+		if !dopplerComputed[k] {
+			t.env[k] = s.Raw
+		}
+	}
+	return envDocument(t.env), nil
+}
+
+// Store updates the secrets whose value is added or changed since the target is loaded,
+// and deletes the ones that aren't in doc anymore, in a single request.
+func (t *dopplerTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	secrets := make(map[string]interface{})
+	for k, v := range env {
+		if old, ok := t.env[k]; !ok || old != v {
+			secrets[k] = v
+		}
+	}
+	for k := range t.env {
+		if _, ok := env[k]; !ok {
+			secrets[k] = nil
+		}
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	in := map[string]interface{}{"secrets": secrets}
+	for k, v := range t.query() {
+		in[k] = v[0]
+	}
+	if err := t.client.do(ctx, http.MethodPost, "/v3/configs/config/secrets", in, nil); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("couldn't write the secrets of %s", t.name))
+	}
+	t.env = env
+	return nil
+}
+
+// query returns the project and config of the target as query parameters, if they are set.
+func (t *dopplerTarget) query() url.Values {
+	q := url.Values{}
+	if t.project != "" {
+		q.Set("project", t.project)
+	}
+	if t.config != "" {
+		q.Set("config", t.config)
+	}
+	return q
+}
@@ -0,0 +1,92 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDoppler serves the secrets API of Doppler for my-project/dev from memory.
+type fakeDoppler struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (f *fakeDoppler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer dp.st.dev" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"messages": ["Invalid auth token"], "success": false}`))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("project") != "my-project" || r.URL.Query().Get("config") != "dev" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		secrets := map[string]map[string]string{"DOPPLER_CONFIG": {"raw": "dev"}}
+		for k, v := range f.secrets {
+			secrets[k] = map[string]string{"raw": v}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"secrets": secrets})
+	case http.MethodPost:
+		var in struct {
+			Project string             `json:"project"`
+			Config  string             `json:"config"`
+			Secrets map[string]*string `json:"secrets"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		for k, v := range in.Secrets {
+			if v == nil {
+				delete(f.secrets, k)
+			} else {
+				f.secrets[k] = *v
+			}
+		}
+		w.Write([]byte(`{"success": true}`))
+	}
+}
+
+func TestSyncer_Sync_Doppler(t *testing.T) {
+	doppler := &fakeDoppler{secrets: map[string]string{
+		"DB_HOST": "db.example.com",
+		"DB_URL":  "postgres://${DB_HOST}",
+		"OLD":     "x",
+	}}
+	server := httptest.NewServer(doppler)
+	defer server.Close()
+	defer setenv(map[string]string{"DOPPLER_TOKEN": "dp.st.dev", "DOPPLER_API_HOST": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_URL=postgres://${DB_HOST}\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	diff, err := syncer.Diff(filepath.Join(dir, "env.sample"), "doppler://my-project/dev")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, diff.OnlyInSource)
+	assert.Equal(t, []string{"OLD"}, diff.OnlyInTarget)
+	assert.Equal(t, []string{"DB_HOST"}, diff.Changed)
+
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "doppler://my-project/dev")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{"DB_HOST": "db.example.com", "DB_URL": "postgres://${DB_HOST}", "DB_PORT": "5432"}, doppler.secrets)
+
+	defer setenv(map[string]string{"DOPPLER_TOKEN": "invalid"})()
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "doppler://my-project/dev")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized: Invalid auth token")
+}
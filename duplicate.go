@@ -0,0 +1,84 @@
+package envsync
+
+import "fmt"
+
+// Duplicate describes what happens to a key declared more than once in a single env file.
+type Duplicate int
+
+const (
+	// DuplicateKeepLast keeps every declaration, the last one giving the value of the key.
+	// This is the default.
+	DuplicateKeepLast Duplicate = iota
+
+	// DuplicateKeepFirst keeps the first declaration and drops the following ones,
+	// so they aren't written back either.
+	DuplicateKeepFirst
+
+	// DuplicateWarn reports each following declaration to Logger, then behaves like DuplicateKeepLast.
+	DuplicateWarn
+
+	// DuplicateError makes reading the env file fail with a ParseError whose reason is ErrDuplicateKey.
+	DuplicateError
+)
+
+var duplicateNames = map[Duplicate]string{
+	DuplicateKeepLast:  "keep-last",
+	DuplicateKeepFirst: "keep-first",
+	DuplicateWarn:      "warn",
+	DuplicateError:     "error",
+}
+
+// String returns the name of the duplicate policy.
+func (d Duplicate) String() string {
+	if name, ok := duplicateNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Duplicate(%d)", int(d))
+}
+
+// ParseDuplicate returns the duplicate policy with the given name.
+// Valid names are "keep-last", "keep-first", "warn", and "error".
+func ParseDuplicate(name string) (Duplicate, error) {
+	for d, n := range duplicateNames {
+		if n == name {
+			return d, nil
+		}
+	}
+	return DuplicateKeepLast, fmt.Errorf("unknown duplicate policy: %s", name)
+}
+
+// check applies the policy to l, a declaration of a key already declared on line first,
+// and reports whether l is kept.
+func (d Duplicate) check(l *line, n, first int, logger Logger) (bool, error) {
+	switch d {
+	case DuplicateKeepFirst:
+		return false, nil
+	case DuplicateWarn:
+		if logger != nil {
+			logger.Printf("Duplicate env: %s on line %d, first declared on line %d", l.key, n, first)
+		}
+	case DuplicateError:
+		return false, &ParseError{Line: n, Text: l.raw, Err: ErrDuplicateKey}
+	}
+	return true, nil
+}
+
+// DuplicateRule flags keys declared more than once in an env file.
+type DuplicateRule struct{}
+
+// Name returns duplicate.
+func (r DuplicateRule) Name() string {
+	return "duplicate"
+}
+
+// Check returns a violation for each declaration of a key following its first one in target.
+func (r DuplicateRule) Check(target, source *Document) []Violation {
+	var violations []Violation
+	for _, k := range target.Keys() {
+		lines := target.lineNumbers(k)
+		for _, n := range lines[1:] {
+			violations = append(violations, Violation{Key: k, Line: n, Message: fmt.Sprintf("duplicate env, first declared on line %d", lines[0])})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,70 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Duplicates(t *testing.T) {
+	src := "HOST=localhost\nPORT=8080\nDEBUG=false\n"
+	dst := "PORT=80\nHOST=example.com\nPORT=81\n"
+
+	var buf bytes.Buffer
+	syncer := envsync.New()
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "81", res.Env["PORT"])
+
+	syncer = envsync.New(envsync.WithDuplicates(envsync.DuplicateKeepFirst))
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "80", res.Env["PORT"])
+	assert.Equal(t, "PORT=80\nHOST=example.com\nDEBUG=false\n", buf.String())
+
+	var logs bytes.Buffer
+	syncer = envsync.New(envsync.WithDuplicates(envsync.DuplicateWarn), envsync.WithLogger(log.New(&logs, "", 0)))
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "81", res.Env["PORT"])
+	assert.Contains(t, logs.String(), "Duplicate env: PORT on line 3, first declared on line 1\n")
+
+	syncer = envsync.New(envsync.WithDuplicates(envsync.DuplicateError))
+	buf.Reset()
+	_, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.True(t, errors.Is(err, envsync.ErrDuplicateKey))
+	var perr *envsync.ParseError
+	assert.True(t, errors.As(err, &perr))
+	assert.Equal(t, 3, perr.Line)
+	assert.Equal(t, "PORT=81", perr.Text)
+	assert.Empty(t, buf.String())
+}
+
+func TestParseDuplicate(t *testing.T) {
+	for _, d := range []envsync.Duplicate{envsync.DuplicateKeepLast, envsync.DuplicateKeepFirst, envsync.DuplicateWarn, envsync.DuplicateError} {
+		parsed, err := envsync.ParseDuplicate(d.String())
+		assert.Nil(t, err)
+		assert.Equal(t, d, parsed)
+	}
+
+	_, err := envsync.ParseDuplicate("keep-both")
+	assert.NotNil(t, err)
+	assert.Equal(t, "Duplicate(7)", envsync.Duplicate(7).String())
+}
+
+func TestLint_DuplicateRule(t *testing.T) {
+	doc, err := envsync.ParseDocument(strings.NewReader("PORT=80\nHOST=localhost\n# again\nPORT=81\nKEY=\"a\nb\"\nPORT=82\n"))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []envsync.Violation{
+		{Key: "PORT", Line: 4, Message: "duplicate env, first declared on line 1", Rule: "duplicate"},
+		{Key: "PORT", Line: 7, Message: "duplicate env, first declared on line 1", Rule: "duplicate"},
+	}, envsync.Lint(doc, nil, envsync.DuplicateRule{}))
+}
@@ -0,0 +1,41 @@
+package envsync
+
+import (
+	"io"
+	"sort"
+)
+
+// Env is the key-values of an env file.
+type Env map[string]string
+
+// Keys returns the keys of env sorted alphabetically.
+func (e Env) Keys() []string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Parse reads the key-values of an env file from r.
+// It follows the same format as Sync: comments and blank lines are skipped,
+// values may be quoted, and export prefixes are dropped.
+// If a key is declared more than once, the last one wins.
+func Parse(r io.Reader) (Env, error) {
+	doc, err := parseDocument(r, parseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Env(), nil
+}
+
+// Write writes env to w as an env file, one key-value per line sorted by key.
+// A value is double-quoted if it can't be read back by Parse as it is.
+func Write(w io.Writer, env Env) error {
+	doc := &Document{}
+	for _, k := range env.Keys() {
+		doc.lines = append(doc.lines, &line{kind: entryLine, key: k, value: env[k]})
+	}
+	return doc.write(w)
+}
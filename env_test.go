@@ -0,0 +1,59 @@
+package envsync_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	file, _ := os.Open("testdata/env.success")
+	defer file.Close()
+
+	env, err := envsync.Parse(file)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{
+		"FOO":                     "bar",
+		"ABC":                     "def=ghi!xyz",
+		"HOME":                    "localhost",
+		"ROULETTE_HOST":           "http://localhost:8989",
+		"ROULETTE_BASIC_USER":     "roulette",
+		"ROULETTE_BASIC_PASSWORD": "roulette",
+	}, env)
+}
+
+func TestParse_Error(t *testing.T) {
+	file, _ := os.Open("testdata/env.error")
+	defer file.Close()
+
+	_, err := envsync.Parse(file)
+	assert.NotNil(t, err)
+}
+
+func TestWrite(t *testing.T) {
+	env := envsync.Env{
+		"PLAIN":   "value",
+		"SPACED":  " padded ",
+		"NEWLINE": "a\nb",
+		"QUOTE":   `"quoted"`,
+		"EMPTY":   "",
+	}
+
+	var buf bytes.Buffer
+	err := envsync.Write(&buf, env)
+	assert.Nil(t, err)
+	assert.Equal(t, "EMPTY=\nNEWLINE=\"a\\nb\"\nPLAIN=value\nQUOTE=\"\\\"quoted\\\"\"\nSPACED=\" padded \"\n", buf.String())
+
+	parsed, err := envsync.Parse(strings.NewReader(buf.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, env, parsed)
+}
+
+func TestEnv_Keys(t *testing.T) {
+	env := envsync.Env{"B": "2", "A": "1", "C": "3"}
+	assert.Equal(t, []string{"A", "B", "C"}, env.Keys())
+}
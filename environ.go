@@ -0,0 +1,76 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// environTarget is the environment of the process as a Target, e.g. env://, to compare an env file with the environment it runs in,
+// or to set the env missing from it before starting a child process, which inherits it.
+// The prefix query parameter limits it to the variables starting with the prefix, which is left out of their keys,
+// e.g. env://?prefix=MYAPP_ holds MYAPP_PORT as PORT.
+type environTarget struct {
+	name   string
+	prefix string
+	env    Env
+}
+
+func openEnviron(u *url.URL) (Target, error) {
+	if u.Host != "" || strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("expected env:// or env://?prefix=PREFIX_, got %s", u)
+	}
+	return &environTarget{name: u.String(), prefix: u.Query().Get("prefix")}, nil
+}
+
+func (t *environTarget) Name() string {
+	return t.name
+}
+
+func (t *environTarget) Load(ctx context.Context) (*Document, error) {
+	t.env = Env{}
+	for _, kv := range os.Environ() {
+		sp := strings.SplitN(kv, "=", 2)
+		// Windows keeps the working directory of each drive in variables like =C:, which aren't env
+		if len(sp) != 2 || sp[0] == "" || !strings.HasPrefix(sp[0], t.prefix) || sp[0] == t.prefix {
+			continue
+		}
+		t.env[strings.TrimPrefix(sp[0], t.prefix)] = sp[1]
+	}
+	return envDocument(t.env), nil
+}
+
+// Store sets the variables whose value is added or changed since the target is loaded,
+// and unsets the ones that aren't in doc anymore.
+func (t *environTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		if err := os.Setenv(t.prefix+k, env[k]); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set %s", t.prefix+k))
+		}
+		t.env[k] = env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := os.Unsetenv(t.prefix + k); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't unset %s", t.prefix+k))
+		}
+		delete(t.env, k)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package envsync_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Sync_Environ(t *testing.T) {
+	defer setenv(map[string]string{"ENVSYNC_TEST_PORT": "9090", "ENVSYNC_TEST_OLD": "x", "ENVSYNC_TEST_HOST": ""})()
+	os.Unsetenv("ENVSYNC_TEST_HOST")
+	dir := makeTree(t, map[string]string{"env.sample": "PORT=8080\nHOST=localhost\n"})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, "env.sample"), "env://?prefix=ENVSYNC_TEST_"
+
+	diff, err := envsync.New().Diff(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST"}, diff.OnlyInSource)
+	assert.Equal(t, []string{"OLD"}, diff.OnlyInTarget)
+	assert.Equal(t, []string{"PORT"}, diff.Changed)
+
+	// the env missing from the process is set, e.g. for a child process
+	res, err := envsync.New(envsync.WithPrune()).SyncWithResult(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, "localhost", os.Getenv("ENVSYNC_TEST_HOST"))
+	assert.Equal(t, "9090", os.Getenv("ENVSYNC_TEST_PORT"))
+	_, ok := os.LookupEnv("ENVSYNC_TEST_OLD")
+	assert.False(t, ok)
+
+	_, err = envsync.New().Diff(source, "env://HOME")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "expected env:// or env://?prefix=PREFIX_")
+}
@@ -1,10 +1,15 @@
 package envsync
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
-	"strings"
+	"path"
+	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -26,8 +31,161 @@ type EnvSyncer interface {
 	Sync(source, target string) error
 }
 
+// Logger receives the messages a Syncer reports, such as the keys it has added.
+// *log.Logger implements Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
 // Syncer implements EnvSyncer.
+// The zero value is ready to use. Use New to configure it with options.
 type Syncer struct {
+	// DryRun makes Sync report the env that would be added to target
+	// without writing anything to it.
+	DryRun bool
+
+	// Prune makes Sync remove any keys in target that don't exist in source.
+	Prune bool
+
+	// Overwrite makes Sync replace the value of keys that exist in both source and target
+	// with the value in source.
+	Overwrite bool
+
+	// Merge describes what happens to keys in both source and target with different values.
+	// It is ignored when Overwrite is set.
+	Merge MergeStrategy
+
+	// OverwritePattern limits Overwrite and Merge to keys matching the pattern.
+	// The pattern syntax is the same as path.Match, e.g. "FEATURE_*".
+	// An empty pattern matches every key.
+	OverwritePattern string
+
+	// Backup makes Sync copy target to a new backup before modifying it.
+	// See History and Rollback to list and restore the backups.
+	Backup bool
+
+	// BackupDir is the directory backups are written to.
+	// By default it is the .envsync/backups directory next to target.
+	BackupDir string
+
+	// Include limits the synchronization to keys matching any of the patterns.
+	// Exclude leaves keys matching any of the patterns out of it.
+	// Keys that are left out are neither added, removed, nor overwritten.
+	//
+	// A pattern wrapped in slashes, e.g. /^FEATURE_/, is a regular expression.
+	// Any other pattern has the same syntax as path.Match, e.g. FEATURE_*, or is an exact key name.
+	Include []string
+	Exclude []string
+
+	// Ignore, if set, leaves the keys it lists untouched like Exclude,
+	// and the files it lists out of SyncTree and SyncGlob. See ParseIgnore.
+	Ignore *Ignore
+
+	// Codecs sets the codec of the files at the given names, for files whose extension doesn't tell it.
+	// Other files are read and written with the codec of their extension. See CodecOf.
+	Codecs map[string]Codec
+
+	// InlineComments makes whitespace followed by '#' in an unquoted value start a comment,
+	// e.g. PORT=8080 # http port has the value 8080.
+	// By default the comment is part of the value.
+	InlineComments bool
+
+	// Duplicates describes what happens to a key declared more than once in source or target.
+	// The zero value keeps every declaration, the last one giving the value of the key.
+	Duplicates Duplicate
+
+	// Expand makes variable references in values, e.g. ${HOST}, expanded before values are compared,
+	// as Document.ExpandedEnv does. Key-values are still written with their references.
+	Expand bool
+
+	// Template makes values in source holding {{ }} evaluated as text/template templates
+	// when their key-value is added to target, e.g. SESSION_ID={{uuid}}.
+	// The functions are uuid, now, randHex n, randAlphaNum n, and env NAME, plus the ones in Funcs.
+	// Values of key-values already in target are never evaluated.
+	Template bool
+
+	// Funcs holds functions available in templates in addition to the default ones,
+	// overriding them on the same name.
+	Funcs template.FuncMap
+
+	// Order describes how the key-values added to target are placed.
+	// The zero value appends them sorted by key.
+	Order Order
+
+	// Strict describes how keys in target that aren't in source are reported.
+	// The zero value doesn't report them.
+	Strict Strict
+
+	// Prompter, if set, decides the value of each key-value added to target.
+	// It isn't used in dry-run mode.
+	Prompter Prompter
+
+	// Placeholder describes what happens to key-values added with an empty value in source.
+	// The zero value adds them with an empty value.
+	Placeholder Placeholder
+
+	// Renames maps deprecated keys to their new name, e.g. DB_URL to DATABASE_URL.
+	// A deprecated key in target is renamed, keeping its value, unless the new name is already in target.
+	// Keys annotated with # envsync:alias in source are renamed too, e.g. # envsync:alias DB_URL above DATABASE_URL.
+	Renames map[string]string
+
+	// Patch makes SyncResult.Patch hold the changes to target as a unified diff, e.g. to review a dry run,
+	// and DiffResult.Patch the differences between target and source.
+	Patch bool
+
+	// Managed limits the synchronization to the section of target between the # envsync:start and # envsync:end comments,
+	// leaving the lines around it untouched, so envsync can coexist with env managed by hand.
+	// Keys outside of the section are neither added, removed, nor overwritten.
+	// If target has no section, an empty one is appended to it.
+	// It can't be used with a YAML or JSON target, whose comments aren't read: syncing returns ErrCommentsNotKept.
+	Managed bool
+
+	// Header and Footer are comments kept at the top and at the bottom of target,
+	// e.g. "managed by envsync, do not edit above this line". Each of their lines is prefixed with # unless it already is.
+	// They are written when target, or its managed section, doesn't start or end with them,
+	// and key-values added to target are placed above Footer.
+	// A Header or Footer that is changed is written next to the previous one, which is left as it is.
+	// Like Managed, they can't be used with a YAML or JSON target.
+	Header string
+	Footer string
+
+	// Style describes how Format lays out an env file.
+	// The zero value keeps quotes and the order of key-values.
+	Style Style
+
+	// Mask holds the patterns of keys whose values are replaced by **** in Patch and Issues,
+	// so running envsync in CI doesn't leak them into build logs.
+	// If it is empty, DefaultMaskPatterns are used. Patterns have the same syntax as Include.
+	Mask []string
+
+	// ShowSecrets disables Mask, showing every value as it is.
+	ShowSecrets bool
+
+	// AgeRecipients makes target written encrypted with age for the recipients, e.g. age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p,
+	// so its values are never on disk in plain text. It is written in the armored format, which is text.
+	// AgeIdentities are the files holding the identities, e.g. written by age-keygen, that decrypt source and target
+	// when they are encrypted with age, so an encrypted target can be synchronized again or compared.
+	AgeRecipients []string
+	AgeIdentities []string
+
+	// Retries is how many times loading or storing a remote source or target is tried again
+	// when it fails on a transient error, e.g. a timeout, a reset connection, or a 503 or 429 response,
+	// so a flaky network doesn't leave a target half synchronized. The zero value doesn't retry.
+	// Retries are RetryBackoff apart, 500ms if it isn't set, the wait doubling on each retry.
+	Retries      int
+	RetryBackoff time.Duration
+
+	// Timeout, if set, bounds each attempt to load or store a remote source or target.
+	// Env files are neither retried nor timed out.
+	Timeout time.Duration
+
+	// Lint holds the rules checking target once it is synchronized, e.g. PlaceholderRule.
+	// Their violations are reported in SyncResult.Issues and don't fail the synchronization.
+	Lint []LintRule
+
+	// Logger receives a message for each key that is added, removed, or overwritten.
+	// If it is nil, nothing is reported.
+	Logger Logger
 }
 
 // Sync implements EnvSyncer.
@@ -39,36 +197,418 @@ type Syncer struct {
 // e.g: FOO=bar.
 // FOO is the key and bar is the value.
 //
-// During the synchronization process, there may be an error.
-// Any key-values that have been synchronized before the error occurred is kept in target.
-// Any key-values that haven't been synchronized because of an error occurred is ignored.
+// A line may be prefixed with export, e.g. export FOO=bar, as in shell-sourced env files.
+// The prefix isn't part of the key and is kept as it is in target.
+// Added key-values are prefixed with export if every key-value in target is.
+//
+// A value may be wrapped in single or double quotes, e.g. FOO="bar baz".
+// A single-quoted value is taken literally.
+// A double-quoted value may contain the escapes \n, \r, \t, \", and \\.
+// A quoted value may span multiple lines until its closing quote,
+// and may be followed by a comment, e.g. FOO="bar" # the bar.
+// A comment following an unquoted value is part of the value unless InlineComments is set.
+// Comments are kept on write, and added key-values carry the comments they have in source.
+// A key-value annotated with # envsync:generate in the comments above it in source is added with a random value,
+// e.g. # envsync:generate hex32 or hex:32 for 32 random bytes in hex. The generators are hex, base64, uuid, and passphrase.
+// If a key-value annotated with # required or # envsync:required in source has an empty value in target
+// once synchronized, Sync fails with ErrRequiredEmpty and target is left untouched.
+// Values are compared without their quotes, and added key-values keep the quotes they have in source.
+//
+// Target is replaced atomically: the new content is written to a temporary file
+// which is then renamed over target.
+// If an error occurs during the synchronization process, target is left untouched.
+//
+// If Prune is set, any key-values in target that aren't in source are removed from target.
+// If Overwrite is set, any key-values in target that are also in source take the value in source.
+// Comments and blank lines in target are kept as they are.
+//
+// If DryRun is set, target is only read and left untouched.
 func (s *Syncer) Sync(source, target string) error {
-	// open the source file
-	sFile, err := os.Open(source)
+	_, err := s.SyncWithResult(source, target)
+	return err
+}
+
+// SyncWithResult works like Sync and returns what has been changed in target.
+// In dry-run mode the result describes what would be changed.
+func (s *Syncer) SyncWithResult(source, target string) (*SyncResult, error) {
+	return s.SyncContext(context.Background(), source, target)
+}
+
+// SyncContext works like SyncWithResult and stops as soon as ctx is done.
+// If ctx is done before target is replaced, target is left untouched.
+func (s *Syncer) SyncContext(ctx context.Context, source, target string) (*SyncResult, error) {
+	src, err := s.open(source, "source")
+	if err != nil {
+		return nil, err
+	}
+	dst, err := s.open(target, "target")
+	if err != nil {
+		return nil, err
+	}
+	return s.SyncProviders(ctx, src, dst)
+}
+
+// SyncProviders works like SyncContext, loading source and target from providers, e.g. FileSource and FileTarget.
+// target is only stored if it is changed.
+// MergeThreeWay compares target with the snapshot of the source last synchronized into it,
+// which is only kept for file targets that aren't encrypted: other targets are merged as if they had never been synchronized.
+func (s *Syncer) SyncProviders(ctx context.Context, source Source, target Target) (*SyncResult, error) {
+	sDoc, err := s.loadProvider(ctx, source)
 	if err != nil {
-		return errors.Wrap(err, "couldn't open source file")
+		return nil, err
 	}
-	defer sFile.Close()
 
-	// open the target file
-	tFile, err := os.OpenFile(target, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+	return s.syncTarget(ctx, sDoc, target)
+}
+
+// maskSecrets returns s with every key masked if the values of target are all secret, unless ShowSecrets is set.
+func (s *Syncer) maskSecrets(target Target) *Syncer {
+	if _, ok := target.(secretTarget); !ok || s.ShowSecrets {
+		return s
+	}
+	masked := *s
+	masked.Mask = []string{"*"}
+	return &masked
+}
+
+// syncTarget applies sDoc to target.
+func (s *Syncer) syncTarget(ctx context.Context, sDoc *Document, target Target) (*SyncResult, error) {
+	s = s.maskSecrets(target)
+	tDoc, err := s.loadProvider(ctx, target)
 	if err != nil {
-		return errors.Wrap(err, "couldn't open target file")
+		return nil, err
+	}
+
+	file, isFile := target.(*fileProvider)
+	snapshot := s.Merge == MergeThreeWay && isFile && s.keepsSnapshot(file.name, tDoc.codec)
+	var base Env
+	if snapshot {
+		if base, err = readSnapshot(file.name); err != nil {
+			return nil, err
+		}
 	}
-	defer tFile.Close()
 
-	sMap, err := s.mapEnv(sFile)
+	_, writeOnly := target.(writeOnlyTarget)
+	result, changed, err := s.syncDocument(sDoc, tDoc, base, target.Name(), writeOnly)
 	if err != nil {
+		return nil, err
+	}
+	if s.DryRun {
+		if dr, ok := target.(DryRunner); ok {
+			return s.dryRun(ctx, target, dr, tDoc, result)
+		}
+		return result, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if changed {
+		if err := s.storeTarget(ctx, target, tDoc); err != nil {
+			return nil, err
+		}
+	}
+	if snapshot {
+		// the snapshot holds the source env as it is compared with target, expanded if Expand is set
+		env, err := s.env(sDoc)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't expand source")
+		}
+		if err := writeSnapshot(file.name, env); err != nil {
+			return nil, err
+		}
+	} else if s.Merge == MergeThreeWay && isFile {
+		if err := removeSnapshot(file.name); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// dryRun checks the changes of result with target through dr, retrying on transient errors like a store,
+// and makes result report what it answers.
+// tDoc is the document of target as it is loaded.
+func (s *Syncer) dryRun(ctx context.Context, target Target, dr DryRunner, tDoc *Document, result *SyncResult) (*SyncResult, error) {
+	if len(result.Added)+len(result.Pruned)+len(result.Overwritten)+len(result.Renamed) == 0 {
+		return result, nil
+	}
+	var doc *Document
+	err := s.retry(ctx, target, func(ctx context.Context) error {
+		var err error
+		doc, err = dr.DryRun(ctx, envDocument(result.Env))
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	result.Env = doc.Env()
+	if s.Patch {
+		masker, err := s.masker()
+		if err != nil {
+			return nil, err
+		}
+		result.Patch = masker.Diff(target.Name(), tDoc, doc)
+	}
+	return result, nil
+}
+
+// SyncReaders works like SyncWithResult, reading source from src and target from dst.
+// The synchronized target is written in full to w, even if nothing has been changed.
+// In dry-run mode nothing is written to w.
+//
+// dst and w may be the same buffer, e.g. a *bytes.Buffer,
+// since dst is read completely before anything is written to w.
+func (s *Syncer) SyncReaders(src, dst io.Reader, w io.Writer) (*SyncResult, error) {
+	return s.SyncReadersContext(context.Background(), src, dst, w)
+}
 
-	tMap, err := s.mapEnv(tFile)
+// SyncReadersContext works like SyncReaders and stops as soon as ctx is done.
+// src and dst are checked for ctx between reads; a read that is already blocked isn't interrupted.
+func (s *Syncer) SyncReadersContext(ctx context.Context, src, dst io.Reader, w io.Writer) (*SyncResult, error) {
+	sDoc, err := parseDocument(newContextReader(ctx, src), s.parseOptions())
 	if err != nil {
-		return err
+		return nil, errors.Wrap(err, "couldn't read source")
+	}
+
+	tDoc, err := parseDocument(newContextReader(ctx, dst), s.parseOptions())
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read target")
 	}
 
-	addedEnv := s.additionalEnv(sMap, tMap)
-	return s.writeEnv(tFile, addedEnv)
+	result, _, err := s.syncDocument(sDoc, tDoc, nil, "target", false)
+	if err != nil {
+		return nil, err
+	}
+	if s.DryRun {
+		return result, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tDoc.write(w); err != nil {
+		return nil, errors.Wrap(err, "couldn't write target")
+	}
+	return result, nil
+}
+
+// syncDocument applies sDoc to tDoc and reports whether tDoc has been modified.
+// base is the source env last synchronized into tDoc, used by MergeThreeWay.
+// target is the name of tDoc in the patch made with Patch.
+// writeOnly tells tDoc is loaded from a writeOnlyTarget, whose keys are set even though their values are empty.
+// In dry-run mode tDoc is left untouched.
+func (s *Syncer) syncDocument(sDoc, tDoc *Document, base Env, target string, writeOnly bool) (*SyncResult, bool, error) {
+	if _, err := path.Match(s.OverwritePattern, ""); err != nil {
+		return nil, false, errors.Wrap(err, "invalid overwrite pattern")
+	}
+	if err := s.checkLayout(tDoc.codec); err != nil {
+		return nil, false, errors.Wrap(err, fmt.Sprintf("couldn't sync %s", target))
+	}
+	if sDoc.codec != tDoc.codec {
+		sDoc = sDoc.recode(tDoc.codec)
+	}
+	masker, err := s.masker()
+	if err != nil {
+		return nil, false, err
+	}
+	var before *Document
+	if s.Patch {
+		before = tDoc.clone()
+	}
+
+	c, err := s.changes(sDoc, tDoc, base)
+	if err != nil {
+		return nil, false, err
+	}
+	tDoc = c.tDoc
+	result := newSyncResult(c.sMap, c.tEnv, c.added, c.pruned, c.overwritten)
+	result.Extra = sortedKeys(c.extra)
+	result.Renamed = c.renamed
+	var unknown map[string]string
+	if writeOnly {
+		unknown = c.tMap
+	}
+	if err := checkRequired(sDoc, c.sMap, result.Env, unknown); err != nil {
+		return nil, false, err
+	}
+	s.print(c.added, c.pruned, c.overwritten, c.renamed)
+	changed := len(c.added)+len(c.pruned)+len(c.overwritten)+len(c.renamed) > 0 || !s.laidOut(tDoc)
+	if !changed {
+		result.Issues = s.lint(tDoc, sDoc, masker)
+		if s.Patch {
+			result.Patch = masker.Diff(target, before, tDoc)
+		}
+		return result, false, nil
+	}
+
+	// in dry-run mode the rules check a copy of target as it would be
+	doc := tDoc
+	if s.DryRun {
+		doc = tDoc.clone()
+	}
+	if err := s.applyChanges(doc, sDoc, c); err != nil {
+		return nil, false, err
+	}
+	result.Issues = s.lint(doc, sDoc, masker)
+	if s.Patch {
+		result.Patch = masker.Diff(target, before, doc)
+	}
+
+	return result, !s.DryRun, nil
+}
+
+// syncChanges are the changes synchronizing a source makes to a target, found by changes.
+type syncChanges struct {
+	// tDoc is the target with its deprecated keys renamed, a copy of it in dry-run mode.
+	tDoc *Document
+
+	// sMap and tMap are the env of source and target, without the keys filtered out.
+	sMap, tMap map[string]string
+
+	// tEnv is the env of target, including the keys filtered out.
+	tEnv Env
+
+	added, pruned, overwritten, renamed map[string]string
+
+	// extra is the env only in target that isn't pruned.
+	extra map[string]string
+
+	// edited is the value of added and overwritten key-values changed by a Prompter, a template, or a generator.
+	edited map[string]string
+}
+
+// changes finds the changes sDoc makes to tDoc: deprecated keys are renamed first,
+// then the env of both, without the keys filtered out, is compared and its conflicts resolved.
+// base is the source env last synchronized into tDoc, used by MergeThreeWay.
+func (s *Syncer) changes(sDoc, tDoc *Document, base Env) (*syncChanges, error) {
+	filter, err := s.keyFilter()
+	if err != nil {
+		return nil, err
+	}
+	if s.Managed {
+		outside, err := tDoc.outsideKeys()
+		if err != nil {
+			return nil, err
+		}
+		filter.excludeKeys(outside...)
+	}
+
+	// deprecated keys are renamed before anything is compared, on a copy of target in dry-run mode
+	renames := s.renames(sDoc, filter)
+	if s.DryRun && len(renames) > 0 {
+		tDoc = tDoc.clone()
+	}
+	c := &syncChanges{tDoc: tDoc, renamed: s.rename(tDoc, renames)}
+
+	// keys filtered out are left as they are in target
+	sEnv, err := s.env(sDoc)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't expand source")
+	}
+	if c.tEnv, err = s.env(tDoc); err != nil {
+		return nil, errors.Wrap(err, "couldn't expand target")
+	}
+	c.sMap, c.tMap = filter.apply(sEnv), filter.apply(c.tEnv)
+	c.added = s.additionalEnv(c.sMap, c.tMap)
+	c.pruned, c.extra = make(map[string]string), make(map[string]string)
+	if s.Prune {
+		c.pruned = s.additionalEnv(c.tMap, c.sMap)
+	} else {
+		c.extra = s.additionalEnv(c.tMap, c.sMap)
+	}
+	if err := s.Strict.check(c.extra, s.Logger); err != nil {
+		return nil, err
+	}
+
+	// the value of added and overwritten key-values may be changed by a Prompter
+	c.edited = make(map[string]string)
+	if c.overwritten, err = s.resolveConflicts(c.sMap, c.tMap, base, c.edited); err != nil {
+		return nil, err
+	}
+	if err := s.evaluate(c.added, c.edited); err != nil {
+		return nil, err
+	}
+	if err := generate(sDoc, c.added, c.edited); err != nil {
+		return nil, err
+	}
+	if err := s.prompt(c.added, c.edited); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// applyChanges writes the changes c of sDoc into doc, only in its managed section with Managed.
+func (s *Syncer) applyChanges(doc, sDoc *Document, c *syncChanges) error {
+	section, restore := doc, func() {}
+	if s.Managed {
+		var err error
+		if section, restore, err = managedSection(doc); err != nil {
+			return err
+		}
+	}
+	s.removeFooter(section)
+	for k := range c.pruned {
+		section.remove(k)
+	}
+	for k := range c.overwritten {
+		section.put(sDoc.line(k))
+	}
+	s.Order.addEnv(section, sDoc, c.added)
+	for k, v := range c.edited {
+		section.Set(k, v)
+	}
+	s.writeBanner(section)
+	restore()
+	return nil
+}
+
+// masker returns the Masker hiding the values of the keys matching Mask, or nil with ShowSecrets.
+func (s *Syncer) masker() (*Masker, error) {
+	if s.ShowSecrets {
+		return nil, nil
+	}
+	return NewMasker(s.Mask...)
+}
+
+// lint returns the violations of the Lint rules in tDoc once synchronized from sDoc,
+// with secret values hidden by masker, reporting each of them to Logger.
+func (s *Syncer) lint(tDoc, sDoc *Document, masker *Masker) []Violation {
+	if len(s.Lint) == 0 {
+		return nil
+	}
+
+	violations := masker.Violations(Lint(tDoc, sDoc, s.Lint...), tDoc.Env())
+	if s.Logger != nil {
+		for _, v := range violations {
+			s.Logger.Printf("Lint: %s", v)
+		}
+	}
+	return violations
+}
+
+func (s *Syncer) print(added, pruned, overwritten, renamed map[string]string) {
+	if s.Logger == nil {
+		return
+	}
+
+	addMsg, pruneMsg, overwriteMsg, renameMsg := "New env added:", "Env removed:", "Env overwritten:", "Deprecated env renamed:"
+	if s.DryRun {
+		addMsg, pruneMsg, overwriteMsg, renameMsg = "Env would be added:", "Env would be removed:", "Env would be overwritten:", "Deprecated env would be renamed:"
+	}
+	for _, k := range sortedKeys(renamed) {
+		s.Logger.Printf("%s %s to %s", renameMsg, k, renamed[k])
+	}
+	for _, k := range sortedKeys(added) {
+		s.Logger.Printf("%s %s", addMsg, k)
+	}
+	for _, k := range sortedKeys(pruned) {
+		s.Logger.Printf("%s %s", pruneMsg, k)
+	}
+	for _, k := range sortedKeys(overwritten) {
+		s.Logger.Printf("%s %s", overwriteMsg, k)
+	}
 }
 
 func (s *Syncer) additionalEnv(sMap, tMap map[string]string) map[string]string {
@@ -81,35 +621,105 @@ func (s *Syncer) additionalEnv(sMap, tMap map[string]string) map[string]string {
 	return addedEnv
 }
 
-func (s *Syncer) writeEnv(file *os.File, env map[string]string) error {
-	for k, v := range env {
-		if _, err := file.WriteString(fmt.Sprintf("%s=%s\n", k, v)); err != nil {
-			return errors.Wrap(err, fmt.Sprintf("error when writing key: %s, and value: %s", k, v))
+// changedEnv returns the key-values in sMap that are in tMap with a different value,
+// limited to keys matching OverwritePattern.
+func (s *Syncer) changedEnv(sMap, tMap map[string]string) map[string]string {
+	changedEnv := make(map[string]string)
+	for k, v := range sMap {
+		if matched, _ := path.Match(s.OverwritePattern, k); s.OverwritePattern != "" && !matched {
+			continue
+		}
+		if tv, found := tMap[k]; found && tv != v {
+			changedEnv[k] = v
 		}
 	}
-	return nil
+	return changedEnv
 }
 
-func (s *Syncer) mapEnv(file *os.File) (map[string]string, error) {
-	res := make(map[string]string)
+// readDocument parses the file at name with its codec.
+// The file is closed once it is read, so it can be replaced afterwards.
+// A file encrypted with age is decrypted with AgeIdentities first, and one encrypted by SOPS by sops.
+func (s *Syncer) readDocument(ctx context.Context, name, kind string) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		sentinel := ErrSourceNotFound
+		if kind == "target" {
+			sentinel = ErrTargetNotFound
+		}
+		err = &notFoundError{sentinel: sentinel, err: err}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't open %s file", kind))
+	}
+	defer file.Close()
 
-	sc := bufio.NewScanner(file)
-	sc.Split(bufio.ScanLines)
+	codec := s.codecOf(name)
+	b, err := ioutil.ReadAll(newContextReader(ctx, file))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s file", kind))
+	}
+	if isAge(b) {
+		if b, err = s.decryptAge(name, b); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s file", kind))
+		}
+	} else if isSOPS(b, codec) {
+		if b, err = decryptSOPS(ctx, name, codec); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s file", kind))
+		}
+	}
 
-	for sc.Scan() {
-		if sc.Text() != "" {
-			if strings.HasPrefix(sc.Text(), "#") {
-				continue
-			}
+	doc, err := decodeDocument(bytes.NewReader(b), codec, s.parseOptions())
+	if perr, ok := err.(*ParseError); ok {
+		perr.File = name
+	}
+	return doc, err
+}
 
-			sp := strings.SplitN(sc.Text(), separator, splitNumber)
-			if len(sp) != splitNumber {
-				return res, fmt.Errorf("couldn't split %s by '=' into two strings", sc.Text())
-			}
+// codecOf returns the codec of the file at name, set in Codecs or following its extension.
+func (s *Syncer) codecOf(name string) Codec {
+	if codec, ok := s.Codecs[name]; ok {
+		return codec
+	}
+	return CodecOf(name)
+}
+
+// env returns the key-values in doc, expanded if Expand is set.
+func (s *Syncer) env(doc *Document) (Env, error) {
+	if s.Expand {
+		return doc.ExpandedEnv()
+	}
+	return doc.Env(), nil
+}
 
-			res[sp[0]] = sp[1]
+func (s *Syncer) parseOptions() parseOptions {
+	return parseOptions{inlineComments: s.InlineComments, duplicates: s.Duplicates, logger: s.Logger}
+}
+
+// writeEnv replaces the content of target with doc.
+// doc is encrypted with age if AgeRecipients is set, or by sops again if target is encrypted by SOPS.
+// A target encrypted with age is never written in plain text.
+func (s *Syncer) writeEnv(target string, doc *Document) error {
+	if len(s.AgeRecipients) == 0 && isAgeFile(target) {
+		return errors.New("couldn't write target file: it is encrypted with age, but no recipient is set to encrypt it for")
+	}
+	if s.Backup {
+		if err := backupFile(target, s.BackupDir); err != nil {
+			return errors.Wrap(err, "couldn't back up target file")
 		}
 	}
+	if len(s.AgeRecipients) > 0 {
+		return errors.Wrap(s.writeAgeFile(target, doc), "couldn't write target file")
+	}
+	if isSOPSFile(target, doc.codec) {
+		return errors.Wrap(writeSOPSFile(target, doc), "couldn't write target file")
+	}
+	return errors.Wrap(writeFile(target, doc), "couldn't write target file")
+}
 
-	return res, nil
+func sortedKeys(env map[string]string) []string {
+	return Env(env).Keys()
 }
@@ -2,6 +2,8 @@ package envsync_test
 
 import (
 	"bufio"
+	"bytes"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
@@ -71,8 +73,164 @@ func TestSyncer_Sync_CorruptTargetFormat(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestSyncer_Sync_DryRun(t *testing.T) {
+	syncer := &envsync.Syncer{DryRun: true}
+
+	result := "testdata/env.result.dryrun"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	tMap := fileToMap(result)
+	assert.Empty(t, tMap)
+}
+
+func TestSyncer_Sync_Prune(t *testing.T) {
+	syncer := &envsync.Syncer{Prune: true}
+
+	result := "testdata/env.result.prune"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	file, _ := os.OpenFile(result, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+	file.WriteString("# keep this comment\nRETIRED=true\nHOME=production\n")
+	file.Close()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	tMap := fileToMap(result)
+	_, found := tMap["RETIRED"]
+	assert.False(t, found)
+	assert.Equal(t, "production", tMap["HOME"])
+	assert.Equal(t, "bar", tMap["FOO"])
+
+	content, _ := ioutil.ReadFile(result)
+	assert.True(t, strings.HasPrefix(string(content), "# keep this comment\n"))
+}
+
+func TestSyncer_Sync_PruneDryRun(t *testing.T) {
+	syncer := &envsync.Syncer{Prune: true, DryRun: true}
+
+	result := "testdata/env.result.prune.dryrun"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	file, _ := os.OpenFile(result, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+	file.WriteString("RETIRED=true\n")
+	file.Close()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	tMap := fileToMap(result)
+	assert.Equal(t, map[string]string{"RETIRED": "true"}, tMap)
+}
+
+func TestSyncer_Sync_Overwrite(t *testing.T) {
+	syncer := &envsync.Syncer{Overwrite: true, OverwritePattern: "ROULETTE_*"}
+
+	result := "testdata/env.result.overwrite"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	file, _ := os.OpenFile(result, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+	file.WriteString("HOME=production\nROULETTE_HOST=http://roulette\n")
+	file.Close()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	tMap := fileToMap(result)
+	assert.Equal(t, "production", tMap["HOME"])
+	assert.Equal(t, "http://localhost:8989", tMap["ROULETTE_HOST"])
+}
+
+func TestSyncer_Sync_InvalidOverwritePattern(t *testing.T) {
+	syncer := &envsync.Syncer{Overwrite: true, OverwritePattern: "["}
+
+	err := syncer.Sync("testdata/env.success", "testdata/env.success")
+	assert.NotNil(t, err)
+}
+
+func TestSyncer_SyncWithResult(t *testing.T) {
+	syncer := &envsync.Syncer{Prune: true, Overwrite: true, OverwritePattern: "ROULETTE_*"}
+
+	result := "testdata/env.result.withresult"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	file, _ := os.OpenFile(result, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+	file.WriteString("HOME=production\nROULETTE_HOST=http://roulette\nRETIRED=true\n")
+	file.Close()
+
+	res, err := syncer.SyncWithResult("testdata/env.success", result)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ABC", "FOO", "ROULETTE_BASIC_PASSWORD", "ROULETTE_BASIC_USER"}, res.Added)
+	assert.Equal(t, []string{"HOME"}, res.Skipped)
+	assert.Equal(t, []string{"RETIRED"}, res.Pruned)
+	assert.Equal(t, []string{"ROULETTE_HOST"}, res.Overwritten)
+	assert.Equal(t, envsync.Env(fileToMap(result)), res.Env)
+}
+
+func TestSyncer_Sync_PreserveCommentsAndBlankLines(t *testing.T) {
+	syncer := &envsync.Syncer{Prune: true, Overwrite: true, OverwritePattern: "HOME"}
+
+	result := "testdata/env.result.preserve"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	file, _ := os.OpenFile(result, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+	file.WriteString("# database\nRETIRED=true\n\n# application\nHOME=production\n")
+	file.Close()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	content, _ := ioutil.ReadFile(result)
+	expected := "# database\n\n# application\nHOME=localhost\n" +
+		"ABC=def=ghi!xyz\nFOO=bar\nROULETTE_BASIC_PASSWORD=roulette\nROULETTE_BASIC_USER=roulette\nROULETTE_HOST=http://localhost:8989\n"
+	assert.Equal(t, expected, string(content))
+}
+
+func TestSyncer_SyncReaders(t *testing.T) {
+	syncer := &envsync.Syncer{Prune: true}
+
+	src := strings.NewReader("# sample\nHOME=localhost\nFOO=bar\n")
+	buf := bytes.NewBufferString("HOME=production\nRETIRED=true\n")
+
+	res, err := syncer.SyncReaders(src, buf, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FOO"}, res.Added)
+	assert.Equal(t, []string{"RETIRED"}, res.Pruned)
+	assert.Equal(t, "HOME=production\nFOO=bar\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_DryRun(t *testing.T) {
+	syncer := &envsync.Syncer{DryRun: true}
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader("FOO=bar\n"), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FOO"}, res.Added)
+	assert.Empty(t, buf.String())
+}
+
+func TestSyncer_SyncReaders_CorruptFormat(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader("FOO\n"), strings.NewReader(""), &buf)
+	assert.NotNil(t, err)
+
+	_, err = syncer.SyncReaders(strings.NewReader(""), strings.NewReader("FOO\n"), &buf)
+	assert.NotNil(t, err)
+}
+
 func fileToMap(loc string) map[string]string {
-	file, _ := os.OpenFile(loc, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
+	file, _ := os.Open(loc)
 	defer file.Close()
 
 	res := make(map[string]string)
@@ -81,7 +239,7 @@ func fileToMap(loc string) map[string]string {
 	sc.Split(bufio.ScanLines)
 
 	for sc.Scan() {
-		if sc.Text() != "" {
+		if sc.Text() != "" && !strings.HasPrefix(sc.Text(), "#") {
 			sp := strings.SplitN(sc.Text(), "=", 2)
 			res[sp[0]] = sp[1]
 		}
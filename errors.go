@@ -0,0 +1,128 @@
+package envsync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSourceNotFound is returned when the source file doesn't exist.
+	ErrSourceNotFound = errors.New("source file not found")
+
+	// ErrTargetNotFound is returned when the target file doesn't exist.
+	ErrTargetNotFound = errors.New("target file not found")
+
+	// ErrEmptyValue is returned when a key-value with an empty value in source is added with PlaceholderFail.
+	ErrEmptyValue = errors.New("empty value in source")
+
+	// ErrConflict is returned with MergeFail when a key has different values in source and target.
+	ErrConflict = errors.New("env has different values in source and target")
+
+	// ErrRequiredEmpty is returned when a key annotated as required in source is empty or missing in target.
+	ErrRequiredEmpty = errors.New("required env is empty")
+
+	// ErrExtraEnv is returned with StrictError when target has keys that aren't in source.
+	ErrExtraEnv = errors.New("env in target isn't in source")
+
+	// ErrExpansionCycle is returned when values reference each other in a cycle, e.g. A=${B} and B=${A}.
+	ErrExpansionCycle = errors.New("cyclic variable reference")
+
+	// ErrTargetExists is returned by Init when the target file already exists.
+	ErrTargetExists = errors.New("target file already exists")
+
+	// ErrUnbalancedSection is returned with Managed when the # envsync:start and # envsync:end markers of target don't pair up.
+	ErrUnbalancedSection = errors.New("unbalanced envsync:start and envsync:end markers")
+
+	// ErrMissingSeparator is the reason of a ParseError on a line without '='.
+	ErrMissingSeparator = errors.New("missing '=' separator")
+
+	// ErrUnterminatedQuote is the reason of a ParseError on a quoted value without its closing quote.
+	ErrUnterminatedQuote = errors.New("unterminated quoted value")
+
+	// ErrDuplicateKey is the reason of a ParseError on a key declared again with DuplicateError.
+	ErrDuplicateKey = errors.New("duplicate key")
+
+	// ErrUnexpectedText is the reason of a ParseError on a quoted value followed by anything but a comment.
+	ErrUnexpectedText = errors.New("unexpected text after quoted value")
+
+	// ErrUnsupportedSyntax is the reason of a ParseError on a line a codec can't read as a key-value, e.g. a table in TOML.
+	ErrUnsupportedSyntax = errors.New("unsupported syntax")
+
+	// ErrInvalidEscape is the reason of a ParseError on a malformed \uXXXX escape, e.g. in a .properties file.
+	ErrInvalidEscape = errors.New("invalid unicode escape")
+
+	// ErrComplexValue is the reason of a ParseError on a list, map, or object value in a .tfvars file,
+	// since only strings, numbers, and booleans can be env values.
+	ErrComplexValue = errors.New("list, map, and object values aren't supported")
+
+	// ErrCommentsNotKept is returned when Managed, Header, or Footer is set for a target in a format whose comments aren't read,
+	// such as YAML or JSON, since the markers and the banner would be lost.
+	ErrCommentsNotKept = errors.New("comments aren't kept in the format of target")
+)
+
+// ParseError describes a line in an env file that couldn't be parsed.
+type ParseError struct {
+	// File is the name of the env file.
+	// It is empty if the env is read from an io.Reader.
+	File string
+
+	// Line is the line number, starting at 1.
+	// For a value spanning multiple lines it is the line where the value starts.
+	Line int
+
+	// Text is the offending text.
+	// It may hold a secret value, so Error only prints it up to the separator of the value.
+	Text string
+
+	// Err is the reason, e.g. ErrMissingSeparator.
+	Err error
+}
+
+// Error returns the error as file:line: reason: text,
+// or line n: reason: text if there is no file name.
+// The value in text is left out, e.g. DB_PASSWORD=..., so that an error printed in a log doesn't leak it.
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d: %s: %q", e.File, e.Line, e.Err, redactedText(e.Text))
+	}
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Err, redactedText(e.Text))
+}
+
+// redactedText returns text up to the separator of its value, or up to its end of line if it has none.
+// A value left out is replaced with "...": it may be a secret,
+// or the rest of a file read as a value whose quote isn't terminated.
+func redactedText(text string) string {
+	i := strings.IndexAny(text, "=:\n")
+	switch {
+	case i < 0:
+		return text
+	case text[i] == '\n':
+		return text[:i] + "..."
+	}
+	return text[:i+1] + "..."
+}
+
+// Unwrap returns the reason of the error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// notFoundError is an error opening a file that doesn't exist.
+// It matches both its sentinel and the underlying error, e.g. os.ErrNotExist.
+type notFoundError struct {
+	sentinel error
+	err      error
+}
+
+func (e *notFoundError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notFoundError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *notFoundError) Unwrap() error {
+	return e.err
+}
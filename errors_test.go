@@ -0,0 +1,95 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Sync_ErrSourceNotFound(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	err := syncer.Sync("testdata/env.empty", "testdata/env.success")
+	assert.True(t, errors.Is(err, envsync.ErrSourceNotFound))
+	assert.False(t, errors.Is(err, envsync.ErrTargetNotFound))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestSyncer_Sync_ErrTargetNotFound(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	err := syncer.Sync("testdata/env.success", "testdata/env.empty")
+	assert.True(t, errors.Is(err, envsync.ErrTargetNotFound))
+	assert.False(t, errors.Is(err, envsync.ErrSourceNotFound))
+}
+
+func TestSyncer_Sync_ParseError(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	err := syncer.Sync("testdata/env.error", "testdata/env.success")
+
+	var perr *envsync.ParseError
+	assert.True(t, errors.As(err, &perr))
+	assert.Equal(t, "testdata/env.error", perr.File)
+	assert.Equal(t, 2, perr.Line)
+	assert.Equal(t, "FOO", perr.Text)
+	assert.True(t, errors.Is(err, envsync.ErrMissingSeparator))
+	assert.Equal(t, `testdata/env.error:2: missing '=' separator: "FOO"`, err.Error())
+}
+
+func TestSyncer_Diff_ParseErrorFile(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	_, err := syncer.Diff("testdata/env.success", "testdata/env.error")
+
+	var perr *envsync.ParseError
+	assert.True(t, errors.As(err, &perr))
+	assert.Equal(t, "testdata/env.error", perr.File)
+}
+
+func TestSyncer_SyncReaders_ParseErrorReason(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	tests := []struct {
+		src    string
+		line   int
+		reason error
+	}{
+		{src: "A=1\n\nB=\"open\nstill open\n", line: 3, reason: envsync.ErrUnterminatedQuote},
+		{src: "A='1' 2\n", line: 1, reason: envsync.ErrUnexpectedText},
+		{src: "A=\"1\n2\"\nB\n", line: 3, reason: envsync.ErrMissingSeparator},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		_, err := syncer.SyncReaders(strings.NewReader(tt.src), strings.NewReader(""), &buf)
+
+		var perr *envsync.ParseError
+		assert.True(t, errors.As(err, &perr), tt.src)
+		assert.Equal(t, tt.line, perr.Line, tt.src)
+		assert.True(t, errors.Is(err, tt.reason), tt.src)
+		assert.Empty(t, perr.File)
+	}
+}
+
+func TestParseError_Error(t *testing.T) {
+	tests := []struct {
+		text   string
+		reason error
+		want   string
+	}{
+		{"FOO", envsync.ErrMissingSeparator, `line 2: missing '=' separator: "FOO"`},
+		{"DB_PASSWORD=\"s3cret\nAPI_KEY=t0ken", envsync.ErrUnterminatedQuote, `line 2: unterminated quoted value: "DB_PASSWORD=..."`},
+		{"db.password: \\uZZZZ", envsync.ErrInvalidEscape, `line 2: invalid unicode escape: "db.password:..."`},
+	}
+
+	for _, tt := range tests {
+		err := &envsync.ParseError{Line: 2, Text: tt.text, Err: tt.reason}
+		assert.Equal(t, tt.want, err.Error())
+	}
+}
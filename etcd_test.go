@@ -0,0 +1,178 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEtcd serves the KV and auth APIs of the JSON gateway of etcd from memory.
+type fakeEtcd struct {
+	mu        sync.Mutex
+	kv        map[string]string
+	revisions map[string]int64
+	revision  int64
+	txns      int
+
+	// unavailable is the number of the transaction that fails as if etcd had no leader, counting from 1
+	unavailable int
+}
+
+type fakeEtcdKV struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value,omitempty"`
+	ModRevision int64  `json:"mod_revision,string,omitempty"`
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/v3/auth/authenticate" {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if in["name"] != "root" || in["password"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "etcdserver: authentication failed, invalid user ID or password", "code": 3, "message": "etcdserver: authentication failed, invalid user ID or password"}`))
+			return
+		}
+		w.Write([]byte(`{"token": "etcd-token"}`))
+		return
+	}
+	if r.Header.Get("Authorization") != "etcd-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		var in struct {
+			Key      []byte `json:"key"`
+			RangeEnd []byte `json:"range_end"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		var keys []string
+		for k := range f.kv {
+			if k >= string(in.Key) && k < string(in.RangeEnd) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		kvs := []fakeEtcdKV{}
+		for _, k := range keys {
+			kvs = append(kvs, fakeEtcdKV{Key: []byte(k), Value: []byte(f.kv[k]), ModRevision: f.revisions[k]})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs, "count": strconv.Itoa(len(kvs))})
+	case "/v3/kv/txn":
+		var in struct {
+			Compare []struct {
+				Key         []byte `json:"key"`
+				ModRevision int64  `json:"mod_revision,string"`
+			} `json:"compare"`
+			Success []struct {
+				Put    *fakeEtcdKV `json:"request_put"`
+				Delete *fakeEtcdKV `json:"request_delete_range"`
+			} `json:"success"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		f.txns++
+		if f.txns == f.unavailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "etcdserver: no leader", "code": 14, "message": "etcdserver: no leader"}`))
+			return
+		}
+		for _, c := range in.Compare {
+			if f.revisions[string(c.Key)] != c.ModRevision {
+				w.Write([]byte(`{"header": {}}`))
+				return
+			}
+		}
+		f.revision++
+		for _, op := range in.Success {
+			if op.Put != nil {
+				f.kv[string(op.Put.Key)], f.revisions[string(op.Put.Key)] = string(op.Put.Value), f.revision
+			} else {
+				delete(f.kv, string(op.Delete.Key))
+				delete(f.revisions, string(op.Delete.Key))
+			}
+		}
+		fmt.Fprintf(w, `{"header": {"revision": "%d"}, "succeeded": true}`, f.revision)
+	}
+}
+
+func TestSyncer_Sync_Etcd(t *testing.T) {
+	etcd := &fakeEtcd{
+		revision:  3,
+		kv:        map[string]string{"/myapp/config/DB_HOST": "db.example.com", "/myapp/config/OLD": "x", "/myapp/config/nested/KEY": "y"},
+		revisions: map[string]int64{"/myapp/config/DB_HOST": 1, "/myapp/config/OLD": 2, "/myapp/config/nested/KEY": 3},
+	}
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	defer setenv(map[string]string{"ETCDCTL_ENDPOINTS": server.URL, "ETCDCTL_USER": "root:secret"})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "etcd:///myapp/config")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{"/myapp/config/DB_HOST": "db.example.com", "/myapp/config/DB_PORT": "5432", "/myapp/config/nested/KEY": "y"}, etcd.kv)
+	assert.Equal(t, 1, etcd.txns)
+
+	// the user of the URL takes over ETCDCTL_USER, and its password is left out of results
+	host := strings.TrimPrefix(server.URL, "http://")
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "etcd://root:secret@"+host+"/other/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT"}, res.Added)
+	assert.Equal(t, "localhost", etcd.kv["/other/DB_HOST"])
+
+	diff, err := syncer.Diff(filepath.Join(dir, "env.sample"), "etcd://root:secret@"+host+"/other/")
+	assert.Nil(t, err)
+	assert.True(t, diff.Equal())
+
+	defer setenv(map[string]string{"ETCDCTL_USER": "root:invalid"})()
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "etcd:///myapp/config/")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't authenticate to etcd as root: 400 Bad Request: etcdserver: authentication failed")
+}
+
+func TestSyncer_Sync_EtcdBatches(t *testing.T) {
+	etcd := &fakeEtcd{kv: map[string]string{}, revisions: map[string]int64{}, unavailable: 2}
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	defer setenv(map[string]string{"ETCDCTL_ENDPOINTS": server.URL, "ETCDCTL_USER": "root:secret"})()
+
+	var sample strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sample, "KEY_%03d=%d\n", i, i)
+	}
+	dir := makeTree(t, map[string]string{"env.sample": sample.String()})
+	defer os.RemoveAll(dir)
+
+	// the retry writes the keys the failed transaction didn't, and not again the ones written before it
+	syncer := envsync.New()
+	syncer.Retries, syncer.RetryBackoff = 1, time.Millisecond
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "etcd:///myapp/")
+	assert.Nil(t, err)
+	assert.Len(t, res.Added, 200)
+	assert.Len(t, etcd.kv, 200)
+	assert.Equal(t, "199", etcd.kv["/myapp/KEY_199"])
+	assert.Equal(t, 3, etcd.txns)
+	assert.Equal(t, int64(1), etcd.revisions["/myapp/KEY_000"])
+	assert.Equal(t, int64(2), etcd.revisions["/myapp/KEY_199"])
+}
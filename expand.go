@@ -0,0 +1,189 @@
+package envsync
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Expand returns a copy of env with the variable references in its values expanded,
+// the same way the dotenv-expand package does:
+//
+//	${KEY} and $KEY are replaced with the value of KEY.
+//	${KEY:-default} is replaced with default if KEY is unset or empty.
+//	${KEY-default} is replaced with default if KEY is unset.
+//	\$ is replaced with a literal $.
+//
+// A key that isn't in env is looked up in the environment of the process,
+// and is replaced with an empty value if it isn't set there either.
+// Expand returns ErrExpansionCycle if values reference each other in a cycle, e.g. A=${B} and B=${A}.
+func Expand(env Env) (Env, error) {
+	return newExpander(env, nil).expandAll()
+}
+
+// ExpandedEnv works like Env with the variable references in the values expanded as Expand does.
+// Single-quoted values are taken literally and aren't expanded.
+func (d *Document) ExpandedEnv() (Env, error) {
+	literal := make(map[string]bool)
+	for _, l := range d.lines {
+		if l.kind == entryLine {
+			literal[l.key] = l.quote == singleQuote
+		}
+	}
+	return newExpander(d.Env(), literal).expandAll()
+}
+
+type expander struct {
+	env      Env
+	literal  map[string]bool
+	expanded Env
+
+	// path holds the keys being expanded, to detect cycles
+	path []string
+}
+
+func newExpander(env Env, literal map[string]bool) *expander {
+	return &expander{env: env, literal: literal, expanded: make(Env)}
+}
+
+func (e *expander) expandAll() (Env, error) {
+	for _, k := range e.env.Keys() {
+		if _, err := e.value(k); err != nil {
+			return nil, err
+		}
+	}
+	return e.expanded, nil
+}
+
+// lookup returns the expanded value of key and reports whether it is set.
+func (e *expander) lookup(key string) (string, bool, error) {
+	if _, found := e.env[key]; !found {
+		v, found := os.LookupEnv(key)
+		return v, found, nil
+	}
+	v, err := e.value(key)
+	return v, true, err
+}
+
+// value returns the expanded value of key in env.
+func (e *expander) value(key string) (string, error) {
+	if v, found := e.expanded[key]; found {
+		return v, nil
+	}
+	for i, k := range e.path {
+		if k == key {
+			cycle := append(append([]string{}, e.path[i:]...), key)
+			return "", errors.Wrap(ErrExpansionCycle, strings.Join(cycle, " -> "))
+		}
+	}
+
+	raw := e.env[key]
+	if e.literal[key] {
+		e.expanded[key] = raw
+		return raw, nil
+	}
+
+	e.path = append(e.path, key)
+	v, err := e.expand(raw)
+	e.path = e.path[:len(e.path)-1]
+	if err != nil {
+		return "", err
+	}
+	e.expanded[key] = v
+	return v, nil
+}
+
+// expand replaces the variable references in s.
+func (e *expander) expand(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && s[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := closingBrace(s[i+2:])
+			if end < 0 {
+				b.WriteString(s[i:])
+				break
+			}
+			v, err := e.reference(s[i+2 : i+2+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += 2 + end
+			continue
+		}
+
+		n := nameLength(s[i+1:])
+		if n == 0 {
+			b.WriteByte(c)
+			continue
+		}
+		v, _, err := e.lookup(s[i+1 : i+1+n])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+		i += n
+	}
+	return b.String(), nil
+}
+
+// reference returns the value of a ${...} reference, given what is between the braces.
+func (e *expander) reference(expr string) (string, error) {
+	name, def, sep := expr, "", ""
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, def, sep = expr[:i], expr[i+2:], ":-"
+	} else if i := strings.Index(expr, "-"); i >= 0 {
+		name, def, sep = expr[:i], expr[i+1:], "-"
+	}
+
+	v, set, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if (sep == ":-" && v == "") || (sep == "-" && !set) {
+		return e.expand(def)
+	}
+	return v, nil
+}
+
+// nameLength returns the length of the variable name at the start of s.
+func nameLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// closingBrace returns the index in s of the brace closing a reference, skipping nested references,
+// or -1 if there isn't any.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}' && depth == 0:
+			return i
+		case s[i] == '}':
+			depth--
+		}
+	}
+	return -1
+}
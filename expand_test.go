@@ -0,0 +1,66 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	os.Setenv("ENVSYNC_TEST_USER", "admin")
+	defer os.Unsetenv("ENVSYNC_TEST_USER")
+
+	env, err := envsync.Expand(envsync.Env{
+		"HOST":     "localhost",
+		"PORT":     "5432",
+		"URL":      "postgres://${ENVSYNC_TEST_USER}@$HOST:${PORT}/db",
+		"EMPTY":    "",
+		"FALLBACK": "${EMPTY:-none} ${EMPTY-unused} ${MISSING-${HOST}}",
+		"PRICE":    `\$5 $`,
+		"BROKEN":   "${HOST",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://admin@localhost:5432/db", env["URL"])
+	assert.Equal(t, "none  localhost", env["FALLBACK"])
+	assert.Equal(t, "$5 $", env["PRICE"])
+	assert.Equal(t, "${HOST", env["BROKEN"])
+	assert.Equal(t, "localhost", env["HOST"])
+}
+
+func TestExpand_Cycle(t *testing.T) {
+	_, err := envsync.Expand(envsync.Env{"A": "${B}", "B": "x$C", "C": "$A"})
+	assert.True(t, errors.Is(err, envsync.ErrExpansionCycle))
+	assert.Contains(t, err.Error(), "A -> B -> C -> A")
+}
+
+func TestDocument_ExpandedEnv(t *testing.T) {
+	doc, err := envsync.ParseDocument(strings.NewReader("HOST=localhost\nURL=\"http://${HOST}\"\nRAW='${HOST}'\n"))
+	assert.Nil(t, err)
+
+	env, err := doc.ExpandedEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "URL": "http://localhost", "RAW": "${HOST}"}, env)
+}
+
+func TestSyncer_SyncReaders_Expand(t *testing.T) {
+	syncer := envsync.New(envsync.WithExpand(), envsync.WithOverwrite(""))
+
+	src := "HOST=localhost\nURL=http://${HOST}\nAPI=${URL}/api\n"
+	dst := "HOST=localhost\nURL=http://localhost\n"
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Overwritten)
+	assert.Equal(t, []string{"API"}, res.Added)
+	assert.Equal(t, "http://localhost/api", res.Env["API"])
+	assert.Equal(t, "HOST=localhost\nURL=http://localhost\nAPI=${URL}/api\n", buf.String())
+
+	_, err = syncer.SyncReaders(strings.NewReader("A=$A\n"), strings.NewReader(""), &buf)
+	assert.True(t, errors.Is(err, envsync.ErrExpansionCycle))
+}
@@ -0,0 +1,15 @@
+package envsync
+
+// UnregisterFormat removes the format c added by RegisterFormat, so that a test can register it again, e.g. with -count=2.
+// c must be the last format registered, since the next one is numbered after the formats registered.
+func UnregisterFormat(c Codec) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for ext, ec := range codecExtensions {
+		if ec == c {
+			delete(codecExtensions, ext)
+		}
+	}
+	delete(codecNames, c)
+	delete(customCodecs, c)
+}
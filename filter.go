@@ -0,0 +1,102 @@
+package envsync
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyFilter decides which keys take part in a synchronization.
+type keyFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+// newKeyFilter compiles include and exclude patterns.
+// A pattern wrapped in slashes, e.g. /^FEATURE_/, is a regular expression.
+// Any other pattern has the same syntax as path.Match, e.g. FEATURE_*,
+// so a key name without any special character matches only itself.
+func newKeyFilter(include, exclude []string) (*keyFilter, error) {
+	f := &keyFilter{}
+	for _, p := range include {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, p := range exclude {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return f, nil
+}
+
+// keyFilter returns the filter of Include, and of Exclude together with the keys of Ignore.
+func (s *Syncer) keyFilter() (*keyFilter, error) {
+	exclude := append(append([]string(nil), s.Exclude...), s.Ignore.keys()...)
+	return newKeyFilter(s.Include, exclude)
+}
+
+// excludeKeys makes keys not match, whatever the patterns.
+func (f *keyFilter) excludeKeys(keys ...string) {
+	for _, k := range keys {
+		k := k
+		f.exclude = append(f.exclude, func(key string) bool {
+			return key == k
+		})
+	}
+}
+
+func compilePattern(p string) (func(string) bool, error) {
+	if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		re, err := regexp.Compile(p[1 : len(p)-1])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid key pattern "+p)
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(p, ""); err != nil {
+		return nil, errors.Wrap(err, "invalid key pattern "+p)
+	}
+	return func(key string) bool {
+		matched, _ := path.Match(p, key)
+		return matched
+	}, nil
+}
+
+// match reports whether key matches any include pattern, or there is none,
+// and doesn't match any exclude pattern.
+func (f *keyFilter) match(key string) bool {
+	for _, m := range f.exclude {
+		if m(key) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, m := range f.include {
+		if m(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the key-values in env whose key matches.
+func (f *keyFilter) apply(env Env) Env {
+	res := make(Env)
+	for k, v := range env {
+		if f.match(k) {
+			res[k] = v
+		}
+	}
+	return res
+}
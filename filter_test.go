@@ -0,0 +1,62 @@
+package envsync_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Include(t *testing.T) {
+	syncer := envsync.New(envsync.WithInclude("FEATURE_*", "/^FLAG_[0-9]+$/"), envsync.WithPrune())
+
+	src := "FEATURE_A=on\nFLAG_1=on\nFLAG_X=on\nDATABASE_URL=postgres://localhost\n"
+	dst := "FEATURE_OLD=on\nSECRET=keep\n"
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FEATURE_A", "FLAG_1"}, res.Added)
+	assert.Equal(t, []string{"FEATURE_OLD"}, res.Pruned)
+	assert.Equal(t, envsync.Env{"SECRET": "keep", "FEATURE_A": "on", "FLAG_1": "on"}, res.Env)
+	assert.Equal(t, "SECRET=keep\nFEATURE_A=on\nFLAG_1=on\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_Exclude(t *testing.T) {
+	syncer := envsync.New(envsync.WithExclude("*_PASSWORD", "TOKEN"), envsync.WithOverwrite(""))
+
+	src := "DB_PASSWORD=sample\nTOKEN=sample\nHOST=localhost\n"
+	dst := "DB_PASSWORD=real\nHOST=example.com\n"
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Added)
+	assert.Equal(t, []string{"HOST"}, res.Overwritten)
+	assert.Equal(t, "DB_PASSWORD=real\nHOST=localhost\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_InvalidFilter(t *testing.T) {
+	for _, syncer := range []*envsync.Syncer{
+		envsync.New(envsync.WithInclude("[")),
+		envsync.New(envsync.WithExclude("/(/")),
+	} {
+		var buf bytes.Buffer
+		_, err := syncer.SyncReaders(strings.NewReader(""), strings.NewReader(""), &buf)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestSyncer_Diff_Filter(t *testing.T) {
+	syncer := envsync.New(envsync.WithInclude("ROULETTE_*"), envsync.WithExclude("ROULETTE_BASIC_PASSWORD"))
+
+	diff, err := syncer.Diff("testdata/env.success", "testdata/env.error")
+	assert.NotNil(t, err)
+	assert.Nil(t, diff)
+
+	diff, err = syncer.Diff("testdata/env.success", "testdata/env.success")
+	assert.Nil(t, err)
+	assert.True(t, diff.Equal())
+}
@@ -0,0 +1,71 @@
+package envsync
+
+import (
+	"context"
+	"io/ioutil"
+)
+
+// FormatResult reports the changes Format makes to an env file.
+type FormatResult struct {
+	// Fixed are the keys changed by the rules, in the order of rules.
+	Fixed []string `json:"fixed"`
+
+	// Changed tells whether the env file is changed, by the rules or by Style.
+	Changed bool `json:"changed"`
+
+	// Patch holds the changes as a unified diff if Patch is set.
+	Patch string `json:"patch,omitempty"`
+}
+
+// Format corrects the violations of rules in the env file at target, e.g. NamingRule renames keys to UPPER_SNAKE_CASE,
+// then lays it out following Style.
+// Rules that aren't a LintFixer are skipped.
+//
+// Target is replaced atomically if it changes, and backed up first if Backup is set.
+// If DryRun is set, target is only read and the changes that would be made are returned.
+func (s *Syncer) Format(ctx context.Context, target string, rules ...LintRule) (*FormatResult, error) {
+	doc, err := s.readDocument(ctx, target, "target")
+	if err != nil {
+		return nil, err
+	}
+	masker, err := s.masker()
+	if err != nil {
+		return nil, err
+	}
+	// line endings are normalized when target is read, so the formatted document is compared to the file itself
+	raw, err := ioutil.ReadFile(target)
+	if err != nil {
+		return nil, err
+	}
+	before := doc.clone()
+
+	result := &FormatResult{}
+	for _, r := range rules {
+		if f, ok := r.(LintFixer); ok {
+			result.Fixed = append(result.Fixed, f.Fix(doc)...)
+		}
+	}
+	if err := s.Style.apply(doc); err != nil {
+		return nil, err
+	}
+	result.Changed = doc.String() != string(raw)
+	if s.Patch {
+		result.Patch = masker.Diff(target, before, doc)
+	}
+	if s.Logger != nil {
+		for _, k := range result.Fixed {
+			s.Logger.Printf("Env formatted: %s", k)
+		}
+	}
+	if s.DryRun || !result.Changed {
+		return result, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.writeEnv(target, doc); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
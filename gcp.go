@@ -0,0 +1,166 @@
+package envsync
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	gcpScope       = "https://www.googleapis.com/auth/cloud-platform"
+	gcpTokenURL    = "https://oauth2.googleapis.com/token"
+	gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcpCredentials is the JSON file of a service account key or of the credentials of gcloud auth application-default login.
+type gcpCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gcpError is an error returned by a Google Cloud API, e.g. NOT_FOUND.
+type gcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (e *gcpError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// newGCPClient returns a client of the Google Cloud API at endpoint, or at the endpoint query parameter of u if it is set,
+// authenticated with the Application Default Credentials.
+func newGCPClient(u *url.URL, endpoint string) *restClient {
+	if e := u.Query().Get("endpoint"); e != "" {
+		endpoint = e
+	}
+	var tokens tokenCache
+	return &restClient{
+		base: endpoint,
+		auth: bearer(func(ctx context.Context) (string, error) {
+			if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+				return token, nil
+			}
+			token, err := tokens.get(func() (string, time.Duration, error) {
+				return gcpAccessToken(ctx)
+			})
+			return token, errors.Wrap(err, "couldn't get Google Cloud credentials")
+		}),
+		errorOf: func(status int, body []byte) error {
+			var e struct {
+				Error *gcpError `json:"error"`
+			}
+			if json.Unmarshal(body, &e) != nil || e.Error == nil || e.Error.Status == "" {
+				return nil
+			}
+			return e.Error
+		},
+	}
+}
+
+// gcpAccessToken returns an OAuth access token of the Application Default Credentials, and the time it is valid for,
+// found like Google's libraries do: the credentials file at GOOGLE_APPLICATION_CREDENTIALS,
+// the one written by gcloud auth application-default login, or the metadata server of Compute Engine.
+func gcpAccessToken(ctx context.Context) (string, time.Duration, error) {
+	name := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if name == "" {
+		dir := os.Getenv("CLOUDSDK_CONFIG")
+		if dir == "" {
+			// gcloud keeps its config in ~/.config on macOS too, unlike os.UserConfigDir
+			home, _ := os.UserHomeDir()
+			dir = filepath.Join(home, ".config", "gcloud")
+			if runtime.GOOS == "windows" {
+				dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+			}
+		}
+		name = filepath.Join(dir, "application_default_credentials.json")
+		if _, err := os.Stat(name); err != nil {
+			name = ""
+		}
+	}
+	if name == "" {
+		return requestToken(ctx, gcpMetadataURL, nil, map[string]string{"Metadata-Flavor": "Google"})
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", 0, err
+	}
+	var creds gcpCredentials
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return "", 0, errors.Wrap(err, fmt.Sprintf("couldn't read %s", name))
+	}
+	switch creds.Type {
+	case "service_account":
+		tokenURL := gcpTokenURL
+		if creds.TokenURI != "" {
+			tokenURL = creds.TokenURI
+		}
+		assertion, err := gcpAssertion(creds, tokenURL, time.Now())
+		if err != nil {
+			return "", 0, err
+		}
+		form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+		return requestToken(ctx, tokenURL, form, nil)
+	case "authorized_user":
+		form := url.Values{"grant_type": {"refresh_token"}, "client_id": {creds.ClientID}, "client_secret": {creds.ClientSecret}, "refresh_token": {creds.RefreshToken}}
+		return requestToken(ctx, gcpTokenURL, form, nil)
+	}
+	return "", 0, fmt.Errorf("unsupported credentials type %q in %s", creds.Type, name)
+}
+
+// gcpAssertion returns the JWT a service account exchanges for an access token at aud.
+func gcpAssertion(creds gcpCredentials, aud string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid private key of service account")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", errors.Wrap(err, "invalid private key of service account")
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private key of service account isn't an RSA key")
+	}
+
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": gcpScope,
+		"aud":   aud,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,191 @@
+package envsync
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com/v1"
+
+// gcpSecretsTarget is Google Cloud Secret Manager as a Target, in either of two ways:
+// gcpsm://my-project maps each key to the secret of the same name in the project, e.g. DB_PASSWORD,
+// optionally prefixed with the prefix query parameter, e.g. ?prefix=myapp_ for myapp_DB_PASSWORD,
+// and gcpsm://my-project/my-secret is a single secret holding a JSON object like secretsManagerTarget.
+//
+// Values are read from the latest version of the secrets, and written as a new version.
+// Missing secrets are created with automatic replication.
+type gcpSecretsTarget struct {
+	name    string
+	project string
+	secret  string
+	prefix  string
+	client  *restClient
+
+	// secrets are the names of the secrets of the keys as they are loaded, and env their values.
+	secrets map[string]bool
+	env     Env
+	exists  bool
+}
+
+func openGCPSecrets(u *url.URL) (Target, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no project in %s", u)
+	}
+	return &gcpSecretsTarget{
+		name:    u.String(),
+		project: u.Host,
+		secret:  strings.Trim(u.Path, "/"),
+		prefix:  u.Query().Get("prefix"),
+		client:  newGCPClient(u, gcpSecretManagerEndpoint),
+	}, nil
+}
+
+func (t *gcpSecretsTarget) Name() string {
+	return t.name
+}
+
+func (t *gcpSecretsTarget) Load(ctx context.Context) (*Document, error) {
+	if t.secret != "" {
+		value, err := t.access(ctx, t.secret)
+		if isGCPNotFound(err) {
+			t.exists = false
+			return &Document{codec: CodecJSON}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.exists = true
+		doc, err := decodeJSON(strings.NewReader(value))
+		return doc, errors.Wrap(err, fmt.Sprintf("couldn't read secret %s", t.secret))
+	}
+
+	t.secrets = make(map[string]bool)
+	t.env = Env{}
+	token := ""
+	for {
+		var out struct {
+			Secrets []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		path := fmt.Sprintf("/projects/%s/secrets?pageSize=250&pageToken=%s", t.project, url.QueryEscape(token))
+		if err := t.client.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't list the secrets of %s", t.project))
+		}
+		for _, s := range out.Secrets {
+			id := s.Name[strings.LastIndex(s.Name, "/")+1:]
+			if !strings.HasPrefix(id, t.prefix) {
+				continue
+			}
+			key := strings.TrimPrefix(id, t.prefix)
+			t.secrets[key] = true
+			value, err := t.access(ctx, id)
+			if isGCPNotFound(err) {
+				// a secret without an enabled version has no value, and is given one when it is stored
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			t.env[key] = value
+		}
+		if token = out.NextPageToken; token == "" {
+			break
+		}
+	}
+	return envDocument(t.env), nil
+}
+
+// Store adds a version to the secrets whose value is added or changed since the target is loaded,
+// and deletes the secrets of the keys that aren't in doc anymore.
+func (t *gcpSecretsTarget) Store(ctx context.Context, doc *Document) error {
+	if t.secret != "" {
+		if !t.exists {
+			if err := t.create(ctx, t.secret); err != nil {
+				return err
+			}
+			t.exists = true
+		}
+		return t.addVersion(ctx, t.secret, doc.recode(CodecJSON).String())
+	}
+
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		if !t.secrets[k] {
+			if err := t.create(ctx, t.prefix+k); err != nil {
+				return err
+			}
+			t.secrets[k] = true
+		}
+		if err := t.addVersion(ctx, t.prefix+k, env[k]); err != nil {
+			return err
+		}
+		t.env[k] = env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		path := fmt.Sprintf("/projects/%s/secrets/%s", t.project, t.prefix+k)
+		if err := t.client.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't delete secret %s", t.prefix+k))
+		}
+		delete(t.env, k)
+		delete(t.secrets, k)
+	}
+	return nil
+}
+
+// access returns the value of the latest version of secret.
+func (t *gcpSecretsTarget) access(ctx context.Context, secret string) (string, error) {
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	path := fmt.Sprintf("/projects/%s/secrets/%s/versions/latest:access", t.project, secret)
+	if err := t.client.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		if isGCPNotFound(err) {
+			return "", err
+		}
+		return "", errors.Wrap(err, fmt.Sprintf("couldn't read secret %s", secret))
+	}
+	b, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	return string(b), errors.Wrap(err, fmt.Sprintf("couldn't decode secret %s", secret))
+}
+
+func (t *gcpSecretsTarget) create(ctx context.Context, secret string) error {
+	path := fmt.Sprintf("/projects/%s/secrets?secretId=%s", t.project, url.QueryEscape(secret))
+	in := map[string]interface{}{"replication": map[string]interface{}{"automatic": map[string]interface{}{}}}
+	err := t.client.do(ctx, http.MethodPost, path, in, nil)
+	return errors.Wrap(err, fmt.Sprintf("couldn't create secret %s", secret))
+}
+
+func (t *gcpSecretsTarget) addVersion(ctx context.Context, secret, value string) error {
+	path := fmt.Sprintf("/projects/%s/secrets/%s:addVersion", t.project, secret)
+	in := map[string]interface{}{"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte(value))}}
+	err := t.client.do(ctx, http.MethodPost, path, in, nil)
+	return errors.Wrap(err, fmt.Sprintf("couldn't write secret %s", secret))
+}
+
+// isGCPNotFound reports whether err is the NOT_FOUND or FAILED_PRECONDITION error of a secret or version
+// that doesn't exist or isn't enabled.
+func isGCPNotFound(err error) bool {
+	e, ok := err.(*gcpError)
+	return ok && (e.Status == "NOT_FOUND" || e.Status == "FAILED_PRECONDITION")
+}
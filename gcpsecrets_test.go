@@ -0,0 +1,140 @@
+package envsync_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGCPSecrets serves the API of Secret Manager for my-project from memory, and the token endpoint of a service account.
+type fakeGCPSecrets struct {
+	mu      sync.Mutex
+	secrets map[string][]string
+}
+
+func (f *fakeGCPSecrets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.FormValue("assertion"), ".") != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"code": 404, "message": "Secret not found", "status": "NOT_FOUND"}}`))
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/projects/my-project/secrets")
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		var names []string
+		for id := range f.secrets {
+			names = append(names, "projects/my-project/secrets/"+id)
+		}
+		sort.Strings(names)
+		var secrets []map[string]string
+		for _, name := range names {
+			secrets = append(secrets, map[string]string{"name": name})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"secrets": secrets})
+	case r.Method == http.MethodPost && path == "":
+		f.secrets[r.URL.Query().Get("secretId")] = nil
+		w.Write([]byte("{}"))
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/versions/latest:access"):
+		versions := f.secrets[strings.TrimSuffix(path[1:], "/versions/latest:access")]
+		if len(versions) == 0 {
+			notFound()
+			return
+		}
+		data := base64.StdEncoding.EncodeToString([]byte(versions[len(versions)-1]))
+		json.NewEncoder(w).Encode(map[string]interface{}{"payload": map[string]string{"data": data}})
+	case r.Method == http.MethodPost && strings.HasSuffix(path, ":addVersion"):
+		id := strings.TrimSuffix(path[1:], ":addVersion")
+		if _, ok := f.secrets[id]; !ok {
+			notFound()
+			return
+		}
+		var in struct {
+			Payload struct {
+				Data []byte `json:"data"`
+			} `json:"payload"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		f.secrets[id] = append(f.secrets[id], string(in.Payload.Data))
+		w.Write([]byte("{}"))
+	case r.Method == http.MethodDelete:
+		delete(f.secrets, path[1:])
+		w.Write([]byte("{}"))
+	default:
+		notFound()
+	}
+}
+
+func TestSyncer_Sync_GCPSecrets(t *testing.T) {
+	gsm := &fakeGCPSecrets{secrets: map[string][]string{
+		"myapp_HOST":  {"localhost", "example.com"},
+		"myapp_OLD":   {"x"},
+		"other_TOKEN": {"t0ken"},
+	}}
+	server := httptest.NewServer(gsm)
+	defer server.Close()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\nPORT=8080\n",
+	})
+	defer os.RemoveAll(dir)
+
+	// a service account exchanges a signed JWT for an access token
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "envsync@my-project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":    server.URL + "/token",
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "key.json"), creds, 0600))
+	defer setenv(map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": filepath.Join(dir, "key.json")})()
+
+	// each key is a secret
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "gcpsm://my-project?prefix=myapp_&endpoint="+server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string][]string{
+		"myapp_HOST":  {"localhost", "example.com"},
+		"myapp_PORT":  {"8080"},
+		"other_TOKEN": {"t0ken"},
+	}, gsm.secrets)
+
+	// or a secret holds a JSON object
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "gcpsm://my-project/myapp?endpoint="+server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST", "PORT"}, res.Added)
+	assert.Equal(t, []string{"{\n  \"HOST\": \"localhost\",\n  \"PORT\": \"8080\"\n}\n"}, gsm.secrets["myapp"])
+}
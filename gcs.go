@@ -0,0 +1,86 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const gcsAPI = "https://storage.googleapis.com"
+
+// gcsObject is an object of a Google Cloud Storage bucket as the store of an objectTarget, e.g. gs://my-bucket/myapp/.env,
+// authenticated with the Application Default Credentials. See newGCPClient.
+// Its version is its generation, and it is written with ifGenerationMatch, which is 0 to create it.
+type gcsObject struct {
+	bucket string
+	object string
+	client *restClient
+}
+
+func openGCS(u *url.URL) (Target, error) {
+	object := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || object == "" || strings.HasSuffix(object, "/") {
+		return nil, fmt.Errorf("expected gs://bucket/object, got %s", u)
+	}
+	return &objectTarget{
+		name:       u.String(),
+		fileFormat: fileFormat{codec: CodecOf(object)},
+		store:      &gcsObject{bucket: u.Host, object: object, client: newGCPClient(u, gcsAPI)},
+	}, nil
+}
+
+// isGCSStatus reports whether err is the error of a response of status.
+// Cloud Storage answers errors without the status name of other Google Cloud APIs, so they are mostly *httpError.
+func isGCSStatus(err error, status int) bool {
+	if e, ok := err.(*gcpError); ok {
+		return e.Code == status
+	}
+	return isHTTPStatus(err, status)
+}
+
+func (o *gcsObject) get(ctx context.Context) ([]byte, string, error) {
+	u := strings.TrimRight(o.client.base, "/") + "/storage/v1/b/" + url.PathEscape(o.bucket) + "/o/" + url.PathEscape(o.object) + "?alt=media"
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	header, b, err := o.client.send(ctx, req)
+	if isGCSStatus(err, http.StatusNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return b, header.Get("X-Goog-Generation"), nil
+}
+
+func (o *gcsObject) put(ctx context.Context, b []byte, version string) (string, error) {
+	if version == "" {
+		version = "0"
+	}
+	q := url.Values{"uploadType": {"media"}, "name": {o.object}, "ifGenerationMatch": {version}}
+	u := strings.TrimRight(o.client.base, "/") + "/upload/storage/v1/b/" + url.PathEscape(o.bucket) + "/o?" + q.Encode()
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	_, body, err := o.client.send(ctx, req)
+	if isGCSStatus(err, http.StatusPreconditionFailed) {
+		return "", errObjectChanged
+	}
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Generation string `json:"generation"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", err
+	}
+	return out.Generation, nil
+}
@@ -0,0 +1,97 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGCS serves the objects of the bucket my-bucket from memory with their generations.
+// If modified is set, an object is changed by someone else right after it is read.
+type fakeGCS struct {
+	mu          sync.Mutex
+	objects     map[string]string
+	generations map[string]int
+	modified    bool
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer gcs-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/my-bucket/o/") && r.URL.Query().Get("alt") == "media":
+		name := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/my-bucket/o/")
+		body, ok := f.objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "No such object: my-bucket/` + name + `"}}`))
+			return
+		}
+		w.Header().Set("X-Goog-Generation", fmt.Sprint(f.generations[name]))
+		w.Write([]byte(body))
+		if f.modified {
+			f.generations[name]++
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/my-bucket/o" && r.URL.Query().Get("uploadType") == "media":
+		name := r.URL.Query().Get("name")
+		if r.URL.Query().Get("ifGenerationMatch") != fmt.Sprint(f.generations[name]) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(`{"error": {"code": 412, "message": "At least one of the pre-conditions you specified did not hold."}}`))
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		f.objects[name] = string(b)
+		f.generations[name]++
+		json.NewEncoder(w).Encode(map[string]string{"name": name, "generation": fmt.Sprint(f.generations[name])})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSyncer_Sync_GCS(t *testing.T) {
+	gcs := &fakeGCS{
+		objects:     map[string]string{"myapp/.env": "HOST=example.com\n"},
+		generations: map[string]int{"myapp/.env": 1},
+	}
+	server := httptest.NewServer(gcs)
+	defer server.Close()
+	defer setenv(map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "gcs-token"})()
+	endpoint := "?endpoint=" + url.QueryEscape(server.URL)
+
+	dir := makeTree(t, map[string]string{"env.sample": "HOST=localhost\nPORT=8080\n"})
+	defer os.RemoveAll(dir)
+
+	res, err := envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "gs://my-bucket/myapp/.env"+endpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Equal(t, "HOST=example.com\nPORT=8080\n", gcs.objects["myapp/.env"])
+	assert.Equal(t, 2, gcs.generations["myapp/.env"])
+
+	// an object is a source too, and a missing one is created
+	res, err = envsync.New().SyncWithResult("gs://my-bucket/myapp/.env"+endpoint, "gs://my-bucket/other/env.yaml"+endpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST", "PORT"}, res.Added)
+	assert.Equal(t, "HOST: example.com\nPORT: 8080\n", gcs.objects["other/env.yaml"])
+
+	// an object changed since it was read isn't overwritten
+	gcs.modified = true
+	_, err = envsync.New(envsync.WithOverwrite("")).SyncWithResult(filepath.Join(dir, "env.sample"), "gs://my-bucket/myapp/.env"+endpoint)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "it changed since it was read")
+	assert.Equal(t, "HOST=example.com\nPORT=8080\n", gcs.objects["myapp/.env"])
+}
@@ -0,0 +1,126 @@
+package envsync
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Default sizes of the generators: a number of bytes, or a number of words for passphrase.
+const (
+	defaultSecretSize     = 32
+	defaultPassphraseSize = 6
+)
+
+// generate gives a random value to the key-values in added annotated with # envsync:generate in src,
+// recording them to edited.
+func generate(src *Document, added, edited map[string]string) error {
+	for _, k := range sortedKeys(added) {
+		annotations := src.annotations(k)
+		spec, ok := annotations[annotationGenerate]
+		if !ok {
+			continue
+		}
+
+		v, err := generateValue(spec)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't generate %s", k))
+		}
+		added[k] = v
+		edited[k] = v
+	}
+	return nil
+}
+
+// generators holds the names of the generators.
+var generators = []string{"hex", "base64", "uuid", "passphrase"}
+
+// generateValue returns a random value following spec: a generator name optionally followed by a size,
+// e.g. hex32 for 32 random bytes encoded in hex, or base64:16 for 16 random bytes encoded in base64.
+// The generators are hex, base64, uuid, and passphrase, whose size is a number of words.
+// An empty spec is the same as hex32.
+func generateValue(spec string) (string, error) {
+	name := ""
+	for _, g := range generators {
+		if strings.HasPrefix(spec, g) {
+			name = g
+		}
+	}
+	size := 0
+	if n := strings.TrimPrefix(spec[len(name):], ":"); n != "" {
+		size, _ = strconv.Atoi(n)
+		if size == 0 {
+			return "", fmt.Errorf("invalid generator size: %s", spec)
+		}
+	}
+
+	switch name {
+	case "", "hex":
+		b, err := randomBytes(size, defaultSecretSize)
+		return hex.EncodeToString(b), err
+	case "base64":
+		b, err := randomBytes(size, defaultSecretSize)
+		return base64.StdEncoding.EncodeToString(b), err
+	case "uuid":
+		if size != 0 {
+			return "", fmt.Errorf("invalid generator size: %s", spec)
+		}
+		b, err := randomBytes(0, 16)
+		if err != nil {
+			return "", err
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+	case "passphrase":
+		if size == 0 {
+			size = defaultPassphraseSize
+		}
+		b, err := randomBytes(size*2, 0)
+		if err != nil {
+			return "", err
+		}
+		words := make([]string, size)
+		for i := range words {
+			words[i] = wordList[int(binary.BigEndian.Uint16(b[i*2:]))%len(wordList)]
+		}
+		return strings.Join(words, "-"), nil
+	}
+	return "", fmt.Errorf("unknown generator: %s", spec)
+}
+
+func randomBytes(size, defaultSize int) ([]byte, error) {
+	if size == 0 {
+		size = defaultSize
+	}
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// wordList holds the words of generated passphrases.
+// Its length divides 65536, so every word is as likely to be picked.
+var wordList = []string{
+	"acid", "acorn", "actor", "adobe", "agent", "alarm", "album", "alley", "amber", "angle", "ankle", "apple", "apron", "arena", "arrow", "atlas",
+	"attic", "audio", "award", "bacon", "badge", "bagel", "baker", "bamboo", "banjo", "barn", "basil", "batch", "beach", "beard", "bench", "berry",
+	"bison", "blade", "blank", "blaze", "bloom", "board", "bonus", "boots", "brain", "brass", "bread", "brick", "bride", "brook", "brush", "bunny",
+	"cabin", "cable", "cacao", "camel", "candy", "canoe", "cargo", "carol", "cedar", "chalk", "charm", "chart", "chess", "chief", "chili", "cider",
+	"cigar", "civic", "claim", "clerk", "cliff", "clock", "cloud", "coach", "cobra", "cocoa", "comet", "coral", "couch", "crane", "crate", "crisp",
+	"crown", "cubic", "curry", "cycle", "daisy", "dance", "delta", "denim", "depot", "diary", "dingo", "disco", "dodge", "donut", "draft", "dream",
+	"drift", "drum", "eagle", "easel", "ebony", "elbow", "elder", "ember", "empty", "envoy", "epoch", "equal", "essay", "evening", "fable", "fairy",
+	"falcon", "fancy", "feast", "fence", "ferry", "fever", "fiber", "field", "flame", "flask", "fleet", "flint", "flora", "flute", "focus", "forge",
+	"fossil", "frame", "frost", "fudge", "galaxy", "gamma", "garlic", "gecko", "genie", "giant", "ginger", "glass", "globe", "glove", "goose", "grain",
+	"grape", "gravy", "green", "guard", "guide", "habit", "harbor", "hazel", "heart", "hedge", "heron", "honey", "horse", "hotel", "humor", "husky",
+	"igloo", "image", "index", "inlet", "ivory", "jacket", "jaguar", "jelly", "jewel", "joker", "judge", "juice", "kayak", "kettle", "kiosk", "koala",
+	"label", "lemon", "level", "lilac", "linen", "llama", "lodge", "lotus", "lunar", "magic", "mango", "maple", "marsh", "medal", "melon", "metal",
+	"mirror", "mocha", "motor", "mural", "music", "nectar", "noble", "north", "novel", "oasis", "ocean", "olive", "omega", "onion", "opera", "orbit",
+	"otter", "oxide", "paddle", "panda", "paper", "pearl", "pecan", "pedal", "piano", "pilot", "pixel", "pizza", "plaza", "polar", "pouch", "prism",
+	"quail", "quartz", "quest", "quilt", "radar", "radio", "raven", "relay", "rhino", "ridge", "river", "robin", "rocket", "rumba", "saddle", "salad",
+	"salsa", "satin", "scarf", "scout", "shell", "sieve", "silver", "sketch", "slate", "solar", "spice", "squid", "stone", "sugar", "tango", "tiger",
+}
@@ -0,0 +1,57 @@
+package envsync_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Generate(t *testing.T) {
+	src := strings.Join([]string{
+		"# envsync:generate",
+		"APP_KEY=changeme",
+		"# envsync:generate hex:16",
+		"HEX=",
+		"# envsync:generate base64",
+		"B64=",
+		"# envsync:generate uuid",
+		"ID=",
+		"# envsync:generate passphrase4",
+		"WORDS=",
+		"# envsync:generate hex",
+		"KEPT=",
+		"",
+	}, "\n")
+
+	var buf bytes.Buffer
+	res, err := envsync.New().SyncReaders(strings.NewReader(src), strings.NewReader("KEPT=mine\n"), &buf)
+	assert.Nil(t, err)
+	assert.Regexp(t, "^[0-9a-f]{64}$", res.Env["APP_KEY"])
+	assert.Regexp(t, "^[0-9a-f]{32}$", res.Env["HEX"])
+	assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", res.Env["ID"])
+	assert.Regexp(t, "^[a-z]+(-[a-z]+){3}$", res.Env["WORDS"])
+	assert.Equal(t, "mine", res.Env["KEPT"])
+
+	b, err := base64.StdEncoding.DecodeString(res.Env["B64"])
+	assert.Nil(t, err)
+	assert.Len(t, b, 32)
+
+	assert.Contains(t, buf.String(), "APP_KEY="+res.Env["APP_KEY"]+"\n")
+	assert.Contains(t, buf.String(), "\nID="+res.Env["ID"]+"\n")
+
+	res2, err := envsync.New().SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.NotEqual(t, res.Env["APP_KEY"], res2.Env["APP_KEY"])
+}
+
+func TestSyncer_SyncReaders_GenerateInvalid(t *testing.T) {
+	for _, spec := range []string{"rot13", "hex0", "uuid4", "base64x"} {
+		var buf bytes.Buffer
+		_, err := envsync.New().SyncReaders(strings.NewReader("# envsync:generate "+spec+"\nKEY=\n"), strings.NewReader(""), &buf)
+		assert.NotNil(t, err, spec)
+	}
+}
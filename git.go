@@ -0,0 +1,100 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// gitSource is an env file at a branch, tag, or commit of a Git repository as a Source, e.g. git://github.com/my-org/my-app#v1.2.0:.env.example,
+// so it can be read where the repository isn't checked out. It is read with the codec of the extension of its path, or the one set in Codecs.
+// The ref may be left out to read the default branch, e.g. git://github.com/my-org/my-app#:.env.example.
+//
+// The repository is fetched with the git command, over HTTPS, or over SSH if the URL has a user, e.g. git://git@github.com/my-org/my-app,
+// or from the local repository at the path of the URL if it has no host, e.g. git:///srv/my-app#main:.env.example.
+// Only the commit of the ref is fetched, without its history.
+// It can't be written, so it isn't a target.
+type gitSource struct {
+	fileFormat
+	name string
+	repo string
+	ref  string
+	path string
+}
+
+func openGit(u *url.URL) (Target, error) {
+	sp := strings.SplitN(u.Fragment, ":", 2)
+	if len(sp) != 2 || sp[1] == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("expected git://repo#ref:path, got %s", redactedURL(u))
+	}
+	ref := sp[0]
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	var repo string
+	switch {
+	case u.Host == "":
+		repo = "file://" + u.Path
+	case u.User != nil:
+		repo = u.User.Username() + "@" + u.Host + ":" + strings.TrimPrefix(u.Path, "/")
+	default:
+		repo = "https://" + u.Host + u.Path
+	}
+	return &gitSource{fileFormat: fileFormat{codec: CodecOf(sp[1])}, name: redactedURL(u), repo: repo, ref: ref, path: sp[1]}, nil
+}
+
+func (t *gitSource) Name() string {
+	return t.name
+}
+
+// Load fetches the ref in a temporary repository, and reads the file at path in its commit.
+func (t *gitSource) Load(ctx context.Context) (*Document, error) {
+	dir, err := ioutil.TempDir("", "envsync-git")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := runGit(ctx, dir, "init", "--quiet"); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, dir, "fetch", "--quiet", "--depth", "1", t.repo, t.ref); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't fetch %s of %s", t.ref, t.repo))
+	}
+	b, err := runGit(ctx, dir, "show", "FETCH_HEAD:"+strings.TrimPrefix(t.path, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s at %s", t.path, t.ref))
+	}
+	return decodeDocument(bytes.NewReader(b), t.codec, t.opts)
+}
+
+// Store fails, since a file in a Git repository can't be written.
+func (t *gitSource) Store(ctx context.Context, doc *Document) error {
+	return fmt.Errorf("%s can only be a source, it can't be written", t.name)
+}
+
+// runGit runs the git command with args in dir, and returns its output, or its error message.
+// git never prompts for credentials, so it fails rather than waits for an answer.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrap(err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
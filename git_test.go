@@ -0,0 +1,68 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// gitRepo commits files to a new repository in dir, tagged v1, then commits changed to the default branch.
+func gitRepo(t *testing.T, dir string, files, changed map[string]string) {
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=envsync", "GIT_AUTHOR_EMAIL=envsync@example.com", "GIT_COMMITTER_NAME=envsync", "GIT_COMMITTER_EMAIL=envsync@example.com")
+		out, err := cmd.CombinedOutput()
+		assert.Nil(t, err, string(out))
+	}
+	commit := func(files map[string]string) {
+		for name, content := range files {
+			os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", "env")
+	}
+	git("init", "--quiet")
+	commit(files)
+	git("tag", "v1")
+	commit(changed)
+}
+
+func TestSyncer_Sync_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir := makeTree(t, map[string]string{".env": ""})
+	defer os.RemoveAll(dir)
+	repo := filepath.Join(dir, "repo")
+	os.Mkdir(repo, 0755)
+	gitRepo(t, repo, map[string]string{"config/.env.example": "PORT=8080\n"}, map[string]string{"config/.env.example": "PORT=8080\nDEBUG=false\n"})
+	target := filepath.Join(dir, ".env")
+
+	res, err := envsync.New().SyncWithResult("git://"+filepath.ToSlash(repo)+"#v1:config/.env.example", target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+
+	// the default branch is read without a ref
+	res, err = envsync.New().SyncWithResult("git://"+filepath.ToSlash(repo)+"#:config/.env.example", target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+
+	_, err = envsync.New().SyncWithResult("git://"+filepath.ToSlash(repo)+"#v1:.env.example", target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't read .env.example at v1")
+
+	_, err = envsync.New().SyncWithResult("git://"+filepath.ToSlash(repo)+"#v2:config/.env.example", target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't fetch v2")
+
+	_, err = envsync.New().SyncWithResult("git://"+filepath.ToSlash(repo), target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "expected git://repo#ref:path")
+}
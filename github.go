@@ -0,0 +1,190 @@
+package envsync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/box"
+)
+
+const (
+	githubAPI        = "https://api.github.com"
+	githubAPIVersion = "2022-11-28"
+
+	// githubPageSize is the number of secrets listed per request, the most GitHub allows.
+	githubPageSize = 100
+)
+
+// githubTarget is the Actions secrets of a GitHub repository as a Target, e.g. github://my-org/my-repo,
+// or the secrets of one of its environments with the environment query parameter, e.g. ?environment=production.
+// The token is read from GITHUB_TOKEN or GH_TOKEN, and the API is at GITHUB_API_URL for GitHub Enterprise Server.
+//
+// The values of secrets can't be read back, so only their keys are compared, e.g. by Diff to report the secrets CI misses.
+// Secrets are written sealed with the public key of the repository, as GitHub requires.
+type githubTarget struct {
+	name   string
+	path   string
+	client *restClient
+	keys   map[string]bool
+}
+
+func openGitHub(u *url.URL) (Target, error) {
+	repo := strings.Trim(u.Path, "/")
+	if u.Host == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("expected github://owner/repo, got %s", u)
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("GITHUB_TOKEN isn't set")
+	}
+	endpoint := os.Getenv("GITHUB_API_URL")
+	if endpoint == "" {
+		endpoint = githubAPI
+	}
+
+	path := "/repos/" + url.PathEscape(u.Host) + "/" + url.PathEscape(repo) + "/actions/secrets"
+	if env := u.Query().Get("environment"); env != "" {
+		path = "/repos/" + url.PathEscape(u.Host) + "/" + url.PathEscape(repo) + "/environments/" + url.PathEscape(env) + "/secrets"
+	}
+	return &githubTarget{
+		name: u.String(),
+		path: path,
+		client: &restClient{
+			base: endpoint,
+			auth: func(_ context.Context, req *http.Request) error {
+				req.Header.Set("Accept", "application/vnd.github+json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				req.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+				return nil
+			},
+			errorOf: func(status int, body []byte) error {
+				var e struct {
+					Message string `json:"message"`
+				}
+				if json.Unmarshal(body, &e) != nil || e.Message == "" {
+					return nil
+				}
+				return &httpError{status: status, message: e.Message}
+			},
+		},
+	}, nil
+}
+
+func (t *githubTarget) Name() string {
+	return t.name
+}
+
+// Load returns the keys of the secrets, with empty values.
+func (t *githubTarget) Load(ctx context.Context) (*Document, error) {
+	keys := make(map[string]bool)
+	env := Env{}
+	for page := 1; ; page++ {
+		var out struct {
+			TotalCount int `json:"total_count"`
+			Secrets    []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		}
+		q := url.Values{"per_page": {fmt.Sprint(githubPageSize)}, "page": {fmt.Sprint(page)}}
+		if err := t.client.do(ctx, http.MethodGet, t.path+"?"+q.Encode(), nil, &out); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't list the secrets of %s", t.name))
+		}
+		for _, s := range out.Secrets {
+			keys[s.Name] = true
+			env[s.Name] = ""
+		}
+		if len(out.Secrets) == 0 || len(keys) >= out.TotalCount {
+			break
+		}
+	}
+	t.keys = keys
+	return envDocument(env), nil
+}
+
+// Store sets the secrets that are added, or whose value in doc isn't empty, and deletes the ones that aren't in doc anymore.
+// Since the values of secrets can't be read, a secret is only changed if doc holds a value for it,
+// i.e. once it is overwritten.
+func (t *githubTarget) Store(ctx context.Context, doc *Document) error {
+	if t.keys == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	var publicKey *githubPublicKey
+	for _, k := range env.Keys() {
+		if t.keys[k] && env[k] == "" {
+			continue
+		}
+		if publicKey == nil {
+			var err error
+			if publicKey, err = t.publicKey(ctx); err != nil {
+				return err
+			}
+		}
+		sealed, err := publicKey.seal(env[k])
+		if err != nil {
+			return err
+		}
+		in := map[string]string{"encrypted_value": sealed, "key_id": publicKey.ID}
+		if err := t.client.do(ctx, http.MethodPut, t.path+"/"+url.PathEscape(k), in, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set secret %s", k))
+		}
+		t.keys[k] = true
+	}
+	for k := range t.keys {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := t.client.do(ctx, http.MethodDelete, t.path+"/"+url.PathEscape(k), nil, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't delete secret %s", k))
+		}
+		delete(t.keys, k)
+	}
+	return nil
+}
+
+func (t *githubTarget) secretValues() {}
+
+func (t *githubTarget) writeOnly() {}
+
+// githubPublicKey is the public key secrets are sealed with.
+type githubPublicKey struct {
+	ID  string `json:"key_id"`
+	Key string `json:"key"`
+}
+
+func (t *githubTarget) publicKey(ctx context.Context) (*githubPublicKey, error) {
+	var key githubPublicKey
+	if err := t.client.do(ctx, http.MethodGet, t.path+"/public-key", nil, &key); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't get the public key of %s", t.name))
+	}
+	return &key, nil
+}
+
+// seal returns value encrypted in a libsodium sealed box for the key, encoded in base64.
+func (k *githubPublicKey) seal(value string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(k.Key)
+	if err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid public key %s", k.ID)
+	}
+	var recipient [32]byte
+	copy(recipient[:], b)
+	sealed, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
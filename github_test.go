@@ -0,0 +1,128 @@
+package envsync_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// fakeGitHub serves the Actions secrets of the repository my-org/my-repo from memory, opening the sealed values it is sent.
+type fakeGitHub struct {
+	mu                 sync.Mutex
+	publicKey, privKey *[32]byte
+	secrets            map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer gh-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "Bad credentials"}`))
+		return
+	}
+
+	const prefix = "/repos/my-org/my-repo/actions/secrets"
+	switch {
+	case r.URL.Path == prefix:
+		var names []string
+		for name := range f.secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		// the secrets are listed one per page, to go through pages
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		secrets := []map[string]string{}
+		if page >= 1 && page <= len(names) {
+			secrets = append(secrets, map[string]string{"name": names[page-1]})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": len(names), "secrets": secrets})
+	case r.URL.Path == prefix+"/public-key":
+		json.NewEncoder(w).Encode(map[string]string{"key_id": "k1", "key": base64.StdEncoding.EncodeToString(f.publicKey[:])})
+	case strings.HasPrefix(r.URL.Path, prefix+"/"):
+		name := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		if r.Method == http.MethodDelete {
+			delete(f.secrets, name)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		sealed, _ := base64.StdEncoding.DecodeString(in["encrypted_value"])
+		value, ok := box.OpenAnonymous(nil, sealed, f.publicKey, f.privKey)
+		if !ok || in["key_id"] != "k1" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message": "Bad encrypted value"}`))
+			return
+		}
+		f.secrets[name] = string(value)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSyncer_Sync_GitHub(t *testing.T) {
+	publicKey, privKey, err := box.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	github := &fakeGitHub{publicKey: publicKey, privKey: privKey, secrets: map[string]string{"DEPLOY_KEY": "k", "OLD": "x"}}
+	server := httptest.NewServer(github)
+	defer server.Close()
+	defer setenv(map[string]string{"GITHUB_TOKEN": "gh-token", "GITHUB_API_URL": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DEPLOY_KEY=changeme\nAPI_TOKEN=t0ken\n",
+	})
+	defer os.RemoveAll(dir)
+
+	// only keys are compared, since the values of secrets can't be read
+	syncer := envsync.New()
+	diff, err := syncer.Diff(filepath.Join(dir, "env.sample"), "github://my-org/my-repo")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"API_TOKEN"}, diff.OnlyInSource)
+	assert.Equal(t, []string{"OLD"}, diff.OnlyInTarget)
+	assert.Empty(t, diff.Changed)
+
+	syncer = envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "github://my-org/my-repo")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"API_TOKEN"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, []string{"DEPLOY_KEY"}, res.Skipped)
+	assert.Equal(t, map[string]string{"DEPLOY_KEY": "k", "API_TOKEN": "t0ken"}, github.secrets)
+
+	syncer.Overwrite = true
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "github://my-org/my-repo")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DEPLOY_KEY": "changeme", "API_TOKEN": "t0ken"}, github.secrets)
+
+	// a required key is set once the repository has a secret for it, though its value can't be read
+	ioutil.WriteFile(filepath.Join(dir, "env.sample"), []byte("# required\nDEPLOY_KEY=\n# required\nSSH_KEY=\nAPI_TOKEN=t0ken\n"), 0644)
+	_, err = envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "github://my-org/my-repo")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "SSH_KEY: required env is empty")
+	assert.NotContains(t, err.Error(), "DEPLOY_KEY")
+	ioutil.WriteFile(filepath.Join(dir, "env.sample"), []byte("# required\nDEPLOY_KEY=\nAPI_TOKEN=t0ken\n"), 0644)
+	_, err = envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "github://my-org/my-repo")
+	assert.Nil(t, err)
+	assert.Equal(t, "changeme", github.secrets["DEPLOY_KEY"])
+
+	defer setenv(map[string]string{"GITHUB_TOKEN": "invalid"})()
+	_, err = syncer.Diff(filepath.Join(dir, "env.sample"), "github://my-org/my-repo")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized: Bad credentials")
+}
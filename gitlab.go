@@ -0,0 +1,165 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	gitlabURL = "https://gitlab.com"
+
+	// gitlabPageSize is the number of variables listed per request, the most GitLab allows.
+	gitlabPageSize = 100
+)
+
+// gitlabTarget is the CI/CD variables of a GitLab project as a Target, e.g. gitlab://my-group/my-project,
+// or of a group with ?kind=group, e.g. gitlab://my-group?kind=group.
+// The environment query parameter sets the environment scope of the variables, e.g. ?environment=production, "*" by default.
+// The token is read from GITLAB_TOKEN, and the instance is at GITLAB_URL, or CI_SERVER_URL in a pipeline, or else gitlab.com.
+//
+// Variables are added unprotected and unmasked; changed variables keep their settings.
+type gitlabTarget struct {
+	name   string
+	path   string
+	scope  string
+	client *restClient
+	env    Env
+}
+
+func openGitLab(u *url.URL) (Target, error) {
+	id := strings.Trim(u.Host+u.Path, "/")
+	if u.Host == "" || id == "" {
+		return nil, fmt.Errorf("expected gitlab://group/project, got %s", u)
+	}
+	kind := "projects"
+	switch k := u.Query().Get("kind"); k {
+	case "", "project":
+	case "group":
+		kind = "groups"
+	default:
+		return nil, fmt.Errorf("unknown kind %s, expected project or group", k)
+	}
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		return nil, errors.New("GITLAB_TOKEN isn't set")
+	}
+	base := os.Getenv("GITLAB_URL")
+	if base == "" {
+		base = os.Getenv("CI_SERVER_URL")
+	}
+	if base == "" {
+		base = gitlabURL
+	}
+	scope := u.Query().Get("environment")
+	if scope == "" {
+		scope = "*"
+	}
+
+	return &gitlabTarget{
+		name:  u.String(),
+		path:  "/api/v4/" + kind + "/" + url.PathEscape(id) + "/variables",
+		scope: scope,
+		client: &restClient{
+			base: base,
+			auth: func(_ context.Context, req *http.Request) error {
+				req.Header.Set("PRIVATE-TOKEN", token)
+				return nil
+			},
+			errorOf: func(status int, body []byte) error {
+				// message is a string, or the errors of each attribute, e.g. {"key": ["has already been taken"]}
+				var e struct {
+					Message json.RawMessage `json:"message"`
+				}
+				if json.Unmarshal(body, &e) != nil || len(e.Message) == 0 {
+					return nil
+				}
+				var msg string
+				if json.Unmarshal(e.Message, &msg) != nil {
+					msg = string(e.Message)
+				}
+				return &httpError{status: status, message: msg}
+			},
+		},
+	}, nil
+}
+
+func (t *gitlabTarget) Name() string {
+	return t.name
+}
+
+func (t *gitlabTarget) Load(ctx context.Context) (*Document, error) {
+	env := Env{}
+	for page := 1; ; page++ {
+		var vars []struct {
+			Key              string `json:"key"`
+			Value            string `json:"value"`
+			EnvironmentScope string `json:"environment_scope"`
+		}
+		q := url.Values{"per_page": {fmt.Sprint(gitlabPageSize)}, "page": {fmt.Sprint(page)}}
+		if err := t.client.do(ctx, http.MethodGet, t.path+"?"+q.Encode(), nil, &vars); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't list the variables of %s", t.name))
+		}
+		for _, v := range vars {
+			// variables of groups have no environment scope without GitLab Premium
+			if v.EnvironmentScope == t.scope || v.EnvironmentScope == "" && t.scope == "*" {
+				env[v.Key] = v.Value
+			}
+		}
+		if len(vars) < gitlabPageSize {
+			break
+		}
+	}
+	t.env = env
+	return envDocument(env), nil
+}
+
+// Store creates or updates the variables whose value is added or changed since the target is loaded,
+// and deletes the ones that aren't in doc anymore.
+func (t *gitlabTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		old, ok := t.env[k]
+		if ok && old == env[k] {
+			continue
+		}
+		var err error
+		if ok {
+			err = t.client.do(ctx, http.MethodPut, t.variable(k), map[string]string{"value": env[k]}, nil)
+		} else {
+			in := map[string]string{"key": k, "value": env[k], "environment_scope": t.scope}
+			err = t.client.do(ctx, http.MethodPost, t.path, in, nil)
+		}
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set variable %s", k))
+		}
+		t.env[k] = env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := t.client.do(ctx, http.MethodDelete, t.variable(k), nil, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't delete variable %s", k))
+		}
+		delete(t.env, k)
+	}
+	return nil
+}
+
+// variable returns the path of the variable key in the environment scope of the target.
+func (t *gitlabTarget) variable(key string) string {
+	return t.path + "/" + url.PathEscape(key) + "?" + url.Values{"filter[environment_scope]": {t.scope}}.Encode()
+}
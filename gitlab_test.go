@@ -0,0 +1,143 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeGitLabVar struct {
+	Key              string `json:"key"`
+	Value            string `json:"value"`
+	EnvironmentScope string `json:"environment_scope"`
+	Protected        bool   `json:"protected"`
+}
+
+// fakeGitLab serves the CI/CD variables of the project my-group/my-project from memory.
+type fakeGitLab struct {
+	mu   sync.Mutex
+	vars []*fakeGitLabVar
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("PRIVATE-TOKEN") != "gl-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "401 Unauthorized"}`))
+		return
+	}
+	const prefix = "/api/v4/projects/my-group%2Fmy-project/variables"
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "404 Project Not Found"}`))
+		return
+	}
+
+	key := strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+	scope := r.URL.Query().Get("filter[environment_scope]")
+	find := func() int {
+		for i, v := range f.vars {
+			if v.Key == key && v.EnvironmentScope == scope {
+				return i
+			}
+		}
+		return -1
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(f.vars)
+	case http.MethodPost:
+		var v fakeGitLabVar
+		json.NewDecoder(r.Body).Decode(&v)
+		for _, old := range f.vars {
+			if old.Key == v.Key && old.EnvironmentScope == v.EnvironmentScope {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"message": {"key": ["(` + v.Key + `) has already been taken"]}}`))
+				return
+			}
+		}
+		f.vars = append(f.vars, &v)
+		json.NewEncoder(w).Encode(v)
+	case http.MethodPut:
+		i := find()
+		if i < 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(f.vars[i])
+		json.NewEncoder(w).Encode(f.vars[i])
+	case http.MethodDelete:
+		i := find()
+		if i < 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.vars = append(f.vars[:i], f.vars[i+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// env returns the values of the variables of the environment scope by key.
+func (f *fakeGitLab) env(scope string) map[string]string {
+	env := make(map[string]string)
+	for _, v := range f.vars {
+		if v.EnvironmentScope == scope {
+			env[v.Key] = v.Value
+		}
+	}
+	return env
+}
+
+func TestSyncer_Sync_GitLab(t *testing.T) {
+	gitlab := &fakeGitLab{vars: []*fakeGitLabVar{
+		{Key: "DB_HOST", Value: "db.example.com", EnvironmentScope: "*", Protected: true},
+		{Key: "OLD", Value: "x", EnvironmentScope: "*"},
+		{Key: "DB_HOST", Value: "db.prod.example.com", EnvironmentScope: "production"},
+	}}
+	server := httptest.NewServer(gitlab)
+	defer server.Close()
+	defer setenv(map[string]string{"GITLAB_TOKEN": "gl-token", "GITLAB_URL": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	syncer.Overwrite = true
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "gitlab://my-group/my-project")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, []string{"DB_HOST"}, res.Overwritten)
+	assert.Equal(t, map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"}, gitlab.env("*"))
+	assert.True(t, gitlab.vars[0].Protected)
+	assert.Equal(t, map[string]string{"DB_HOST": "db.prod.example.com"}, gitlab.env("production"))
+
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "gitlab://my-group/my-project?environment=production")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"}, gitlab.env("production"))
+
+	var keys []string
+	for _, v := range gitlab.vars {
+		keys = append(keys, v.Key+"@"+v.EnvironmentScope)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"DB_HOST@*", "DB_HOST@production", "DB_PORT@*", "DB_PORT@production"}, keys)
+
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "gitlab://my-group/other")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found: 404 Project Not Found")
+}
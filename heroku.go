@@ -0,0 +1,148 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const herokuAPI = "https://api.heroku.com"
+
+// herokuTarget is the config vars of a Heroku app as a Target, e.g. heroku://my-app,
+// authenticated with the API key in HEROKU_API_KEY, or else the one heroku login writes to ~/.netrc.
+// Config vars are all secrets, so their values are masked in patches unless ShowSecrets is set.
+type herokuTarget struct {
+	name   string
+	app    string
+	client *restClient
+	env    Env
+}
+
+func openHeroku(u *url.URL) (Target, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no app in %s", u)
+	}
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = herokuAPI
+	}
+	return &herokuTarget{
+		name: u.String(),
+		app:  u.Host,
+		client: &restClient{
+			base: endpoint,
+			auth: func(_ context.Context, req *http.Request) error {
+				key := os.Getenv("HEROKU_API_KEY")
+				if key == "" {
+					key = netrcPassword("api.heroku.com")
+				}
+				if key == "" {
+					return errors.New("HEROKU_API_KEY isn't set, and there is no login of api.heroku.com in ~/.netrc")
+				}
+				req.Header.Set("Accept", "application/vnd.heroku+json; version=3")
+				req.Header.Set("Authorization", "Bearer "+key)
+				return nil
+			},
+			errorOf: func(status int, body []byte) error {
+				var e struct {
+					Message string `json:"message"`
+				}
+				if json.Unmarshal(body, &e) != nil || e.Message == "" {
+					return nil
+				}
+				return &httpError{status: status, message: e.Message}
+			},
+		},
+	}, nil
+}
+
+// netrcPassword returns the password of machine in ~/.netrc, or "" if there is none.
+func netrcPassword(machine string) string {
+	home, _ := os.UserHomeDir()
+	name := filepath.Join(home, ".netrc")
+	if runtime.GOOS == "windows" {
+		name = filepath.Join(home, "_netrc")
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	// a netrc file is a list of tokens, each followed by its value, e.g. machine api.heroku.com login me password key
+	fields := strings.Fields(string(b))
+	current := ""
+	for i := 0; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = fields[i+1]
+			i++
+		case "default":
+			current = ""
+		case "login", "account":
+			i++
+		case "password":
+			if current == machine {
+				return fields[i+1]
+			}
+			i++
+		}
+	}
+	return ""
+}
+
+func (t *herokuTarget) Name() string {
+	return t.name
+}
+
+func (t *herokuTarget) Load(ctx context.Context) (*Document, error) {
+	var env Env
+	if err := t.client.do(ctx, http.MethodGet, "/apps/"+url.PathEscape(t.app)+"/config-vars", nil, &env); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read the config vars of %s", t.app))
+	}
+	if env == nil {
+		env = Env{}
+	}
+	t.env = env
+	return envDocument(env), nil
+}
+
+// Store updates the config vars whose value is added or changed since the target is loaded,
+// and removes the ones that aren't in doc anymore, in a single request, which restarts the app once.
+func (t *herokuTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	vars := make(map[string]interface{})
+	for k, v := range env {
+		if old, ok := t.env[k]; !ok || old != v {
+			vars[k] = v
+		}
+	}
+	for k := range t.env {
+		if _, ok := env[k]; !ok {
+			vars[k] = nil
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	if err := t.client.do(ctx, http.MethodPatch, "/apps/"+url.PathEscape(t.app)+"/config-vars", vars, nil); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("couldn't write the config vars of %s", t.app))
+	}
+	t.env = env
+	return nil
+}
+
+func (t *herokuTarget) secretValues() {}
@@ -0,0 +1,98 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHeroku serves the config vars of the app my-app from memory.
+type fakeHeroku struct {
+	mu   sync.Mutex
+	vars map[string]string
+}
+
+func (f *fakeHeroku) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer heroku-key" || r.Header.Get("Accept") != "application/vnd.heroku+json; version=3" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"id": "unauthorized", "message": "Invalid credentials provided."}`))
+		return
+	}
+	if r.URL.Path != "/apps/my-app/config-vars" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"id": "not_found", "message": "Couldn't find that app."}`))
+		return
+	}
+
+	if r.Method == http.MethodPatch {
+		var vars map[string]*string
+		json.NewDecoder(r.Body).Decode(&vars)
+		for k, v := range vars {
+			if v == nil {
+				delete(f.vars, k)
+			} else {
+				f.vars[k] = *v
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(f.vars)
+}
+
+func TestSyncer_Sync_Heroku(t *testing.T) {
+	heroku := &fakeHeroku{vars: map[string]string{"DATABASE_URL": "postgres://prod", "OLD": "x"}}
+	server := httptest.NewServer(heroku)
+	defer server.Close()
+	target := "heroku://my-app?endpoint=" + server.URL
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DATABASE_URL=postgres://localhost\nWEB_CONCURRENCY=2\n",
+		".netrc":     "machine api.heroku.com\n  login me@example.com\n  password heroku-key\nmachine git.heroku.com login me@example.com password other\n",
+	})
+	defer os.RemoveAll(dir)
+	defer setenv(map[string]string{"HOME": dir, "HEROKU_API_KEY": ""})()
+
+	// values are masked in the patch, since every config var is a secret
+	syncer := envsync.New(envsync.WithDryRun())
+	syncer.Patch, syncer.Overwrite = true, true
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"WEB_CONCURRENCY"}, res.Added)
+	assert.Contains(t, res.Patch, "-DATABASE_URL=****\n+DATABASE_URL=****\n")
+	assert.NotContains(t, res.Patch, "postgres")
+
+	diff, err := envsync.New(envsync.WithPatch()).Diff(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DATABASE_URL"}, diff.Changed)
+	assert.Equal(t, "****", diff.Target["DATABASE_URL"])
+	assert.Contains(t, diff.Patch, "-DATABASE_URL=****\n-OLD=****\n+DATABASE_URL=****\n")
+	assert.NotContains(t, diff.Patch, "postgres")
+
+	syncer = envsync.New(envsync.WithPrune())
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"WEB_CONCURRENCY"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{"DATABASE_URL": "postgres://prod", "WEB_CONCURRENCY": "2"}, heroku.vars)
+
+	// HEROKU_API_KEY takes over ~/.netrc
+	defer setenv(map[string]string{"HEROKU_API_KEY": "invalid"})()
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized: Invalid credentials provided.")
+
+	ioutil.WriteFile(filepath.Join(dir, ".netrc"), nil, 0600)
+	defer setenv(map[string]string{"HEROKU_API_KEY": ""})()
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "HEROKU_API_KEY isn't set")
+}
@@ -0,0 +1,116 @@
+package envsync
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// backupsDir is the directory in the state directory where backups are written by default.
+	backupsDir = "backups"
+
+	// backupTimeFormat is the format of the time in the name of a backup.
+	// Names sort in the same order as their time.
+	backupTimeFormat = "20060102T150405.000000000"
+)
+
+// Backup describes a copy of target made before it was modified.
+type Backup struct {
+	// Path is the location of the copy.
+	Path string
+
+	// Time is when the copy was made.
+	Time time.Time
+}
+
+// History returns the backups of target, from the most recent to the oldest.
+// It returns an empty list if there isn't any.
+func (s *Syncer) History(target string) ([]Backup, error) {
+	target, err := resolve(target)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := backupDir(target, s.BackupDir)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read backup directory")
+	}
+
+	prefix := filepath.Base(target) + "."
+	backups := []Backup{}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), backupSuffix))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, name), Time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// Rollback restores target as it was n backups ago: 1 restores the most recent backup,
+// 2 the one before it, and so on.
+// target is backed up before it is restored, so a rollback can be rolled back too.
+func (s *Syncer) Rollback(target string, n int) error {
+	backups, err := s.History(target)
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > len(backups) {
+		return fmt.Errorf("couldn't find backup %d of %s, there are %d", n, target, len(backups))
+	}
+	backup := backups[n-1]
+
+	if _, err := os.Stat(target); err == nil {
+		if err := backupFile(target, s.BackupDir); err != nil {
+			return errors.Wrap(err, "couldn't back up target file")
+		}
+	}
+
+	src, err := os.Open(backup.Path)
+	if err != nil {
+		return errors.Wrap(err, "couldn't open backup file")
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return errors.Wrap(err, "couldn't open backup file")
+	}
+
+	name, err := resolve(target)
+	if err != nil {
+		return err
+	}
+	err = replaceFile(name, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+	return errors.Wrap(err, "couldn't restore target file")
+}
+
+// backupDir returns the directory the backups of target are written to.
+func backupDir(target, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(target), stateDir, backupsDir)
+}
@@ -0,0 +1,54 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Rollback(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example": "HOST=localhost\nPORT=8080\n",
+		".env":         "",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, ".env.example"), filepath.Join(dir, ".env")
+
+	syncer := envsync.New(envsync.WithBackup(), envsync.WithPrune())
+	assert.Nil(t, syncer.Sync(source, target))
+	ioutil.WriteFile(source, []byte("HOST=localhost\n"), 0644)
+	assert.Nil(t, syncer.Sync(source, target))
+
+	backups, err := syncer.History(target)
+	assert.Nil(t, err)
+	assert.Len(t, backups, 2)
+	assert.True(t, backups[0].Time.After(backups[1].Time))
+
+	assert.Nil(t, syncer.Rollback(target, 1))
+	content, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "HOST=localhost\nPORT=8080\n", string(content))
+
+	assert.Nil(t, syncer.Rollback(target, 3))
+	content, _ = ioutil.ReadFile(target)
+	assert.Equal(t, "", string(content))
+
+	backups, _ = syncer.History(target)
+	assert.Len(t, backups, 4)
+}
+
+func TestSyncer_Rollback_NoBackup(t *testing.T) {
+	dir := makeTree(t, map[string]string{".env": "HOST=localhost\n"})
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, ".env")
+
+	syncer := &envsync.Syncer{}
+	assert.NotNil(t, syncer.Rollback(target, 1))
+	assert.NotNil(t, syncer.Rollback(target, 0))
+
+	content, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "HOST=localhost\n", string(content))
+}
@@ -0,0 +1,86 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePortal serves .env.example with an ETag, counting the times it is downloaded in full.
+type fakePortal struct {
+	mu        sync.Mutex
+	body      string
+	etag      string
+	downloads int
+}
+
+func (f *fakePortal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("PRIVATE-TOKEN") != "portal-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/myapp/.env.example" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Header.Get("If-None-Match") == f.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	f.downloads++
+	w.Header().Set("ETag", f.etag)
+	w.Write([]byte(f.body))
+}
+
+func TestSyncer_Sync_HTTP(t *testing.T) {
+	portal := &fakePortal{body: "PORT=8080\n", etag: `"v1"`}
+	server := httptest.NewServer(portal)
+	defer server.Close()
+	source := server.URL + "/myapp/.env.example"
+
+	dir := makeTree(t, map[string]string{".env": ""})
+	defer os.RemoveAll(dir)
+	defer setenv(map[string]string{"HOME": dir, "XDG_CACHE_HOME": filepath.Join(dir, "cache"), "ENVSYNC_HTTP_HEADER": "PRIVATE-TOKEN: portal-token"})()
+	target := filepath.Join(dir, ".env")
+
+	res, err := envsync.New().SyncWithResult(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+
+	// the cached file is used until the server changes it
+	portal.body = "PORT=9090\nDEBUG=false\n"
+	res, err = envsync.New(envsync.WithOverwrite("")).SyncWithResult(source, target)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Added)
+	assert.Equal(t, 1, portal.downloads)
+
+	portal.etag = `"v2"`
+	res, err = envsync.New(envsync.WithOverwrite("")).SyncWithResult(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+	assert.Equal(t, []string{"PORT"}, res.Overwritten)
+	assert.Equal(t, 2, portal.downloads)
+
+	ioutil.WriteFile(target, []byte("PORT=9090\nDEBUG=false\nEXTRA=1\n"), 0644)
+	_, err = envsync.New().SyncWithResult(target, source)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "can only be a source")
+
+	_, err = envsync.New().SyncWithResult(server.URL+"/other/.env.example", target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+
+	defer setenv(map[string]string{"ENVSYNC_HTTP_HEADER": "PRIVATE-TOKEN: invalid"})()
+	_, err = envsync.New().SyncWithResult(source, target)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized")
+}
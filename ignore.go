@@ -0,0 +1,191 @@
+package envsync
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultIgnoreFile is the name of the ignore file read by the envsync binary.
+const DefaultIgnoreFile = ".envsyncignore"
+
+// keyPrefix starts the lines of an ignore file holding a key pattern rather than a path.
+const keyPrefix = "$"
+
+// Ignore lists the keys and files a synchronization leaves untouched, e.g. across a monorepo.
+// The zero value ignores nothing, and so does a nil *Ignore.
+type Ignore struct {
+	// Keys are the patterns of keys left untouched, with the same syntax as Syncer.Exclude.
+	Keys []string
+
+	paths []ignorePath
+}
+
+// ignorePath is a path pattern of an ignore file.
+type ignorePath struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ParseIgnore reads an ignore file from r. Each line is a pattern, e.g.
+//
+//	# files
+//	legacy/
+//	**/testdata/.env
+//	!legacy/api/.env
+//	# keys
+//	$LOCAL_*
+//	$/^DEBUG_/
+//
+// Lines starting with $ hold a key pattern. Any other line holds a path pattern with the semantics of .gitignore:
+// a pattern without a slash matches a file or directory name at any depth, a pattern with one is relative to the directory of the ignore file,
+// ** matches any number of directories, a trailing slash matches directories only, ! re-includes what a previous pattern ignores,
+// and everything under an ignored directory is ignored. Blank lines and lines starting with # are skipped.
+func ParseIgnore(r io.Reader) (*Ignore, error) {
+	ig := &Ignore{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, keyPrefix) {
+			p := strings.TrimPrefix(text, keyPrefix)
+			if _, err := compilePattern(p); err != nil {
+				return nil, &ParseError{Line: n, Text: scanner.Text(), Err: err}
+			}
+			ig.Keys = append(ig.Keys, p)
+			continue
+		}
+
+		p, err := compileIgnorePath(text)
+		if err != nil {
+			return nil, &ParseError{Line: n, Text: scanner.Text(), Err: err}
+		}
+		ig.paths = append(ig.paths, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "couldn't read ignore file")
+	}
+	return ig, nil
+}
+
+// LoadIgnore reads the ignore file at name.
+func LoadIgnore(name string) (*Ignore, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't open ignore file")
+	}
+	defer file.Close()
+
+	ig, err := ParseIgnore(file)
+	if perr, ok := err.(*ParseError); ok {
+		perr.File = name
+	}
+	return ig, err
+}
+
+func compileIgnorePath(p string) (ignorePath, error) {
+	ip := ignorePath{}
+	if strings.HasPrefix(p, "!") {
+		ip.negate, p = true, p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		ip.dirOnly, p = true, strings.TrimSuffix(p, "/")
+	}
+	if p == "" {
+		return ip, errors.New("empty path pattern")
+	}
+
+	// a pattern without a slash matches at any depth
+	expr := globExpr(strings.TrimPrefix(p, "/"))
+	if !strings.Contains(p, "/") {
+		expr = "(.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ip, errors.Wrap(err, "invalid path pattern "+p)
+	}
+	ip.re = re
+	return ip, nil
+}
+
+// globExpr returns the regular expression matching the same paths as the glob p,
+// where * and ? don't match a slash and ** matches any number of directories.
+func globExpr(p string) string {
+	var buf strings.Builder
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			buf.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			buf.WriteString(".*")
+			i++
+		case c == '*':
+			buf.WriteString("[^/]*")
+		case c == '?':
+			buf.WriteString("[^/]")
+		case c == '\\' && i+1 < len(p):
+			buf.WriteString(regexp.QuoteMeta(p[i+1 : i+2]))
+			i++
+		case c == '[' && strings.IndexByte(p[i:], ']') > 1:
+			end := i + strings.IndexByte(p[i:], ']')
+			class := p[i+1 : end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i = end
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return buf.String()
+}
+
+// Match reports whether the file at path is ignored, or the directory if dir is set.
+// path is slash-separated and relative to the directory of the ignore file.
+// A file or directory inside an ignored directory is ignored too.
+func (ig *Ignore) Match(path string, dir bool) bool {
+	if ig == nil {
+		return false
+	}
+
+	path = strings.Trim(path, "/")
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if ig.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return ig.match(path, dir)
+}
+
+// match applies the path patterns to path in order, the last matching one deciding.
+func (ig *Ignore) match(path string, dir bool) bool {
+	ignored := false
+	for _, p := range ig.paths {
+		if p.dirOnly && !dir {
+			continue
+		}
+		if p.re.MatchString(path) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// keys returns the key patterns, or nil on a nil *Ignore.
+func (ig *Ignore) keys() []string {
+	if ig == nil {
+		return nil
+	}
+	return ig.Keys
+}
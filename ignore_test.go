@@ -0,0 +1,104 @@
+package envsync_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnore_Match(t *testing.T) {
+	ig, err := envsync.ParseIgnore(strings.NewReader(`# files
+legacy/
+/tools/.env
+**/testdata/.env
+*.local
+!keep.local
+services/*/tmp
+
+# keys
+$LOCAL_*
+$/^DEBUG_/
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"LOCAL_*", "/^DEBUG_/"}, ig.Keys)
+
+	for path, dir := range map[string]bool{
+		"legacy":                  true,
+		"legacy/api/.env":         false,
+		"apps/legacy/.env":        false,
+		"tools/.env":              false,
+		"testdata/.env":           false,
+		"a/b/testdata/.env":       false,
+		".env.local":              false,
+		"api/.env.local":          false,
+		"services/api/tmp":        true,
+		"services/api/tmp/.env":   false,
+		"services/api/tmp/x/.env": false,
+	} {
+		assert.True(t, ig.Match(path, dir), path)
+	}
+	for path, dir := range map[string]bool{
+		"legacy":               false,
+		"apps/tools/.env":      false,
+		"testdata/.env.sample": false,
+		"keep.local":           false,
+		"services/a/b/tmp":     true,
+		"api/.env":             false,
+	} {
+		assert.False(t, ig.Match(path, dir), path)
+	}
+
+	var nilIgnore *envsync.Ignore
+	assert.False(t, nilIgnore.Match("legacy", true))
+}
+
+func TestParseIgnore_Error(t *testing.T) {
+	_, err := envsync.ParseIgnore(strings.NewReader("legacy/\n$[\n"))
+	var perr *envsync.ParseError
+	assert.True(t, errors.As(err, &perr))
+	assert.Equal(t, 2, perr.Line)
+
+	_, err = envsync.ParseIgnore(strings.NewReader("!/\n"))
+	assert.NotNil(t, err)
+}
+
+func TestSyncer_SyncReaders_Ignore(t *testing.T) {
+	ig, err := envsync.ParseIgnore(strings.NewReader("$LOCAL_*\n"))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	syncer := envsync.New(envsync.WithIgnore(ig), envsync.WithPrune())
+	res, err := syncer.SyncReaders(strings.NewReader("HOST=localhost\nLOCAL_PATH=/tmp\n"), strings.NewReader("LOCAL_USER=me\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST"}, res.Added)
+	assert.Empty(t, res.Pruned)
+	assert.Equal(t, "LOCAL_USER=me\nHOST=localhost\n", buf.String())
+}
+
+func TestSyncer_SyncTree_Ignore(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"api/.env.example":        "PORT=8080\n",
+		"api/.env":                "",
+		"legacy/app/.env.example": "PORT=80\n",
+		"legacy/app/.env":         "",
+		"web/.env.example":        "PORT=3000\n",
+		"web/.env":                "",
+	})
+	defer os.RemoveAll(dir)
+
+	ig, err := envsync.ParseIgnore(strings.NewReader("legacy/\nweb/.env\n"))
+	assert.Nil(t, err)
+
+	syncer := envsync.New(envsync.WithIgnore(ig))
+	results, err := syncer.SyncTree(context.Background(), dir, ".env.example", ".env")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, filepath.Join(dir, "api", ".env"), results[0].Target)
+}
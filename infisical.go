@@ -0,0 +1,155 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const infisicalAPI = "https://app.infisical.com/api"
+
+// infisicalTarget is the secrets of an environment of an Infisical project as a Target,
+// e.g. infisical://6470b3a5c8d5e4f3a2b1c0d9/dev, or of a folder of it, e.g. infisical://6470b3a5c8d5e4f3a2b1c0d9/prod/backend.
+// It is authenticated like the infisical CLI: with the token in INFISICAL_TOKEN,
+// or the machine identity of INFISICAL_UNIVERSAL_AUTH_CLIENT_ID and INFISICAL_UNIVERSAL_AUTH_CLIENT_SECRET,
+// and the API is at INFISICAL_API_URL for a self-hosted instance, e.g. https://infisical.example.com/api.
+//
+// Only shared secrets are synchronized; personal overrides are left out. Values are read and written raw:
+// a reference to another secret, e.g. ${DB_HOST}, is synchronized as it is written rather than as the value it refers to.
+type infisicalTarget struct {
+	name        string
+	workspace   string
+	environment string
+	path        string
+	client      *restClient
+	env         Env
+}
+
+func openInfisical(u *url.URL) (Target, error) {
+	sp := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if u.Host == "" || sp[0] == "" {
+		return nil, fmt.Errorf("expected infisical://project/environment, got %s", u)
+	}
+	path := "/"
+	if len(sp) == 2 {
+		path += sp[1]
+	}
+	base := os.Getenv("INFISICAL_API_URL")
+	if base == "" {
+		base = infisicalAPI
+	}
+
+	client := &restClient{
+		base: base,
+		errorOf: func(status int, body []byte) error {
+			var e struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &e) != nil || e.Message == "" {
+				return nil
+			}
+			return &httpError{status: status, message: e.Message}
+		},
+	}
+	if token := os.Getenv("INFISICAL_TOKEN"); token != "" {
+		client.auth = bearer(func(context.Context) (string, error) {
+			return token, nil
+		})
+	} else {
+		id, secret := os.Getenv("INFISICAL_UNIVERSAL_AUTH_CLIENT_ID"), os.Getenv("INFISICAL_UNIVERSAL_AUTH_CLIENT_SECRET")
+		if id == "" || secret == "" {
+			return nil, errors.New("INFISICAL_TOKEN isn't set, nor INFISICAL_UNIVERSAL_AUTH_CLIENT_ID and INFISICAL_UNIVERSAL_AUTH_CLIENT_SECRET")
+		}
+		var tokens tokenCache
+		client.auth = bearer(func(ctx context.Context) (string, error) {
+			token, err := tokens.get(func() (string, time.Duration, error) {
+				var out struct {
+					AccessToken string `json:"accessToken"`
+					ExpiresIn   int64  `json:"expiresIn"`
+				}
+				// logging in is a request of its own, without a token
+				login := &restClient{base: client.base, errorOf: client.errorOf}
+				in := map[string]string{"clientId": id, "clientSecret": secret}
+				err := login.do(ctx, http.MethodPost, "/v1/auth/universal-auth/login", in, &out)
+				return out.AccessToken, time.Duration(out.ExpiresIn) * time.Second, err
+			})
+			return token, errors.Wrap(err, "couldn't log in to Infisical")
+		})
+	}
+	return &infisicalTarget{name: u.String(), workspace: u.Host, environment: sp[0], path: path, client: client}, nil
+}
+
+func (t *infisicalTarget) Name() string {
+	return t.name
+}
+
+func (t *infisicalTarget) Load(ctx context.Context) (*Document, error) {
+	q := url.Values{"workspaceId": {t.workspace}, "environment": {t.environment}, "secretPath": {t.path}, "expandSecretReferences": {"false"}}
+	var out struct {
+		Secrets []struct {
+			Key   string `json:"secretKey"`
+			Value string `json:"secretValue"`
+			Type  string `json:"type"`
+		} `json:"secrets"`
+	}
+	if err := t.client.do(ctx, http.MethodGet, "/v3/secrets/raw?"+q.Encode(), nil, &out); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read the secrets of %s", t.name))
+	}
+	t.env = Env{}
+	for _, s := range out.Secrets {
+		if s.Type == "" || s.Type == "shared" {
+			t.env[s.Key] = s.Value
+		}
+	}
+	return envDocument(t.env), nil
+}
+
+// Store creates or updates the secrets whose value is added or changed since the target is loaded,
+// and deletes the ones that aren't in doc anymore.
+func (t *infisicalTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		old, ok := t.env[k]
+		if ok && old == env[k] {
+			continue
+		}
+		method := http.MethodPost
+		if ok {
+			method = http.MethodPatch
+		}
+		in := t.scope()
+		in["secretValue"] = env[k]
+		if err := t.client.do(ctx, method, "/v3/secrets/raw/"+url.PathEscape(k), in, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set secret %s", k))
+		}
+		t.env[k] = env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := t.client.do(ctx, http.MethodDelete, "/v3/secrets/raw/"+url.PathEscape(k), t.scope(), nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't delete secret %s", k))
+		}
+		delete(t.env, k)
+	}
+	return nil
+}
+
+// scope returns the fields of a request telling the project, environment, and folder of a shared secret.
+func (t *infisicalTarget) scope() map[string]string {
+	return map[string]string{"workspaceId": t.workspace, "environment": t.environment, "secretPath": t.path, "type": "shared"}
+}
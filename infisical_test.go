@@ -0,0 +1,127 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeInfisical serves the raw secrets of the environment dev of the project p1 from memory,
+// with a personal override of DB_HOST, and logs in the machine identity id1.
+type fakeInfisical struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	logins  int
+}
+
+func (f *fakeInfisical) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/api/v1/auth/universal-auth/login":
+		f.login(w, r)
+	case r.Header.Get("Authorization") != "Bearer infisical-token":
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"statusCode": 401, "message": "Token missing"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/secrets/raw":
+		f.list(w, r)
+	default:
+		f.write(w, r)
+	}
+}
+
+// login logs in the machine identity id1 with the client secret s1.
+func (f *fakeInfisical) login(w http.ResponseWriter, r *http.Request) {
+	var in map[string]string
+	json.NewDecoder(r.Body).Decode(&in)
+	if in["clientId"] != "id1" || in["clientSecret"] != "s1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"statusCode": 401, "message": "Invalid credentials"}`))
+		return
+	}
+	f.logins++
+	w.Write([]byte(`{"accessToken": "infisical-token", "expiresIn": 7200, "tokenType": "Bearer"}`))
+}
+
+// list lists the secrets of the folder /backend, with the personal override of DB_HOST.
+func (f *fakeInfisical) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("workspaceId") != "p1" || q.Get("environment") != "dev" || q.Get("secretPath") != "/backend" {
+		w.Write([]byte(`{"secrets": []}`))
+		return
+	}
+	secrets := []map[string]string{{"secretKey": "DB_HOST", "secretValue": "my-laptop", "type": "personal"}}
+	for k, v := range f.secrets {
+		secrets = append(secrets, map[string]string{"secretKey": k, "secretValue": v, "type": "shared"})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"secrets": secrets})
+}
+
+// write creates, updates, or deletes a shared secret of the folder /backend.
+func (f *fakeInfisical) write(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/api/v3/secrets/raw/")
+	var in map[string]string
+	json.NewDecoder(r.Body).Decode(&in)
+	if in["workspaceId"] != "p1" || in["environment"] != "dev" || in["secretPath"] != "/backend" || in["type"] != "shared" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	_, exists := f.secrets[key]
+	switch {
+	case r.Method == http.MethodPost && exists:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"statusCode": 400, "message": "Secret already exist"}`))
+		return
+	case r.Method == http.MethodPost, r.Method == http.MethodPatch && exists:
+		f.secrets[key] = in["secretValue"]
+	case r.Method == http.MethodDelete && exists:
+		delete(f.secrets, key)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"statusCode": 404, "message": "Secret not found"}`))
+		return
+	}
+	w.Write([]byte(`{"secret": {}}`))
+}
+
+func TestSyncer_Sync_Infisical(t *testing.T) {
+	infisical := &fakeInfisical{secrets: map[string]string{"DB_HOST": "db.internal", "API_URL": "${DB_HOST}/api", "OLD": "x"}}
+	server := httptest.NewServer(infisical)
+	defer server.Close()
+	defer setenv(map[string]string{
+		"INFISICAL_API_URL":                      server.URL + "/api",
+		"INFISICAL_TOKEN":                        "",
+		"INFISICAL_UNIVERSAL_AUTH_CLIENT_ID":     "id1",
+		"INFISICAL_UNIVERSAL_AUTH_CLIENT_SECRET": "s1",
+	})()
+
+	dir := makeTree(t, map[string]string{"env.sample": "DB_HOST=localhost\nAPI_URL=http://localhost/api\nPORT=8080\n"})
+	defer os.RemoveAll(dir)
+
+	res, err := envsync.New(envsync.WithPrune(), envsync.WithOverwrite("DB_*")).SyncWithResult(filepath.Join(dir, "env.sample"), "infisical://p1/dev/backend")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, []string{"DB_HOST"}, res.Overwritten)
+	assert.Equal(t, map[string]string{"DB_HOST": "localhost", "API_URL": "${DB_HOST}/api", "PORT": "8080"}, infisical.secrets)
+	assert.Equal(t, 1, infisical.logins)
+
+	defer setenv(map[string]string{"INFISICAL_TOKEN": "infisical-token"})()
+	res, err = envsync.New().SyncWithResult("infisical://p1/dev/backend", filepath.Join(dir, "env.sample"))
+	assert.Nil(t, err)
+	assert.Empty(t, res.Added)
+	assert.Equal(t, 1, infisical.logins)
+
+	defer setenv(map[string]string{"INFISICAL_TOKEN": "", "INFISICAL_UNIVERSAL_AUTH_CLIENT_SECRET": "invalid"})()
+	_, err = envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "infisical://p1/dev/backend")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't log in to Infisical: 401 Unauthorized: Invalid credentials")
+}
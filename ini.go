@@ -0,0 +1,162 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// iniSectionSeparator joins the name of a section to the keys in it, e.g. [database] host is DATABASE_HOST.
+const iniSectionSeparator = "_"
+
+// decodeINI reads the key-values of an INI file, its comments, blank lines, and section headers.
+// The key of a key-value in a section is prefixed with the name of the section, see iniKey.
+// Values are read like in CodecDotenv, so a quoted value may hold escapes such as \n.
+func decodeINI(r io.Reader) (*Document, error) {
+	doc := &Document{codec: CodecINI}
+	sc := bufio.NewScanner(r)
+	n, section := 0, ""
+	for sc.Scan() {
+		n++
+		text := sc.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+		case trimmed[0] == '#' || trimmed[0] == ';':
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+		case trimmed[0] == '[':
+			if !strings.HasSuffix(trimmed, "]") {
+				return doc, &ParseError{Line: n, Text: text, Err: ErrUnsupportedSyntax}
+			}
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			doc.lines = append(doc.lines, &line{kind: sectionLine, key: section, raw: text})
+		default:
+			l, err := parseINILine(text, section)
+			if err != nil {
+				return doc, &ParseError{Line: n, Text: text, Err: err}
+			}
+			doc.lines = append(doc.lines, l)
+		}
+	}
+	return doc, sc.Err()
+}
+
+// parseINILine parses a name = value or name: value line of an INI file in section.
+func parseINILine(text, section string) (*line, error) {
+	i := strings.IndexAny(text, "=:")
+	if i < 0 {
+		return nil, ErrMissingSeparator
+	}
+	name := strings.TrimSpace(text[:i])
+	if name == "" {
+		return nil, ErrMissingSeparator
+	}
+	value, quote, _, err := decodeValue(strings.TrimSpace(text[i+1:]), false)
+	if err != nil {
+		return nil, err
+	}
+	return &line{kind: entryLine, key: iniKey(section, name), value: value, quote: quote, raw: text}, nil
+}
+
+// iniKey returns the key of name in section: both are upper-cased, any character but letters and digits becomes '_',
+// and they are joined with '_', e.g. DATABASE_HOST for host in [database] and MY_APP_LOG_LEVEL for log-level in [my.app].
+// The key of a name outside of any section is the name alone, e.g. DEBUG for debug.
+func iniKey(section, name string) string {
+	if section == "" {
+		return iniName(name)
+	}
+	return iniName(section) + iniSectionSeparator + iniName(name)
+}
+
+func iniName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// encodeINI writes d to buf as an INI file.
+//
+// Each key-value is written in the section whose key prefix is the longest one its key starts with,
+// or before any section if there is none, so the file reads back as the same key-values.
+// A key-value in another section, e.g. one added by Sync at the end of d, is moved to the end of its section,
+// above the blank lines ending it.
+// Lines that already hold the same key-value are written as they are,
+// and others as name = value, the name being the key without the prefix of the section, in lower case.
+// Sections are never created: a key without a matching section, e.g. REDIS_URL, is written as it is before any section.
+func (d *Document) encodeINI(buf *bytes.Buffer) {
+	var sections []*line
+	lines := map[string][]*line{"": nil}
+	current := ""
+	for _, l := range d.lines {
+		if l.kind == sectionLine {
+			current = iniName(l.key)
+			if _, found := lines[current]; !found {
+				sections = append(sections, l)
+				lines[current] = nil
+			}
+			continue
+		}
+		section := current
+		if l.kind == entryLine {
+			section = iniSection(l.key, sections)
+		}
+		if section == current {
+			lines[section] = append(lines[section], l)
+			continue
+		}
+		// a moved key-value is placed above the blank lines ending its section
+		ls := lines[section]
+		i := len(ls)
+		for i > 0 && ls[i-1].kind == blankLine {
+			i--
+		}
+		lines[section] = append(ls[:i:i], append([]*line{l}, ls[i:]...)...)
+	}
+
+	write := func(section string) {
+		for _, l := range lines[section] {
+			text := l.raw
+			if l.kind == entryLine {
+				text = iniLine(l, section)
+			}
+			buf.WriteString(text + "\n")
+		}
+	}
+	write("")
+	for _, s := range sections {
+		buf.WriteString(s.raw + "\n")
+		write(iniName(s.key))
+	}
+}
+
+// iniSection returns the prefix of the section key is written in, or an empty string if it is written before any section.
+func iniSection(key string, sections []*line) string {
+	best := ""
+	for _, s := range sections {
+		prefix := iniName(s.key)
+		if strings.HasPrefix(key, prefix+iniSectionSeparator) && len(key) > len(prefix)+1 && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return best
+}
+
+// iniLine returns l as a line of section, keeping its raw text if it reads back as the same key-value there.
+func iniLine(l *line, section string) string {
+	if p, err := parseINILine(l.raw, section); l.raw != "" && !strings.Contains(l.raw, "\n") && err == nil && p.key == l.key && p.value == l.value {
+		return l.raw
+	}
+	name := l.key
+	if section != "" {
+		name = strings.ToLower(strings.TrimPrefix(l.key, section+iniSectionSeparator))
+	}
+	return name + " = " + encodeValue(l.value, l.quote)
+}
@@ -0,0 +1,87 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Init creates target as a copy of source, e.g. .env from .env.example, when target doesn't exist yet.
+// If target exists, Init returns ErrTargetExists and leaves it untouched.
+//
+// Keys annotated with # envsync:secret in the comments above them are given a random hex value,
+// and keys annotated with # envsync:generate are given a random value as Sync does.
+// If Prompter is set, it is asked for the value of keys annotated with # envsync:required.
+// Header and Footer are written at the top and at the bottom of target.
+// Target is encrypted for AgeRecipients if they are set.
+// Every key in the created target is reported as added.
+func (s *Syncer) Init(ctx context.Context, source, target string) (*SyncResult, error) {
+	if _, err := os.Stat(target); err == nil {
+		return nil, errors.Wrap(ErrTargetExists, fmt.Sprintf("couldn't create %s", target))
+	}
+
+	if len(s.AgeRecipients) == 0 && strings.HasSuffix(target, ageExtension) {
+		return nil, errors.New("couldn't create target file: it is named to be encrypted with age, but no recipient is set to encrypt it for")
+	}
+
+	sDoc, err := s.load(ctx, source, "source")
+	if err != nil {
+		return nil, err
+	}
+
+	tDoc := sDoc.recode(s.codecOf(target))
+	if err := s.checkLayout(tDoc.codec); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't create %s", target))
+	}
+	for _, k := range sDoc.Keys() {
+		annotations := sDoc.annotations(k)
+		if _, ok := annotations[annotationSecret]; ok {
+			annotations[annotationGenerate] = ""
+		}
+		if spec, ok := annotations[annotationGenerate]; ok {
+			v, err := generateValue(spec)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("couldn't generate %s", k))
+			}
+			tDoc.Set(k, v)
+			continue
+		}
+		if _, ok := annotations[annotationRequired]; !ok || s.Prompter == nil || s.DryRun {
+			continue
+		}
+
+		v, _ := tDoc.Get(k)
+		v, skip, err := s.Prompter.Prompt(k, v)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't prompt for %s", k))
+		}
+		if skip {
+			tDoc.remove(k)
+		} else {
+			tDoc.Set(k, v)
+		}
+	}
+
+	s.writeBanner(tDoc)
+
+	env := tDoc.Env()
+	result := newSyncResult(env, nil, env, nil, nil)
+	s.print(env, nil, nil, nil)
+	if s.DryRun {
+		return result, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// there is no target to back up yet
+	init := *s
+	init.Backup = false
+	if err := init.writeEnv(target, tDoc); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package envsync_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Init(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example": "# database\nDB_HOST=localhost\n# envsync:required\nDB_PASSWORD=\n# envsync:secret\nSESSION_KEY=changeme\n",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, ".env.example"), filepath.Join(dir, ".env")
+
+	var out bytes.Buffer
+	syncer := envsync.New(envsync.WithPrompter(envsync.NewPrompter(strings.NewReader("s3cret\n"), &out)))
+	res, err := syncer.Init(context.Background(), source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST", "DB_PASSWORD", "SESSION_KEY"}, res.Added)
+	assert.Equal(t, "DB_PASSWORD []: ", out.String())
+	assert.Equal(t, "s3cret", res.Env["DB_PASSWORD"])
+	assert.Len(t, res.Env["SESSION_KEY"], 64)
+
+	content, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "# database\nDB_HOST=localhost\n# envsync:required\nDB_PASSWORD=s3cret\n# envsync:secret\nSESSION_KEY="+res.Env["SESSION_KEY"]+"\n", string(content))
+
+	res, err = syncer.Init(context.Background(), source, target)
+	assert.True(t, errors.Is(err, envsync.ErrTargetExists))
+	assert.Nil(t, res)
+}
+
+func TestSyncer_Init_DryRun(t *testing.T) {
+	target := "testdata/.env.init"
+	syncer := envsync.New(envsync.WithDryRun())
+	res, err := syncer.Init(context.Background(), "testdata/env.success", target)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, res.Added)
+
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,113 @@
+package envsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// WriteJSON writes v to w as indented JSON followed by a newline,
+// e.g. a *SyncResult, a *DiffResult, a []TargetResult, or a []Violation, for scripts to consume.
+func WriteJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// MarshalJSON encodes r with its error message, if any, as error.
+func (r TargetResult) MarshalJSON() ([]byte, error) {
+	// targetResult doesn't have the methods of TargetResult, so it is encoded with the default encoding
+	type targetResult TargetResult
+	v := struct {
+		targetResult
+		Error string `json:"error,omitempty"`
+	}{targetResult: targetResult(r)}
+	if r.Err != nil {
+		v.Error = r.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+func decodeJSON(r io.Reader) (*Document, error) {
+	dec := json.NewDecoder(r)
+	doc := &Document{codec: CodecJSON}
+	t, err := dec.Token()
+	if err == io.EOF {
+		// an empty file is an empty object, like an empty .env file
+		return doc, nil
+	}
+	if err != nil || t != json.Delim('{') {
+		return nil, errors.New("couldn't decode JSON: expected an object")
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decode JSON")
+		}
+		key, _ := t.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, errors.Wrap(err, "couldn't decode JSON")
+		}
+
+		// a number or a boolean keeps its text as raw, so it is written back as it is while its value is unchanged
+		l := &line{kind: entryLine, key: key}
+		switch raw[0] {
+		case '"':
+			json.Unmarshal(raw, &l.value)
+			l.quote = doubleQuote
+		case '{', '[':
+			return nil, fmt.Errorf("couldn't decode JSON: %s isn't a string, number, boolean, or null", key)
+		case 'n':
+		default:
+			l.value, l.raw = string(raw), string(raw)
+		}
+		doc.lines = append(doc.lines, l)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, errors.Wrap(err, "couldn't decode JSON")
+	}
+	return doc, nil
+}
+
+// encodeJSON writes d to buf as an indented JSON object.
+// Comments and blank lines are dropped, since JSON has none, and so is the export prefix.
+func (d *Document) encodeJSON(buf *bytes.Buffer) {
+	var entries []*line
+	for _, l := range d.lines {
+		if l.kind == entryLine {
+			entries = append(entries, l)
+		}
+	}
+	if len(entries) == 0 {
+		buf.WriteString("{}\n")
+		return
+	}
+
+	buf.WriteString("{\n")
+	for i, l := range entries {
+		v := l.raw
+		if l.raw == "" || l.raw != l.value {
+			v = jsonString(l.value)
+		}
+		buf.WriteString("  " + jsonString(l.key) + ": " + v)
+		if i < len(entries)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+}
+
+// jsonString returns s as a JSON string, leaving <, >, and & unescaped unlike json.Marshal.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
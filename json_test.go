@@ -0,0 +1,55 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON(t *testing.T) {
+	src := "HOST=localhost\nPORT=8080\n"
+	dst := "PORT=80\nSECRET=s3cr3t\n"
+
+	var buf bytes.Buffer
+	res, err := envsync.New(envsync.WithDryRun()).SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+
+	assert.Nil(t, envsync.WriteJSON(&buf, res))
+	assert.Equal(t, `{
+  "added": [
+    "HOST"
+  ],
+  "skipped": [
+    "PORT"
+  ],
+  "pruned": [],
+  "overwritten": [],
+  "extra": [
+    "SECRET"
+  ]
+}
+`, buf.String())
+	assert.NotContains(t, buf.String(), "s3cr3t")
+
+	buf.Reset()
+	results := []envsync.TargetResult{
+		{Source: ".env.example", Target: ".env", Result: &envsync.SyncResult{Added: []string{"HOST"}}},
+		{Source: ".env.example", Target: ".env.test", Err: errors.New("target file not found")},
+	}
+	assert.Nil(t, envsync.WriteJSON(&buf, results))
+	assert.Contains(t, buf.String(), `"target": ".env",
+    "result": {
+      "added": [
+        "HOST"
+      ],`)
+	assert.Contains(t, buf.String(), `"target": ".env.test",
+    "error": "target file not found"`)
+
+	buf.Reset()
+	assert.Nil(t, envsync.WriteJSON(&buf, &envsync.DiffResult{OnlyInSource: []string{"HOST"}}))
+	assert.Equal(t, "{\n  \"only_in_source\": [\n    \"HOST\"\n  ],\n  \"only_in_target\": null,\n  \"changed\": null\n}\n", buf.String())
+}
@@ -0,0 +1,78 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyringService is the service of the items envsync keeps in the credential store of the OS.
+const keyringService = "envsync"
+
+// keyringTarget is env kept in the credential store of the OS as a Target, e.g. keyring://myapp:
+// the macOS Keychain, the Windows Credential Manager, or the Secret Service of libsecret, e.g. GNOME Keyring, elsewhere.
+// It is a single item of the envsync service, whose account is the name of the URL and whose secret is the env in JSON,
+// so a developer can keep real secret values out of plain text files, and sync the keys of a sample into it.
+// Its values are all secrets, so they are masked in patches unless ShowSecrets is set.
+type keyringTarget struct {
+	name    string
+	account string
+	env     Env
+}
+
+func openKeyring(u *url.URL) (Target, error) {
+	account := strings.Trim(u.Host+u.Path, "/")
+	if account == "" || strings.ContainsAny(account, "\" ") {
+		return nil, fmt.Errorf("expected keyring://name, got %s", u)
+	}
+	return &keyringTarget{name: u.String(), account: account}, nil
+}
+
+func (t *keyringTarget) Name() string {
+	return t.name
+}
+
+func (t *keyringTarget) Load(ctx context.Context) (*Document, error) {
+	secret, ok, err := keyringGet(ctx, keyringService, t.account)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s from the keyring", t.account))
+	}
+	env := Env{}
+	if ok {
+		if err := json.Unmarshal([]byte(secret), &env); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't decode %s from the keyring", t.account))
+		}
+	}
+	t.env = env
+	return envDocument(env), nil
+}
+
+// Store replaces the env of the item with the one of doc, unless it is the same.
+func (t *keyringTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	if reflect.DeepEqual(env, t.env) {
+		return nil
+	}
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	if err := keyringSet(ctx, keyringService, t.account, string(b)); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("couldn't write %s to the keyring", t.account))
+	}
+	t.env = env
+	return nil
+}
+
+func (t *keyringTarget) secretValues() {}
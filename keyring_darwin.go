@@ -0,0 +1,48 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyringNotFound is the exit code of the security command when the item isn't in the keychain.
+const keyringNotFound = 44
+
+// keyringGet returns the password of the generic password of service and account in the login keychain,
+// and whether there is one.
+func keyringGet(ctx context.Context, service, account string) (string, bool, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == keyringNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(out), "\n"), true, nil
+}
+
+// keyringSet adds or updates the generic password of service and account in the login keychain.
+// The command is given to security on its input, rather than on the command line where other processes see the password.
+func keyringSet(ctx context.Context, service, account, secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -X %s\n", service, account, hex.EncodeToString([]byte(secret))))
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	// security -i reports the errors of its commands without failing
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
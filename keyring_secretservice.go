@@ -0,0 +1,43 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyringGet returns the secret of service and account in the Secret Service, read by the secret-tool command of libsecret,
+// and whether there is one.
+func keyringGet(ctx context.Context, service, account string) (string, bool, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	// secret-tool fails without a message when there is no such secret
+	if _, ok := err.(*exec.ExitError); ok && stderr.Len() == 0 {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), true, nil
+}
+
+// keyringSet stores the secret of service and account in the Secret Service with the secret-tool command of libsecret,
+// which reads it on its input rather than on the command line where other processes see it.
+func keyringSet(ctx context.Context, service, account, secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
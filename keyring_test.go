@@ -0,0 +1,55 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecretTool is a secret-tool command keeping each secret in a file named after its attributes.
+const fakeSecretTool = `#!/bin/sh
+dir=$(dirname "$0")/secrets
+case "$1" in
+lookup)
+	cat "$dir/$3.$5" 2>/dev/null ;;
+store)
+	mkdir -p "$dir" && cat > "$dir/$5.$7" ;;
+esac
+`
+
+func TestSyncer_Sync_Keyring(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the fake keyring is the Secret Service")
+	}
+	dir := makeTree(t, map[string]string{
+		"bin/secret-tool": fakeSecretTool,
+		"env.sample":      "DB_PASSWORD=changeme\nPORT=8080\n",
+	})
+	defer os.RemoveAll(dir)
+	os.Chmod(filepath.Join(dir, "bin", "secret-tool"), 0755)
+	defer setenv(map[string]string{"PATH": filepath.Join(dir, "bin") + string(os.PathListSeparator) + os.Getenv("PATH")})()
+
+	res, err := envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "keyring://myapp")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD", "PORT"}, res.Added)
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "bin", "secrets", "envsync.myapp"))
+	assert.JSONEq(t, `{"DB_PASSWORD": "changeme", "PORT": "8080"}`, string(b))
+
+	// values are masked in the patch, since they are all secrets
+	ioutil.WriteFile(filepath.Join(dir, "bin", "secrets", "envsync.myapp"), []byte(`{"DB_PASSWORD": "hunter2", "PORT": "8080"}`), 0600)
+	syncer := envsync.New(envsync.WithDryRun(), envsync.WithPatch())
+	syncer.Overwrite = true
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "keyring://myapp")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD"}, res.Overwritten)
+	assert.NotContains(t, res.Patch, "hunter2")
+
+	_, err = envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "keyring://")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "expected keyring://name")
+}
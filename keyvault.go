@@ -0,0 +1,158 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	keyVaultResource   = "https://vault.azure.net"
+	keyVaultAPIVersion = "7.4"
+)
+
+// keyVaultName matches the names Key Vault allows for secrets, which have no underscore.
+var keyVaultName = regexp.MustCompile(`^[0-9A-Za-z-]{1,127}$`)
+
+// keyVaultTarget is an Azure Key Vault as a Target, e.g. keyvault://my-vault for https://my-vault.vault.azure.net:
+// each key is a secret whose name is the key with '_' written as '-', e.g. DB-PASSWORD for DB_PASSWORD.
+// The prefix query parameter limits the vault to the secrets starting with it, e.g. ?prefix=myapp- for myapp-DB-PASSWORD.
+//
+// Disabled secrets have no value and are left as they are. Removed secrets are deleted, which is a soft delete
+// if the vault has soft delete enabled.
+type keyVaultTarget struct {
+	name   string
+	vault  string
+	prefix string
+	client *restClient
+
+	// secrets are the names of the secrets by key as they are loaded, and env their values.
+	secrets map[string]string
+	env     Env
+}
+
+func openKeyVault(u *url.URL) (Target, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no vault in %s", u)
+	}
+	vault := u.Query().Get("endpoint")
+	if vault == "" {
+		vault = "https://" + u.Host + ".vault.azure.net"
+	}
+	return &keyVaultTarget{
+		name:   u.String(),
+		vault:  strings.TrimRight(vault, "/"),
+		prefix: u.Query().Get("prefix"),
+		client: newAzureClient(keyVaultResource),
+	}, nil
+}
+
+// keyVaultKey returns the key of the secret named name, e.g. DB_PASSWORD for DB-PASSWORD.
+func keyVaultKey(name string) string {
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// keyVaultSecret returns the name of the secret of key, e.g. DB-PASSWORD for DB_PASSWORD,
+// or an error if Key Vault doesn't allow it.
+func keyVaultSecret(key string) (string, error) {
+	name := strings.Replace(key, "_", "-", -1)
+	if !keyVaultName.MatchString(name) {
+		return "", fmt.Errorf("%s can't be the name of a Key Vault secret", key)
+	}
+	return name, nil
+}
+
+func (t *keyVaultTarget) Name() string {
+	return t.name
+}
+
+func (t *keyVaultTarget) Load(ctx context.Context) (*Document, error) {
+	t.secrets = make(map[string]string)
+	t.env = Env{}
+	next := t.url("")
+	for next != "" {
+		var out struct {
+			Value []struct {
+				ID         string `json:"id"`
+				Attributes struct {
+					Enabled bool `json:"enabled"`
+				} `json:"attributes"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := t.client.do(ctx, http.MethodGet, next, nil, &out); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't list the secrets of %s", t.vault))
+		}
+		for _, s := range out.Value {
+			name := s.ID[strings.LastIndex(s.ID, "/")+1:]
+			if !s.Attributes.Enabled || !strings.HasPrefix(name, t.prefix) {
+				continue
+			}
+			key := keyVaultKey(strings.TrimPrefix(name, t.prefix))
+			var secret struct {
+				Value string `json:"value"`
+			}
+			if err := t.client.do(ctx, http.MethodGet, t.url(name), nil, &secret); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("couldn't read secret %s", name))
+			}
+			t.secrets[key] = name
+			t.env[key] = secret.Value
+		}
+		next = out.NextLink
+	}
+	return envDocument(t.env), nil
+}
+
+// Store sets the secrets whose value is added or changed since the target is loaded,
+// and deletes the secrets of the keys that aren't in doc anymore.
+func (t *keyVaultTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		name, ok := t.secrets[k]
+		if !ok {
+			secret, err := keyVaultSecret(k)
+			if err != nil {
+				return err
+			}
+			name = t.prefix + secret
+		}
+		if err := t.client.do(ctx, http.MethodPut, t.url(name), map[string]string{"value": env[k]}, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set secret %s", name))
+		}
+		t.secrets[k], t.env[k] = name, env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := t.client.do(ctx, http.MethodDelete, t.url(t.secrets[k]), nil, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't delete secret %s", t.secrets[k]))
+		}
+		delete(t.env, k)
+		delete(t.secrets, k)
+	}
+	return nil
+}
+
+// url returns the URL of the secret named name, or of the list of secrets if name is empty.
+func (t *keyVaultTarget) url(name string) string {
+	u := t.vault + "/secrets"
+	if name != "" {
+		u += "/" + name
+	}
+	return u + "?api-version=" + keyVaultAPIVersion
+}
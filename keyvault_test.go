@@ -0,0 +1,102 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKeyVault serves the secrets API of Key Vault from memory, and the token endpoint of Azure AD.
+type fakeKeyVault struct {
+	url     string
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (f *fakeKeyVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+		if r.FormValue("client_secret") != "s3cret" || r.FormValue("scope") != "https://vault.azure.net/.default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("api-version") != "7.4" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/secrets"), "/")
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		var names []string
+		for n := range f.secrets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		var value []map[string]interface{}
+		for _, n := range names {
+			value = append(value, map[string]interface{}{"id": f.url + "/secrets/" + n, "attributes": map[string]bool{"enabled": true}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+	case r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]string{"value": f.secrets[name]})
+	case r.Method == http.MethodPut:
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		f.secrets[name] = in["value"]
+		w.Write([]byte("{}"))
+	case r.Method == http.MethodDelete:
+		delete(f.secrets, name)
+		w.Write([]byte("{}"))
+	}
+}
+
+func TestSyncer_Sync_KeyVault(t *testing.T) {
+	kv := &fakeKeyVault{secrets: map[string]string{
+		"myapp-DB-HOST": "db.example.com",
+		"myapp-OLD":     "x",
+		"other-TOKEN":   "t0ken",
+	}}
+	server := httptest.NewServer(kv)
+	defer server.Close()
+	kv.url = server.URL
+	defer setenv(map[string]string{"AZURE_TENANT_ID": "tenant", "AZURE_CLIENT_ID": "envsync", "AZURE_CLIENT_SECRET": "s3cret", "AZURE_AUTHORITY_HOST": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "keyvault://my-vault?prefix=myapp-&endpoint="+server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{
+		"myapp-DB-HOST": "db.example.com",
+		"myapp-DB-PORT": "5432",
+		"other-TOKEN":   "t0ken",
+	}, kv.secrets)
+
+	// a key Key Vault can't name isn't written
+	dir2 := makeTree(t, map[string]string{
+		"env.sample": "app.name=x\n",
+	})
+	defer os.RemoveAll(dir2)
+	_, err = syncer.SyncWithResult(filepath.Join(dir2, "env.sample"), "keyvault://my-vault?prefix=myapp-&endpoint="+server.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "app.name can't be the name of a Key Vault secret")
+}
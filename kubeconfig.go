@@ -0,0 +1,311 @@
+package envsync
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// kubeServiceAccount is where the credentials of the service account of a pod are mounted.
+const kubeServiceAccount = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeConfig is a kubeconfig file, or several of them merged like kubectl does: the first one to set a value wins.
+type kubeConfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string      `yaml:"name"`
+		Cluster kubeCluster `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string      `yaml:"name"`
+		Context kubeContext `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string   `yaml:"name"`
+		User kubeUser `yaml:"user"`
+	} `yaml:"users"`
+}
+
+type kubeCluster struct {
+	Server                   string `yaml:"server"`
+	CertificateAuthority     string `yaml:"certificate-authority"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+}
+
+type kubeContext struct {
+	Cluster   string `yaml:"cluster"`
+	User      string `yaml:"user"`
+	Namespace string `yaml:"namespace"`
+}
+
+type kubeUser struct {
+	Token                 string    `yaml:"token"`
+	TokenFile             string    `yaml:"tokenFile"`
+	ClientCertificate     string    `yaml:"client-certificate"`
+	ClientCertificateData string    `yaml:"client-certificate-data"`
+	ClientKey             string    `yaml:"client-key"`
+	ClientKeyData         string    `yaml:"client-key-data"`
+	Username              string    `yaml:"username"`
+	Password              string    `yaml:"password"`
+	Exec                  *kubeExec `yaml:"exec"`
+}
+
+// kubeExec is a credential plugin, e.g. aws eks get-token, run for a token.
+type kubeExec struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+// readKubeConfig reads the kubeconfig files listed in KUBECONFIG, or ~/.kube/config.
+// It returns nil if there is none.
+func readKubeConfig() (*kubeConfig, error) {
+	names := filepath.SplitList(os.Getenv("KUBECONFIG"))
+	if len(names) == 0 {
+		home, _ := os.UserHomeDir()
+		names = []string{filepath.Join(home, ".kube", "config")}
+	}
+
+	var merged *kubeConfig
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var c kubeConfig
+		if err := yaml.Unmarshal(b, &c); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s", name))
+		}
+		// the files a kubeconfig refers to are relative to it
+		dir := filepath.Dir(name)
+		for i := range c.Clusters {
+			kubePath(dir, &c.Clusters[i].Cluster.CertificateAuthority)
+		}
+		for i := range c.Users {
+			u := &c.Users[i].User
+			kubePath(dir, &u.TokenFile)
+			kubePath(dir, &u.ClientCertificate)
+			kubePath(dir, &u.ClientKey)
+		}
+
+		if merged == nil {
+			merged = &c
+			continue
+		}
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = c.CurrentContext
+		}
+		merged.Clusters = append(merged.Clusters, c.Clusters...)
+		merged.Contexts = append(merged.Contexts, c.Contexts...)
+		merged.Users = append(merged.Users, c.Users...)
+	}
+	return merged, nil
+}
+
+// kubePath makes *name relative to dir unless it is empty or absolute.
+func kubePath(dir string, name *string) {
+	if *name != "" && !filepath.IsAbs(*name) {
+		*name = filepath.Join(dir, *name)
+	}
+}
+
+// resolve returns the cluster, user, and namespace of the context named name, or of the current context if name is empty.
+func (c *kubeConfig) resolve(name string) (kubeCluster, kubeUser, string, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return kubeCluster{}, kubeUser{}, "", errors.New("no current context in kubeconfig")
+	}
+
+	var ctx *kubeContext
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			ctx = &c.Contexts[i].Context
+			break
+		}
+	}
+	if ctx == nil {
+		return kubeCluster{}, kubeUser{}, "", fmt.Errorf("no context %s in kubeconfig", name)
+	}
+	var cluster *kubeCluster
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == ctx.Cluster {
+			cluster = &c.Clusters[i].Cluster
+			break
+		}
+	}
+	if cluster == nil {
+		return kubeCluster{}, kubeUser{}, "", fmt.Errorf("no cluster %s in kubeconfig", ctx.Cluster)
+	}
+	var user kubeUser
+	for i := range c.Users {
+		if c.Users[i].Name == ctx.User {
+			user = c.Users[i].User
+			break
+		}
+	}
+	return *cluster, user, ctx.Namespace, nil
+}
+
+// newKubeClient returns a client of the API server of the kubeconfig context named context, or of the current one,
+// and the namespace of the context, found like kubectl does: in the kubeconfig files of KUBECONFIG or ~/.kube/config,
+// or else in the service account of the pod it runs in.
+func newKubeClient(context string) (*restClient, string, error) {
+	config, err := readKubeConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	if config == nil {
+		return inClusterKubeClient()
+	}
+	cluster, user, namespace, err := config.resolve(context)
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := kubeClient(cluster, user)
+	return client, namespace, err
+}
+
+// inClusterKubeClient returns a client of the API server of the cluster a pod runs in, with its service account.
+func inClusterKubeClient() (*restClient, string, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, "", errors.New("no kubeconfig, and not running in a cluster")
+	}
+	namespace, _ := ioutil.ReadFile(filepath.Join(kubeServiceAccount, "namespace"))
+	client, err := kubeClient(kubeCluster{
+		Server:               "https://" + net.JoinHostPort(host, port),
+		CertificateAuthority: filepath.Join(kubeServiceAccount, "ca.crt"),
+	}, kubeUser{TokenFile: filepath.Join(kubeServiceAccount, "token")})
+	return client, strings.TrimSpace(string(namespace)), err
+}
+
+// kubeClient returns a client of the API server of cluster, authenticated as user.
+func kubeClient(cluster kubeCluster, user kubeUser) (*restClient, error) {
+	if cluster.Server == "" {
+		return nil, errors.New("no server in kubeconfig")
+	}
+	ca, err := kubeData(cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := kubeData(user.ClientCertificateData, user.ClientCertificate)
+	if err != nil {
+		return nil, err
+	}
+	key, err := kubeData(user.ClientKeyData, user.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := tlsClient(ca, cert, key, cluster.InsecureSkipTLSVerify)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid kubeconfig")
+	}
+
+	client := &restClient{
+		base: cluster.Server,
+		http: httpClient,
+		errorOf: func(status int, body []byte) error {
+			var e struct {
+				Kind    string `json:"kind"`
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(body, &e) != nil || e.Kind != "Status" {
+				return nil
+			}
+			return &httpError{status: status, message: e.Message}
+		},
+	}
+	switch {
+	case user.Token != "":
+		client.auth = bearer(func(context.Context) (string, error) {
+			return user.Token, nil
+		})
+	case user.TokenFile != "":
+		// the token is read for every request since a service account token is rotated
+		client.auth = bearer(func(context.Context) (string, error) {
+			b, err := ioutil.ReadFile(user.TokenFile)
+			return strings.TrimSpace(string(b)), err
+		})
+	case user.Exec != nil:
+		var tokens tokenCache
+		client.auth = bearer(func(ctx context.Context) (string, error) {
+			token, err := tokens.get(func() (string, time.Duration, error) {
+				return user.Exec.token(ctx)
+			})
+			return token, errors.Wrap(err, fmt.Sprintf("couldn't get a token from %s", user.Exec.Command))
+		})
+	case user.Username != "":
+		client.auth = func(_ context.Context, req *http.Request) error {
+			req.SetBasicAuth(user.Username, user.Password)
+			return nil
+		}
+	}
+	return client, nil
+}
+
+// kubeData returns the base64-decoded data if it is set, or else the content of the file named name, or nil if neither is.
+func kubeData(data, name string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if name != "" {
+		return ioutil.ReadFile(name)
+	}
+	return nil, nil
+}
+
+// token runs the credential plugin and returns the token of the ExecCredential it writes, and the time it is valid for.
+// A token without an expiration time is kept for an hour.
+func (e *kubeExec) token(ctx context.Context) (string, time.Duration, error) {
+	apiVersion := e.APIVersion
+	if apiVersion == "" {
+		apiVersion = "client.authentication.k8s.io/v1beta1"
+	}
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf(`KUBERNETES_EXEC_INFO={"apiVersion":%q,"kind":"ExecCredential","spec":{"interactive":false}}`, apiVersion))
+	for _, v := range e.Env {
+		cmd.Env = append(cmd.Env, v.Name+"="+v.Value)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", 0, err
+	}
+
+	var cred struct {
+		Status struct {
+			Token               string    `json:"token"`
+			ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &cred); err != nil || cred.Status.Token == "" {
+		return "", 0, errors.New("no token in the ExecCredential")
+	}
+	ttl := time.Hour
+	if !cred.Status.ExpirationTimestamp.IsZero() {
+		ttl = time.Until(cred.Status.ExpirationTimestamp)
+	}
+	return cred.Status.Token, ttl, nil
+}
@@ -0,0 +1,195 @@
+package envsync
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// kubeTarget is a Secret of a live Kubernetes cluster as a Target, e.g. k8s://my-namespace/myapp for the Secret myapp,
+// or a ConfigMap with ?kind=configmap: each key is a key of its data block.
+// k8s:///myapp is in the namespace of the kubeconfig context, which the context query parameter selects, e.g. ?context=prod.
+//
+// The Secret is created if it doesn't exist, and replaced with the resource version it is loaded at,
+// so that a change made to it meanwhile fails the synchronization rather than being lost.
+// In dry-run mode the change is sent as a server-side dry run, so the admission of the cluster checks it.
+type kubeTarget struct {
+	name      string
+	namespace string
+	object    string
+	kind      string
+	client    *restClient
+
+	// obj is the object as it is loaded, or nil if it doesn't exist, and env its data.
+	obj map[string]interface{}
+	env Env
+}
+
+// kubeKinds are the kinds of objects a kubeTarget can be, by the value of the kind query parameter.
+var kubeKinds = map[string]string{"secret": "Secret", "configmap": "ConfigMap"}
+
+func openKube(u *url.URL) (Target, error) {
+	object := strings.Trim(u.Path, "/")
+	if object == "" || strings.Contains(object, "/") {
+		return nil, fmt.Errorf("expected k8s://namespace/name, got %s", u)
+	}
+	kind := "secret"
+	if k := u.Query().Get("kind"); k != "" {
+		kind = strings.ToLower(k)
+	}
+	if _, ok := kubeKinds[kind]; !ok {
+		return nil, fmt.Errorf("unknown kind %s, expected secret or configmap", kind)
+	}
+
+	client, namespace, err := newKubeClient(u.Query().Get("context"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != "" {
+		namespace = u.Host
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &kubeTarget{
+		name:      u.String(),
+		namespace: namespace,
+		object:    object,
+		kind:      kubeKinds[kind],
+		client:    client,
+	}, nil
+}
+
+func (t *kubeTarget) Name() string {
+	return t.name
+}
+
+func (t *kubeTarget) Load(ctx context.Context) (*Document, error) {
+	var obj map[string]interface{}
+	err := t.client.do(ctx, http.MethodGet, t.path(t.object, false), nil, &obj)
+	if isHTTPStatus(err, http.StatusNotFound) {
+		obj, err = nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s %s/%s", t.kind, t.namespace, t.object))
+	}
+	env, err := t.data(obj)
+	if err != nil {
+		return nil, err
+	}
+	t.obj, t.env = obj, env
+	return envDocument(env), nil
+}
+
+// Store replaces the data of the object with doc, or creates the object if it doesn't exist.
+func (t *kubeTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+	env := doc.Env()
+	if t.obj != nil && reflect.DeepEqual(env, t.env) {
+		return nil
+	}
+	obj, err := t.write(ctx, env, false)
+	if err != nil {
+		return err
+	}
+	t.obj, t.env = obj, env
+	return nil
+}
+
+// DryRun sends the object with the data of doc as a server-side dry run, and returns the data the server would store.
+func (t *kubeTarget) DryRun(ctx context.Context, doc *Document) (*Document, error) {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := t.write(ctx, doc.Env(), true)
+	if err != nil {
+		return nil, err
+	}
+	env, err := t.data(obj)
+	if err != nil {
+		return nil, err
+	}
+	return envDocument(env), nil
+}
+
+// write sends the object as loaded with its data replaced by env, or a new object if there is none,
+// and returns the object as the server answers.
+func (t *kubeTarget) write(ctx context.Context, env Env, dryRun bool) (map[string]interface{}, error) {
+	obj := t.obj
+	if obj == nil {
+		obj = map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       t.kind,
+			"metadata":   map[string]interface{}{"name": t.object, "namespace": t.namespace},
+		}
+		if t.kind == "Secret" {
+			obj["type"] = "Opaque"
+		}
+	}
+	data := make(map[string]string, len(env))
+	for k, v := range env {
+		if t.kind == "Secret" {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		data[k] = v
+	}
+	// the object as loaded is copied so that a failed request leaves it as it is
+	in := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		in[k] = v
+	}
+	in["data"] = data
+	delete(in, "stringData")
+
+	method, path := http.MethodPut, t.path(t.object, dryRun)
+	if t.obj == nil {
+		method, path = http.MethodPost, t.path("", dryRun)
+	}
+	var out map[string]interface{}
+	if err := t.client.do(ctx, method, path, in, &out); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't write %s %s/%s", t.kind, t.namespace, t.object))
+	}
+	return out, nil
+}
+
+// data returns the key-values of the data block of obj, base64-decoded in a Secret.
+func (t *kubeTarget) data(obj map[string]interface{}) (Env, error) {
+	env := Env{}
+	data, _ := obj["data"].(map[string]interface{})
+	for k, v := range data {
+		value, _ := v.(string)
+		if t.kind == "Secret" {
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("couldn't decode %s of %s %s/%s", k, t.kind, t.namespace, t.object))
+			}
+			value = string(b)
+		}
+		env[k] = value
+	}
+	return env, nil
+}
+
+// path returns the path of the object named name, or of the collection of objects of its kind if name is empty.
+func (t *kubeTarget) path(name string, dryRun bool) string {
+	p := "/api/v1/namespaces/" + url.PathEscape(t.namespace) + "/" + strings.ToLower(t.kind) + "s"
+	if name != "" {
+		p += "/" + url.PathEscape(name)
+	}
+	if dryRun {
+		p += "?dryRun=All"
+	}
+	return p
+}
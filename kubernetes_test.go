@@ -0,0 +1,173 @@
+package envsync_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKube serves the Secrets of the namespace prod from memory.
+// Its admission adds a REGION key to the Secrets that don't have one.
+type fakeKube struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]interface{}
+	version int
+
+	// modified makes a Secret change once it is read, as if someone else wrote it.
+	modified bool
+}
+
+func (f *fakeKube) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer kube-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"kind": "Status", "message": "Unauthorized", "code": 401}`))
+		return
+	}
+	const prefix = "/api/v1/namespaces/prod/secrets"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"kind": "Status", "message": "secrets %q not found", "code": 404}`, name)
+	}
+
+	if r.Method == http.MethodGet {
+		if _, ok := f.secrets[name]; !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(f.secrets[name])
+		if f.modified {
+			f.version++
+			f.secrets[name]["metadata"].(map[string]interface{})["resourceVersion"] = strconv.Itoa(f.version)
+		}
+		return
+	}
+
+	var obj map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&obj)
+	metadata := obj["metadata"].(map[string]interface{})
+	if r.Method == http.MethodPut {
+		old, ok := f.secrets[name]
+		if !ok {
+			notFound()
+			return
+		}
+		if metadata["resourceVersion"] != old["metadata"].(map[string]interface{})["resourceVersion"] {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"kind": "Status", "message": "the object has been modified", "code": 409}`))
+			return
+		}
+	} else {
+		name = metadata["name"].(string)
+	}
+	data := obj["data"].(map[string]interface{})
+	if _, ok := data["REGION"]; !ok {
+		data["REGION"] = base64.StdEncoding.EncodeToString([]byte("eu-west-1"))
+	}
+	if r.URL.Query().Get("dryRun") != "All" {
+		f.version++
+		metadata["resourceVersion"] = strconv.Itoa(f.version)
+		f.secrets[name] = obj
+	}
+	json.NewEncoder(w).Encode(obj)
+}
+
+// secret returns the key-values of the Secret named name, base64-decoded.
+func (f *fakeKube) secret(name string) map[string]string {
+	env := make(map[string]string)
+	for k, v := range f.secrets[name]["data"].(map[string]interface{}) {
+		b, _ := base64.StdEncoding.DecodeString(v.(string))
+		env[k] = string(b)
+	}
+	return env
+}
+
+func TestSyncer_Sync_Kubernetes(t *testing.T) {
+	b64 := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	kube := &fakeKube{version: 1, secrets: map[string]map[string]interface{}{
+		"myapp": {
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "myapp", "namespace": "prod", "resourceVersion": "1", "labels": map[string]interface{}{"app": "myapp"}},
+			"type":       "Opaque",
+			"data":       map[string]interface{}{"DB_HOST": b64("db.example.com"), "OLD": b64("x"), "REGION": b64("us-east-1")},
+		},
+	}}
+	server := httptest.NewTLSServer(kube)
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+		"ca.crt":     string(ca),
+		// kubeconfig files are YAML, which JSON is a subset of
+		"kubeconfig": `{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "current-context": "prod",
+  "clusters": [{"name": "prod", "cluster": {"server": "` + server.URL + `", "certificate-authority": "ca.crt"}}],
+  "contexts": [{"name": "prod", "context": {"cluster": "prod", "user": "deployer", "namespace": "prod"}}],
+  "users": [{"name": "deployer", "user": {"token": "kube-token"}}]
+}`,
+	})
+	defer os.RemoveAll(dir)
+	defer setenv(map[string]string{"KUBECONFIG": filepath.Join(dir, "kubeconfig")})()
+
+	// the dry run reports what the server would store, and doesn't change the Secret
+	syncer := envsync.New(envsync.WithPrune(), envsync.WithDryRun())
+	syncer.Patch, syncer.ShowSecrets = true, true
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "k8s:///new")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT"}, res.Added)
+	assert.Equal(t, envsync.Env{"DB_HOST": "localhost", "DB_PORT": "5432", "REGION": "eu-west-1"}, res.Env)
+	assert.Contains(t, res.Patch, "+REGION=eu-west-1")
+	_, exists := kube.secrets["new"]
+	assert.False(t, exists)
+
+	syncer = envsync.New(envsync.WithPrune())
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "k8s://prod/myapp")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD", "REGION"}, res.Pruned)
+	assert.Equal(t, map[string]string{"DB_HOST": "db.example.com", "DB_PORT": "5432", "REGION": "eu-west-1"}, kube.secret("myapp"))
+	assert.Equal(t, map[string]interface{}{"app": "myapp"}, kube.secrets["myapp"]["metadata"].(map[string]interface{})["labels"])
+
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "k8s:///new")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT"}, res.Added)
+	assert.Equal(t, map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432", "REGION": "eu-west-1"}, kube.secret("new"))
+
+	// a Secret changed since it is loaded isn't overwritten
+	kube.modified = true
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "k8s://prod/myapp")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "409 Conflict: the object has been modified")
+	kube.modified = false
+
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "kubeconfig"))
+	ioutil.WriteFile(filepath.Join(dir, "kubeconfig"), []byte(strings.Replace(string(b), "kube-token", "invalid", 1)), 0644)
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "k8s://prod/myapp")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized: Unauthorized")
+}
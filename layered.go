@@ -0,0 +1,53 @@
+package envsync
+
+import (
+	"context"
+	"errors"
+)
+
+// SyncLayered works like SyncContext with several sources merged into a single source.
+// sources are ordered from the lowest to the highest precedence,
+// e.g. .env.defaults, .env.example, .env.local.example:
+// a key in a later source overrides the same key in an earlier one.
+//
+// Keys keep the position where they are first declared,
+// so added key-values follow the order of the earliest source declaring them.
+func (s *Syncer) SyncLayered(ctx context.Context, sources []string, target string) (*SyncResult, error) {
+	sDoc, err := s.mergeSources(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := s.open(target, "target")
+	if err != nil {
+		return nil, err
+	}
+	return s.syncTarget(ctx, sDoc, dst)
+}
+
+// mergeSources reads sources and merges them in precedence order.
+func (s *Syncer) mergeSources(ctx context.Context, sources []string) (*Document, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no source file given")
+	}
+
+	merged, err := s.load(ctx, sources[0], "source")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources[1:] {
+		doc, err := s.load(ctx, source, "source")
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(doc.recode(merged.codec))
+	}
+	return merged, nil
+}
+
+// merge puts every key-value in other into d, replacing the ones d already has.
+func (d *Document) merge(other *Document) {
+	for _, k := range other.Keys() {
+		d.put(other.line(k))
+	}
+}
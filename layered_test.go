@@ -0,0 +1,46 @@
+package envsync_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncLayered(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "envsync")
+	defer os.RemoveAll(dir)
+
+	defaults := filepath.Join(dir, ".env.defaults")
+	example := filepath.Join(dir, ".env.example")
+	local := filepath.Join(dir, ".env.local.example")
+	target := filepath.Join(dir, ".env")
+	ioutil.WriteFile(defaults, []byte("HOST=localhost\nPORT=80\nDEBUG=false\n"), 0644)
+	ioutil.WriteFile(example, []byte("PORT=8080\nTOKEN=changeme\n"), 0644)
+	ioutil.WriteFile(local, []byte("DEBUG=true\n"), 0644)
+	ioutil.WriteFile(target, []byte("HOST=example.com\n"), 0644)
+
+	syncer := &envsync.Syncer{Order: envsync.OrderNone}
+	res, err := syncer.SyncLayered(context.Background(), []string{defaults, example, local}, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG", "PORT", "TOKEN"}, res.Added)
+	assert.Equal(t, []string{"HOST"}, res.Skipped)
+
+	content, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "HOST=example.com\nPORT=8080\nDEBUG=true\nTOKEN=changeme\n", string(content))
+}
+
+func TestSyncer_SyncLayered_Error(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	_, err := syncer.SyncLayered(context.Background(), nil, "testdata/env.success")
+	assert.NotNil(t, err)
+
+	_, err = syncer.SyncLayered(context.Background(), []string{"testdata/env.success", "testdata/env.empty"}, "testdata/env.success")
+	assert.True(t, errors.Is(err, envsync.ErrSourceNotFound))
+}
@@ -0,0 +1,110 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintRule checks an env file for a kind of mistake.
+type LintRule interface {
+	// Name identifies the rule, e.g. placeholder.
+	Name() string
+
+	// Check returns the violations of the rule in target.
+	// source is the sample env target is synchronized from, or nil if there is none.
+	Check(target, source *Document) []Violation
+}
+
+// LintFixer is a LintRule that can correct its violations.
+type LintFixer interface {
+	LintRule
+
+	// Fix corrects the violations of the rule in doc and returns the keys it changes.
+	Fix(doc *Document) []string
+}
+
+// Lint returns the violations of rules in target, ordered by line and then in the order of rules.
+// source is the sample env target is synchronized from, or nil if there is none.
+// The Rule of each violation is the name of the rule that found it.
+func Lint(target, source *Document, rules ...LintRule) []Violation {
+	var violations []Violation
+	for _, r := range rules {
+		for _, v := range r.Check(target, source) {
+			v.Rule = r.Name()
+			violations = append(violations, v)
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Line < violations[j].Line
+	})
+	return violations
+}
+
+// LintFile returns the violations of rules in the env file at name, as Lint does without a source.
+func LintFile(name string, rules ...LintRule) ([]Violation, error) {
+	s := &Syncer{}
+	doc, err := s.readDocument(context.Background(), name, "target")
+	if err != nil {
+		return nil, err
+	}
+	return Lint(doc, nil, rules...), nil
+}
+
+// DefaultPlaceholders are the values PlaceholderRule flags when it has no Values.
+// They are compared regardless of case.
+var DefaultPlaceholders = []string{"changeme", "change_me", "change-me", "todo", "fixme", "xxx", "replaceme"}
+
+// placeholderPattern matches values like <your-key-here> or <CHANGE_ME>.
+var placeholderPattern = regexp.MustCompile(`^<[^<>]+>$`)
+
+// PlaceholderRule flags values left as sample placeholders, e.g. changeme, TODO, or <your-key-here>,
+// and empty values of keys annotated as required in source.
+type PlaceholderRule struct {
+	// Values are the placeholders to flag, compared regardless of case.
+	// If it is empty, DefaultPlaceholders are flagged.
+	// Values wrapped in angle brackets are always flagged.
+	Values []string
+}
+
+// Name returns placeholder.
+func (r PlaceholderRule) Name() string {
+	return "placeholder"
+}
+
+// Check returns a warning for each key in target holding a placeholder,
+// and an error for each key annotated as required in source that is empty in target.
+func (r PlaceholderRule) Check(target, source *Document) []Violation {
+	values := r.Values
+	if len(values) == 0 {
+		values = DefaultPlaceholders
+	}
+
+	env := target.Env()
+	var violations []Violation
+	for _, k := range target.Keys() {
+		v := strings.TrimSpace(env[k])
+		if placeholderPattern.MatchString(v) || containsFold(values, v) {
+			violations = append(violations, Violation{Key: k, Line: target.lineNumber(k), Message: fmt.Sprintf("%q is a placeholder", v), Warning: true})
+		}
+	}
+	if source != nil {
+		for _, k := range source.requiredKeys(source.Env()) {
+			if v, found := env[k]; found && v == "" {
+				violations = append(violations, Violation{Key: k, Line: target.lineNumber(k), Message: "required env is empty"})
+			}
+		}
+	}
+	return violations
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package envsync_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint_PlaceholderRule(t *testing.T) {
+	source, err := envsync.ParseDocument(strings.NewReader("# required\nAPI_KEY=\nHOST=localhost\n"))
+	assert.Nil(t, err)
+	target, err := envsync.ParseDocument(strings.NewReader("API_KEY=\nHOST=localhost\nTOKEN=<your-token-here>\nSECRET=ChangeMe\nNOTE=TODO later\n"))
+	assert.Nil(t, err)
+
+	violations := envsync.Lint(target, source, envsync.PlaceholderRule{})
+	assert.Equal(t, []envsync.Violation{
+		{Key: "API_KEY", Line: 1, Message: "required env is empty", Rule: "placeholder"},
+		{Key: "TOKEN", Line: 3, Message: `"<your-token-here>" is a placeholder`, Warning: true, Rule: "placeholder"},
+		{Key: "SECRET", Line: 4, Message: `"ChangeMe" is a placeholder`, Warning: true, Rule: "placeholder"},
+	}, violations)
+	assert.Equal(t, `warning: line 4: SECRET: "ChangeMe" is a placeholder (placeholder)`, violations[2].String())
+
+	violations = envsync.Lint(target, nil, envsync.PlaceholderRule{Values: []string{"localhost"}})
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "HOST", violations[0].Key)
+	assert.Equal(t, "TOKEN", violations[1].Key)
+}
+
+func TestSyncer_SyncReaders_Lint(t *testing.T) {
+	src := "HOST=localhost\nAPI_KEY=\n"
+	dst := "HOST=example.com\n"
+
+	var logs bytes.Buffer
+	syncer := envsync.New(
+		envsync.WithPlaceholder(envsync.PlaceholderMarker),
+		envsync.WithLint(envsync.PlaceholderRule{}),
+		envsync.WithLogger(log.New(&logs, "", 0)),
+	)
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []envsync.Violation{
+		{Key: "API_KEY", Line: 2, Message: `"<CHANGE_ME>" is a placeholder`, Warning: true, Rule: "placeholder"},
+	}, res.Issues)
+	assert.Contains(t, logs.String(), `Lint: warning: line 2: API_KEY: "<CHANGE_ME>" is a placeholder (placeholder)`)
+
+	syncer.DryRun = true
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Len(t, res.Issues, 1)
+	assert.Empty(t, buf.String())
+}
+
+func TestLint_Rules(t *testing.T) {
+	schema, err := envsync.ParseSchema(strings.NewReader("keys:\n  PORT:\n    type: int\n"))
+	assert.Nil(t, err)
+	doc, err := envsync.ParseDocument(strings.NewReader("api-key=changeme\nPORT=eighty\nPORT=80\n"))
+	assert.Nil(t, err)
+
+	violations := envsync.Lint(doc, nil, envsync.SchemaRule{Schema: schema}, envsync.NamingRule{}, envsync.PlaceholderRule{}, envsync.DuplicateRule{})
+	var rules []string
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	assert.Equal(t, []string{"naming", "placeholder", "duplicate"}, rules)
+	assert.Empty(t, envsync.Lint(doc, nil, envsync.SchemaRule{}))
+
+	doc, _ = envsync.ParseDocument(strings.NewReader("PORT=eighty\n"))
+	violations = envsync.Lint(doc, nil, envsync.SchemaRule{Schema: schema})
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "schema", violations[0].Rule)
+}
@@ -0,0 +1,197 @@
+package envsync
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// kindConfigMap and kindSecret are the kinds of the Kubernetes manifests whose data block holds the key-values.
+	kindConfigMap = "ConfigMap"
+	kindSecret    = "Secret"
+
+	// manifestData holds the key-values of a manifest, base64-encoded in a Secret,
+	// and manifestStringData the ones of a Secret that aren't encoded.
+	manifestData       = "data"
+	manifestStringData = "stringData"
+)
+
+// yamlTyped matches the plain scalars YAML reads as something else than a string, e.g. 8080, true, or no.
+// Kubernetes only accepts strings in the data of a manifest, so they are quoted.
+var yamlTyped = regexp.MustCompile(`^([-+.]?[0-9]|(?i:true|false|yes|no|on|off|y|n)$)`)
+
+// manifestKind returns the kind of the Kubernetes manifest root, or an empty string if it isn't one envsync reads.
+func manifestKind(root yaml.MapSlice) string {
+	for _, item := range root {
+		if item.Key == "kind" && (item.Value == kindConfigMap || item.Value == kindSecret) {
+			return item.Value.(string)
+		}
+	}
+	return ""
+}
+
+// decodeManifest reads the key-values in the data block of the Kubernetes manifest b.
+// The values of a Secret are base64-decoded, and the ones of its stringData block are read as they are,
+// overriding the data block like Kubernetes does.
+// The text around the blocks is kept as it is, so the rest of the manifest is written back untouched.
+func decodeManifest(b []byte, root yaml.MapSlice, kind string) (*Document, error) {
+	doc := &Document{codec: CodecYAML}
+	doc.yaml.manifest = kind
+	doc.yaml.head, doc.yaml.tail = yamlSplit(string(b), manifestData, manifestStringData)
+	doc.yaml.blocks = make(map[string]bool)
+	doc.yaml.stringData = make(map[string]bool)
+
+	var v struct {
+		Data       map[string]string `yaml:"data"`
+		StringData map[string]string `yaml:"stringData"`
+	}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrap(err, "couldn't decode YAML")
+	}
+	for _, item := range root {
+		block := fmt.Sprint(item.Key)
+		if block != manifestData && (kind != kindSecret || block != manifestStringData) {
+			continue
+		}
+		doc.yaml.blocks[block] = true
+		data, _ := item.Value.(yaml.MapSlice)
+		for _, d := range data {
+			k := fmt.Sprint(d.Key)
+			value := v.StringData[k]
+			switch {
+			case block == manifestStringData:
+				doc.yaml.stringData[k] = true
+				doc.remove(k)
+			case kind == kindSecret:
+				decoded, err := base64.StdEncoding.DecodeString(v.Data[k])
+				if err != nil {
+					return nil, errors.Wrapf(err, "couldn't decode %s in the data of Secret", k)
+				}
+				value = string(decoded)
+			default:
+				value = v.Data[k]
+			}
+			if !doc.yaml.stringData[k] || block == manifestStringData {
+				doc.lines = append(doc.lines, &line{kind: entryLine, key: k, value: value})
+			}
+		}
+	}
+	return doc, nil
+}
+
+// yamlSplit returns the text of the YAML mapping text before and after the blocks of the top-level keys,
+// the blocks being removed from it and placed where the first one is, or at the end if there is none.
+// Blank lines and comments ending a block are left after it.
+func yamlSplit(text string, keys ...string) (string, string) {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	lines := strings.SplitAfter(text, "\n")
+	removed := make([]bool, len(lines))
+	at := len(lines)
+	for _, key := range keys {
+		start, end := yamlBlock(lines, key)
+		if start < 0 {
+			continue
+		}
+		for i := start; i < end; i++ {
+			removed[i] = true
+		}
+		if start < at {
+			at = start
+		}
+	}
+
+	var head, tail strings.Builder
+	for i, l := range lines {
+		switch {
+		case removed[i]:
+		case i < at:
+			head.WriteString(l)
+		default:
+			tail.WriteString(l)
+		}
+	}
+	return head.String(), tail.String()
+}
+
+// yamlBlock returns the range of lines of the block of the top-level key, without the blank lines and comments ending it,
+// or -1 if there is no such block.
+func yamlBlock(lines []string, key string) (int, int) {
+	start := -1
+	for i, l := range lines {
+		if strings.HasPrefix(l, key+":") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+
+	end := start + 1
+	for end < len(lines) && lines[end] != "" && (strings.TrimSpace(lines[end]) == "" || lines[end][0] == ' ' || lines[end][0] == '\t' || lines[end][0] == '#') {
+		end++
+	}
+	for end > start+1 && (strings.TrimSpace(lines[end-1]) == "" || lines[end-1][0] == '#') {
+		end--
+	}
+	return start, end
+}
+
+// encodeManifest writes d to buf as the Kubernetes manifest it is read from, with its key-values in the data block.
+// Values are always written as strings, e.g. "8080" rather than 8080, and base64-encoded in the data block of a Secret.
+// A key-value of a Secret is written in its stringData block if it is read from there,
+// or if it is added to a Secret that has a stringData block and no data block.
+func (d *Document) encodeManifest(buf *bytes.Buffer) {
+	blocks := map[string][]string{}
+	var pending []string
+	for _, l := range d.lines {
+		switch l.kind {
+		case commentLine:
+			pending = append(pending, "  "+l.raw)
+		case entryLine:
+			block, value := manifestData, yamlString(l.value)
+			if d.yaml.manifest == kindSecret {
+				if d.yaml.stringData[l.key] || d.yaml.blocks[manifestStringData] && !d.yaml.blocks[manifestData] {
+					block = manifestStringData
+				} else {
+					value = yamlScalar(base64.StdEncoding.EncodeToString([]byte(l.value)))
+				}
+			}
+			blocks[block] = append(append(blocks[block], pending...), "  "+yamlScalar(l.key)+": "+value+l.comment)
+			pending = nil
+		}
+	}
+
+	buf.WriteString(d.yaml.head)
+	// a manifest always has a data block, unless it only has a stringData block
+	present := map[string]bool{
+		manifestData:       d.yaml.blocks[manifestData] || !d.yaml.blocks[manifestStringData] && len(blocks[manifestStringData]) == 0,
+		manifestStringData: d.yaml.blocks[manifestStringData],
+	}
+	for _, block := range []string{manifestData, manifestStringData} {
+		switch {
+		case len(blocks[block]) > 0:
+			buf.WriteString(block + ":\n" + strings.Join(blocks[block], "\n") + "\n")
+		case present[block]:
+			buf.WriteString(block + ": {}\n")
+		}
+	}
+	buf.WriteString(d.yaml.tail)
+}
+
+// yamlString returns s as a YAML scalar read as a string, quoting it if it would be read as a number or a boolean.
+func yamlString(s string) string {
+	if yamlTyped.MatchString(s) {
+		return strconv.Quote(s)
+	}
+	return yamlScalar(s)
+}
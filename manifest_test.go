@@ -0,0 +1,72 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeDocument_ConfigMap(t *testing.T) {
+	in := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  HOST: localhost\n  PORT: \"8080\"\n\n# immutable config\nimmutable: false\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecYAML)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "PORT": "8080"}, doc.Env())
+	assert.Equal(t, in, doc.String())
+
+	doc.Set("DEBUG", "true")
+	doc.Delete("HOST")
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  PORT: \"8080\"\n  DEBUG: \"true\"\n\n# immutable config\nimmutable: false\n", doc.String())
+
+	doc.Delete("PORT")
+	doc.Delete("DEBUG")
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata: {}\n\n# immutable config\nimmutable: false\n", doc.String())
+}
+
+func TestSyncer_Sync_ConfigMap(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example":  "HOST=localhost\nPORT=8080\n",
+		"configmap.yml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n",
+	})
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "configmap.yml")
+	err := (&envsync.Syncer{}).Sync(filepath.Join(dir, ".env.example"), target)
+	assert.Nil(t, err)
+
+	b, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  HOST: localhost\n  PORT: \"8080\"\n", string(b))
+
+	// and back into a .env
+	ioutil.WriteFile(filepath.Join(dir, ".env"), nil, 0644)
+	err = (&envsync.Syncer{}).Sync(target, filepath.Join(dir, ".env"))
+	assert.Nil(t, err)
+	b, err = ioutil.ReadFile(filepath.Join(dir, ".env"))
+	assert.Nil(t, err)
+	assert.Equal(t, "HOST=localhost\nPORT=8080\n", string(b))
+}
+
+func TestDecodeDocument_Secret(t *testing.T) {
+	in := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n  DB_PASSWORD: c2VjcmV0\n  API_KEY: b2xk\ntype: Opaque\nstringData:\n  API_KEY: new\n"
+	doc, err := envsync.DecodeDocument(strings.NewReader(in), envsync.CodecYAML)
+	assert.Nil(t, err)
+	assert.Equal(t, envsync.Env{"DB_PASSWORD": "secret", "API_KEY": "new"}, doc.Env())
+
+	// the blocks are written where the first one is, and each key-value stays in its block
+	doc.Set("PORT", "8080")
+	assert.Equal(t, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n  DB_PASSWORD: c2VjcmV0\n  PORT: ODA4MA==\nstringData:\n  API_KEY: new\ntype: Opaque\n", doc.String())
+
+	// added key-values go to stringData when there is no data block
+	doc, err = envsync.DecodeDocument(strings.NewReader("kind: Secret\nstringData:\n  TOKEN: abc\n"), envsync.CodecYAML)
+	assert.Nil(t, err)
+	doc.Set("PORT", "8080")
+	assert.Equal(t, "kind: Secret\nstringData:\n  TOKEN: abc\n  PORT: \"8080\"\n", doc.String())
+
+	_, err = envsync.DecodeDocument(strings.NewReader("kind: Secret\ndata:\n  TOKEN: not-base64!\n"), envsync.CodecYAML)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,131 @@
+package envsync
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maskedValue replaces the values of secret keys in output.
+const maskedValue = "****"
+
+// DefaultMaskPatterns are the keys whose values a Masker hides when it has no pattern.
+var DefaultMaskPatterns = []string{"*_SECRET", "*_TOKEN", "*_PASSWORD"}
+
+// Masker hides the values of secret keys, e.g. API_TOKEN, from output like patches and violations,
+// so they don't leak into build logs.
+type Masker struct {
+	match []func(string) bool
+}
+
+// NewMasker returns a Masker hiding the values of keys matching any of patterns,
+// or any of DefaultMaskPatterns if there is none.
+// Patterns have the same syntax as Syncer.Include.
+func NewMasker(patterns ...string) (*Masker, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultMaskPatterns
+	}
+
+	m := &Masker{}
+	for _, p := range patterns {
+		match, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		m.match = append(m.match, match)
+	}
+	return m, nil
+}
+
+// Secret reports whether the value of key is hidden.
+// A nil Masker hides nothing.
+func (m *Masker) Secret(key string) bool {
+	if m == nil {
+		return false
+	}
+	for _, match := range m.match {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Violations returns violations with the value env holds for each secret key replaced by **** in their message.
+func (m *Masker) Violations(violations []Violation, env Env) []Violation {
+	res := make([]Violation, len(violations))
+	for i, v := range violations {
+		if value := env[v.Key]; value != "" && m.Secret(v.Key) {
+			v.Message = strings.Replace(v.Message, strconv.Quote(value), strconv.Quote(maskedValue), -1)
+			v.Message = strings.Replace(v.Message, value, maskedValue, -1)
+		}
+		res[i] = v
+	}
+	return res
+}
+
+// Diff returns the changes from before to after as UnifiedDiff does,
+// with the value of each secret key replaced by ****.
+// Lines are still compared with their value, so a changed secret shows up as a masked change.
+func (m *Masker) Diff(name string, before, after *Document) string {
+	return unifiedDiff(name, splitLines(before.String()), splitLines(after.String()), m.lines(before), m.lines(after))
+}
+
+// lines returns the lines of doc as it is written, with the value of each secret key replaced by ****.
+// A multi-line value is replaced line by line, so the lines match the ones of doc.
+func (m *Masker) lines(doc *Document) []string {
+	if doc.codec != CodecDotenv {
+		return m.encodedLines(doc)
+	}
+	var res []string
+	for _, l := range doc.lines {
+		text := l.String()
+		if l.kind != entryLine || !m.Secret(l.key) {
+			res = append(res, splitLines(text+"\n")...)
+			continue
+		}
+
+		masked := l.clone()
+		masked.value, masked.quote, masked.raw = maskedValue, noQuote, ""
+		res = append(res, masked.String())
+		for i := strings.Count(text, "\n"); i > 0; i-- {
+			res = append(res, maskedValue)
+		}
+	}
+	return res
+}
+
+// encodedLines returns the lines of doc written in a codec other than dotenv, with the value of each secret key replaced by ****.
+// A line of doc isn't a line of the file in these codecs, so the values are masked before doc is written,
+// each value keeping the line breaks it has.
+// If the masked doc still takes another number of lines, e.g. once a line continuation is dropped,
+// each line of doc the masked doc doesn't have is masked whole.
+func (m *Masker) encodedLines(doc *Document) []string {
+	masked := doc.clone()
+	for _, l := range masked.lines {
+		if l.kind != entryLine || !m.Secret(l.key) {
+			continue
+		}
+		n := strings.Count(l.value, "\n")
+		if c := strings.Count(l.raw, "\n"); c > n {
+			n = c
+		}
+		l.value, l.raw = maskedValue+strings.Repeat("\n"+maskedValue, n), ""
+	}
+
+	real, shown := splitLines(doc.String()), splitLines(masked.String())
+	if len(shown) == len(real) {
+		return shown
+	}
+	written := make(map[string]bool, len(shown))
+	for _, text := range shown {
+		written[text] = true
+	}
+	res := make([]string, len(real))
+	for i, text := range real {
+		res[i] = text
+		if !written[text] {
+			res[i] = maskedValue
+		}
+	}
+	return res
+}
@@ -0,0 +1,105 @@
+package envsync_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasker_Secret(t *testing.T) {
+	m, err := envsync.NewMasker()
+	assert.Nil(t, err)
+	assert.True(t, m.Secret("API_TOKEN"))
+	assert.True(t, m.Secret("DATABASE_PASSWORD"))
+	assert.True(t, m.Secret("CLIENT_SECRET"))
+	assert.False(t, m.Secret("HOST"))
+
+	m, err = envsync.NewMasker("/_KEY$/", "SENTRY_DSN")
+	assert.Nil(t, err)
+	assert.True(t, m.Secret("STRIPE_KEY"))
+	assert.True(t, m.Secret("SENTRY_DSN"))
+	assert.False(t, m.Secret("API_TOKEN"))
+
+	_, err = envsync.NewMasker("[")
+	assert.NotNil(t, err)
+
+	var nilMasker *envsync.Masker
+	assert.False(t, nilMasker.Secret("API_TOKEN"))
+}
+
+func TestMasker_Violations(t *testing.T) {
+	m, err := envsync.NewMasker()
+	assert.Nil(t, err)
+
+	violations := []envsync.Violation{
+		{Key: "API_TOKEN", Line: 1, Message: `"changeme" is a placeholder`},
+		{Key: "HOST", Line: 2, Message: `"todo" is a placeholder`},
+	}
+	env := envsync.Env{"API_TOKEN": "changeme", "HOST": "todo"}
+	assert.Equal(t, []envsync.Violation{
+		{Key: "API_TOKEN", Line: 1, Message: `"****" is a placeholder`},
+		{Key: "HOST", Line: 2, Message: `"todo" is a placeholder`},
+	}, m.Violations(violations, env))
+	assert.Equal(t, `"changeme" is a placeholder`, violations[0].Message)
+}
+
+func TestSyncer_SyncReaders_PatchMask(t *testing.T) {
+	src := "HOST=localhost\nAPI_TOKEN=sample\nDB_PASSWORD=\"multi\nline\"\n"
+	dst := "HOST=localhost\nAPI_TOKEN=s3cr3t\n"
+
+	var buf bytes.Buffer
+	syncer := envsync.New(envsync.WithDryRun(), envsync.WithPatch())
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "--- a/target\n+++ b/target\n@@ -1,2 +1,4 @@\n HOST=localhost\n API_TOKEN=****\n+DB_PASSWORD=****\n+****\n", res.Patch)
+	assert.NotContains(t, res.Patch, "s3cr3t")
+
+	syncer = envsync.New(envsync.WithDryRun(), envsync.WithPatch(), envsync.WithOverwrite("*"), envsync.WithMask("HOST"))
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader("HOST=example.com\n"), &buf)
+	assert.Nil(t, err)
+	assert.Contains(t, res.Patch, "-HOST=****\n+HOST=****\n")
+
+	syncer = envsync.New(envsync.WithDryRun(), envsync.WithPatch(), envsync.WithShowSecrets())
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Contains(t, res.Patch, " API_TOKEN=s3cr3t\n+DB_PASSWORD=\"multi\n+line\"\n")
+
+	syncer = envsync.New(envsync.WithMask("["))
+	_, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.NotNil(t, err)
+}
+
+func TestSyncer_SyncReaders_LintMask(t *testing.T) {
+	src := "API_TOKEN=\n"
+	dst := "API_TOKEN=changeme\n"
+
+	var buf bytes.Buffer
+	syncer := envsync.New(envsync.WithLint(envsync.PlaceholderRule{}))
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []envsync.Violation{{Key: "API_TOKEN", Line: 1, Message: `"****" is a placeholder`, Warning: true, Rule: "placeholder"}}, res.Issues)
+}
+
+func TestSyncer_Sync_PatchMaskMultiline(t *testing.T) {
+	codecs := map[string]envsync.Codec{"app.sh": envsync.CodecShell, "app.conf": envsync.CodecSystemd}
+	for name, codec := range codecs {
+		dir := makeTree(t, map[string]string{
+			"env.sample": "HOST=localhost\nAPI_TOKEN=sample\n",
+			name:         "API_TOKEN=\"line1\nline2\nline3\"\nHOST=example.com\nPORT=8080\n",
+		})
+		defer os.RemoveAll(dir)
+
+		target := filepath.Join(dir, name)
+		syncer := &envsync.Syncer{Patch: true, Prune: true, Overwrite: true, Codecs: map[string]envsync.Codec{target: codec}}
+		res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), target)
+		assert.Nil(t, err, name)
+		assert.Contains(t, res.Patch, "@@ -1,5 +1,2 @@\n-API_TOKEN=\"****\n-****\n-****\"\n-HOST=example.com\n-PORT=8080\n", name)
+		assert.NotContains(t, res.Patch, "line", name)
+		assert.NotContains(t, res.Patch, "sample", name)
+	}
+}
@@ -0,0 +1,111 @@
+package envsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MergeStrategy describes what happens to a key that is in both source and target with different values.
+// Only keys matching OverwritePattern are considered.
+type MergeStrategy int
+
+const (
+	// MergeKeepTarget keeps the value in target.
+	// This is the default, unless Overwrite is set.
+	MergeKeepTarget MergeStrategy = iota
+
+	// MergeKeepSource replaces the value in target with the value in source, the same as Overwrite.
+	MergeKeepSource
+
+	// MergePrompt asks Prompter for the value, given the value in source.
+	// Accepting it takes the value in source and skipping it keeps the value in target.
+	MergePrompt
+
+	// MergeFail makes the synchronization fail with ErrConflict.
+	MergeFail
+
+	// MergeThreeWay compares target with a snapshot of the source last synchronized into it.
+	// A value in target that hasn't been changed since is replaced with the updated value in source,
+	// while a value that has been changed in target is kept.
+	// The snapshot is kept in the .envsync directory next to target and updated on every synchronization.
+	// It isn't kept for a target encrypted with age or by SOPS, which is merged as if it had never been synchronized.
+	// If there isn't any snapshot yet, or target is read from an io.Reader, the value in target is kept.
+	MergeThreeWay
+)
+
+var mergeNames = map[MergeStrategy]string{
+	MergeKeepTarget: "keep-target",
+	MergeKeepSource: "keep-source",
+	MergePrompt:     "prompt",
+	MergeFail:       "fail",
+	MergeThreeWay:   "three-way",
+}
+
+// String returns the name of the merge strategy.
+func (m MergeStrategy) String() string {
+	if name, ok := mergeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("MergeStrategy(%d)", int(m))
+}
+
+// ParseMergeStrategy returns the merge strategy with the given name.
+// Valid names are "keep-target", "keep-source", "prompt", "fail", and "three-way".
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	for m, n := range mergeNames {
+		if n == name {
+			return m, nil
+		}
+	}
+	return MergeKeepTarget, fmt.Errorf("unknown merge strategy: %s", name)
+}
+
+// resolveConflicts returns the key-values in sMap that take over the value in tMap following the merge strategy,
+// and records the ones given another value than in source to edited.
+// base is the source env last synchronized into target, used by MergeThreeWay.
+func (s *Syncer) resolveConflicts(sMap, tMap, base, edited map[string]string) (map[string]string, error) {
+	strategy := s.Merge
+	if s.Overwrite {
+		strategy = MergeKeepSource
+	}
+
+	overwritten := make(map[string]string)
+	switch strategy {
+	case MergeKeepSource:
+		overwritten = s.changedEnv(sMap, tMap)
+	case MergeThreeWay:
+		for k, v := range s.changedEnv(sMap, tMap) {
+			if bv, found := base[k]; found && bv == tMap[k] {
+				overwritten[k] = v
+			}
+		}
+	case MergeFail:
+		if conflicts := s.changedEnv(sMap, tMap); len(conflicts) > 0 {
+			return nil, errors.Wrap(ErrConflict, strings.Join(sortedKeys(conflicts), ", "))
+		}
+	case MergePrompt:
+		if s.DryRun {
+			break
+		}
+		if s.Prompter == nil {
+			return nil, errors.New("merge strategy prompt requires a Prompter")
+		}
+		conflicts := s.changedEnv(sMap, tMap)
+		for _, k := range sortedKeys(conflicts) {
+			v, skip, err := s.Prompter.Prompt(k, conflicts[k])
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("couldn't prompt for %s", k))
+			}
+			if skip || v == tMap[k] {
+				continue
+			}
+			overwritten[k] = v
+			if v != conflicts[k] {
+				edited[k] = v
+			}
+		}
+	}
+	return overwritten, nil
+}
@@ -0,0 +1,134 @@
+package envsync_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Merge(t *testing.T) {
+	src := "HOST=localhost\nPORT=8080\nTIMEOUT=10\n"
+	dst := "HOST=example.com\nPORT=80\nTIMEOUT=10\n"
+
+	cases := []struct {
+		merge       envsync.MergeStrategy
+		answers     string
+		prompts     string
+		overwritten []string
+		expected    string
+	}{
+		{envsync.MergeKeepTarget, "", "", []string{}, dst},
+		{envsync.MergeKeepSource, "", "", []string{"HOST", "PORT"}, src},
+		{envsync.MergePrompt, "-\n9090\n", "HOST [localhost]: PORT [8080]: ", []string{"PORT"}, "HOST=example.com\nPORT=9090\nTIMEOUT=10\n"},
+	}
+
+	for _, c := range cases {
+		var out bytes.Buffer
+		syncer := envsync.New(envsync.WithMerge(c.merge))
+		if c.merge == envsync.MergePrompt {
+			syncer.Prompter = envsync.NewPrompter(strings.NewReader(c.answers), &out)
+		}
+
+		var buf bytes.Buffer
+		res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+		assert.Nil(t, err, c.merge.String())
+		assert.Equal(t, c.overwritten, res.Overwritten, c.merge.String())
+		assert.Equal(t, c.expected, buf.String(), c.merge.String())
+		assert.Equal(t, c.prompts, out.String(), c.merge.String())
+	}
+}
+
+func TestSyncer_SyncReaders_MergeFail(t *testing.T) {
+	syncer := envsync.New(envsync.WithMerge(envsync.MergeFail))
+	syncer.OverwritePattern = "P*"
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader("HOST=localhost\nPORT=8080\n"), strings.NewReader("HOST=example.com\nPORT=80\n"), &buf)
+	assert.True(t, errors.Is(err, envsync.ErrConflict))
+	assert.Contains(t, err.Error(), "PORT")
+	assert.NotContains(t, err.Error(), "HOST")
+	assert.Empty(t, buf.String())
+
+	_, err = syncer.SyncReaders(strings.NewReader("HOST=localhost\n"), strings.NewReader("HOST=example.com\n"), &buf)
+	assert.Nil(t, err)
+}
+
+func TestSyncer_SyncReaders_MergeOverwrite(t *testing.T) {
+	syncer := envsync.New(envsync.WithMerge(envsync.MergeFail), envsync.WithOverwrite(""))
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader("PORT=8080\n"), strings.NewReader("PORT=80\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Overwritten)
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	for _, m := range []envsync.MergeStrategy{envsync.MergeKeepTarget, envsync.MergeKeepSource, envsync.MergePrompt, envsync.MergeFail, envsync.MergeThreeWay} {
+		parsed, err := envsync.ParseMergeStrategy(m.String())
+		assert.Nil(t, err)
+		assert.Equal(t, m, parsed)
+	}
+
+	_, err := envsync.ParseMergeStrategy("union")
+	assert.NotNil(t, err)
+	assert.Equal(t, "MergeStrategy(5)", envsync.MergeStrategy(5).String())
+}
+
+func TestSyncer_SyncContext_MergeThreeWay(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example": "HOST=localhost\nPORT=8080\n",
+		".env":         "HOST=localhost\nPORT=8080\n",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, ".env.example"), filepath.Join(dir, ".env")
+	syncer := envsync.New(envsync.WithMerge(envsync.MergeThreeWay))
+
+	// without a snapshot the target is kept
+	ioutil.WriteFile(target, []byte("HOST=example.com\nPORT=8080\n"), 0644)
+	res, err := syncer.SyncContext(context.Background(), source, target)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Overwritten)
+	info, err := os.Stat(filepath.Join(dir, ".envsync", ".env.base"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dir, ".envsync"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	ioutil.WriteFile(source, []byte("HOST=127.0.0.1\nPORT=9090\n"), 0644)
+	res, err = syncer.SyncContext(context.Background(), source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Overwritten)
+
+	content, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "HOST=example.com\nPORT=9090\n", string(content))
+}
+
+func TestSyncer_SyncContext_MergeThreeWayExpand(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example": "HOST=localhost\nURL=http://${HOST}:8080\n",
+		".env":         "HOST=localhost\nURL=http://localhost:8080\n",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, ".env.example"), filepath.Join(dir, ".env")
+	syncer := envsync.New(envsync.WithMerge(envsync.MergeThreeWay), envsync.WithExpand())
+
+	res, err := syncer.SyncContext(context.Background(), source, target)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Overwritten)
+
+	// the snapshot holds the expanded value, the one target is compared with
+	ioutil.WriteFile(source, []byte("HOST=localhost\nURL=http://${HOST}:9090\n"), 0644)
+	res, err = syncer.SyncContext(context.Background(), source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"URL"}, res.Overwritten)
+}
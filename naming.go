@@ -0,0 +1,99 @@
+package envsync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// UpperSnakeCase is the naming convention NamingRule enforces when it has no Pattern, e.g. DATABASE_URL.
+const UpperSnakeCase = `^[A-Z][A-Z0-9_]*$`
+
+// NamingRule flags keys that don't follow a naming convention, e.g. database_url or db-host.
+// It fixes them by renaming them to UPPER_SNAKE_CASE.
+type NamingRule struct {
+	// Pattern is a regular expression every key must match.
+	// If it is empty, keys must be UPPER_SNAKE_CASE.
+	Pattern string
+}
+
+// Name returns naming.
+func (r NamingRule) Name() string {
+	return "naming"
+}
+
+// Check returns a violation for each key in target that doesn't match Pattern.
+// An invalid Pattern is reported as a violation of the first key.
+func (r NamingRule) Check(target, source *Document) []Violation {
+	re, err := r.regexp()
+	if err != nil {
+		keys := target.Keys()
+		if len(keys) == 0 {
+			return nil
+		}
+		return []Violation{{Key: keys[0], Line: target.lineNumber(keys[0]), Message: fmt.Sprintf("invalid naming pattern %q", r.Pattern)}}
+	}
+
+	var violations []Violation
+	for _, k := range target.Keys() {
+		if re.MatchString(k) {
+			continue
+		}
+		msg := fmt.Sprintf("doesn't match %q", re)
+		if fixed := upperSnakeCase(k); fixed != k && re.MatchString(fixed) {
+			msg = fmt.Sprintf("%s, use %s", msg, fixed)
+		}
+		violations = append(violations, Violation{Key: k, Line: target.lineNumber(k), Message: msg})
+	}
+	return violations
+}
+
+// Fix renames each key in doc that doesn't match Pattern to UPPER_SNAKE_CASE, e.g. db-host to DB_HOST,
+// and returns the keys it renames, sorted.
+// A key is left as it is if its new name doesn't match Pattern either, or is already declared.
+func (r NamingRule) Fix(doc *Document) []string {
+	re, err := r.regexp()
+	if err != nil {
+		return nil
+	}
+
+	declared := doc.Env()
+	renamed := make(map[string]string)
+	for _, k := range doc.Keys() {
+		fixed := upperSnakeCase(k)
+		if re.MatchString(k) || !re.MatchString(fixed) {
+			continue
+		}
+		if _, found := declared[fixed]; found {
+			continue
+		}
+		doc.Rename(k, fixed)
+		declared[fixed] = ""
+		renamed[k] = fixed
+	}
+	return sortedKeys(renamed)
+}
+
+func (r NamingRule) regexp() (*regexp.Regexp, error) {
+	if r.Pattern == "" {
+		return regexp.Compile(UpperSnakeCase)
+	}
+	return regexp.Compile(r.Pattern)
+}
+
+// upperSnakeCase returns key in UPPER_SNAKE_CASE, e.g. DB_HOST for db-host, db.host, or dbHost.
+func upperSnakeCase(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '.' || r == ' ':
+			r = '_'
+		case i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]):
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package envsync_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint_NamingRule(t *testing.T) {
+	doc, err := envsync.ParseDocument(strings.NewReader("DATABASE_URL=postgres://localhost\ndb-host=localhost\napiKey=abc\n_PRIVATE=1\n"))
+	assert.Nil(t, err)
+
+	violations := envsync.Lint(doc, nil, envsync.NamingRule{})
+	assert.Len(t, violations, 3)
+	assert.Equal(t, "db-host", violations[0].Key)
+	assert.Equal(t, 2, violations[0].Line)
+	assert.Equal(t, `doesn't match "^[A-Z][A-Z0-9_]*$", use DB_HOST`, violations[0].Message)
+	assert.Equal(t, "apiKey", violations[1].Key)
+	assert.Contains(t, violations[1].Message, "use API_KEY")
+	assert.Equal(t, `doesn't match "^[A-Z][A-Z0-9_]*$"`, violations[2].Message)
+
+	violations = envsync.Lint(doc, nil, envsync.NamingRule{Pattern: `^[A-Za-z_-]+$`})
+	assert.Len(t, violations, 0)
+
+	violations = envsync.Lint(doc, nil, envsync.NamingRule{Pattern: `[`})
+	assert.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Message, "invalid naming pattern")
+}
+
+func TestNamingRule_Fix(t *testing.T) {
+	doc, err := envsync.ParseDocument(strings.NewReader("# the host\ndb-host=localhost\ndb.port=5432\nDB_NAME=app\ndb_name=other\n"))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"db-host", "db.port"}, envsync.NamingRule{}.Fix(doc))
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT", "DB_NAME", "db_name"}, doc.Keys())
+	assert.Len(t, envsync.Lint(doc, nil, envsync.NamingRule{}), 1)
+}
+
+func TestSyncer_Format(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envsync")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, ".env")
+	ioutil.WriteFile(target, []byte("# the host\ndb-host=localhost\nPORT=8080\n"), 0644)
+
+	syncer := envsync.New(envsync.WithDryRun())
+	res, err := syncer.Format(context.Background(), target, envsync.PlaceholderRule{}, envsync.NamingRule{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db-host"}, res.Fixed)
+	assert.True(t, res.Changed)
+	b, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "# the host\ndb-host=localhost\nPORT=8080\n", string(b))
+
+	syncer = envsync.New()
+	res, err = syncer.Format(context.Background(), target, envsync.NamingRule{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db-host"}, res.Fixed)
+	b, _ = ioutil.ReadFile(target)
+	assert.Equal(t, "# the host\nDB_HOST=localhost\nPORT=8080\n", string(b))
+}
@@ -0,0 +1,79 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// objectStore is where an objectTarget keeps its env file, e.g. an object of an S3 bucket.
+type objectStore interface {
+	// get returns the content of the object and its version, e.g. its ETag,
+	// or a nil content if the object doesn't exist.
+	get(ctx context.Context) ([]byte, string, error)
+
+	// put replaces the content of the object if its version is still version, or creates it if version is empty
+	// and it doesn't exist, and returns its new version. It returns errObjectChanged otherwise.
+	put(ctx context.Context, b []byte, version string) (string, error)
+}
+
+// errObjectChanged is returned by objectStore.put when the object changed since it was read.
+var errObjectChanged = errors.New("it changed since it was read")
+
+// objectTarget is an env file kept as an object in a bucket as a Target, e.g. s3://my-bucket/myapp/.env,
+// read and written with the codec of the extension of its key, or the one set in Codecs, like a file.
+// A missing object is empty, and created once the target is stored.
+//
+// The object is written only if it hasn't changed since it was loaded, so concurrent updates aren't overwritten,
+// unless it already holds what is written.
+type objectTarget struct {
+	fileFormat
+	name    string
+	store   objectStore
+	loaded  bool
+	version string
+}
+
+func (t *objectTarget) Name() string {
+	return t.name
+}
+
+func (t *objectTarget) Load(ctx context.Context) (*Document, error) {
+	b, version, err := t.store.get(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s", t.name))
+	}
+	t.loaded, t.version = true, version
+	if b == nil {
+		return &Document{codec: t.codec}, nil
+	}
+	return decodeDocument(bytes.NewReader(b), t.codec, t.opts)
+}
+
+// Store writes doc to the object, unless it has changed since the target is loaded.
+func (t *objectTarget) Store(ctx context.Context, doc *Document) error {
+	if !t.loaded {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	var b bytes.Buffer
+	if err := doc.write(&b); err != nil {
+		return err
+	}
+	version, err := t.store.put(ctx, b.Bytes(), t.version)
+	if err == errObjectChanged {
+		// a store retried after its answer was lost finds the object it has written
+		if current, v, getErr := t.store.get(ctx); getErr == nil && current != nil && bytes.Equal(current, b.Bytes()) {
+			version, err = v, nil
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("couldn't write %s", t.name))
+	}
+	t.version = version
+	return nil
+}
@@ -0,0 +1,255 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// opItem is a 1Password item as it is encoded in JSON by the Connect API and the op CLI.
+// It is kept as a map so that what envsync doesn't know of, e.g. sections and URLs, is written back as it is.
+type opItem map[string]interface{}
+
+// opFields returns the fields of the item.
+func (it opItem) opFields() []interface{} {
+	fields, _ := it["fields"].([]interface{})
+	return fields
+}
+
+// env returns the values of the fields of the item by label, except for its notes.
+func (it opItem) env() Env {
+	env := Env{}
+	for _, f := range it.opFields() {
+		field, _ := f.(map[string]interface{})
+		label, _ := field["label"].(string)
+		if label == "" || field["purpose"] == "NOTES" {
+			continue
+		}
+		value, _ := field["value"].(string)
+		env[label] = value
+	}
+	return env
+}
+
+// setEnv sets the fields of the item to env: fields are changed in place, removed if their label isn't in env,
+// and added as concealed fields if env has a label no field has.
+func (it opItem) setEnv(env Env) {
+	set := make(map[string]bool)
+	var fields []interface{}
+	for _, f := range it.opFields() {
+		field, _ := f.(map[string]interface{})
+		label, _ := field["label"].(string)
+		if label == "" || field["purpose"] == "NOTES" {
+			fields = append(fields, f)
+			continue
+		}
+		v, ok := env[label]
+		if !ok || set[label] {
+			continue
+		}
+		field["value"] = v
+		set[label] = true
+		fields = append(fields, field)
+	}
+	for _, k := range env.Keys() {
+		if !set[k] {
+			fields = append(fields, map[string]interface{}{"label": k, "value": env[k], "type": "CONCEALED"})
+		}
+	}
+	it["fields"] = fields
+}
+
+// opAPI reads and writes an item of 1Password, through the Connect API or the op CLI.
+type opAPI interface {
+	// get returns the item, or nil if it doesn't exist.
+	get(ctx context.Context, vault, title string) (opItem, error)
+
+	// put creates the item if it has no id, or replaces it.
+	put(ctx context.Context, vault string, item opItem) error
+}
+
+// onePasswordTarget is an item of a 1Password vault as a Target, e.g. op://Private/myapp for the item myapp in the vault Private:
+// each key is a field of the item, labelled with the key.
+// Fields are added as concealed fields. The item is created as a secure note if it doesn't exist.
+//
+// The item is read and written through 1Password Connect if OP_CONNECT_HOST and OP_CONNECT_TOKEN are set,
+// or else through the op CLI, signed in or given a service account token in OP_SERVICE_ACCOUNT_TOKEN.
+type onePasswordTarget struct {
+	name  string
+	vault string
+	title string
+	api   opAPI
+	item  opItem
+}
+
+func openOnePassword(u *url.URL) (Target, error) {
+	title := strings.Trim(u.Path, "/")
+	if u.Host == "" || title == "" || strings.Contains(title, "/") {
+		return nil, fmt.Errorf("expected op://vault/item, got %s", u)
+	}
+
+	var api opAPI = opCLI{}
+	if host, token := os.Getenv("OP_CONNECT_HOST"), os.Getenv("OP_CONNECT_TOKEN"); host != "" && token != "" {
+		api = &opConnect{client: &restClient{
+			base: host,
+			auth: bearer(func(context.Context) (string, error) {
+				return token, nil
+			}),
+		}}
+	}
+	return &onePasswordTarget{name: u.String(), vault: u.Host, title: title, api: api}, nil
+}
+
+func (t *onePasswordTarget) Name() string {
+	return t.name
+}
+
+func (t *onePasswordTarget) Load(ctx context.Context) (*Document, error) {
+	item, err := t.api.get(ctx, t.vault, t.title)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s", t.name))
+	}
+	if item == nil {
+		item = opItem{"title": t.title, "category": "SECURE_NOTE"}
+	}
+	t.item = item
+	return envDocument(item.env()), nil
+}
+
+// Store writes the item with the fields of doc, if they differ from the ones loaded.
+func (t *onePasswordTarget) Store(ctx context.Context, doc *Document) error {
+	if t.item == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+	env := doc.Env()
+	if _, exists := t.item["id"]; exists && reflect.DeepEqual(env, t.item.env()) {
+		return nil
+	}
+	t.item.setEnv(env)
+	return errors.Wrap(t.api.put(ctx, t.vault, t.item), fmt.Sprintf("couldn't write %s", t.name))
+}
+
+// opConnect is the API of a 1Password Connect server.
+type opConnect struct {
+	client *restClient
+	// vaultID is the ID of the vault, once it is found.
+	vaultID string
+}
+
+// find returns the id of the first of the resources at path, e.g. /v1/vaults, whose attr is value, or "" if there is none.
+func (c *opConnect) find(ctx context.Context, path, attr, value string) (string, error) {
+	var found []struct {
+		ID string `json:"id"`
+	}
+	filter := url.Values{"filter": {fmt.Sprintf("%s eq %q", attr, value)}}
+	if err := c.client.do(ctx, http.MethodGet, path+"?"+filter.Encode(), nil, &found); err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0].ID, nil
+}
+
+func (c *opConnect) vault(ctx context.Context, name string) (string, error) {
+	if c.vaultID != "" {
+		return c.vaultID, nil
+	}
+	id, err := c.find(ctx, "/v1/vaults", "name", name)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("no vault %s", name)
+	}
+	c.vaultID = id
+	return id, nil
+}
+
+func (c *opConnect) get(ctx context.Context, vault, title string) (opItem, error) {
+	vaultID, err := c.vault(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.find(ctx, "/v1/vaults/"+vaultID+"/items", "title", title)
+	if err != nil || id == "" {
+		return nil, err
+	}
+	var item opItem
+	err = c.client.do(ctx, http.MethodGet, "/v1/vaults/"+vaultID+"/items/"+id, nil, &item)
+	return item, err
+}
+
+func (c *opConnect) put(ctx context.Context, vault string, item opItem) error {
+	vaultID, err := c.vault(ctx, vault)
+	if err != nil {
+		return err
+	}
+	item["vault"] = map[string]string{"id": vaultID}
+	id, exists := item["id"].(string)
+	if !exists {
+		return c.client.do(ctx, http.MethodPost, "/v1/vaults/"+vaultID+"/items", item, &item)
+	}
+	return c.client.do(ctx, http.MethodPut, "/v1/vaults/"+vaultID+"/items/"+id, item, &item)
+}
+
+// opCLI is the op CLI of 1Password.
+type opCLI struct{}
+
+func (opCLI) get(ctx context.Context, vault, title string) (opItem, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "op", "item", "get", title, "--vault", vault, "--format", "json")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "isn't an item") {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, msg)
+	}
+	var item opItem
+	err = json.Unmarshal(out, &item)
+	return item, err
+}
+
+// put writes item to a template file read by op, rather than giving the values on the command line where other processes see them.
+func (opCLI) put(ctx context.Context, vault string, item opItem) error {
+	file, err := ioutil.TempFile("", "envsync-op-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	err = json.NewEncoder(file).Encode(item)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	args := []string{"item", "create", "--vault", vault, "--template", file.Name(), "--format", "json"}
+	if id, ok := item["id"].(string); ok {
+		args = []string{"item", "edit", id, "--vault", vault, "--template", file.Name(), "--format", "json"}
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "op", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return json.Unmarshal(out, &item)
+}
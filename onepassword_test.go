@@ -0,0 +1,119 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConnect serves the items API of 1Password Connect for the vault Private from memory.
+type fakeConnect struct {
+	mu    sync.Mutex
+	items map[string]map[string]interface{}
+}
+
+func (f *fakeConnect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer connect-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	filter := r.URL.Query().Get("filter")
+	switch {
+	case r.URL.Path == "/v1/vaults":
+		if filter == `name eq "Private"` {
+			w.Write([]byte(`[{"id": "v1"}]`))
+		} else {
+			w.Write([]byte(`[]`))
+		}
+	case r.URL.Path == "/v1/vaults/v1/items" && r.Method == http.MethodGet:
+		found := []map[string]string{}
+		for id, item := range f.items {
+			if filter == `title eq "`+item["title"].(string)+`"` {
+				found = append(found, map[string]string{"id": id})
+			}
+		}
+		json.NewEncoder(w).Encode(found)
+	case r.URL.Path == "/v1/vaults/v1/items" && r.Method == http.MethodPost:
+		var item map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&item)
+		item["id"] = "i" + item["title"].(string)
+		f.items[item["id"].(string)] = item
+		json.NewEncoder(w).Encode(item)
+	case strings.HasPrefix(r.URL.Path, "/v1/vaults/v1/items/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/vaults/v1/items/")
+		if _, ok := f.items[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPut {
+			var item map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&item)
+			f.items[id] = item
+		}
+		json.NewEncoder(w).Encode(f.items[id])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// fields returns the values of the fields of the item titled title by label.
+func (f *fakeConnect) fields(title string) map[string]string {
+	fields := make(map[string]string)
+	for _, item := range f.items {
+		if item["title"] != title {
+			continue
+		}
+		for _, v := range item["fields"].([]interface{}) {
+			field := v.(map[string]interface{})
+			fields[field["label"].(string)] = field["value"].(string)
+		}
+	}
+	return fields
+}
+
+func TestSyncer_Sync_OnePassword(t *testing.T) {
+	connect := &fakeConnect{items: map[string]map[string]interface{}{
+		"i1": {"id": "i1", "title": "myapp", "category": "LOGIN", "fields": []interface{}{
+			map[string]interface{}{"id": "notesPlain", "label": "notesPlain", "purpose": "NOTES", "value": "kept"},
+			map[string]interface{}{"id": "f1", "label": "DB_HOST", "type": "STRING", "value": "db.example.com"},
+			map[string]interface{}{"id": "f2", "label": "OLD", "type": "CONCEALED", "value": "x"},
+		}},
+	}}
+	server := httptest.NewServer(connect)
+	defer server.Close()
+	defer setenv(map[string]string{"OP_CONNECT_HOST": server.URL, "OP_CONNECT_TOKEN": "connect-token"})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "op://Private/myapp")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{"notesPlain": "kept", "DB_HOST": "db.example.com", "DB_PORT": "5432"}, connect.fields("myapp"))
+	assert.Equal(t, "LOGIN", connect.items["i1"]["category"])
+
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "op://Private/other")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_HOST", "DB_PORT"}, res.Added)
+	assert.Equal(t, map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"}, connect.fields("other"))
+	assert.Equal(t, "SECURE_NOTE", connect.items["iother"]["category"])
+
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "op://Shared/myapp")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no vault Shared")
+}
@@ -0,0 +1,240 @@
+package envsync
+
+import (
+	"text/template"
+	"time"
+)
+
+// Option configures a Syncer created by New.
+type Option func(*Syncer)
+
+// New returns a Syncer configured by opts.
+// New() is the same as &Syncer{}.
+func New(opts ...Option) *Syncer {
+	s := &Syncer{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithDryRun makes the Syncer report changes without writing target.
+func WithDryRun() Option {
+	return func(s *Syncer) {
+		s.DryRun = true
+	}
+}
+
+// WithPrune makes the Syncer remove keys in target that don't exist in source.
+func WithPrune() Option {
+	return func(s *Syncer) {
+		s.Prune = true
+	}
+}
+
+// WithOverwrite makes the Syncer replace target values with source values for keys matching pattern.
+// An empty pattern matches every key.
+func WithOverwrite(pattern string) Option {
+	return func(s *Syncer) {
+		s.Overwrite = true
+		s.OverwritePattern = pattern
+	}
+}
+
+// WithOrder sets how the key-values added to target are placed.
+func WithOrder(o Order) Option {
+	return func(s *Syncer) {
+		s.Order = o
+	}
+}
+
+// WithBackup makes the Syncer copy target to a new backup before modifying it.
+func WithBackup() Option {
+	return func(s *Syncer) {
+		s.Backup = true
+	}
+}
+
+// WithBackupDir makes the Syncer copy target to a new backup in dir before modifying it.
+func WithBackupDir(dir string) Option {
+	return func(s *Syncer) {
+		s.Backup = true
+		s.BackupDir = dir
+	}
+}
+
+// WithInlineComments makes whitespace followed by '#' in an unquoted value start a comment.
+func WithInlineComments() Option {
+	return func(s *Syncer) {
+		s.InlineComments = true
+	}
+}
+
+// WithDuplicates makes the Syncer handle keys declared more than once in a file following d.
+func WithDuplicates(d Duplicate) Option {
+	return func(s *Syncer) {
+		s.Duplicates = d
+	}
+}
+
+// WithRename makes the Syncer rename the deprecated key old to key in target.
+func WithRename(old, key string) Option {
+	return func(s *Syncer) {
+		if s.Renames == nil {
+			s.Renames = make(map[string]string)
+		}
+		s.Renames[old] = key
+	}
+}
+
+// WithPatch makes the Syncer report the changes to target as a unified diff in SyncResult.Patch.
+func WithPatch() Option {
+	return func(s *Syncer) {
+		s.Patch = true
+	}
+}
+
+// WithManaged makes the Syncer synchronize only the section of target between the # envsync:start and # envsync:end comments.
+func WithManaged() Option {
+	return func(s *Syncer) {
+		s.Managed = true
+	}
+}
+
+// WithBanner makes the Syncer keep header at the top of target and footer at the bottom.
+// Either may be empty.
+func WithBanner(header, footer string) Option {
+	return func(s *Syncer) {
+		s.Header = header
+		s.Footer = footer
+	}
+}
+
+// WithStyle makes the Syncer format env files following st.
+func WithStyle(st Style) Option {
+	return func(s *Syncer) {
+		s.Style = st
+	}
+}
+
+// WithMask makes the Syncer hide the values of keys matching patterns instead of DefaultMaskPatterns.
+func WithMask(patterns ...string) Option {
+	return func(s *Syncer) {
+		s.Mask = patterns
+	}
+}
+
+// WithShowSecrets makes the Syncer show every value as it is, without masking.
+func WithShowSecrets() Option {
+	return func(s *Syncer) {
+		s.ShowSecrets = true
+	}
+}
+
+// WithLint makes the Syncer check target with rules once it is synchronized.
+func WithLint(rules ...LintRule) Option {
+	return func(s *Syncer) {
+		s.Lint = append(s.Lint, rules...)
+	}
+}
+
+// WithLogger makes the Syncer report the keys it adds, removes, or overwrites to l.
+func WithLogger(l Logger) Option {
+	return func(s *Syncer) {
+		s.Logger = l
+	}
+}
+
+// WithInclude limits the Syncer to keys matching any of patterns.
+func WithInclude(patterns ...string) Option {
+	return func(s *Syncer) {
+		s.Include = append(s.Include, patterns...)
+	}
+}
+
+// WithExclude makes the Syncer leave keys matching any of patterns untouched.
+func WithExclude(patterns ...string) Option {
+	return func(s *Syncer) {
+		s.Exclude = append(s.Exclude, patterns...)
+	}
+}
+
+// WithIgnore makes the Syncer leave the keys and files listed by ig untouched.
+func WithIgnore(ig *Ignore) Option {
+	return func(s *Syncer) {
+		s.Ignore = ig
+	}
+}
+
+// WithCodec makes the Syncer read and write the file at name with codec, whatever its extension.
+func WithCodec(name string, codec Codec) Option {
+	return func(s *Syncer) {
+		if s.Codecs == nil {
+			s.Codecs = make(map[string]Codec)
+		}
+		s.Codecs[name] = codec
+	}
+}
+
+// WithPrompter makes the Syncer ask p for the value of each key-value added to target.
+func WithPrompter(p Prompter) Option {
+	return func(s *Syncer) {
+		s.Prompter = p
+	}
+}
+
+// WithPlaceholder makes the Syncer handle key-values added with an empty value following p.
+func WithPlaceholder(p Placeholder) Option {
+	return func(s *Syncer) {
+		s.Placeholder = p
+	}
+}
+
+// WithStrict makes the Syncer report keys in target that aren't in source following st.
+func WithStrict(st Strict) Option {
+	return func(s *Syncer) {
+		s.Strict = st
+	}
+}
+
+// WithMerge makes the Syncer handle keys with different values in source and target following m.
+func WithMerge(m MergeStrategy) Option {
+	return func(s *Syncer) {
+		s.Merge = m
+	}
+}
+
+// WithExpand makes the Syncer expand variable references in values before comparing them.
+func WithExpand() Option {
+	return func(s *Syncer) {
+		s.Expand = true
+	}
+}
+
+// WithTemplate makes the Syncer evaluate templates in values added to target,
+// with funcs available in addition to the default functions.
+func WithTemplate(funcs template.FuncMap) Option {
+	return func(s *Syncer) {
+		s.Template = true
+		s.Funcs = funcs
+	}
+}
+
+// WithAge makes the Syncer write target encrypted with age for recipients,
+// and decrypt files encrypted with age with the identities in the files of identities.
+func WithAge(recipients, identities []string) Option {
+	return func(s *Syncer) {
+		s.AgeRecipients = recipients
+		s.AgeIdentities = identities
+	}
+}
+
+// WithRetry makes the Syncer try loading or storing a remote source or target again up to retries times
+// on transient errors, waiting backoff before the first retry, and bounds each attempt to timeout if it isn't zero.
+func WithRetry(retries int, backoff, timeout time.Duration) Option {
+	return func(s *Syncer) {
+		s.Retries = retries
+		s.RetryBackoff = backoff
+		s.Timeout = timeout
+	}
+}
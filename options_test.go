@@ -0,0 +1,68 @@
+package envsync_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, &envsync.Syncer{}, envsync.New())
+
+	syncer := envsync.New(
+		envsync.WithDryRun(),
+		envsync.WithPrune(),
+		envsync.WithOverwrite("FEATURE_*"),
+		envsync.WithOrder(envsync.OrderSource),
+		envsync.WithBackupDir("backups"),
+		envsync.WithInlineComments(),
+	)
+	expected := &envsync.Syncer{
+		DryRun:           true,
+		Prune:            true,
+		Overwrite:        true,
+		OverwritePattern: "FEATURE_*",
+		Order:            envsync.OrderSource,
+		Backup:           true,
+		BackupDir:        "backups",
+		InlineComments:   true,
+	}
+	assert.Equal(t, expected, syncer)
+	assert.True(t, envsync.New(envsync.WithBackup()).Backup)
+}
+
+func TestSyncer_Sync_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	syncer := envsync.New(envsync.WithLogger(log.New(&buf, "", 0)), envsync.WithPrune())
+
+	_, err := syncer.SyncReaders(strings.NewReader("FOO=bar\n"), strings.NewReader("BAZ=qux\n"), ioutil.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, "New env added: FOO\nEnv removed: BAZ\n", buf.String())
+}
+
+func TestSyncer_Sync_BackupDir(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "envsync")
+	defer os.RemoveAll(dir)
+
+	result := filepath.Join(dir, ".env")
+	ioutil.WriteFile(result, []byte("HOME=production\n"), 0644)
+
+	syncer := envsync.New(envsync.WithBackupDir(filepath.Join(dir, "backups")))
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	backups, err := syncer.History(result)
+	assert.Nil(t, err)
+	assert.Len(t, backups, 1)
+	assert.Equal(t, filepath.Join(dir, "backups"), filepath.Dir(backups[0].Path))
+
+	backup, _ := ioutil.ReadFile(backups[0].Path)
+	assert.Equal(t, "HOME=production\n", string(backup))
+}
@@ -0,0 +1,85 @@
+package envsync
+
+import "fmt"
+
+// Order describes how the key-values added to target are placed.
+// Lines that already exist in target are never moved.
+type Order int
+
+const (
+	// OrderAlphabetical appends the added key-values sorted by key.
+	// This is the default.
+	OrderAlphabetical Order = iota
+
+	// OrderSource places each added key-value right after the key that precedes it in source,
+	// so target follows the grouping of source.
+	// If none of the preceding keys is in target, it is placed before the key that follows it in source,
+	// or appended when there isn't any.
+	OrderSource
+
+	// OrderNone appends the added key-values in the order they are found in source.
+	OrderNone
+)
+
+var orderNames = map[Order]string{
+	OrderAlphabetical: "alphabetical",
+	OrderSource:       "source",
+	OrderNone:         "none",
+}
+
+// String returns the name of the order.
+func (o Order) String() string {
+	if name, ok := orderNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Order(%d)", int(o))
+}
+
+// ParseOrder returns the order with the given name.
+// Valid names are "alphabetical", "source", and "none".
+func ParseOrder(name string) (Order, error) {
+	for o, n := range orderNames {
+		if n == name {
+			return o, nil
+		}
+	}
+	return OrderAlphabetical, fmt.Errorf("unknown order: %s", name)
+}
+
+// addEnv copies the lines in src holding any key in env to doc following the order.
+func (o Order) addEnv(doc, src *Document, env map[string]string) {
+	keys := src.Keys()
+	switch o {
+	case OrderSource:
+		for i, k := range keys {
+			if _, found := env[k]; found {
+				doc.insert(src.line(k), neighbour(doc, keys, i))
+			}
+		}
+	case OrderNone:
+		for _, k := range keys {
+			if _, found := env[k]; found {
+				doc.put(src.line(k))
+			}
+		}
+	default:
+		for _, k := range sortedKeys(env) {
+			doc.put(src.line(k))
+		}
+	}
+}
+
+// neighbour returns the index in doc where keys[i] should be inserted.
+func neighbour(doc *Document, keys []string, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if idx := doc.index(keys[j]); idx >= 0 {
+			return idx + 1
+		}
+	}
+	for j := i + 1; j < len(keys); j++ {
+		if idx := doc.index(keys[j]); idx >= 0 {
+			return idx
+		}
+	}
+	return len(doc.lines)
+}
@@ -0,0 +1,59 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrder(t *testing.T) {
+	for _, o := range []envsync.Order{envsync.OrderAlphabetical, envsync.OrderSource, envsync.OrderNone} {
+		parsed, err := envsync.ParseOrder(o.String())
+		assert.Nil(t, err)
+		assert.Equal(t, o, parsed)
+	}
+
+	_, err := envsync.ParseOrder("random")
+	assert.NotNil(t, err)
+}
+
+func TestSyncer_Sync_Order(t *testing.T) {
+	tests := []struct {
+		order    envsync.Order
+		expected string
+	}{
+		{
+			order: envsync.OrderSource,
+			expected: "FOO=bar\nABC=def=ghi!xyz\nHOME=production\n" +
+				"ROULETTE_HOST=http://localhost:8989\nROULETTE_BASIC_USER=roulette\nROULETTE_BASIC_PASSWORD=roulette\n# end\n",
+		},
+		{
+			order: envsync.OrderNone,
+			expected: "HOME=production\n# end\nFOO=bar\nABC=def=ghi!xyz\n" +
+				"ROULETTE_HOST=http://localhost:8989\nROULETTE_BASIC_USER=roulette\nROULETTE_BASIC_PASSWORD=roulette\n",
+		},
+	}
+
+	for _, tt := range tests {
+		syncer := &envsync.Syncer{Order: tt.order}
+
+		result := "testdata/env.result.order"
+		exec.Command("touch", result).Run()
+
+		file, _ := os.OpenFile(result, os.O_APPEND|os.O_RDWR, os.ModeAppend)
+		file.WriteString("HOME=production\n# end\n")
+		file.Close()
+
+		err := syncer.Sync("testdata/env.success", result)
+		assert.Nil(t, err)
+
+		content, _ := ioutil.ReadFile(result)
+		assert.Equal(t, tt.expected, string(content), tt.order.String())
+
+		exec.Command("rm", "-rf", result).Run()
+	}
+}
@@ -0,0 +1,165 @@
+package envsync
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// patchContext is the number of unchanged lines shown around each change of a patch.
+const patchContext = 3
+
+// UnifiedDiff returns the changes from before to after as a unified diff of the file name, as diff -u prints it,
+// or an empty string if they are the same.
+func UnifiedDiff(name, before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+	return unifiedDiff(name, a, b, a, b)
+}
+
+// unifiedDiff returns the changes from the lines a to b as UnifiedDiff does,
+// showing each of them as the line at the same index in aShown or bShown.
+func unifiedDiff(name string, a, b, aShown, bShown []string) string {
+	ops := diffLines(a, b)
+	for i, op := range ops {
+		if op.kind == '+' {
+			ops[i].text = bShown[op.bLine-1]
+		} else {
+			ops[i].text = aShown[op.aLine-1]
+		}
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(ops); {
+		// find the next change and the hunk it starts
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - patchContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			// a hunk ends after more unchanged lines than two contexts
+			same := end
+			for same < len(ops) && ops[same].kind == ' ' {
+				same++
+			}
+			if same == len(ops) || same-end > 2*patchContext {
+				if same-end > patchContext {
+					same = end + patchContext
+				}
+				end = same
+				break
+			}
+			end = same
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- a/%s\n+++ b/%s\n", name, name)
+		}
+		writeHunk(&buf, ops[start:end])
+		i = end
+	}
+	return buf.String()
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-'), or added ('+').
+// aLine and bLine are the numbers of the line before and after, starting at 1,
+// or of the line preceding it if it isn't there.
+type diffOp struct {
+	kind         byte
+	text         string
+	aLine, bLine int
+}
+
+// diffLines returns the shortest edit from a to b, using the longest common subsequence of their lines.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', text: a[i], aLine: i + 1, bLine: j + 1})
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', text: a[i], aLine: i + 1, bLine: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', text: b[j], aLine: i, bLine: j + 1})
+			j++
+		}
+	}
+	return ops
+}
+
+// writeHunk writes ops as a hunk, starting with its @@ -l,s +l,s @@ header.
+func writeHunk(buf *bytes.Buffer, ops []diffOp) {
+	aStart, bStart, aCount, bCount := 0, 0, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			if aCount == 0 {
+				aStart = op.aLine
+			}
+			aCount++
+		}
+		if op.kind != '-' {
+			if bCount == 0 {
+				bStart = op.bLine
+			}
+			bCount++
+		}
+	}
+	// an empty range starts at the line preceding it
+	if aCount == 0 {
+		aStart = ops[0].aLine
+	}
+	if bCount == 0 {
+		bStart = ops[0].bLine
+	}
+
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, op := range ops {
+		fmt.Fprintf(buf, "%c%s\n", op.kind, op.text)
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines returns the lines of s without their line break.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,51 @@
+package envsync_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "A=1\nB=2\nC=3\nD=4\nE=5\nF=6\nG=7\nH=8\nI=9\nJ=10\nK=11\n"
+	after := "A=1\nB=two\nC=3\nD=4\nE=5\nF=6\nG=7\nH=8\nI=9\nJ=10\nK=11\nL=12\n"
+
+	assert.Equal(t, `--- a/.env
++++ b/.env
+@@ -1,5 +1,5 @@
+ A=1
+-B=2
++B=two
+ C=3
+ D=4
+ E=5
+@@ -9,3 +9,4 @@
+ I=9
+ J=10
+ K=11
++L=12
+`, envsync.UnifiedDiff(".env", before, after))
+
+	assert.Equal(t, "--- a/.env\n+++ b/.env\n@@ -0,0 +1,2 @@\n+A=1\n+B=2\n", envsync.UnifiedDiff(".env", "", "A=1\nB=2\n"))
+	assert.Equal(t, "--- a/.env\n+++ b/.env\n@@ -1 +0,0 @@\n-X=1\n", envsync.UnifiedDiff(".env", "X=1\n", ""))
+	assert.Empty(t, envsync.UnifiedDiff(".env", before, before))
+}
+
+func TestSyncer_SyncReaders_Patch(t *testing.T) {
+	src := "HOST=localhost\nPORT=8080\nDEBUG=false\n"
+	dst := "# local\nPORT=80\nLEGACY=1\n"
+
+	var buf bytes.Buffer
+	syncer := envsync.New(envsync.WithDryRun(), envsync.WithPrune(), envsync.WithPatch())
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "--- a/target\n+++ b/target\n@@ -1,3 +1,4 @@\n # local\n PORT=80\n-LEGACY=1\n+DEBUG=false\n+HOST=localhost\n", res.Patch)
+	assert.Empty(t, buf.String())
+
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(src), &buf)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Patch)
+}
@@ -0,0 +1,81 @@
+package envsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// placeholderMarker is the value PlaceholderMarker gives to key-values added with an empty value.
+const placeholderMarker = "<CHANGE_ME>"
+
+// Placeholder describes what happens to a key-value added to target when its value in source is empty.
+type Placeholder int
+
+const (
+	// PlaceholderEmpty adds the key-value with an empty value.
+	// This is the default.
+	PlaceholderEmpty Placeholder = iota
+
+	// PlaceholderMarker adds the key-value with the <CHANGE_ME> value, so it is easy to spot in target.
+	PlaceholderMarker
+
+	// PlaceholderPrompt asks Prompter for the value.
+	// Only key-values with an empty value are prompted for.
+	PlaceholderPrompt
+
+	// PlaceholderFail makes the synchronization fail with ErrEmptyValue.
+	PlaceholderFail
+)
+
+var placeholderNames = map[Placeholder]string{
+	PlaceholderEmpty:  "empty",
+	PlaceholderMarker: "marker",
+	PlaceholderPrompt: "prompt",
+	PlaceholderFail:   "fail",
+}
+
+// String returns the name of the placeholder.
+func (p Placeholder) String() string {
+	if name, ok := placeholderNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Placeholder(%d)", int(p))
+}
+
+// ParsePlaceholder returns the placeholder with the given name.
+// Valid names are "empty", "marker", "prompt", and "fail".
+func ParsePlaceholder(name string) (Placeholder, error) {
+	for p, n := range placeholderNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return PlaceholderEmpty, fmt.Errorf("unknown placeholder: %s", name)
+}
+
+// fill applies the placeholder to the key-values in added with an empty value,
+// recording the ones it changes to edited.
+func (p Placeholder) fill(added, edited map[string]string) error {
+	var empty []string
+	for _, k := range sortedKeys(added) {
+		if added[k] == "" {
+			empty = append(empty, k)
+		}
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+
+	switch p {
+	case PlaceholderMarker:
+		for _, k := range empty {
+			added[k] = placeholderMarker
+			edited[k] = placeholderMarker
+		}
+	case PlaceholderFail:
+		return errors.Wrap(ErrEmptyValue, strings.Join(empty, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Placeholder(t *testing.T) {
+	src := "API_KEY=\nHOST=localhost\nTOKEN=\"\"\n"
+
+	cases := []struct {
+		placeholder envsync.Placeholder
+		answers     string
+		prompts     string
+		expected    string
+	}{
+		{envsync.PlaceholderEmpty, "", "", "API_KEY=\nHOST=localhost\nTOKEN=\"\"\n"},
+		{envsync.PlaceholderMarker, "", "", "API_KEY=<CHANGE_ME>\nHOST=localhost\nTOKEN=\"<CHANGE_ME>\"\n"},
+		{envsync.PlaceholderPrompt, "abc\n-\n", "API_KEY []: TOKEN []: ", "API_KEY=abc\nHOST=localhost\n"},
+	}
+
+	for _, c := range cases {
+		var out bytes.Buffer
+		syncer := envsync.New(envsync.WithPlaceholder(c.placeholder))
+		if c.placeholder == envsync.PlaceholderPrompt {
+			syncer.Prompter = envsync.NewPrompter(strings.NewReader(c.answers), &out)
+		}
+
+		var buf bytes.Buffer
+		_, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+		assert.Nil(t, err, c.placeholder.String())
+		assert.Equal(t, c.expected, buf.String(), c.placeholder.String())
+		assert.Equal(t, c.prompts, out.String(), c.placeholder.String())
+	}
+}
+
+func TestSyncer_SyncReaders_PlaceholderFail(t *testing.T) {
+	syncer := envsync.New(envsync.WithPlaceholder(envsync.PlaceholderFail))
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader("API_KEY=\nHOST=localhost\n"), strings.NewReader(""), &buf)
+	assert.True(t, errors.Is(err, envsync.ErrEmptyValue))
+	assert.Contains(t, err.Error(), "API_KEY")
+	assert.Empty(t, buf.String())
+
+	_, err = syncer.SyncReaders(strings.NewReader("API_KEY=\n"), strings.NewReader("API_KEY=abc\n"), &buf)
+	assert.Nil(t, err)
+}
+
+func TestSyncer_SyncReaders_PlaceholderPromptWithoutPrompter(t *testing.T) {
+	syncer := envsync.New(envsync.WithPlaceholder(envsync.PlaceholderPrompt))
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader("API_KEY=\n"), strings.NewReader(""), &buf)
+	assert.NotNil(t, err)
+}
+
+func TestParsePlaceholder(t *testing.T) {
+	for _, p := range []envsync.Placeholder{envsync.PlaceholderEmpty, envsync.PlaceholderMarker, envsync.PlaceholderPrompt, envsync.PlaceholderFail} {
+		parsed, err := envsync.ParsePlaceholder(p.String())
+		assert.Nil(t, err)
+		assert.Equal(t, p, parsed)
+	}
+
+	_, err := envsync.ParsePlaceholder("random")
+	assert.NotNil(t, err)
+	assert.Equal(t, "Placeholder(9)", envsync.Placeholder(9).String())
+}
@@ -0,0 +1,91 @@
+package envsync
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// skipAnswer is the answer making a linePrompter skip a key.
+const skipAnswer = "-"
+
+// Prompter decides the value of each key-value added to target,
+// e.g. to fill in real secrets in place of the sample values on first setup.
+type Prompter interface {
+	// Prompt returns the value key is added to target with, given its value in source.
+	// If skip is true, key isn't added to target.
+	Prompt(key, value string) (v string, skip bool, err error)
+}
+
+// NewPrompter returns a Prompter asking for each value on w and reading the answer from r, one line each.
+// An empty answer accepts the value in source, "-" skips the key, and anything else replaces the value.
+func NewPrompter(r io.Reader, w io.Writer) Prompter {
+	return &linePrompter{r: bufio.NewReader(r), w: w}
+}
+
+type linePrompter struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func (p *linePrompter) Prompt(key, value string) (string, bool, error) {
+	fmt.Fprintf(p.w, "%s [%s]: ", key, value)
+	answer, err := p.r.ReadString('\n')
+	if err == io.EOF && answer == "" {
+		return "", false, io.ErrUnexpectedEOF
+	}
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+
+	answer = strings.TrimRight(answer, "\r\n")
+	switch answer {
+	case "":
+		return value, false, nil
+	case skipAnswer:
+		return "", true, nil
+	default:
+		return answer, false, nil
+	}
+}
+
+// prompt fills the placeholders and asks the Prompter for the value of each key in added, in order.
+// With PlaceholderPrompt only keys with an empty value are prompted for.
+// The skipped keys are removed from added.
+// The key-values whose value has been changed are recorded to edited.
+func (s *Syncer) prompt(added, edited map[string]string) error {
+	if err := s.Placeholder.fill(added, edited); err != nil {
+		return err
+	}
+	if s.DryRun {
+		return nil
+	}
+	if s.Prompter == nil {
+		if s.Placeholder == PlaceholderPrompt {
+			return errors.New("placeholder prompt requires a Prompter")
+		}
+		return nil
+	}
+
+	for _, k := range sortedKeys(added) {
+		if s.Placeholder == PlaceholderPrompt && added[k] != "" {
+			continue
+		}
+		v, skip, err := s.Prompter.Prompt(k, added[k])
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't prompt for %s", k))
+		}
+		switch {
+		case skip:
+			delete(added, k)
+			delete(edited, k)
+		case v != added[k]:
+			added[k] = v
+			edited[k] = v
+		}
+	}
+	return nil
+}
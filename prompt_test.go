@@ -0,0 +1,48 @@
+package envsync_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Prompter(t *testing.T) {
+	var out bytes.Buffer
+	answers := strings.NewReader("\nsecret value\n-\n")
+	syncer := envsync.New(envsync.WithPrompter(envsync.NewPrompter(answers, &out)))
+
+	src := "HOST=localhost\nPASSWORD='changeme'\nPORT=8080\nTIMEOUT=10\n"
+	dst := "TIMEOUT=30\n"
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "HOST [localhost]: PASSWORD [changeme]: PORT [8080]: ", out.String())
+	assert.Equal(t, []string{"HOST", "PASSWORD"}, res.Added)
+	assert.Equal(t, "secret value", res.Env["PASSWORD"])
+	assert.Equal(t, "TIMEOUT=30\nHOST=localhost\nPASSWORD='secret value'\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_PrompterNoAnswer(t *testing.T) {
+	var out bytes.Buffer
+	syncer := envsync.New(envsync.WithPrompter(envsync.NewPrompter(strings.NewReader(""), &out)))
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader("HOST=localhost\n"), strings.NewReader(""), &buf)
+	assert.NotNil(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestSyncer_SyncReaders_PrompterDryRun(t *testing.T) {
+	var out bytes.Buffer
+	syncer := envsync.New(envsync.WithDryRun(), envsync.WithPrompter(envsync.NewPrompter(strings.NewReader(""), &out)))
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader("HOST=localhost\n"), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST"}, res.Added)
+	assert.Empty(t, out.String())
+}
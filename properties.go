@@ -0,0 +1,191 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// decodeProperties reads the key-values of a Java .properties file, its comments, and blank lines.
+// A key is separated from its value by '=', ':', or whitespace.
+// A line ending with an odd number of backslashes continues on the next line, whose leading whitespace is skipped.
+func decodeProperties(r io.Reader) (*Document, error) {
+	doc := &Document{codec: CodecProperties}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		start, text := n, sc.Text()
+		trimmed := strings.TrimLeft(text, " \t\f")
+		switch {
+		case trimmed == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+			continue
+		case trimmed[0] == '#' || trimmed[0] == '!':
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+			continue
+		}
+		for continued(text) && sc.Scan() {
+			n++
+			text += "\n" + sc.Text()
+		}
+		l, err := parsePropertiesLine(text)
+		if err != nil {
+			return doc, &ParseError{Line: start, Text: text, Err: err}
+		}
+		doc.lines = append(doc.lines, l)
+	}
+	return doc, sc.Err()
+}
+
+// continued reports whether text ends with an odd number of backslashes, continuing on the next line.
+func continued(text string) bool {
+	n := len(text) - len(strings.TrimRight(text, `\`))
+	return n%2 == 1
+}
+
+// parsePropertiesLine parses a key-value of a .properties file, spanning the lines of text.
+func parsePropertiesLine(text string) (*line, error) {
+	// the lines are joined first, so escapes are read across them
+	physical := strings.Split(text, "\n")
+	for i, p := range physical {
+		if i > 0 {
+			p = strings.TrimLeft(p, " \t\f")
+		}
+		if i < len(physical)-1 {
+			if !continued(p) {
+				return nil, ErrUnsupportedSyntax
+			}
+			p = p[:len(p)-1]
+		}
+		physical[i] = p
+	}
+	logical := strings.TrimLeft(strings.Join(physical, ""), " \t\f")
+
+	// the key ends at the first unescaped separator
+	end := len(logical)
+	for i := 0; i < len(logical); i++ {
+		if c := logical[i]; c == '\\' {
+			i++
+		} else if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			end = i
+			break
+		}
+	}
+	key, err := unescapeProperties(logical[:end])
+	if err != nil {
+		return nil, err
+	}
+
+	// whitespace around the separator isn't part of the value, nor is a second separator after whitespace
+	rest := strings.TrimLeft(logical[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	value, err := unescapeProperties(rest)
+	if err != nil {
+		return nil, err
+	}
+	return &line{kind: entryLine, key: key, value: value, raw: text}, nil
+}
+
+// unescapeProperties reads the escapes of s: \t, \n, \r, \f, \uXXXX, and any other character taken as it is.
+func unescapeProperties(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", ErrInvalidEscape
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", ErrInvalidEscape
+			}
+			i += 4
+			// a character beyond the basic plane is escaped as a surrogate pair, e.g. \ud83d\ude00
+			if utf16.IsSurrogate(rune(r)) && strings.HasPrefix(s[i+1:], `\u`) && i+7 <= len(s) {
+				if low, err := strconv.ParseUint(s[i+3:i+7], 16, 16); err == nil {
+					if pair := utf16.DecodeRune(rune(r), rune(low)); pair != unicode.ReplacementChar {
+						b.WriteRune(pair)
+						i += 6
+						continue
+					}
+				}
+			}
+			b.WriteRune(rune(r))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// encodeProperties writes d to buf as a .properties file.
+// Lines that already hold the same key-value are written as they are, and others as key=value,
+// escaping non-ASCII characters as \uXXXX so the file reads the same in ISO-8859-1.
+func (d *Document) encodeProperties(buf *bytes.Buffer) {
+	for _, l := range d.lines {
+		if l.kind != entryLine {
+			buf.WriteString(l.raw + "\n")
+			continue
+		}
+		if p, err := parsePropertiesLine(l.raw); l.raw != "" && err == nil && p.key == l.key && p.value == l.value {
+			buf.WriteString(l.raw + "\n")
+			continue
+		}
+		buf.WriteString(escapeProperties(l.key, true) + separator + escapeProperties(l.value, false) + "\n")
+	}
+}
+
+// escapeProperties escapes s as a key, or as a value, of a .properties file.
+func escapeProperties(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04x`, u)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
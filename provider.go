@@ -0,0 +1,130 @@
+package envsync
+
+import (
+	"context"
+	"sync"
+)
+
+// Source is where the env synchronized into a Target is loaded from, e.g. a file, a remote store, or a map.
+type Source interface {
+	// Name identifies the source in results and patches, e.g. the path of a file.
+	Name() string
+
+	// Load reads the env of the source as a document.
+	Load(ctx context.Context) (*Document, error)
+}
+
+// Target is what a Source is synchronized into: it is loaded like a source, then stored once changed.
+type Target interface {
+	Source
+
+	// Store replaces the env of the target with doc.
+	Store(ctx context.Context, doc *Document) error
+}
+
+// DryRunner is a Target that can check a change without making it, e.g. with a server-side dry run.
+// In dry-run mode, a Syncer hands such a target the document it would store,
+// and reports the env and patch of what the target answers rather than of what it would send.
+type DryRunner interface {
+	// DryRun returns the document the target would hold once doc is stored, leaving the target unchanged.
+	DryRun(ctx context.Context, doc *Document) (*Document, error)
+}
+
+// secretTarget is a Target whose values are all secret, e.g. the config vars of a Heroku app:
+// they are masked in patches and issues whatever Mask holds, unless ShowSecrets is set.
+type secretTarget interface {
+	Target
+	secretValues()
+}
+
+// writeOnlyTarget is a Target whose values can't be read back, e.g. the Actions secrets of a GitHub repository:
+// it loads its keys with empty values, and Diff only compares its keys.
+type writeOnlyTarget interface {
+	Target
+	writeOnly()
+}
+
+// fileProvider is an env file as a Source or a Target.
+// kind is either "source" or "target", telling which errors it reports, e.g. ErrSourceNotFound.
+type fileProvider struct {
+	s    *Syncer
+	name string
+	kind string
+}
+
+// FileSource returns the env file at name as a Source, read with the codec set in Codecs or following its extension.
+func (s *Syncer) FileSource(name string) Source {
+	return &fileProvider{s: s, name: name, kind: "source"}
+}
+
+// FileTarget returns the env file at name as a Target.
+// It is replaced atomically when it is stored, and backed up first if Backup is set.
+func (s *Syncer) FileTarget(name string) Target {
+	return &fileProvider{s: s, name: name, kind: "target"}
+}
+
+func (f *fileProvider) Name() string {
+	return f.name
+}
+
+func (f *fileProvider) Load(ctx context.Context) (*Document, error) {
+	return f.s.readDocument(ctx, f.name, f.kind)
+}
+
+func (f *fileProvider) Store(ctx context.Context, doc *Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.s.writeEnv(f.name, doc)
+}
+
+// Memory is an env held in memory as a Source or a Target, e.g. to synchronize a map.
+// It is safe for concurrent use.
+type Memory struct {
+	mu  sync.Mutex
+	doc *Document
+}
+
+// NewMemory returns a Memory holding env, its keys in alphabetical order.
+func NewMemory(env Env) *Memory {
+	return &Memory{doc: envDocument(env)}
+}
+
+// Name returns "memory".
+func (m *Memory) Name() string {
+	return "memory"
+}
+
+// Load returns a copy of the document last stored.
+func (m *Memory) Load(ctx context.Context) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.doc == nil {
+		return &Document{}, nil
+	}
+	return m.doc.clone(), nil
+}
+
+// Store keeps a copy of doc.
+func (m *Memory) Store(ctx context.Context, doc *Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.doc = doc.clone()
+	return nil
+}
+
+// Env returns the key-values last stored.
+func (m *Memory) Env() Env {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.doc == nil {
+		return Env{}
+	}
+	return m.doc.Env()
+}
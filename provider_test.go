@@ -0,0 +1,42 @@
+package envsync_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncProviders(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\nPORT=8080\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New()
+	target := envsync.NewMemory(envsync.Env{"PORT": "80"})
+	res, err := syncer.SyncProviders(context.Background(), syncer.FileSource(filepath.Join(dir, "env.sample")), target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST"}, res.Added)
+	assert.Equal(t, envsync.Env{"HOST": "localhost", "PORT": "80"}, target.Env())
+
+	// a memory source is written to a file target like any other source
+	source := envsync.NewMemory(envsync.Env{"DEBUG": "false", "HOST": "example.com"})
+	name := filepath.Join(dir, ".env")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("HOST=localhost\n"), 0644))
+	res, err = syncer.SyncProviders(context.Background(), source, syncer.FileTarget(name))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+
+	b, err := ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, "HOST=localhost\nDEBUG=false\n", string(b))
+
+	_, err = syncer.SyncProviders(context.Background(), syncer.FileSource(filepath.Join(dir, "missing")), target)
+	assert.True(t, errors.Is(err, envsync.ErrSourceNotFound))
+}
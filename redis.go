@@ -0,0 +1,279 @@
+package envsync
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const redisPort = "6379"
+
+// redisTarget is a hash of a Redis database as a Target, e.g. redis://localhost/0#myapp:env,
+// or rediss:// over TLS: each key is a field of the hash.
+// The password is the one of the URL, e.g. redis://:s3cret@localhost/0#myapp:env, or the one in REDISCLI_AUTH like redis-cli,
+// and the ttl query parameter makes the hash expire after it is written, e.g. ?ttl=24h.
+type redisTarget struct {
+	name     string
+	addr     string
+	tls      bool
+	user     string
+	password string
+	db       int
+	key      string
+	ttl      time.Duration
+	env      Env
+}
+
+func openRedis(u *url.URL) (Target, error) {
+	if u.Fragment == "" {
+		return nil, fmt.Errorf("expected %s://host/db#key, got %s", u.Scheme, redactedURL(u))
+	}
+	t := &redisTarget{name: redactedURL(u), addr: u.Host, tls: u.Scheme == "rediss", key: u.Fragment}
+	if t.addr == "" {
+		t.addr = "localhost"
+	}
+	if u.Port() == "" {
+		t.addr = net.JoinHostPort(strings.Trim(t.addr, "[]"), redisPort)
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s isn't the number of a Redis database", db)
+		}
+		t.db = n
+	}
+	if ttl := u.Query().Get("ttl"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < time.Millisecond {
+			return nil, fmt.Errorf("invalid ttl %s", ttl)
+		}
+		t.ttl = d
+	}
+	t.password = os.Getenv("REDISCLI_AUTH")
+	if u.User != nil {
+		t.user = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			t.password = password
+		}
+	}
+	return t, nil
+}
+
+func (t *redisTarget) Name() string {
+	return t.name
+}
+
+func (t *redisTarget) Load(ctx context.Context) (*Document, error) {
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reply, err := conn.do([]string{"HGETALL", t.key})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read %s", t.key))
+	}
+	fields, _ := reply.([]interface{})
+	env := Env{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		k, _ := fields[i].(string)
+		v, _ := fields[i+1].(string)
+		env[k] = v
+	}
+	t.env = env
+	return envDocument(env), nil
+}
+
+// Store sets the fields whose value is added or changed since the target is loaded,
+// deletes the ones of the keys that aren't in doc anymore, and renews the expiry of the hash, in a transaction.
+func (t *redisTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	if reflect.DeepEqual(env, t.env) {
+		return nil
+	}
+	cmds := [][]string{{"MULTI"}}
+	set := []string{"HSET", t.key}
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; !ok || v != env[k] {
+			set = append(set, k, env[k])
+		}
+	}
+	if len(set) > 2 {
+		cmds = append(cmds, set)
+	}
+	del := []string{"HDEL", t.key}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; !ok {
+			del = append(del, k)
+		}
+	}
+	if len(del) > 2 {
+		cmds = append(cmds, del)
+	}
+	if t.ttl > 0 {
+		cmds = append(cmds, []string{"PEXPIRE", t.key, strconv.FormatInt(int64(t.ttl/time.Millisecond), 10)})
+	}
+	cmds = append(cmds, []string{"EXEC"})
+
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.do(cmds...); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("couldn't write %s", t.key))
+	}
+	t.env = env
+	return nil
+}
+
+// dial connects to the server, authenticated and on the database of the target,
+// with the deadline of ctx if it has one.
+func (t *redisTarget) dial(ctx context.Context) (*redisConn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't connect to %s", t.addr))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+	if t.tls {
+		host, _, _ := net.SplitHostPort(t.addr)
+		nc = tls.Client(nc, &tls.Config{ServerName: host})
+	}
+	conn := &redisConn{conn: nc, r: bufio.NewReader(nc)}
+
+	var cmds [][]string
+	if t.password != "" {
+		auth := []string{"AUTH", t.password}
+		if t.user != "" {
+			auth = []string{"AUTH", t.user, t.password}
+		}
+		cmds = append(cmds, auth)
+	}
+	if t.db != 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(t.db)})
+	}
+	if len(cmds) > 0 {
+		if _, err := conn.do(cmds...); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't connect to %s", t.addr))
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply of Redis, e.g. WRONGTYPE Operation against a key holding the wrong kind of value.
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisConn is a connection to a Redis server speaking RESP2.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends cmds at once and returns the reply of the last one, or the first error replied,
+// including the ones of the commands of a transaction.
+func (c *redisConn) do(cmds ...[]string) (interface{}, error) {
+	var b strings.Builder
+	for _, args := range cmds {
+		fmt.Fprintf(&b, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	var last interface{}
+	var first error
+	for range cmds {
+		reply, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if items, ok := reply.([]interface{}); ok {
+			for _, item := range items {
+				if e, ok := item.(redisError); ok && first == nil {
+					first = e
+				}
+			}
+		}
+		if e, ok := reply.(redisError); ok && first == nil {
+			first = e
+		}
+		last = reply
+	}
+	return last, first
+}
+
+// read reads a reply: a string, an int64, a redisError, nil, or a []interface{} of them.
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("invalid Redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("invalid Redis reply %q", line)
+}
@@ -0,0 +1,171 @@
+package envsync_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis serves the hash commands of Redis from memory, on database 1 with the password s3cret.
+type fakeRedis struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+	ttls   map[string]string
+}
+
+func (f *fakeRedis) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed, db := false, 0
+	var queue [][]string
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == "s3cret"
+			if !authed {
+				io.WriteString(conn, "-WRONGPASS invalid username-password pair\r\n")
+				continue
+			}
+			io.WriteString(conn, "+OK\r\n")
+		case !authed:
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "SELECT":
+			db, _ = strconv.Atoi(args[1])
+			io.WriteString(conn, "+OK\r\n")
+		case db != 1:
+			io.WriteString(conn, "-ERR not database 1\r\n")
+		case args[0] == "MULTI":
+			queue = [][]string{}
+			io.WriteString(conn, "+OK\r\n")
+		case args[0] == "EXEC":
+			fmt.Fprintf(conn, "*%d\r\n", len(queue))
+			for _, cmd := range queue {
+				io.WriteString(conn, f.exec(cmd))
+			}
+			queue = nil
+		case queue != nil:
+			queue = append(queue, args)
+			io.WriteString(conn, "+QUEUED\r\n")
+		default:
+			io.WriteString(conn, f.exec(args))
+		}
+	}
+}
+
+// exec runs a command and returns its reply.
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hashes[args[1]] == nil {
+		f.hashes[args[1]] = make(map[string]string)
+	}
+	h := f.hashes[args[1]]
+	switch args[0] {
+	case "HGETALL":
+		var keys []string
+		for k := range h {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		reply := fmt.Sprintf("*%d\r\n", 2*len(keys))
+		for _, k := range keys {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n$%d\r\n%s\r\n", len(k), k, len(h[k]), h[k])
+		}
+		return reply
+	case "HSET":
+		for i := 2; i+1 < len(args); i += 2 {
+			h[args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", (len(args)-2)/2)
+	case "HDEL":
+		for _, k := range args[2:] {
+			delete(h, k)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-2)
+	case "PEXPIRE":
+		f.ttls[args[1]] = args[2]
+		return ":1\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func TestSyncer_Sync_Redis(t *testing.T) {
+	redis := &fakeRedis{
+		hashes: map[string]map[string]string{"myapp:env": {"DB_HOST": "db.example.com", "OLD": "x"}},
+		ttls:   make(map[string]string),
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	go redis.serve(l)
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "DB_HOST=localhost\nDB_PORT=5432\nGREETING=\"hello\\r\\nworld\"\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "redis://:s3cret@"+l.Addr().String()+"/1?ttl=24h#myapp:env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PORT", "GREETING"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]string{"DB_HOST": "db.example.com", "DB_PORT": "5432", "GREETING": "hello\r\nworld"}, redis.hashes["myapp:env"])
+	assert.Equal(t, "86400000", redis.ttls["myapp:env"])
+
+	// the password can be the one of redis-cli
+	defer setenv(map[string]string{"REDISCLI_AUTH": "s3cret"})()
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "redis://"+l.Addr().String()+"/1#myapp:env")
+	assert.Nil(t, err)
+	assert.Empty(t, res.Added)
+
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "redis://:wrong@"+l.Addr().String()+"/1#myapp:env")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "WRONGPASS")
+}
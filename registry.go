@@ -0,0 +1,92 @@
+package envsync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// CustomCodec reads and writes the env files of a format registered with RegisterFormat,
+// e.g. a proprietary config file.
+type CustomCodec interface {
+	// Decode reads the key-values of an env file from r, in the order they are written.
+	Decode(r io.Reader) ([]Entry, error)
+
+	// Encode writes entries to w as an env file.
+	Encode(w io.Writer, entries []Entry) error
+}
+
+var (
+	// formatsMu guards codecNames, codecExtensions, and customCodecs, which RegisterFormat adds to.
+	formatsMu    sync.RWMutex
+	customCodecs = make(map[Codec]CustomCodec)
+)
+
+// RegisterFormat adds a format named name, read and written with codec, and returns the Codec it is known by.
+// Files with one of extensions, e.g. ".conf", are read with it, and the name is accepted by ParseCodec,
+// so the format is used like the built-in ones, e.g. with --source-format.
+// It is an error to register a name or an extension twice.
+func RegisterFormat(name string, codec CustomCodec, extensions ...string) (Codec, error) {
+	if name == "" || codec == nil {
+		return 0, fmt.Errorf("invalid format: %q", name)
+	}
+
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for _, n := range codecNames {
+		if n == name {
+			return 0, fmt.Errorf("format already registered: %s", name)
+		}
+	}
+	exts := make([]string, len(extensions))
+	for i, ext := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if c, ok := codecExtensions[ext]; ok {
+			return 0, fmt.Errorf("extension %s already registered by %s", ext, codecNames[c])
+		}
+		exts[i] = ext
+	}
+
+	c := Codec(len(codecNames))
+	codecNames[c] = name
+	for _, ext := range exts {
+		codecExtensions[ext] = c
+	}
+	customCodecs[c] = codec
+	return c, nil
+}
+
+// customCodec returns the registered codec of c, or nil if c is built in or unknown.
+func customCodec(c Codec) CustomCodec {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return customCodecs[c]
+}
+
+// decodeCustom reads an env file from r with codec, its key-values added to a document as with Append.
+func decodeCustom(r io.Reader, c Codec, codec CustomCodec) (*Document, error) {
+	entries, err := codec.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{codec: c}
+	for _, e := range entries {
+		doc.Append(e)
+	}
+	return doc, nil
+}
+
+// encodeCustom writes the key-values of d to buf with codec.
+func (d *Document) encodeCustom(buf *bytes.Buffer, codec CustomCodec) error {
+	var entries []Entry
+	d.Iterate(func(e Entry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	return codec.Encode(buf, entries)
+}
@@ -0,0 +1,70 @@
+package envsync_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// tabCodec reads and writes KEY<tab>value lines.
+type tabCodec struct{}
+
+func (tabCodec) Decode(r io.Reader) ([]envsync.Entry, error) {
+	var entries []envsync.Entry
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		sp := strings.SplitN(sc.Text(), "\t", 2)
+		if len(sp) != 2 {
+			return nil, fmt.Errorf("invalid line: %s", sc.Text())
+		}
+		entries = append(entries, envsync.Entry{Key: sp[0], Value: sp[1]})
+	}
+	return entries, sc.Err()
+}
+
+func (tabCodec) Encode(w io.Writer, entries []envsync.Entry) error {
+	for _, e := range entries {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	codec, err := envsync.RegisterFormat("tab", tabCodec{}, ".TSV")
+	assert.Nil(t, err)
+	defer envsync.UnregisterFormat(codec)
+	assert.Equal(t, "tab", codec.String())
+	assert.Equal(t, codec, envsync.CodecOf("env.tsv"))
+	parsed, err := envsync.ParseCodec("tab")
+	assert.Nil(t, err)
+	assert.Equal(t, codec, parsed)
+
+	_, err = envsync.RegisterFormat("tab", tabCodec{})
+	assert.Equal(t, "format already registered: tab", err.Error())
+	_, err = envsync.RegisterFormat("yml", tabCodec{}, "yml")
+	assert.Equal(t, "extension .yml already registered by yaml", err.Error())
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\nPORT=8080\n",
+		"env.tsv":    "PORT\t80\n",
+	})
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "env.tsv")
+	err = envsync.New().Sync(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+
+	b, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "PORT\t80\nHOST\tlocalhost\n", string(b))
+}
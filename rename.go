@@ -0,0 +1,52 @@
+package envsync
+
+import "strings"
+
+// renames returns the deprecated keys mapped to their new name,
+// from Renames and the # envsync:alias annotations in sDoc, limited to new names that match filter.
+// An annotation wins over Renames for the same deprecated key.
+func (s *Syncer) renames(sDoc *Document, filter *keyFilter) map[string]string {
+	renames := make(map[string]string)
+	for old, k := range s.Renames {
+		if filter.match(k) {
+			renames[old] = k
+		}
+	}
+	for _, k := range sDoc.Keys() {
+		aliases, ok := sDoc.annotations(k)[annotationAlias]
+		if !ok || !filter.match(k) {
+			continue
+		}
+		for _, old := range strings.Split(aliases, ",") {
+			if old = strings.TrimSpace(old); old != "" {
+				renames[old] = k
+			}
+		}
+	}
+	return renames
+}
+
+// rename renames the deprecated keys in doc to their new name in renames, keeping their value and comments,
+// and returns the renamed keys mapped to their new name.
+// A deprecated key whose new name is already declared in doc is left as it is and reported to Logger.
+func (s *Syncer) rename(doc *Document, renames map[string]string) map[string]string {
+	renamed := make(map[string]string)
+	env := doc.Env()
+	for _, old := range sortedKeys(renames) {
+		k := renames[old]
+		if _, found := env[old]; !found || old == k {
+			continue
+		}
+		if _, found := env[k]; found {
+			if s.Logger != nil {
+				s.Logger.Printf("Env deprecated: %s, use %s", old, k)
+			}
+			continue
+		}
+		doc.Rename(old, k)
+		env[k] = env[old]
+		delete(env, old)
+		renamed[old] = k
+	}
+	return renamed
+}
@@ -0,0 +1,46 @@
+package envsync_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Rename(t *testing.T) {
+	src := "# envsync:alias DB_URL, DATABASE\nDATABASE_URL=postgres://localhost\nPORT=8080\n"
+	dst := "# the database\nDB_URL=postgres://example.com\nHOST=example.com\n"
+
+	var logs bytes.Buffer
+	syncer := envsync.New(envsync.WithRename("HOST", "HOSTNAME"), envsync.WithLogger(log.New(&logs, "", 0)))
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DB_URL": "DATABASE_URL", "HOST": "HOSTNAME"}, res.Renamed)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Equal(t, "postgres://example.com", res.Env["DATABASE_URL"])
+	assert.Equal(t, "# the database\nDATABASE_URL=postgres://example.com\nHOSTNAME=example.com\nPORT=8080\n", buf.String())
+	assert.Contains(t, logs.String(), "Deprecated env renamed: DB_URL to DATABASE_URL\n")
+
+	// a deprecated key is kept if the new name is already declared
+	dst = "DB_URL=postgres://example.com\nDATABASE_URL=postgres://localhost\nPORT=80\n"
+	logs.Reset()
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Renamed)
+	assert.Contains(t, logs.String(), "Env deprecated: DB_URL, use DATABASE_URL\n")
+
+	syncer = envsync.New(envsync.WithDryRun())
+	dst = "DB_URL=postgres://example.com\nPORT=80\n"
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DB_URL": "DATABASE_URL"}, res.Renamed)
+	assert.Empty(t, res.Added)
+	assert.Empty(t, buf.String())
+}
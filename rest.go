@@ -0,0 +1,214 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// restClient calls a JSON REST API at base, e.g. https://api.doppler.com.
+type restClient struct {
+	base string
+	http *http.Client
+
+	// auth sets the credentials of req, e.g. its Authorization header.
+	auth func(ctx context.Context, req *http.Request) error
+
+	// errorOf returns the error of a response whose status isn't 2xx, or nil for an *httpError.
+	errorOf func(status int, body []byte) error
+}
+
+// httpError is the error of a response whose status isn't 2xx.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("%d %s", e.status, http.StatusText(e.status))
+	}
+	return fmt.Sprintf("%d %s: %s", e.status, http.StatusText(e.status), e.message)
+}
+
+// isHTTPStatus reports whether err is an *httpError of status.
+func isHTTPStatus(err error, status int) bool {
+	e, ok := err.(*httpError)
+	return ok && e.status == status
+}
+
+// do sends a request of method to path, relative to base unless it is a URL,
+// with in encoded in JSON if it isn't nil, and decodes the response into out if it isn't nil.
+func (c *restClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	u := path
+	if !strings.Contains(path, "://") {
+		u = strings.TrimRight(c.base, "/") + path
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	_, b, err := c.send(ctx, req)
+	if err != nil || out == nil || len(b) == 0 {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// send sends req with the credentials of auth, and returns the header and the body of the response,
+// or an error if its status isn't 2xx, e.g. an *httpError of 304 for a conditional request.
+func (c *restClient) send(ctx context.Context, req *http.Request) (http.Header, []byte, error) {
+	req = req.WithContext(ctx)
+	if c.auth != nil {
+		if err := c.auth(ctx, req); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	client := c.http
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if c.errorOf != nil {
+			if err := c.errorOf(resp.StatusCode, b); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, &httpError{status: resp.StatusCode, message: strings.TrimSpace(string(b))}
+	}
+	return resp.Header, b, nil
+}
+
+// redactedURL returns u without its password, so it doesn't show in results.
+func redactedURL(u *url.URL) string {
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	redacted := *u
+	redacted.User = url.User(u.User.Username())
+	return redacted.String()
+}
+
+// tlsClient returns an HTTP client trusting the PEM certificates of ca, or the system ones if it is nil,
+// and authenticating with the PEM certificate and key cert and key, if cert isn't nil.
+func tlsClient(ca, cert, key []byte, insecure bool) (*http.Client, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if ca != nil {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid certificate authority")
+		}
+	}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client certificate")
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}, nil
+}
+
+// tokenCache keeps an access token until shortly before it expires.
+type tokenCache struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// get returns the token, calling fetch for a new one and the time it is valid for if there is none.
+func (c *tokenCache) get(fetch func() (string, time.Duration, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+	token, ttl, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	// the token is renewed a minute early so it doesn't expire during a request
+	c.token, c.expiry = token, time.Now().Add(ttl-time.Minute)
+	return token, nil
+}
+
+// bearer returns the auth of a restClient setting the token of fetch as a bearer token.
+func bearer(fetch func(ctx context.Context) (string, error)) func(context.Context, *http.Request) error {
+	return func(ctx context.Context, req *http.Request) error {
+		token, err := fetch(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// requestToken gets an OAuth access token from the endpoint at u, posting form unless it is nil,
+// and returns it with the time it is valid for.
+func requestToken(ctx context.Context, u string, form url.Values, headers map[string]string) (string, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if form != nil {
+		req, err = http.NewRequest(http.MethodPost, u, strings.NewReader(form.Encode()))
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	// expires_in is a number, or a string in the responses of Azure
+	var token struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", 0, fmt.Errorf("no access token from %s: %s", req.URL.Host, resp.Status)
+	}
+	expiresIn, _ := strconv.Atoi(token.ExpiresIn.String())
+	return token.AccessToken, time.Duration(expiresIn) * time.Second, nil
+}
@@ -0,0 +1,66 @@
+package envsync
+
+// SyncResult describes what a synchronization changed in target.
+// It is encoded in JSON without Env, so values don't leak into logs.
+type SyncResult struct {
+	// Added holds the keys in source that were added to target.
+	Added []string `json:"added"`
+
+	// Skipped holds the keys in source that were already in target and left as they are.
+	Skipped []string `json:"skipped"`
+
+	// Pruned holds the keys in target that were removed because they aren't in source.
+	Pruned []string `json:"pruned"`
+
+	// Overwritten holds the keys in target that took the value in source.
+	Overwritten []string `json:"overwritten"`
+
+	// Extra holds the keys in target that aren't in source and have been kept.
+	Extra []string `json:"extra"`
+
+	// Renamed maps the deprecated keys in target that were renamed to their new name.
+	Renamed map[string]string `json:"renamed,omitempty"`
+
+	// Patch holds the changes to target as a unified diff if Patch is set.
+	// In dry-run mode it holds the changes that would be made.
+	Patch string `json:"patch,omitempty"`
+
+	// Issues holds the violations of the Lint rules in target after the synchronization.
+	Issues []Violation `json:"issues,omitempty"`
+
+	// Env is the key-values of target after the synchronization.
+	// In dry-run mode it is the key-values target would have.
+	Env Env `json:"-"`
+}
+
+func newSyncResult(sMap, tMap, added, pruned, overwritten map[string]string) *SyncResult {
+	env := make(Env)
+	skipped := make(map[string]string)
+	for k, v := range tMap {
+		if _, found := pruned[k]; found {
+			continue
+		}
+		if nv, found := overwritten[k]; found {
+			v = nv
+		}
+		env[k] = v
+	}
+	for k, v := range added {
+		env[k] = v
+	}
+	for k, v := range sMap {
+		_, inTarget := tMap[k]
+		_, isOverwritten := overwritten[k]
+		if inTarget && !isOverwritten {
+			skipped[k] = v
+		}
+	}
+
+	return &SyncResult{
+		Added:       sortedKeys(added),
+		Skipped:     sortedKeys(skipped),
+		Pruned:      sortedKeys(pruned),
+		Overwritten: sortedKeys(overwritten),
+		Env:         env,
+	}
+}
@@ -0,0 +1,144 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultRetryBackoff is the wait before the first retry when RetryBackoff isn't set.
+	defaultRetryBackoff = 500 * time.Millisecond
+
+	// maxRetryBackoff caps the wait between two attempts.
+	maxRetryBackoff = 30 * time.Second
+)
+
+// loadProvider loads p, retrying on transient errors if it is remote.
+func (s *Syncer) loadProvider(ctx context.Context, p Source) (*Document, error) {
+	var doc *Document
+	err := s.retry(ctx, p, func(ctx context.Context) error {
+		var err error
+		doc, err = p.Load(ctx)
+		return err
+	})
+	return doc, err
+}
+
+// storeTarget stores doc into t, retrying on transient errors if it is remote.
+// A retry stores doc again as a whole, so a target that writes it in several requests, or conditionally,
+// keeps track of what a failed attempt wrote: etcdTarget keeps the revisions of the keys each of its transactions writes,
+// and objectTarget takes an object that already holds doc as stored by an attempt whose answer was lost.
+func (s *Syncer) storeTarget(ctx context.Context, t Target, doc *Document) error {
+	return s.retry(ctx, t, func(ctx context.Context) error {
+		return t.Store(ctx, doc)
+	})
+}
+
+// retry runs f, a load or a store of p, within Timeout if p isn't an env file or a Memory,
+// and runs it again up to Retries times as long as it fails on a transient error,
+// waiting RetryBackoff, doubled on each retry, give or take a random half of it, so clients don't retry in lockstep.
+func (s *Syncer) retry(ctx context.Context, p Source, f func(context.Context) error) error {
+	switch p.(type) {
+	case *fileProvider, *Memory:
+		return f(ctx)
+	}
+
+	backoff := s.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := s.attempt(ctx, f)
+		if err == nil || attempt >= s.Retries || ctx.Err() != nil || !isTransient(err) {
+			return err
+		}
+
+		wait := backoff << uint(attempt)
+		if wait <= 0 || wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		if s.Logger != nil {
+			s.Logger.Printf("Retrying %s in %s: %s", p.Name(), wait.Round(time.Millisecond), err)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt runs f within Timeout if it is set, returning a *timeoutError if it runs out of it.
+func (s *Syncer) attempt(ctx context.Context, f func(context.Context) error) error {
+	if s.Timeout <= 0 {
+		return f(ctx)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	err := f(attemptCtx)
+	if err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return &timeoutError{timeout: s.Timeout, err: err}
+	}
+	return err
+}
+
+// timeoutError is the error of an attempt that didn't end within Timeout.
+type timeoutError struct {
+	timeout time.Duration
+	err     error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s: %s", e.timeout, e.err)
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+// isTransient reports whether err may not happen again on a retry:
+// a timeout, a network error, a connection closed early, or an error of a server that is unavailable or throttles requests.
+func isTransient(err error) bool {
+	var timeoutErr *timeoutError
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		return isTransientStatus(httpErr.status)
+	}
+	var awsErr *awsError
+	if errors.As(err, &awsErr) {
+		return isTransientStatus(awsErr.status) || strings.Contains(awsErr.code, "Throttl")
+	}
+	var azureErr *azureError
+	if errors.As(err, &azureErr) {
+		return isTransientStatus(azureErr.status)
+	}
+	var gcpErr *gcpError
+	if errors.As(err, &gcpErr) {
+		return isTransientStatus(gcpErr.Code)
+	}
+	var redisErr redisError
+	if errors.As(err, &redisErr) {
+		return strings.HasPrefix(string(redisErr), "LOADING") || strings.HasPrefix(string(redisErr), "TRYAGAIN")
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTransientStatus reports whether an HTTP status tells a request may succeed later:
+// a timeout, too many requests, or a server error.
+func isTransientStatus(status int) bool {
+	return status == 408 || status == 429 || status >= 500
+}
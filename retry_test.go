@@ -0,0 +1,84 @@
+package envsync_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyServer serves an env file once it has failed with status, or hung for delay, on the first failures requests.
+type flakyServer struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	delay    time.Duration
+	requests int
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	failed := f.requests <= f.failures
+	f.mu.Unlock()
+	if failed && f.delay > 0 {
+		time.Sleep(f.delay)
+	} else if failed {
+		w.WriteHeader(f.status)
+		return
+	}
+	w.Write([]byte("PORT=8080\n"))
+}
+
+// count returns the number of requests served so far.
+func (f *flakyServer) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func TestSyncer_Sync_Retry(t *testing.T) {
+	dir := makeTree(t, map[string]string{".env": ""})
+	defer os.RemoveAll(dir)
+	defer setenv(map[string]string{"HOME": dir, "XDG_CACHE_HOME": filepath.Join(dir, "cache")})()
+	target := filepath.Join(dir, ".env")
+
+	tests := []struct {
+		name     string
+		server   *flakyServer
+		syncer   *envsync.Syncer
+		err      string
+		requests int
+	}{
+		{"unavailable", &flakyServer{failures: 2, status: http.StatusServiceUnavailable}, envsync.New(envsync.WithRetry(2, time.Millisecond, 0)), "", 3},
+		{"throttled", &flakyServer{failures: 1, status: http.StatusTooManyRequests}, envsync.New(envsync.WithRetry(2, time.Millisecond, 0)), "", 2},
+		{"too many failures", &flakyServer{failures: 3, status: http.StatusBadGateway}, envsync.New(envsync.WithRetry(2, time.Millisecond, 0)), "502 Bad Gateway", 3},
+		{"no retry", &flakyServer{failures: 1, status: http.StatusServiceUnavailable}, envsync.New(), "503 Service Unavailable", 1},
+		{"not transient", &flakyServer{failures: 1, status: http.StatusForbidden}, envsync.New(envsync.WithRetry(2, time.Millisecond, 0)), "403 Forbidden", 1},
+		{"timeout", &flakyServer{failures: 1, delay: 500 * time.Millisecond}, envsync.New(envsync.WithRetry(1, time.Millisecond, 100*time.Millisecond)), "", 2},
+		{"timeout without retry", &flakyServer{failures: 1, delay: 500 * time.Millisecond}, envsync.New(envsync.WithRetry(0, 0, 100*time.Millisecond)), "timed out after 100ms", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.server)
+			defer server.Close()
+
+			res, err := tt.syncer.SyncWithResult(server.URL+"/.env.example", target)
+			if tt.err == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"PORT"}, res.Added)
+			} else {
+				assert.NotNil(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			}
+			assert.Equal(t, tt.requests, tt.server.count())
+			assert.Nil(t, os.Truncate(target, 0))
+		})
+	}
+}
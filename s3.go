@@ -0,0 +1,89 @@
+package envsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// s3Object is an object of an S3 bucket as the store of an objectTarget, e.g. s3://my-bucket/myapp/.env,
+// read and written with the credentials and in the region found like the AWS CLI does. See newAWSClient.
+// Its version is its ETag, and it is written with If-Match, or If-None-Match: * to create it.
+type s3Object struct {
+	url    string
+	client *awsClient
+}
+
+func openS3(u *url.URL) (Target, error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("expected s3://bucket/key, got %s", u)
+	}
+	client, err := newAWSClient(u)
+	if err != nil {
+		return nil, err
+	}
+	// the endpoints of AWS have the bucket in their host, and others, e.g. MinIO, in their path
+	object := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", u.Host, client.region, s3Escape(key))
+	if client.endpoint != "" {
+		object = strings.TrimRight(client.endpoint, "/") + "/" + u.Host + "/" + s3Escape(key)
+	}
+	return &objectTarget{
+		name:       u.String(),
+		fileFormat: fileFormat{codec: CodecOf(key)},
+		store:      &s3Object{url: object, client: client},
+	}, nil
+}
+
+// s3Escape returns key escaped like S3 does to sign requests: every byte but unreserved characters and '/'.
+func s3Escape(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func (o *s3Object) get(ctx context.Context) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, o.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	header, b, err := o.client.send(ctx, req, nil, "s3")
+	if e, ok := err.(*awsError); ok && e.status == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return b, header.Get("ETag"), nil
+}
+
+func (o *s3Object) put(ctx context.Context, b []byte, version string) (string, error) {
+	req, err := http.NewRequest(http.MethodPut, o.url, bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		req.Header.Set("If-None-Match", "*")
+	} else {
+		req.Header.Set("If-Match", version)
+	}
+	header, _, err := o.client.send(ctx, req, b, "s3")
+	// S3 answers 409 to a conditional write racing another one
+	if e, ok := err.(*awsError); ok && (e.status == http.StatusPreconditionFailed || e.status == http.StatusConflict) {
+		return "", errObjectChanged
+	}
+	if err != nil {
+		return "", err
+	}
+	return header.Get("ETag"), nil
+}
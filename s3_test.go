@@ -0,0 +1,143 @@
+package envsync_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 serves the objects of the bucket my-bucket from memory, path-style, with conditional writes.
+// If modified is set, an object is changed by someone else right after it is read.
+// If lost is set, the answer to the next write is lost: the object is written, but 503 is answered.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string]string
+	versions map[string]int
+	modified bool
+	lost     bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(r.Header.Get("Authorization"), "/s3/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/my-bucket/")
+	body, ok := f.objects[key]
+	etag := fmt.Sprintf(`"%d"`, f.versions[key])
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>"))
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+		if f.modified {
+			f.versions[key]++
+		}
+	case http.MethodPut:
+		if ok && r.Header.Get("If-Match") != etag || !ok && r.Header.Get("If-None-Match") != "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte("<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>"))
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = string(b)
+		f.versions[key]++
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, f.versions[key]))
+		if f.lost {
+			f.lost = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>"))
+		}
+	}
+}
+
+func TestSyncer_Sync_S3(t *testing.T) {
+	s3 := &fakeS3{
+		objects:  map[string]string{"myapp/.env": "# managed in S3\nHOST=example.com\n"},
+		versions: map[string]int{"myapp/.env": 1},
+	}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+	defer setenv(map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1", "AWS_ENDPOINT_URL": server.URL})()
+
+	dir := makeTree(t, map[string]string{"env.sample": "HOST=localhost\nPORT=8080\n"})
+	defer os.RemoveAll(dir)
+
+	res, err := envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "s3://my-bucket/myapp/.env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Equal(t, "# managed in S3\nHOST=example.com\nPORT=8080\n", s3.objects["myapp/.env"])
+
+	// a missing object is created
+	res, err = envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), "s3://my-bucket/other/env.json")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"HOST", "PORT"}, res.Added)
+	assert.Contains(t, s3.objects["other/env.json"], `"PORT": "8080"`)
+
+	// a retried write whose answer was lost finds the object written
+	s3.lost = true
+	syncer := envsync.New()
+	syncer.Retries, syncer.RetryBackoff = 1, time.Millisecond
+	ioutil.WriteFile(filepath.Join(dir, "env.sample"), []byte("HOST=localhost\nPORT=8080\nDEBUG=false\n"), 0644)
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "s3://my-bucket/myapp/.env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+	assert.Equal(t, "# managed in S3\nHOST=example.com\nPORT=8080\nDEBUG=false\n", s3.objects["myapp/.env"])
+
+	// an object changed since it was read isn't overwritten
+	s3.modified = true
+	_, err = envsync.New(envsync.WithOverwrite("")).SyncWithResult(filepath.Join(dir, "env.sample"), "s3://my-bucket/myapp/.env")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't write s3://my-bucket/myapp/.env: it changed since it was read")
+	assert.Equal(t, "# managed in S3\nHOST=example.com\nPORT=8080\nDEBUG=false\n", s3.objects["myapp/.env"])
+}
+
+func TestSyncer_Sync_S3Format(t *testing.T) {
+	s3 := &fakeS3{
+		objects:  map[string]string{"myapp/config": "{\"HOST\": \"example.com\"}\n", "myapp/.env": "HOST=example.com # the host\n"},
+		versions: map[string]int{"myapp/config": 1, "myapp/.env": 1},
+	}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+	defer setenv(map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1", "AWS_ENDPOINT_URL": server.URL})()
+
+	dir := makeTree(t, map[string]string{"env.sample": "HOST=example.com\nPORT=8080\n"})
+	defer os.RemoveAll(dir)
+
+	// an object is read and written like a file, with the codec set for it
+	target := "s3://my-bucket/myapp/config"
+	res, err := envsync.New(envsync.WithCodec(target, envsync.CodecJSON)).SyncWithResult(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Equal(t, "{\n  \"HOST\": \"example.com\",\n  \"PORT\": \"8080\"\n}\n", s3.objects["myapp/config"])
+
+	// and with the parse options of the syncer
+	syncer := envsync.New(envsync.WithDryRun())
+	syncer.InlineComments = true
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "s3://my-bucket/myapp/.env")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Added)
+	assert.Empty(t, res.Overwritten)
+	assert.Equal(t, "example.com", res.Env["HOST"])
+
+	// other backends hold key-values, which have no format
+	_, err = envsync.New(envsync.WithCodec("heroku://my-app", envsync.CodecJSON)).Diff(filepath.Join(dir, "env.sample"), "heroku://my-app")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't open target: heroku://my-app holds key-values rather than a file, so its format can't be set")
+}
@@ -0,0 +1,357 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultSchemaFile is the name of the schema file looked up by the validate command.
+const DefaultSchemaFile = "envsync.schema.yaml"
+
+// Schema declares the keys an env file is expected to have.
+type Schema struct {
+	// Keys maps each declared key to its constraints.
+	Keys map[string]*KeySchema
+}
+
+// KeySchema holds the constraints of a key.
+type KeySchema struct {
+	// Description tells what the key is for.
+	Description string
+
+	// Required makes a key that is missing or empty a violation, unless it has a Default.
+	Required bool
+
+	// Default is the value the application uses when the key is missing.
+	Default string
+
+	// Type is the type a non-empty value must have:
+	// string, int, float, bool, url, or duration as read by time.ParseDuration.
+	// An empty type is the same as string.
+	Type string
+
+	// Pattern is a regular expression a non-empty value must match, as in JSON Schema.
+	// It matches anywhere in the value unless it is anchored with ^ and $.
+	Pattern string
+
+	// Enum holds the values a non-empty value must be one of, e.g. debug, info, warn, and error.
+	// An empty Enum accepts any value.
+	Enum []string
+
+	// RequiredIf holds conditions on other keys making the key required when any of them holds, e.g. FEATURE_X=true.
+	// A condition KEY=value holds when KEY has the value, and a condition KEY holds when KEY isn't empty.
+	RequiredIf []string
+
+	// Warn makes the violations of the key warnings, which don't fail the validation.
+	Warn bool
+}
+
+// schemaFile is how a schema is written in a file.
+// It is either a list of keys, or a JSON Schema describing an object with a property per key.
+type schemaFile struct {
+	Keys map[string]schemaKey `yaml:"keys"`
+
+	Properties        map[string]schemaKey `yaml:"properties"`
+	Required          []string             `yaml:"required"`
+	DependentRequired map[string][]string  `yaml:"dependentRequired"`
+}
+
+type schemaKey struct {
+	Description string        `yaml:"description"`
+	Required    bool          `yaml:"required"`
+	Default     interface{}   `yaml:"default"`
+	Type        string        `yaml:"type"`
+	Format      string        `yaml:"format"`
+	Pattern     string        `yaml:"pattern"`
+	Enum        []interface{} `yaml:"enum"`
+	RequiredIf  []string      `yaml:"required_if"`
+	Warn        bool          `yaml:"warn"`
+}
+
+// ParseSchema reads a schema from r, written in YAML or JSON, e.g.
+//
+//	keys:
+//	  DATABASE_URL:
+//	    description: the database to connect to
+//	    required: true
+//	  PORT:
+//	    type: int
+//	    default: 8080
+//
+// A key may be required only when another key has a value, e.g. required_if: [FEATURE_X=true].
+//
+// A JSON Schema describing an object is accepted too:
+// its properties are the keys, its required list marks the required keys,
+// and its dependentRequired lists the keys required when a key isn't empty.
+// The JSON Schema types integer, number, and boolean are the same as int, float, and bool,
+// and the uri format is the same as the url type.
+func ParseSchema(r io.Reader) (*Schema, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read schema")
+	}
+
+	var f schemaFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, errors.Wrap(err, "couldn't parse schema")
+	}
+
+	keys := f.Keys
+	if len(keys) == 0 {
+		keys = f.Properties
+	}
+	schema := &Schema{Keys: make(map[string]*KeySchema)}
+	for k, sk := range keys {
+		ks := &KeySchema{Description: sk.Description, Required: sk.Required, Pattern: sk.Pattern, RequiredIf: sk.RequiredIf, Warn: sk.Warn}
+		for _, v := range sk.Enum {
+			ks.Enum = append(ks.Enum, fmt.Sprint(v))
+		}
+		if _, err := regexp.Compile(ks.Pattern); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema of %s", k))
+		}
+		if err := checkConditions(ks.RequiredIf); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema of %s", k))
+		}
+		if sk.Default != nil {
+			ks.Default = fmt.Sprint(sk.Default)
+		}
+		// JSON Schema declares URLs as strings with the uri format
+		typ := sk.Type
+		if sk.Format == "uri" {
+			typ = sk.Format
+		}
+		if typ != "" {
+			if ks.Type, err = valueType(typ); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("invalid schema of %s", k))
+			}
+		}
+		schema.Keys[k] = ks
+	}
+	for _, k := range f.Required {
+		schema.key(k).Required = true
+	}
+	for dep, ks := range f.DependentRequired {
+		for _, k := range ks {
+			schema.key(k).RequiredIf = append(schema.key(k).RequiredIf, dep)
+		}
+	}
+	return schema, nil
+}
+
+// key returns the constraints of k, adding them if k isn't declared yet.
+func (s *Schema) key(k string) *KeySchema {
+	ks, found := s.Keys[k]
+	if !found {
+		ks = &KeySchema{}
+		s.Keys[k] = ks
+	}
+	return ks
+}
+
+// LoadSchema reads the schema in the file at name.
+func LoadSchema(name string) (*Schema, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't open schema file")
+	}
+	defer file.Close()
+
+	return ParseSchema(file)
+}
+
+// Violation describes a key of an env file that doesn't satisfy a schema.
+type Violation struct {
+	// Key is the key the violation is about.
+	Key string `json:"key"`
+
+	// Line is the number of the line holding the key, or 0 if the key is missing.
+	Line int `json:"line,omitempty"`
+
+	// Message describes the violation.
+	Message string `json:"message"`
+
+	// Warning tells the violation doesn't fail the validation.
+	Warning bool `json:"warning,omitempty"`
+
+	// Rule is the name of the lint rule that found the violation.
+	// It is empty for a violation of a schema.
+	Rule string `json:"rule,omitempty"`
+}
+
+// ValidationError is returned when an env file doesn't satisfy a schema.
+// It holds every violation found, not only the first one.
+type ValidationError struct {
+	// File is the name of the env file.
+	File string
+
+	// Violations are ordered by key.
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("%s doesn't satisfy the schema: %s", e.File, strings.Join(msgs, "; "))
+}
+
+// String returns the violation as KEY: message, prefixed with the line number if there is one
+// and with warning if it is a warning, and followed by the rule in parentheses if there is one.
+func (v Violation) String() string {
+	msg := fmt.Sprintf("%s: %s", v.Key, v.Message)
+	if v.Rule != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, v.Rule)
+	}
+	if v.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", v.Line, msg)
+	}
+	if v.Warning {
+		msg = "warning: " + msg
+	}
+	return msg
+}
+
+// Validate checks the env file at target against schema.
+// It returns a *ValidationError holding every violation found, warnings included,
+// or nil if target satisfies schema or there are only warnings.
+func Validate(target string, schema *Schema) error {
+	violations, err := Violations(target, schema)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range violations {
+		if !v.Warning {
+			return &ValidationError{File: target, Violations: violations}
+		}
+	}
+	return nil
+}
+
+// Violations returns every violation of schema in the env file at target, ordered by key,
+// including warnings.
+func Violations(target string, schema *Schema) ([]Violation, error) {
+	s := &Syncer{}
+	doc, err := s.readDocument(context.Background(), target, "target")
+	if err != nil {
+		return nil, err
+	}
+	return schema.validate(doc), nil
+}
+
+// validate returns the violations of schema in doc, ordered by key.
+func (s *Schema) validate(doc *Document) []Violation {
+	keys := make([]string, 0, len(s.Keys))
+	for k := range s.Keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := doc.Env()
+	var violations []Violation
+	for _, k := range keys {
+		ks := s.Keys[k]
+		v, found := env[k]
+		required, reason := ks.Required, ""
+		if cond := holdingCondition(ks.RequiredIf, env); !required && cond != "" {
+			required, reason = true, fmt.Sprintf(" while %s", cond)
+		}
+		switch {
+		case required && ks.Default == "" && !found:
+			violations = append(violations, Violation{Key: k, Message: "required env is missing" + reason, Warning: ks.Warn})
+		case required && ks.Default == "" && v == "":
+			violations = append(violations, Violation{Key: k, Line: doc.lineNumber(k), Message: "required env is empty" + reason, Warning: ks.Warn})
+		case found && v != "":
+			for _, msg := range ks.check(v) {
+				violations = append(violations, Violation{Key: k, Line: doc.lineNumber(k), Message: msg, Warning: ks.Warn})
+			}
+		}
+	}
+	return violations
+}
+
+// SchemaRule flags the violations of a schema, e.g. missing required env or values that don't have the declared type.
+type SchemaRule struct {
+	// Schema is the schema target must satisfy.
+	// If it is nil, nothing is flagged.
+	Schema *Schema
+}
+
+// Name returns schema.
+func (r SchemaRule) Name() string {
+	return "schema"
+}
+
+// Check returns the violations of Schema in target, ordered by key.
+func (r SchemaRule) Check(target, source *Document) []Violation {
+	if r.Schema == nil {
+		return nil
+	}
+	return r.Schema.validate(target)
+}
+
+// check returns the messages describing how the non-empty value v doesn't satisfy ks.
+func (ks *KeySchema) check(v string) []string {
+	var msgs []string
+	if msg := checkType(ks.Type, v); msg != "" {
+		msgs = append(msgs, msg)
+	}
+	if ks.Pattern != "" {
+		re, err := regexp.Compile(ks.Pattern)
+		switch {
+		case err != nil:
+			msgs = append(msgs, fmt.Sprintf("invalid pattern %q", ks.Pattern))
+		case !re.MatchString(v):
+			msgs = append(msgs, fmt.Sprintf("%q doesn't match %q", v, ks.Pattern))
+		}
+	}
+	if len(ks.Enum) > 0 && !contains(ks.Enum, v) {
+		msgs = append(msgs, fmt.Sprintf("%q isn't one of %s", v, strings.Join(ks.Enum, ", ")))
+	}
+	return msgs
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConditions returns an error if a condition in conds has no key, e.g. =true.
+func checkConditions(conds []string) error {
+	for _, c := range conds {
+		if strings.TrimSpace(strings.SplitN(c, "=", 2)[0]) == "" {
+			return fmt.Errorf("invalid condition %q, expected KEY or KEY=value", c)
+		}
+	}
+	return nil
+}
+
+// holdingCondition returns the first condition in conds holding in env, or an empty string if there is none.
+// A condition KEY=value holds when KEY has the value, and a condition KEY holds when KEY isn't empty.
+func holdingCondition(conds []string, env Env) string {
+	for _, c := range conds {
+		sp := strings.SplitN(c, "=", 2)
+		k := strings.TrimSpace(sp[0])
+		if len(sp) == 1 && env[k] != "" {
+			return fmt.Sprintf("%s is set", k)
+		}
+		if v, found := env[k]; len(sp) == 2 && found && v == strings.TrimSpace(sp[1]) {
+			return fmt.Sprintf("%s=%s", k, v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,190 @@
+package envsync_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSchema(t *testing.T) {
+	for name, n := range map[string]int{"testdata/schema.yaml": 9, "testdata/schema.json": 4} {
+		schema, err := envsync.LoadSchema(name)
+		assert.Nil(t, err, name)
+		assert.Len(t, schema.Keys, n, name)
+		assert.True(t, schema.Keys["DATABASE_URL"].Required, name)
+		assert.Equal(t, "the database to connect to", schema.Keys["DATABASE_URL"].Description, name)
+		assert.True(t, schema.Keys["API_KEY"].Required, name)
+		assert.Equal(t, "8080", schema.Keys["PORT"].Default, name)
+		assert.Equal(t, "int", schema.Keys["PORT"].Type, name)
+	}
+
+	schema, _ := envsync.LoadSchema("testdata/schema.json")
+	assert.Equal(t, "url", schema.Keys["DATABASE_URL"].Type)
+
+	_, err := envsync.LoadSchema("testdata/missing.yaml")
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := envsync.LoadSchema("testdata/schema.yaml")
+	assert.Nil(t, err)
+
+	dir := makeTree(t, map[string]string{
+		"invalid.env": "# database\nDATABASE_URL=\nDEBUG=true\n",
+		"valid.env":   "DATABASE_URL=postgres://localhost\nAPI_KEY=abc\n",
+	})
+	defer os.RemoveAll(dir)
+
+	err = envsync.Validate(filepath.Join(dir, "invalid.env"), schema)
+	var verr *envsync.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []envsync.Violation{
+		{Key: "API_KEY", Message: "required env is missing"},
+		{Key: "DATABASE_URL", Line: 2, Message: "required env is empty"},
+	}, verr.Violations)
+	assert.Contains(t, err.Error(), "line 2: DATABASE_URL: required env is empty")
+
+	assert.Nil(t, envsync.Validate(filepath.Join(dir, "valid.env"), schema))
+
+	err = envsync.Validate(filepath.Join(dir, "missing.env"), schema)
+	assert.True(t, errors.Is(err, envsync.ErrTargetNotFound))
+}
+
+func TestParseSchema_Invalid(t *testing.T) {
+	dir := makeTree(t, map[string]string{"schema.yaml": "keys: [unclosed"})
+	defer os.RemoveAll(dir)
+
+	_, err := envsync.LoadSchema(filepath.Join(dir, "schema.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestValidate_Types(t *testing.T) {
+	schema, err := envsync.LoadSchema("testdata/schema.yaml")
+	assert.Nil(t, err)
+
+	dir := makeTree(t, map[string]string{
+		".env": "DATABASE_URL=postgres://localhost\nAPI_KEY=abc\nPORT=eighty\n\nDEBUG=maybe\nTIMEOUT=\n",
+	})
+	defer os.RemoveAll(dir)
+
+	err = envsync.Validate(filepath.Join(dir, ".env"), schema)
+	var verr *envsync.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []envsync.Violation{
+		{Key: "DEBUG", Line: 5, Message: `"maybe" isn't a valid bool`},
+		{Key: "PORT", Line: 3, Message: `"eighty" isn't a valid int`},
+	}, verr.Violations)
+}
+
+func TestParseSchema_UnknownType(t *testing.T) {
+	_, err := envsync.ParseSchema(strings.NewReader("keys:\n  PORT:\n    type: port\n"))
+	assert.NotNil(t, err)
+}
+
+func TestDocument_Schema(t *testing.T) {
+	doc, err := envsync.ParseDocument(strings.NewReader("# required\n# envsync:type url\nDATABASE_URL=\n# envsync:type duration\nTIMEOUT=5s\nNAME=app\n"))
+	assert.Nil(t, err)
+
+	schema, err := doc.Schema()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]*envsync.KeySchema{
+		"DATABASE_URL": {Required: true, Type: "url"},
+		"TIMEOUT":      {Type: "duration"},
+		"NAME":         {},
+	}, schema.Keys)
+
+	doc, _ = envsync.ParseDocument(strings.NewReader("# envsync:type color\nBG=red\n"))
+	_, err = doc.Schema()
+	assert.NotNil(t, err)
+}
+
+func TestValidate_Pattern(t *testing.T) {
+	schema, err := envsync.LoadSchema("testdata/schema.yaml")
+	assert.Nil(t, err)
+
+	dir := makeTree(t, map[string]string{
+		".env": "DATABASE_URL=mysql://localhost\nAPI_KEY=abc\n",
+	})
+	defer os.RemoveAll(dir)
+
+	err = envsync.Validate(filepath.Join(dir, ".env"), schema)
+	var verr *envsync.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []envsync.Violation{
+		{Key: "DATABASE_URL", Line: 1, Message: `"mysql://localhost" doesn't match "^postgres://"`},
+	}, verr.Violations)
+
+	_, err = envsync.ParseSchema(strings.NewReader("keys:\n  PORT:\n    pattern: \"[0-9\"\n"))
+	assert.NotNil(t, err)
+
+	doc, _ := envsync.ParseDocument(strings.NewReader("# envsync:pattern ^[a-z]+$\nNAME=app\n"))
+	schema, err = doc.Schema()
+	assert.Nil(t, err)
+	assert.Equal(t, "^[a-z]+$", schema.Keys["NAME"].Pattern)
+}
+
+func TestValidate_Enum(t *testing.T) {
+	schema, err := envsync.LoadSchema("testdata/schema.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2", "4"}, schema.Keys["WORKERS"].Enum)
+
+	dir := makeTree(t, map[string]string{
+		"warn.env":  "DATABASE_URL=postgres://localhost\nAPI_KEY=abc\nLOG_LEVEL=verbose\nWORKERS=2\n",
+		"error.env": "DATABASE_URL=postgres://localhost\nAPI_KEY=abc\nLOG_LEVEL=info\nWORKERS=3\n",
+	})
+	defer os.RemoveAll(dir)
+
+	violations, err := envsync.Violations(filepath.Join(dir, "warn.env"), schema)
+	assert.Nil(t, err)
+	assert.Equal(t, []envsync.Violation{
+		{Key: "LOG_LEVEL", Line: 3, Message: `"verbose" isn't one of debug, info, warn, error`, Warning: true},
+	}, violations)
+	assert.Equal(t, `warning: line 3: LOG_LEVEL: "verbose" isn't one of debug, info, warn, error`, violations[0].String())
+	assert.Nil(t, envsync.Validate(filepath.Join(dir, "warn.env"), schema))
+
+	err = envsync.Validate(filepath.Join(dir, "error.env"), schema)
+	assert.Contains(t, err.Error(), `line 4: WORKERS: "3" isn't one of 1, 2, 4`)
+
+	doc, _ := envsync.ParseDocument(strings.NewReader("# envsync:enum debug, info\nLOG_LEVEL=info\n"))
+	schema, err = doc.Schema()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"debug", "info"}, schema.Keys["LOG_LEVEL"].Enum)
+}
+
+func TestValidate_RequiredIf(t *testing.T) {
+	schema, err := envsync.LoadSchema("testdata/schema.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FEATURE_X=true"}, schema.Keys["FEATURE_X_URL"].RequiredIf)
+
+	dir := makeTree(t, map[string]string{
+		"on.env":  "DATABASE_URL=postgres://localhost\nAPI_KEY=abc\nFEATURE_X=true\nFEATURE_X_URL=\n",
+		"off.env": "DATABASE_URL=postgres://localhost\nAPI_KEY=abc\nFEATURE_X=false\n",
+	})
+	defer os.RemoveAll(dir)
+
+	err = envsync.Validate(filepath.Join(dir, "on.env"), schema)
+	var verr *envsync.ValidationError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, []envsync.Violation{
+		{Key: "FEATURE_X_URL", Line: 4, Message: "required env is empty while FEATURE_X=true"},
+	}, verr.Violations)
+	assert.Nil(t, envsync.Validate(filepath.Join(dir, "off.env"), schema))
+
+	schema, err = envsync.LoadSchema("testdata/schema.json")
+	assert.Nil(t, err)
+	err = envsync.Validate(filepath.Join(dir, "off.env"), schema)
+	assert.Contains(t, err.Error(), "DATABASE_PASSWORD: required env is missing while DATABASE_URL is set")
+
+	_, err = envsync.ParseSchema(strings.NewReader("keys:\n  URL:\n    required_if: [=true]\n"))
+	assert.NotNil(t, err)
+
+	doc, _ := envsync.ParseDocument(strings.NewReader("# envsync:required-if FEATURE_X=true, FEATURE_Y\nFEATURE_X_URL=\n"))
+	schema, err = doc.Schema()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FEATURE_X=true", "FEATURE_Y"}, schema.Keys["FEATURE_X_URL"].RequiredIf)
+}
@@ -0,0 +1,123 @@
+package envsync
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Default thresholds of SecretRule, in bits of Shannon entropy per character.
+// They are low enough to catch random tokens and high enough to let words and sample values through.
+const (
+	DefaultHexThreshold    = 3.0
+	DefaultBase64Threshold = 4.5
+)
+
+// secretMinLength is the length below which a value isn't checked for entropy.
+const secretMinLength = 20
+
+// credentialPatterns match well-known credential formats, mapped to what they are.
+var credentialPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"an AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA|AGPA|AIDA|AROA|AIPA|ANPA|ANVA|A3T[A-Z0-9])[A-Z0-9]{16}\b`)},
+	{"a JSON Web Token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"a GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+	{"a Slack token", regexp.MustCompile(`\bxox[abpors]-[A-Za-z0-9-]{10,}`)},
+}
+
+var (
+	hexValue    = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	base64Value = regexp.MustCompile(`^[A-Za-z0-9+/_-]+=*$`)
+)
+
+// SecretRule flags values that look like real secrets, so they aren't committed in a sample env, e.g. .env.example.
+// A value is flagged if it matches a known credential format, such as an AWS access key ID or a JSON Web Token,
+// or if it is a long hex or base64 string with a high entropy.
+type SecretRule struct {
+	// HexThreshold is the entropy from which a hex value is flagged.
+	// If it is 0, DefaultHexThreshold is used.
+	HexThreshold float64
+
+	// Base64Threshold is the entropy from which a base64 value is flagged.
+	// If it is 0, DefaultBase64Threshold is used.
+	Base64Threshold float64
+
+	// Ignore holds the keys whose values are never flagged, e.g. well-known test keys.
+	Ignore []string
+}
+
+// Name returns secret.
+func (r SecretRule) Name() string {
+	return "secret"
+}
+
+// Check returns a violation for each value in target that looks like a real secret.
+// Values referencing other env or holding templates aren't checked.
+func (r SecretRule) Check(target, source *Document) []Violation {
+	env := target.Env()
+	var violations []Violation
+	for _, k := range target.Keys() {
+		if contains(r.Ignore, k) {
+			continue
+		}
+		if msg := r.check(env[k]); msg != "" {
+			violations = append(violations, Violation{Key: k, Line: target.lineNumber(k), Message: msg})
+		}
+	}
+	return violations
+}
+
+// check returns why v looks like a real secret, or an empty string if it doesn't.
+func (r SecretRule) check(v string) string {
+	if strings.Contains(v, "${") || strings.Contains(v, "{{") {
+		return ""
+	}
+	for _, p := range credentialPatterns {
+		if p.re.MatchString(v) {
+			return fmt.Sprintf("value looks like %s", p.name)
+		}
+	}
+	if len(v) < secretMinLength {
+		return ""
+	}
+
+	threshold := 0.0
+	switch {
+	case hexValue.MatchString(v):
+		threshold = r.HexThreshold
+		if threshold == 0 {
+			threshold = DefaultHexThreshold
+		}
+	case base64Value.MatchString(v):
+		threshold = r.Base64Threshold
+		if threshold == 0 {
+			threshold = DefaultBase64Threshold
+		}
+	default:
+		return ""
+	}
+	if e := entropy(v); e >= threshold {
+		return fmt.Sprintf("value has a high entropy (%.2f bits per character) and looks like a secret", e)
+	}
+	return ""
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	e := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		e -= p * math.Log2(p)
+	}
+	return e
+}
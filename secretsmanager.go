@@ -0,0 +1,73 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// secretsManagerTarget is a secret of AWS Secrets Manager holding a JSON object as a Target,
+// e.g. secretsmanager://myapp/prod for the secret named myapp/prod: each key is a member of the object.
+// Numbers and booleans are kept as they are until their value changes, as in CodecJSON.
+// A secret that doesn't exist is an empty target, created once it is stored.
+type secretsManagerTarget struct {
+	name   string
+	id     string
+	client *awsClient
+	exists bool
+}
+
+func openSecretsManager(u *url.URL) (Target, error) {
+	id := strings.Trim(u.Host+u.Path, "/")
+	if id == "" {
+		return nil, fmt.Errorf("no secret in %s", u)
+	}
+	client, err := newAWSClient(u)
+	if err != nil {
+		return nil, err
+	}
+	return &secretsManagerTarget{name: u.String(), id: id, client: client}, nil
+}
+
+func (t *secretsManagerTarget) Name() string {
+	return t.name
+}
+
+func (t *secretsManagerTarget) Load(ctx context.Context) (*Document, error) {
+	var out struct {
+		SecretString *string `json:"SecretString"`
+	}
+	err := t.client.call(ctx, "secretsmanager", "secretsmanager.GetSecretValue", map[string]string{"SecretId": t.id}, &out)
+	if aerr, ok := err.(*awsError); ok && aerr.code == "ResourceNotFoundException" {
+		t.exists = false
+		return &Document{codec: CodecJSON}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read secret %s", t.id))
+	}
+	t.exists = true
+	if out.SecretString == nil {
+		return nil, fmt.Errorf("secret %s isn't a string", t.id)
+	}
+	doc, err := decodeJSON(strings.NewReader(*out.SecretString))
+	return doc, errors.Wrap(err, fmt.Sprintf("couldn't read secret %s", t.id))
+}
+
+// Store writes doc as a new version of the secret, or creates it if it didn't exist when it was loaded.
+func (t *secretsManagerTarget) Store(ctx context.Context, doc *Document) error {
+	payload := doc.recode(CodecJSON).String()
+	if !t.exists {
+		in := map[string]string{"Name": t.id, "SecretString": payload}
+		if err := t.client.call(ctx, "secretsmanager", "secretsmanager.CreateSecret", in, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't create secret %s", t.id))
+		}
+		t.exists = true
+		return nil
+	}
+	in := map[string]string{"SecretId": t.id, "SecretString": payload}
+	err := t.client.call(ctx, "secretsmanager", "secretsmanager.PutSecretValue", in, nil)
+	return errors.Wrap(err, fmt.Sprintf("couldn't write secret %s", t.id))
+}
@@ -0,0 +1,80 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecretsManager serves the secrets API of Secrets Manager from memory.
+type fakeSecretsManager struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (f *fakeSecretsManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var in map[string]string
+	json.NewDecoder(r.Body).Decode(&in)
+	notFound := func() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+	}
+	switch r.Header.Get("X-Amz-Target") {
+	case "secretsmanager.GetSecretValue":
+		s, ok := f.secrets[in["SecretId"]]
+		if !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"SecretString": s})
+	case "secretsmanager.PutSecretValue":
+		if _, ok := f.secrets[in["SecretId"]]; !ok {
+			notFound()
+			return
+		}
+		f.secrets[in["SecretId"]] = in["SecretString"]
+		w.Write([]byte("{}"))
+	case "secretsmanager.CreateSecret":
+		f.secrets[in["Name"]] = in["SecretString"]
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestSyncer_Sync_SecretsManager(t *testing.T) {
+	sm := &fakeSecretsManager{secrets: map[string]string{
+		"myapp/prod": `{"HOST": "example.com", "PORT": 80}`,
+	}}
+	server := httptest.NewServer(sm)
+	defer server.Close()
+	defer setenv(map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1", "AWS_ENDPOINT_URL": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\nPORT=8080\nDEBUG=false\n",
+	})
+	defer os.RemoveAll(dir)
+
+	// missing keys are added, and existing values are kept
+	syncer := envsync.New()
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "secretsmanager://myapp/prod")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+	assert.Equal(t, "{\n  \"HOST\": \"example.com\",\n  \"PORT\": 80,\n  \"DEBUG\": \"false\"\n}\n", sm.secrets["myapp/prod"])
+
+	// a missing secret is created
+	res, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "secretsmanager:///myapp/staging")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG", "HOST", "PORT"}, res.Added)
+	assert.Equal(t, "{\n  \"DEBUG\": \"false\",\n  \"HOST\": \"localhost\",\n  \"PORT\": \"8080\"\n}\n", sm.secrets["myapp/staging"])
+}
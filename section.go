@@ -0,0 +1,102 @@
+package envsync
+
+import "strings"
+
+// Markers of the managed section of target, e.g. # envsync:start.
+const (
+	sectionStart = "# " + annotationPrefix + "start"
+	sectionEnd   = "# " + annotationPrefix + "end"
+)
+
+// sectionBounds returns the indexes of the start and end markers of the managed section of d,
+// or -1 and -1 if there is none.
+func (d *Document) sectionBounds() (int, int, error) {
+	start, end := -1, -1
+	for i, l := range d.lines {
+		if l.kind != commentLine {
+			continue
+		}
+		switch strings.TrimSpace(l.raw) {
+		case sectionStart:
+			if start >= 0 {
+				return 0, 0, ErrUnbalancedSection
+			}
+			start = i
+		case sectionEnd:
+			if start < 0 || end >= 0 {
+				return 0, 0, ErrUnbalancedSection
+			}
+			end = i
+		}
+	}
+	if start >= 0 && end < 0 {
+		return 0, 0, ErrUnbalancedSection
+	}
+	return start, end, nil
+}
+
+// outsideKeys returns the keys of d outside of its managed section, or every key if there is none.
+func (d *Document) outsideKeys() ([]string, error) {
+	start, end, err := d.sectionBounds()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for i, l := range d.lines {
+		if l.kind == entryLine && (i < start || i > end) {
+			keys = append(keys, l.key)
+		}
+	}
+	return keys, nil
+}
+
+// managedSection returns the lines of the managed section of doc as a document,
+// after adding the markers at the end of doc if there is none,
+// and the function writing the lines of the returned document back between the markers.
+func managedSection(doc *Document) (*Document, func(), error) {
+	start, end, err := doc.sectionBounds()
+	if err != nil {
+		return nil, nil, err
+	}
+	if start < 0 {
+		if n := len(doc.lines); n > 0 && doc.lines[n-1].kind != blankLine {
+			doc.lines = append(doc.lines, &line{kind: blankLine})
+		}
+		doc.lines = append(doc.lines, &line{kind: commentLine, raw: sectionStart}, &line{kind: commentLine, raw: sectionEnd})
+		start, end = len(doc.lines)-2, len(doc.lines)-1
+	}
+
+	section := &Document{lines: append([]*line(nil), doc.lines[start+1:end]...)}
+	restore := func() {
+		lines := append([]*line(nil), doc.lines[:start+1]...)
+		lines = append(lines, section.lines...)
+		doc.lines = append(lines, doc.lines[end:]...)
+	}
+	return section, restore, nil
+}
+
+// laidOut reports whether doc needs no change but to its key-values:
+// it has a managed section if Managed is set, and the section, or doc, has its banner.
+func (s *Syncer) laidOut(doc *Document) bool {
+	if !s.Managed {
+		return s.hasBanner(doc)
+	}
+	start, end, err := doc.sectionBounds()
+	if err != nil || start < 0 {
+		return false
+	}
+	return s.hasBanner(&Document{lines: doc.lines[start+1 : end]})
+}
+
+// checkLayout returns ErrCommentsNotKept if Managed, Header, or Footer is set for a target in codec,
+// whose comments aren't read back: the section and the banner written in it wouldn't be found by the next sync,
+// which would add them again and leave every key outside of the section.
+func (s *Syncer) checkLayout(codec Codec) error {
+	if !s.Managed && s.Header == "" && s.Footer == "" {
+		return nil
+	}
+	if codec == CodecYAML || codec == CodecJSON {
+		return ErrCommentsNotKept
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package envsync_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Managed(t *testing.T) {
+	src := "HOST=localhost\nPORT=8080\nDEBUG=false\n"
+	syncer := envsync.New(envsync.WithManaged())
+
+	// keys outside of the section are left untouched, and missing keys are added inside it
+	var buf bytes.Buffer
+	tgt := "# by hand\nPORT=80\n\n# envsync:start\nHOST=example.com\n# envsync:end\n\nEXTRA=1\n"
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(tgt), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+	assert.Equal(t, "# by hand\nPORT=80\n\n# envsync:start\nHOST=example.com\nDEBUG=false\n# envsync:end\n\nEXTRA=1\n", buf.String())
+
+	// the section is appended to a target without one
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader("PORT=80\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG", "HOST"}, res.Added)
+	assert.Equal(t, "PORT=80\n\n# envsync:start\nDEBUG=false\nHOST=localhost\n# envsync:end\n", buf.String())
+
+	// pruning doesn't reach keys outside of the section
+	buf.Reset()
+	syncer = envsync.New(envsync.WithManaged(), envsync.WithPrune())
+	tgt = "EXTRA=1\n# envsync:start\nOLD=1\n# envsync:end\n"
+	res, err = syncer.SyncReaders(strings.NewReader("HOST=localhost\n"), strings.NewReader(tgt), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, "EXTRA=1\n# envsync:start\nHOST=localhost\n# envsync:end\n", buf.String())
+
+	for _, tgt := range []string{
+		"# envsync:start\nHOST=localhost\n",
+		"# envsync:end\n# envsync:start\n",
+		"# envsync:start\n# envsync:start\n# envsync:end\n",
+	} {
+		_, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(tgt), &buf)
+		assert.Equal(t, envsync.ErrUnbalancedSection, err, tgt)
+	}
+}
+
+func TestSyncer_Sync_ManagedCommentsNotKept(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"env.sample": "HOST=localhost\n",
+		"env.yml":    "PORT: 80\n",
+		"env.json":   "{\"PORT\": 80}\n",
+	})
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "env.sample")
+
+	// the markers and the banner would be lost once target is read again
+	for _, name := range []string{"env.yml", "env.json"} {
+		target := filepath.Join(dir, name)
+		before, _ := ioutil.ReadFile(target)
+		for _, opt := range []envsync.Option{envsync.WithManaged(), envsync.WithBanner("generated", "")} {
+			_, err := envsync.New(opt).SyncWithResult(source, target)
+			assert.True(t, errors.Is(err, envsync.ErrCommentsNotKept), name)
+			after, _ := ioutil.ReadFile(target)
+			assert.Equal(t, before, after, name)
+
+			_, err = envsync.New(opt).Init(context.Background(), source, filepath.Join(dir, "new"+filepath.Ext(name)))
+			assert.True(t, errors.Is(err, envsync.ErrCommentsNotKept), name)
+		}
+	}
+}
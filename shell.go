@@ -0,0 +1,197 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// shellAssignment matches the lines of a shell script assigning a variable, e.g. export KEY=value.
+var shellAssignment = regexp.MustCompile(`^\s*(export\s+)?[A-Za-z_][A-Za-z0-9_]*=`)
+
+// shellSpecial are the characters that make a value quoted in a shell script.
+const shellSpecial = " \t\n\r\"'\\$`;&|<>()*?[]#~!{}"
+
+// decodeShell reads the variables a shell script assigns, e.g. export KEY="value" in a .envrc file,
+// its comments, and blank lines. Values are read like the shell does, without expanding them.
+// Any other line, e.g. a command, is kept as it is.
+func decodeShell(r io.Reader) (*Document, error) {
+	doc := &Document{codec: CodecShell}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		start, text := n, sc.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+			continue
+		case trimmed[0] == '#':
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+			continue
+		case !shellAssignment.MatchString(text):
+			doc.lines = append(doc.lines, &line{kind: rawLine, raw: text})
+			continue
+		}
+
+		l, err := parseShellLine(text)
+		for (err == ErrUnterminatedQuote || err == errContinued) && sc.Scan() {
+			n++
+			text += "\n" + sc.Text()
+			l, err = parseShellLine(text)
+		}
+		if err != nil {
+			return doc, &ParseError{Line: start, Text: text, Err: err}
+		}
+		doc.lines = append(doc.lines, l)
+	}
+	return doc, sc.Err()
+}
+
+// parseShellLine parses a KEY=value assignment, optionally exported, spanning the lines of text.
+// The value is a single shell word: outside of quotes, a backslash escapes the next character and whitespace ends it.
+// In double quotes, a backslash only escapes ", \, `, $, and newlines, and in single quotes, nothing.
+// The word may be followed by a comment.
+func parseShellLine(text string) (*line, error) {
+	if !shellAssignment.MatchString(text) {
+		return nil, ErrMissingSeparator
+	}
+	l := &line{kind: entryLine, raw: text}
+	rest := strings.TrimLeft(text, " \t")
+	if strings.HasPrefix(rest, "export") && strings.IndexAny(rest, " \t") == len("export") {
+		l.export, rest = true, strings.TrimLeft(rest[len("export"):], " \t")
+	}
+	i := strings.IndexByte(rest, '=')
+	l.key, rest = rest[:i], rest[i+1:]
+
+	value, n, err := shellWord(rest)
+	if err != nil {
+		return nil, err
+	}
+	l.value = value
+	if word := rest[:n]; len(word) > 1 && (word[0] == singleQuote || word[0] == doubleQuote) && word[len(word)-1] == word[0] {
+		l.quote = word[0]
+	}
+
+	if l.comment, err = trailingComment(rest[n:]); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// shellWord returns the value of the shell word at the start of rest, ended by whitespace outside of quotes, and its length.
+func shellWord(rest string) (string, int, error) {
+	var b strings.Builder
+	i := 0
+	for i < len(rest) && rest[i] != ' ' && rest[i] != '\t' {
+		var err error
+		switch rest[i] {
+		case singleQuote:
+			i, err = shellSingleQuoted(&b, rest, i+1)
+		case doubleQuote:
+			i, err = shellDoubleQuoted(&b, rest, i+1)
+		case '\\':
+			i, err = shellEscape(&b, rest, i+1, noQuote)
+		default:
+			b.WriteByte(rest[i])
+			i++
+		}
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	return b.String(), i, nil
+}
+
+// shellSingleQuoted writes the single-quoted text starting at rest[i] to b as it is,
+// and returns the index following its closing quote.
+func shellSingleQuoted(b *strings.Builder, rest string, i int) (int, error) {
+	end := strings.IndexByte(rest[i:], singleQuote)
+	if end < 0 {
+		return i, ErrUnterminatedQuote
+	}
+	b.WriteString(rest[i : i+end])
+	return i + end + 1, nil
+}
+
+// shellDoubleQuoted writes the double-quoted text starting at rest[i] to b without its escapes,
+// and returns the index following its closing quote.
+func shellDoubleQuoted(b *strings.Builder, rest string, i int) (int, error) {
+	for i < len(rest) {
+		switch c := rest[i]; c {
+		case doubleQuote:
+			return i + 1, nil
+		case '\\':
+			var err error
+			if i, err = shellEscape(b, rest, i+1, doubleQuote); err != nil {
+				return i, err
+			}
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return i, ErrUnterminatedQuote
+}
+
+// shellEscape writes the character escaped by the backslash before rest[i], within quote, to b,
+// and returns the index following it. A backslash followed by a newline is removed,
+// and one ending rest outside of quotes returns errContinued, since the line goes on on the next one.
+func shellEscape(b *strings.Builder, rest string, i int, quote byte) (int, error) {
+	switch {
+	case i == len(rest) && quote == noQuote:
+		return i, errContinued
+	case i == len(rest):
+		return i, ErrUnterminatedQuote
+	case rest[i] == '\n':
+	case quote == doubleQuote && !strings.ContainsRune(systemdEscaped, rune(rest[i])):
+		b.WriteByte('\\')
+		b.WriteByte(rest[i])
+	default:
+		b.WriteByte(rest[i])
+	}
+	return i + 1, nil
+}
+
+// encodeShell writes d to buf as a shell script.
+// Lines that already hold the same key-value are written as they are,
+// and others as KEY=value, prefixed with export if they are exported,
+// and quoted as they are read if the shell would read the value otherwise.
+func (d *Document) encodeShell(buf *bytes.Buffer) {
+	for _, l := range d.lines {
+		text := l.raw
+		if l.kind == entryLine {
+			if p, err := parseShellLine(l.raw); l.raw == "" || err != nil || p.key != l.key || p.value != l.value || p.export != l.export {
+				text = l.key + separator + shellQuote(l.value, l.quote) + l.comment
+				if l.export {
+					text = exportPrefix + text
+				}
+			}
+		}
+		buf.WriteString(text + "\n")
+	}
+}
+
+// shellQuote returns value quoted with quote, or with the quotes the shell reads it back with if quote can't hold it.
+func shellQuote(value string, quote byte) string {
+	plain := !strings.ContainsAny(value, shellSpecial)
+	switch {
+	case quote == noQuote && plain:
+		return value
+	case quote != doubleQuote && strings.IndexByte(value, singleQuote) < 0:
+		return "'" + value + "'"
+	}
+	var b strings.Builder
+	b.WriteByte(doubleQuote)
+	for i := 0; i < len(value); i++ {
+		if strings.IndexByte(systemdEscaped, value[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte(doubleQuote)
+	return b.String()
+}
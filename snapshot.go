@@ -0,0 +1,67 @@
+package envsync
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// stateDir is the directory next to target where envsync keeps its state.
+	stateDir = ".envsync"
+
+	// snapshotSuffix is the suffix of the snapshot of the source last synchronized into target.
+	snapshotSuffix = ".base"
+)
+
+// snapshotPath returns the path of the snapshot of the source last synchronized into target.
+func snapshotPath(target string) string {
+	return filepath.Join(filepath.Dir(target), stateDir, filepath.Base(target)+snapshotSuffix)
+}
+
+// readSnapshot returns the source env last synchronized into target.
+// It returns nil if there isn't any snapshot yet.
+func readSnapshot(target string) (Env, error) {
+	file, err := os.Open(snapshotPath(target))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't open snapshot file")
+	}
+	defer file.Close()
+
+	env, err := Parse(file)
+	return env, errors.Wrap(err, "couldn't read snapshot file")
+}
+
+// keepsSnapshot reports whether the snapshot of target, read with codec, is kept.
+// A snapshot is in plain text, so it isn't kept for a target written encrypted with age or by SOPS.
+func (s *Syncer) keepsSnapshot(target string, codec Codec) bool {
+	return len(s.AgeRecipients) == 0 && !isAgeFile(target) && !isSOPSFile(target, codec)
+}
+
+// writeSnapshot records env as the source env last synchronized into target.
+// The snapshot holds the values of the source, so it is only readable by the user.
+func writeSnapshot(target string, env Env) error {
+	name := snapshotPath(target)
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return errors.Wrap(err, "couldn't create snapshot directory")
+	}
+
+	err := replaceFile(name, 0600, func(w io.Writer) error {
+		return Write(w, env)
+	})
+	return errors.Wrap(err, "couldn't write snapshot file")
+}
+
+// removeSnapshot removes the snapshot of target if there is one.
+func removeSnapshot(target string) error {
+	err := os.Remove(snapshotPath(target))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "couldn't remove snapshot file")
+	}
+	return nil
+}
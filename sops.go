@@ -0,0 +1,125 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sopsTypes are the codecs of the files SOPS encrypts, and the names SOPS gives them.
+var sopsTypes = map[Codec]string{
+	CodecDotenv: "dotenv",
+	CodecYAML:   "yaml",
+	CodecJSON:   "json",
+	CodecINI:    "ini",
+}
+
+// sopsMarkers start the line of the metadata SOPS keeps in the files it encrypts, by codec.
+var sopsMarkers = map[Codec]string{
+	CodecDotenv: "sops_mac=",
+	CodecYAML:   "sops:",
+	CodecINI:    "[sops]",
+}
+
+// isSOPS reports whether b is a file encrypted by SOPS, which keeps its metadata in the file under the sops key.
+func isSOPS(b []byte, codec Codec) bool {
+	switch codec {
+	case CodecDotenv, CodecYAML, CodecINI:
+		scanner := bufio.NewScanner(bytes.NewReader(b))
+		scanner.Buffer(nil, len(b)+1)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), sopsMarkers[codec]) {
+				return true
+			}
+		}
+	case CodecJSON:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(b, &object) == nil {
+			_, ok := object["sops"]
+			return ok
+		}
+	}
+	return false
+}
+
+// isSOPSFile reports whether the file at name is encrypted by SOPS.
+// A file that can't be read isn't.
+func isSOPSFile(name string, codec Codec) bool {
+	if _, ok := sopsTypes[codec]; !ok {
+		return false
+	}
+	b, err := ioutil.ReadFile(name)
+	return err == nil && isSOPS(b, codec)
+}
+
+// decryptSOPS returns the content of the file at name decrypted by sops.
+func decryptSOPS(ctx context.Context, name string, codec Codec) ([]byte, error) {
+	typ := sopsTypes[codec]
+	out, err := runSOPS(ctx, "--decrypt", "--input-type", typ, "--output-type", typ, name)
+	return out, errors.Wrap(err, "couldn't decrypt with sops")
+}
+
+// encryptSOPS returns plain encrypted by sops with the creation rules of .sops.yaml for the file at name.
+// The plain text is given to sops in a temporary file only the user can read, as sops doesn't read it from its input.
+func encryptSOPS(ctx context.Context, name string, codec Codec, plain []byte) ([]byte, error) {
+	file, err := ioutil.TempFile("", "envsync-sops-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(plain)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	typ := sopsTypes[codec]
+	out, err := runSOPS(ctx, "--encrypt", "--filename-override", name, "--input-type", typ, "--output-type", typ, file.Name())
+	return out, errors.Wrap(err, "couldn't encrypt with sops")
+}
+
+// runSOPS runs the sops command with args and returns its output, or its error message.
+func runSOPS(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sops", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrap(err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// writeSOPSFile replaces the content of the file at name with doc encrypted by sops.
+func writeSOPSFile(name string, doc *Document) error {
+	name, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	var plain bytes.Buffer
+	if err := doc.write(&plain); err != nil {
+		return err
+	}
+	encrypted, err := encryptSOPS(context.Background(), name, doc.codec, plain.Bytes())
+	if err != nil {
+		return err
+	}
+	return replaceFile(name, 0644, func(w io.Writer) error {
+		_, err := w.Write(encrypted)
+		return err
+	})
+}
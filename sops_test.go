@@ -0,0 +1,66 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSOPS is a sops command that "encrypts" the values of dotenv files as ENC[value],
+// with creation rules for .env files only.
+const fakeSOPS = `#!/bin/sh
+for file; do :; done
+case "$1" in
+--decrypt)
+	sed -e '/^sops_/d' -e 's/=ENC\[\(.*\)\]$/=\1/' "$file" ;;
+--encrypt)
+	case "$3" in
+	*.env) ;;
+	*) echo "error loading config: no matching creation rules found" >&2; exit 1 ;;
+	esac
+	sed -e 's/=\(.*\)$/=ENC[\1]/' "$file"
+	echo 'sops_mac=ENC[mac]' ;;
+esac
+`
+
+func TestSyncer_Sync_SOPS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops is a shell script")
+	}
+	encrypted := "A=ENC[old]\nsops_version=3.8.1\nsops_mac=ENC[mac]\n"
+	dir := makeTree(t, map[string]string{
+		"bin/sops":    fakeSOPS,
+		"env.sample":  "A=1\nB=2\n",
+		".env":        encrypted,
+		"secrets.txt": encrypted,
+		"plain.env":   "",
+	})
+	defer os.RemoveAll(dir)
+	os.Chmod(filepath.Join(dir, "bin", "sops"), 0755)
+	defer setenv(map[string]string{"PATH": filepath.Join(dir, "bin") + string(os.PathListSeparator) + os.Getenv("PATH")})()
+
+	// the target is decrypted to be synced, and encrypted again
+	res, err := envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), filepath.Join(dir, ".env"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"B"}, res.Added)
+	b, _ := ioutil.ReadFile(filepath.Join(dir, ".env"))
+	assert.Equal(t, "A=ENC[old]\nB=ENC[2]\nsops_mac=ENC[mac]\n", string(b))
+
+	// an encrypted source is decrypted, and a plain target stays plain
+	res, err = envsync.New().SyncWithResult(filepath.Join(dir, ".env"), filepath.Join(dir, "plain.env"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B"}, res.Added)
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "plain.env"))
+	assert.Contains(t, string(b), "A=old\nB=2\n")
+
+	_, err = envsync.New().SyncWithResult(filepath.Join(dir, "env.sample"), filepath.Join(dir, "secrets.txt"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "couldn't encrypt with sops: error loading config: no matching creation rules found")
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "secrets.txt"))
+	assert.Equal(t, encrypted, string(b))
+}
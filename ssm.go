@@ -0,0 +1,146 @@
+package envsync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ssmBatchSize is the most parameters DeleteParameters removes at once.
+const ssmBatchSize = 10
+
+// ssmTarget is the AWS Systems Manager Parameter Store as a Target, e.g. ssm:///myapp/prod/:
+// each key is a parameter under the path, e.g. /myapp/prod/DB_HOST for DB_HOST.
+//
+// Parameters are read decrypted. An added parameter is a SecureString if its key matches
+// the secure query parameter, e.g. ?secure=*_KEY,*_PASSWORD, or DefaultMaskPatterns, and a String otherwise;
+// a changed parameter keeps its type. SSM has no empty parameters, so a key with an empty value is ErrEmptyValue.
+type ssmTarget struct {
+	name   string
+	path   string
+	secure *Masker
+	client *awsClient
+
+	// types are the types of the parameters, by key, as they are loaded.
+	types map[string]string
+	env   Env
+}
+
+type ssmParameter struct {
+	Name  string `json:"Name"`
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+func openSSM(u *url.URL) (Target, error) {
+	path := "/" + strings.Trim(u.Host+u.Path, "/") + "/"
+	if path == "//" {
+		path = "/"
+	}
+	var patterns []string
+	if secure := u.Query().Get("secure"); secure != "" {
+		patterns = strings.Split(secure, ",")
+	}
+	secure, err := NewMasker(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newAWSClient(u)
+	if err != nil {
+		return nil, err
+	}
+	return &ssmTarget{name: u.String(), path: path, secure: secure, client: client}, nil
+}
+
+func (t *ssmTarget) Name() string {
+	return t.name
+}
+
+func (t *ssmTarget) Load(ctx context.Context) (*Document, error) {
+	t.types = make(map[string]string)
+	t.env = Env{}
+	in := map[string]interface{}{"Path": t.path, "WithDecryption": true}
+	for {
+		var out struct {
+			Parameters []ssmParameter `json:"Parameters"`
+			NextToken  string         `json:"NextToken"`
+		}
+		if err := t.client.call(ctx, "ssm", "AmazonSSM.GetParametersByPath", in, &out); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("couldn't read parameters under %s", t.path))
+		}
+		for _, p := range out.Parameters {
+			key := strings.TrimPrefix(p.Name, t.path)
+			t.types[key] = p.Type
+			t.env[key] = p.Value
+		}
+		if out.NextToken == "" {
+			break
+		}
+		in["NextToken"] = out.NextToken
+	}
+	return envDocument(t.env), nil
+}
+
+// Store puts the parameters of doc that are added or changed since the target is loaded,
+// and deletes the ones that aren't in doc anymore.
+func (t *ssmTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	var puts []string
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		// SSM rejects an empty value, so nothing is written rather than part of the keys
+		if env[k] == "" {
+			return errors.Wrap(ErrEmptyValue, fmt.Sprintf("couldn't put parameter %s, SSM doesn't store empty values", t.path+k))
+		}
+		puts = append(puts, k)
+	}
+	for _, k := range puts {
+		typ, ok := t.types[k]
+		if !ok {
+			typ = "String"
+			if t.secure.Secret(k) {
+				typ = "SecureString"
+			}
+		}
+		in := map[string]interface{}{"Name": t.path + k, "Value": env[k], "Type": typ, "Overwrite": true}
+		if err := t.client.call(ctx, "ssm", "AmazonSSM.PutParameter", in, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't put parameter %s", t.path+k))
+		}
+		t.env[k], t.types[k] = env[k], typ
+	}
+
+	var removed []string
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; !ok {
+			removed = append(removed, t.path+k)
+		}
+	}
+	for len(removed) > 0 {
+		n := len(removed)
+		if n > ssmBatchSize {
+			n = ssmBatchSize
+		}
+		in := map[string]interface{}{"Names": removed[:n]}
+		if err := t.client.call(ctx, "ssm", "AmazonSSM.DeleteParameters", in, nil); err != nil {
+			return errors.Wrap(err, "couldn't delete parameters")
+		}
+		for _, name := range removed[:n] {
+			key := strings.TrimPrefix(name, t.path)
+			delete(t.env, key)
+			delete(t.types, key)
+		}
+		removed = removed[n:]
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSSM serves the parameters API of SSM from memory.
+type fakeSSM struct {
+	mu     sync.Mutex
+	params map[string]map[string]string
+}
+
+func (f *fakeSSM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var in map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&in)
+	switch r.Header.Get("X-Amz-Target") {
+	case "AmazonSSM.GetParametersByPath":
+		var names []string
+		for name := range f.params {
+			if strings.HasPrefix(name, in["Path"].(string)) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var params []map[string]string
+		for _, name := range names {
+			params = append(params, f.params[name])
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Parameters": params})
+	case "AmazonSSM.PutParameter":
+		name := in["Name"].(string)
+		if in["Value"] == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ValidationException", "message": "1 validation error detected: Value '' at 'value' failed to satisfy constraint: Member must have length greater than or equal to 1"}`))
+			return
+		}
+		f.params[name] = map[string]string{"Name": name, "Value": in["Value"].(string), "Type": in["Type"].(string)}
+		w.Write([]byte("{}"))
+	case "AmazonSSM.DeleteParameters":
+		for _, name := range in["Names"].([]interface{}) {
+			delete(f.params, name.(string))
+		}
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type": "com.amazonaws#InvalidAction", "message": "unknown action"}`))
+	}
+}
+
+// setenv sets the environment variables of env until the returned function is called.
+func setenv(env map[string]string) func() {
+	old := make(map[string]string)
+	for k, v := range env {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = prev
+		}
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k := range env {
+			if prev, ok := old[k]; ok {
+				os.Setenv(k, prev)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}
+}
+
+func TestSyncer_Sync_SSM(t *testing.T) {
+	ssm := &fakeSSM{params: map[string]map[string]string{
+		"/myapp/prod/HOST":   {"Name": "/myapp/prod/HOST", "Value": "example.com", "Type": "String"},
+		"/myapp/prod/OLD":    {"Name": "/myapp/prod/OLD", "Value": "x", "Type": "String"},
+		"/other/DB_PASSWORD": {"Name": "/other/DB_PASSWORD", "Value": "s3cret", "Type": "SecureString"},
+	}}
+	server := httptest.NewServer(ssm)
+	defer server.Close()
+	defer setenv(map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1", "AWS_ENDPOINT_URL": server.URL})()
+
+	dir := makeTree(t, map[string]string{
+		"env.sample":   "HOST=localhost\nDB_PASSWORD=changeme\nPORT=8080\n",
+		"empty.sample": "API_KEY=\nDEBUG=false\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "ssm:///myapp/prod/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD", "PORT"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, map[string]map[string]string{
+		"/myapp/prod/HOST":        {"Name": "/myapp/prod/HOST", "Value": "example.com", "Type": "String"},
+		"/myapp/prod/DB_PASSWORD": {"Name": "/myapp/prod/DB_PASSWORD", "Value": "changeme", "Type": "SecureString"},
+		"/myapp/prod/PORT":        {"Name": "/myapp/prod/PORT", "Value": "8080", "Type": "String"},
+		"/other/DB_PASSWORD":      {"Name": "/other/DB_PASSWORD", "Value": "s3cret", "Type": "SecureString"},
+	}, ssm.params)
+
+	// a key with an empty value fails the sync before any parameter is put
+	_, err = envsync.New().SyncWithResult(filepath.Join(dir, "empty.sample"), "ssm:///empty/")
+	assert.True(t, errors.Is(err, envsync.ErrEmptyValue))
+	assert.Contains(t, err.Error(), "couldn't put parameter /empty/API_KEY")
+	assert.NotContains(t, ssm.params, "/empty/DEBUG")
+
+	// parameters are a source too
+	diff, err := syncer.Diff("ssm:///other", filepath.Join(dir, "env.sample"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD"}, diff.Changed)
+}
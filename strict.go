@@ -0,0 +1,71 @@
+package envsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Strict describes how keys in target that aren't in source are reported.
+// Such keys are often typos, e.g. DATABSE_URL, that would linger unnoticed.
+// Keys removed by Prune are never reported.
+type Strict int
+
+const (
+	// StrictOff doesn't report anything.
+	// This is the default.
+	StrictOff Strict = iota
+
+	// StrictWarn reports each key to Logger.
+	StrictWarn
+
+	// StrictError makes the synchronization fail with ErrExtraEnv.
+	StrictError
+)
+
+var strictNames = map[Strict]string{
+	StrictOff:   "off",
+	StrictWarn:  "warn",
+	StrictError: "error",
+}
+
+// String returns the name of the strict mode.
+func (s Strict) String() string {
+	if name, ok := strictNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Strict(%d)", int(s))
+}
+
+// ParseStrict returns the strict mode with the given name.
+// Valid names are "off", "warn", and "error".
+func ParseStrict(name string) (Strict, error) {
+	for s, n := range strictNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return StrictOff, fmt.Errorf("unknown strict mode: %s", name)
+}
+
+// check reports the keys in extra following the strict mode.
+func (s Strict) check(extra map[string]string, logger Logger) error {
+	if len(extra) == 0 {
+		return nil
+	}
+
+	keys := sortedKeys(extra)
+	switch s {
+	case StrictWarn:
+		if logger == nil {
+			return nil
+		}
+		for _, k := range keys {
+			logger.Printf("Env not in source: %s", k)
+		}
+	case StrictError:
+		return errors.Wrap(ErrExtraEnv, strings.Join(keys, ", "))
+	}
+	return nil
+}
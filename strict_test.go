@@ -0,0 +1,51 @@
+package envsync_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Strict(t *testing.T) {
+	src := "DATABASE_URL=postgres://localhost\nPORT=8080\n"
+	dst := "DATABSE_URL=postgres://example.com\nPORT=80\n"
+
+	var logs bytes.Buffer
+	syncer := envsync.New(envsync.WithStrict(envsync.StrictWarn), envsync.WithLogger(log.New(&logs, "", 0)))
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DATABSE_URL"}, res.Extra)
+	assert.Contains(t, logs.String(), "Env not in source: DATABSE_URL\n")
+
+	syncer = envsync.New(envsync.WithStrict(envsync.StrictError))
+	buf.Reset()
+	_, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.True(t, errors.Is(err, envsync.ErrExtraEnv))
+	assert.Contains(t, err.Error(), "DATABSE_URL")
+	assert.Empty(t, buf.String())
+
+	syncer = envsync.New(envsync.WithStrict(envsync.StrictError), envsync.WithPrune())
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Extra)
+	assert.Equal(t, []string{"DATABSE_URL"}, res.Pruned)
+}
+
+func TestParseStrict(t *testing.T) {
+	for _, st := range []envsync.Strict{envsync.StrictOff, envsync.StrictWarn, envsync.StrictError} {
+		parsed, err := envsync.ParseStrict(st.String())
+		assert.Nil(t, err)
+		assert.Equal(t, st, parsed)
+	}
+
+	_, err := envsync.ParseStrict("loud")
+	assert.NotNil(t, err)
+	assert.Equal(t, "Strict(7)", envsync.Strict(7).String())
+}
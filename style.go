@@ -0,0 +1,395 @@
+package envsync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Quoting describes how Format quotes values.
+type Quoting int
+
+const (
+	// QuotingKeep keeps the quotes of each value. This is the default.
+	QuotingKeep Quoting = iota
+
+	// QuotingMinimal unquotes every value that can be read back without quotes,
+	// and double-quotes the other ones.
+	QuotingMinimal
+
+	// QuotingDouble double-quotes every value.
+	QuotingDouble
+
+	// QuotingSingle single-quotes every value, or double-quotes it if it holds a single quote or a newline.
+	QuotingSingle
+)
+
+var quotingNames = map[Quoting]string{
+	QuotingKeep:    "keep",
+	QuotingMinimal: "minimal",
+	QuotingDouble:  "double",
+	QuotingSingle:  "single",
+}
+
+// String returns the name of the quoting.
+func (q Quoting) String() string {
+	if name, ok := quotingNames[q]; ok {
+		return name
+	}
+	return fmt.Sprintf("Quoting(%d)", int(q))
+}
+
+// ParseQuoting returns the quoting with the given name.
+// Valid names are "keep", "minimal", "double", and "single".
+func ParseQuoting(name string) (Quoting, error) {
+	for q, n := range quotingNames {
+		if n == name {
+			return q, nil
+		}
+	}
+	return QuotingKeep, fmt.Errorf("unknown quoting: %s", name)
+}
+
+// quote returns the quote of a value quoted with quote before formatting.
+func (q Quoting) quote(quote byte) byte {
+	switch q {
+	case QuotingMinimal:
+		return noQuote
+	case QuotingDouble:
+		return doubleQuote
+	case QuotingSingle:
+		return singleQuote
+	}
+	return quote
+}
+
+// Layout describes how Format orders key-values.
+type Layout int
+
+const (
+	// LayoutKeep keeps key-values where they are. This is the default.
+	LayoutKeep Layout = iota
+
+	// LayoutSorted sorts key-values by key, each with the comment lines directly above it.
+	// Comment lines separated from any key-value by a blank line are moved to the top.
+	LayoutSorted
+
+	// LayoutGrouped sorts key-values like LayoutSorted, in groups decided by Style.Grouping,
+	// e.g. DB_HOST and DB_PORT sharing the prefix DB. Each group is separated by a blank line and headed by a comment naming it,
+	// unless Style.NoHeadings is set.
+	// Keys in no group come first, without a heading.
+	LayoutGrouped
+)
+
+var layoutNames = map[Layout]string{
+	LayoutKeep:    "keep",
+	LayoutSorted:  "sorted",
+	LayoutGrouped: "grouped",
+}
+
+// String returns the name of the layout.
+func (l Layout) String() string {
+	if name, ok := layoutNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Layout(%d)", int(l))
+}
+
+// ParseLayout returns the layout with the given name.
+// Valid names are "keep", "sorted", and "grouped".
+func ParseLayout(name string) (Layout, error) {
+	for l, n := range layoutNames {
+		if n == name {
+			return l, nil
+		}
+	}
+	return LayoutKeep, fmt.Errorf("unknown layout: %s", name)
+}
+
+// Grouping describes how LayoutGrouped groups key-values.
+type Grouping int
+
+const (
+	// GroupingPrefix groups keys sharing the prefix made of their first Style.PrefixDepth words,
+	// e.g. DB for DB_HOST with a depth of 1, or AWS_S3 for AWS_S3_BUCKET with a depth of 2.
+	// Keys with no more words than the depth are in no group. This is the default.
+	GroupingPrefix Grouping = iota
+
+	// GroupingExplicit groups keys matching the patterns of each of Style.Groups, in their order.
+	GroupingExplicit
+
+	// GroupingSource groups keys like the sections of Style.Source, in their order.
+	// A section starts at a comment line following a blank line, or starting the file, e.g. # Database,
+	// and holds the key-values up to the next section.
+	GroupingSource
+
+	// GroupingNone puts keys in no group, so LayoutGrouped is the same as LayoutSorted.
+	GroupingNone
+)
+
+var groupingNames = map[Grouping]string{
+	GroupingPrefix:   "prefix",
+	GroupingExplicit: "explicit",
+	GroupingSource:   "source",
+	GroupingNone:     "none",
+}
+
+// String returns the name of the grouping.
+func (g Grouping) String() string {
+	if name, ok := groupingNames[g]; ok {
+		return name
+	}
+	return fmt.Sprintf("Grouping(%d)", int(g))
+}
+
+// ParseGrouping returns the grouping with the given name.
+// Valid names are "prefix", "explicit", "source", and "none".
+func ParseGrouping(name string) (Grouping, error) {
+	for g, n := range groupingNames {
+		if n == name {
+			return g, nil
+		}
+	}
+	return GroupingPrefix, fmt.Errorf("unknown grouping: %s", name)
+}
+
+// Group is a group of keys declared for GroupingExplicit.
+type Group struct {
+	// Name heads the group, e.g. Database.
+	Name string
+
+	// Keys are the patterns of the keys in the group, with the same syntax as Syncer.Include.
+	// A key matching several groups is in the first one.
+	Keys []string
+}
+
+// Style describes how Format lays out an env file.
+// Whatever the style, Format trims whitespace around keys, unquoted values, and comments,
+// collapses blank lines, and writes line endings as \n.
+type Style struct {
+	// Quoting describes how values are quoted.
+	Quoting Quoting
+
+	// Layout describes how key-values are ordered.
+	Layout Layout
+
+	// Grouping describes how LayoutGrouped groups key-values.
+	Grouping Grouping
+
+	// PrefixDepth is the number of words of the prefix shared by the keys of a group with GroupingPrefix.
+	// If it is 0, it is 1.
+	PrefixDepth int
+
+	// Groups are the groups of GroupingExplicit.
+	Groups []Group
+
+	// Source is the document whose sections are the groups of GroupingSource, usually the sample env.
+	Source *Document
+
+	// NoHeadings makes LayoutGrouped separate groups by a blank line only, without a comment naming them.
+	// Comments naming a group are then left as they are.
+	NoHeadings bool
+}
+
+// block is a key-value with the comment lines directly above it.
+type block struct {
+	comments []*line
+	entry    *line
+}
+
+// apply lays out doc following the style.
+func (st Style) apply(doc *Document) error {
+	for _, l := range doc.lines {
+		switch l.kind {
+		case commentLine:
+			l.raw = strings.TrimRight(l.raw, " \t")
+		case entryLine:
+			// a trimmed key already declared is left as it is, so no key is declared twice
+			if key := strings.TrimSpace(l.key); key != l.key && doc.index(key) < 0 {
+				l.key = key
+			}
+			if l.quote == noQuote {
+				l.value = strings.TrimSpace(l.value)
+			}
+			l.quote = st.Quoting.quote(l.quote)
+			l.comment = strings.TrimRight(l.comment, " \t")
+			l.raw = ""
+		}
+	}
+
+	if st.Layout != LayoutKeep {
+		var header []*line
+		var blocks []block
+		var pending []*line
+		for _, l := range doc.lines {
+			switch l.kind {
+			case commentLine:
+				pending = append(pending, l)
+			case blankLine:
+				header, pending = append(header, pending...), nil
+			case sectionLine, rawLine:
+				// an INI section is kept, since encodeINI writes key-values in their section whatever their order,
+				// and so is any line that isn't read, e.g. a command in a shell script
+				header, pending = append(append(header, pending...), l), nil
+			case entryLine:
+				blocks, pending = append(blocks, block{comments: pending, entry: l}), nil
+			}
+		}
+		header = append(header, pending...)
+		sort.SliceStable(blocks, func(i, j int) bool {
+			return blocks[i].entry.key < blocks[j].entry.key
+		})
+
+		if st.Layout == LayoutGrouped {
+			group, order, err := st.grouper()
+			if err != nil {
+				return err
+			}
+			doc.lines = groupBlocks(header, blocks, group, order, !st.NoHeadings)
+		} else {
+			doc.lines = header
+			doc.lines = append(doc.lines, &line{kind: blankLine})
+			for _, b := range blocks {
+				doc.lines = append(append(doc.lines, b.comments...), b.entry)
+			}
+		}
+	}
+	doc.lines = collapseBlankLines(doc.lines)
+	return nil
+}
+
+// grouper returns the function giving the name of the group of a key following Grouping,
+// or an empty string if the key is in no group, and the names of the groups in their order, or nil to sort them by name.
+func (st Style) grouper() (func(key string) string, []string, error) {
+	switch st.Grouping {
+	case GroupingExplicit:
+		var names []string
+		var matchers [][]func(string) bool
+		for _, g := range st.Groups {
+			var ms []func(string) bool
+			for _, p := range g.Keys {
+				m, err := compilePattern(p)
+				if err != nil {
+					return nil, nil, errors.Wrap(err, "invalid group "+g.Name)
+				}
+				ms = append(ms, m)
+			}
+			names, matchers = append(names, g.Name), append(matchers, ms)
+		}
+		return func(key string) string {
+			for i, ms := range matchers {
+				for _, m := range ms {
+					if m(key) {
+						return names[i]
+					}
+				}
+			}
+			return ""
+		}, names, nil
+	case GroupingSource:
+		var names []string
+		sections := make(map[string]string)
+		if st.Source != nil {
+			section := ""
+			for i, l := range st.Source.lines {
+				switch {
+				case l.kind == commentLine && (i == 0 || st.Source.lines[i-1].kind == blankLine):
+					section = strings.TrimSpace(strings.TrimPrefix(l.raw, "#"))
+					names = append(names, section)
+				case l.kind == entryLine:
+					sections[l.key] = section
+				}
+			}
+		}
+		return func(key string) string {
+			return sections[key]
+		}, names, nil
+	case GroupingNone:
+		return func(string) string {
+			return ""
+		}, nil, nil
+	}
+
+	depth := st.PrefixDepth
+	if depth <= 0 {
+		depth = 1
+	}
+	return func(key string) string {
+		words := strings.Split(key, "_")
+		if len(words) <= depth {
+			return ""
+		}
+		return strings.Join(words[:depth], "_")
+	}, nil, nil
+}
+
+// groupBlocks returns the lines of header followed by blocks, sorted by key, in the groups given by group.
+// Groups are ordered like order, or by name if order is nil. Keys in no group come first.
+// Each group is headed by a comment naming it if headings is set,
+// and headings of groups left by a previous run are dropped, so they aren't repeated.
+func groupBlocks(header []*line, blocks []block, group func(string) string, order []string, headings bool) []*line {
+	var names []string
+	groups := make(map[string][]block)
+	for _, b := range blocks {
+		name := group(b.entry.key)
+		if _, found := groups[name]; !found {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], b)
+	}
+	if order == nil {
+		sort.Strings(names)
+	} else {
+		rank := make(map[string]int)
+		for i := len(order) - 1; i >= 0; i-- {
+			rank[order[i]] = i + 1
+		}
+		sort.SliceStable(names, func(i, j int) bool {
+			return rank[names[i]] < rank[names[j]]
+		})
+	}
+
+	heading := func(l *line) bool {
+		name := strings.TrimSpace(strings.TrimPrefix(l.raw, "#"))
+		_, found := groups[name]
+		return headings && found && name != ""
+	}
+	lines := make([]*line, 0, len(header))
+	for _, l := range header {
+		if !heading(l) {
+			lines = append(lines, l)
+		}
+	}
+	for _, name := range names {
+		lines = append(lines, &line{kind: blankLine})
+		if headings && name != "" {
+			lines = append(lines, &line{kind: commentLine, raw: "# " + name})
+		}
+		for _, b := range groups[name] {
+			for _, c := range b.comments {
+				if !heading(c) {
+					lines = append(lines, c)
+				}
+			}
+			lines = append(lines, b.entry)
+		}
+	}
+	return lines
+}
+
+// collapseBlankLines returns lines without leading, trailing, or consecutive blank lines.
+func collapseBlankLines(lines []*line) []*line {
+	res := make([]*line, 0, len(lines))
+	for _, l := range lines {
+		if l.kind == blankLine && (len(res) == 0 || res[len(res)-1].kind == blankLine) {
+			continue
+		}
+		res = append(res, l)
+	}
+	for len(res) > 0 && res[len(res)-1].kind == blankLine {
+		res = res[:len(res)-1]
+	}
+	return res
+}
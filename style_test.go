@@ -0,0 +1,103 @@
+package envsync_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func formatFile(t *testing.T, content string, st envsync.Style) (*envsync.FormatResult, string) {
+	dir, err := ioutil.TempDir("", "envsync")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, ".env")
+	ioutil.WriteFile(target, []byte(content), 0644)
+
+	res, err := envsync.New(envsync.WithStyle(st)).Format(context.Background(), target)
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadFile(target)
+	return res, string(b)
+}
+
+func TestSyncer_Format_Style(t *testing.T) {
+	src := "\r\n# app   \r\nPORT = 8080  \r\nDB_HOST='localhost'\r\n\r\n\r\n# the db\r\nDB_NAME=\"app\"\r\nNAME=it's\r\n\r\n"
+
+	res, content := formatFile(t, src, envsync.Style{})
+	assert.True(t, res.Changed)
+	assert.Empty(t, res.Fixed)
+	assert.Equal(t, "# app\nPORT=8080\nDB_HOST='localhost'\n\n# the db\nDB_NAME=\"app\"\nNAME=it's\n", content)
+
+	res, content = formatFile(t, content, envsync.Style{})
+	assert.False(t, res.Changed)
+
+	_, content = formatFile(t, src, envsync.Style{Quoting: envsync.QuotingMinimal, Layout: envsync.LayoutSorted})
+	assert.Equal(t, "DB_HOST=localhost\n# the db\nDB_NAME=app\nNAME=it's\n# app\nPORT=8080\n", content)
+
+	_, content = formatFile(t, src, envsync.Style{Quoting: envsync.QuotingSingle, Layout: envsync.LayoutGrouped})
+	assert.Equal(t, "NAME=\"it's\"\n# app\nPORT='8080'\n\n# DB\nDB_HOST='localhost'\n# the db\nDB_NAME='app'\n", content)
+
+	res, again := formatFile(t, content, envsync.Style{Quoting: envsync.QuotingSingle, Layout: envsync.LayoutGrouped})
+	assert.False(t, res.Changed)
+	assert.Equal(t, content, again)
+
+	_, content = formatFile(t, "A=\"x\"\nB=y\n", envsync.Style{Quoting: envsync.QuotingDouble})
+	assert.Equal(t, "A=\"x\"\nB=\"y\"\n", content)
+}
+
+func TestSyncer_Format_Grouping(t *testing.T) {
+	src := "PORT=8080\nAWS_S3_BUCKET=b\nAWS_S3_REGION=r\nAWS_KEY=k\nDB_HOST=h\nDATABASE_URL=u\n"
+
+	_, content := formatFile(t, src, envsync.Style{Layout: envsync.LayoutGrouped, PrefixDepth: 2})
+	assert.Equal(t, "AWS_KEY=k\nDATABASE_URL=u\nDB_HOST=h\nPORT=8080\n\n# AWS_S3\nAWS_S3_BUCKET=b\nAWS_S3_REGION=r\n", content)
+
+	groups := []envsync.Group{{Name: "Storage", Keys: []string{"AWS_S3_*"}}, {Name: "Database", Keys: []string{"DB_*", "DATABASE_*"}}}
+	_, content = formatFile(t, src, envsync.Style{Layout: envsync.LayoutGrouped, Grouping: envsync.GroupingExplicit, Groups: groups})
+	assert.Equal(t, "AWS_KEY=k\nPORT=8080\n\n# Storage\nAWS_S3_BUCKET=b\nAWS_S3_REGION=r\n\n# Database\nDATABASE_URL=u\nDB_HOST=h\n", content)
+
+	sample, err := envsync.ParseDocument(strings.NewReader("# Server\nPORT=80\n\n# Database\n# the main one\nDB_HOST=\nDATABASE_URL=\n\n# Amazon\nAWS_KEY=\n"))
+	assert.Nil(t, err)
+	_, content = formatFile(t, src, envsync.Style{Layout: envsync.LayoutGrouped, Grouping: envsync.GroupingSource, Source: sample})
+	assert.Equal(t, "AWS_S3_BUCKET=b\nAWS_S3_REGION=r\n\n# Server\nPORT=8080\n\n# Database\nDATABASE_URL=u\nDB_HOST=h\n\n# Amazon\nAWS_KEY=k\n", content)
+
+	_, content = formatFile(t, "# the host\nDB_HOST=h\nA=1\nDB_NAME=n\n# Redis\nREDIS_URL=r\n", envsync.Style{Layout: envsync.LayoutGrouped, NoHeadings: true})
+	assert.Equal(t, "A=1\n\n# the host\nDB_HOST=h\nDB_NAME=n\n\n# Redis\nREDIS_URL=r\n", content)
+
+	_, content = formatFile(t, "# DB\nDB_HOST=h\nA=1\n", envsync.Style{Layout: envsync.LayoutGrouped, Grouping: envsync.GroupingNone})
+	assert.Equal(t, "A=1\n# DB\nDB_HOST=h\n", content)
+}
+
+func TestParseQuoting(t *testing.T) {
+	for _, q := range []envsync.Quoting{envsync.QuotingKeep, envsync.QuotingMinimal, envsync.QuotingDouble, envsync.QuotingSingle} {
+		parsed, err := envsync.ParseQuoting(q.String())
+		assert.Nil(t, err)
+		assert.Equal(t, q, parsed)
+	}
+	_, err := envsync.ParseQuoting("backtick")
+	assert.NotNil(t, err)
+}
+
+func TestParseGrouping(t *testing.T) {
+	for _, g := range []envsync.Grouping{envsync.GroupingPrefix, envsync.GroupingExplicit, envsync.GroupingSource, envsync.GroupingNone} {
+		parsed, err := envsync.ParseGrouping(g.String())
+		assert.Nil(t, err)
+		assert.Equal(t, g, parsed)
+	}
+	_, err := envsync.ParseGrouping("random")
+	assert.NotNil(t, err)
+}
+
+func TestParseLayout(t *testing.T) {
+	for _, l := range []envsync.Layout{envsync.LayoutKeep, envsync.LayoutSorted, envsync.LayoutGrouped} {
+		parsed, err := envsync.ParseLayout(l.String())
+		assert.Nil(t, err)
+		assert.Equal(t, l, parsed)
+	}
+	_, err := envsync.ParseLayout("random")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,173 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+// errContinued is returned by parseSystemdLine when the text ends with a backslash continuing it on the next line.
+var errContinued = errors.New("continued line")
+
+// systemdEscaped are the characters escaped with a backslash in a double-quoted value of a systemd EnvironmentFile.
+const systemdEscaped = "\"\\`$"
+
+// decodeSystemd reads the key-values of a systemd EnvironmentFile, its comments, and blank lines,
+// following the rules of systemd rather than the ones of a shell:
+// lines starting with '#' or ';' are comments, a line ending with a backslash continues on the next one,
+// and a quoted value may span lines.
+// '#' after a value is part of it, since only whole lines are comments.
+func decodeSystemd(r io.Reader) (*Document, error) {
+	doc := &Document{codec: CodecSystemd}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		start, text := n, sc.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+			continue
+		case trimmed[0] == '#' || trimmed[0] == ';':
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+			continue
+		}
+
+		l, err := parseSystemdLine(text)
+		for (err == ErrUnterminatedQuote || err == errContinued) && sc.Scan() {
+			n++
+			text += "\n" + sc.Text()
+			l, err = parseSystemdLine(text)
+		}
+		if err == errContinued {
+			// the backslash ending the file is dropped
+			l, err = parseSystemdLine(text + "\n")
+		}
+		if err != nil {
+			return doc, &ParseError{Line: start, Text: text, Err: err}
+		}
+		doc.lines = append(doc.lines, l)
+	}
+	return doc, sc.Err()
+}
+
+// parseSystemdLine parses a KEY=VALUE line of a systemd EnvironmentFile, spanning the lines of text.
+// Whitespace around the key and the value is trimmed, unless it is quoted.
+// Outside of quotes, a backslash escapes the next character, and a backslash followed by a newline is removed.
+// In double quotes, it only escapes ", \, `, $, and newlines, and in single quotes, nothing.
+func parseSystemdLine(text string) (*line, error) {
+	i := strings.IndexByte(text, '=')
+	if i < 0 {
+		return nil, ErrMissingSeparator
+	}
+	key := strings.TrimSpace(text[:i])
+	if key == "" {
+		return nil, ErrMissingSeparator
+	}
+	raw := strings.TrimLeft(text[i+1:], " \t")
+	value, err := unquoteSystemd(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &line{kind: entryLine, key: key, value: value, raw: text}
+	if len(raw) > 1 && (raw[0] == singleQuote || raw[0] == doubleQuote) && raw[len(raw)-1] == raw[0] {
+		l.quote = raw[0]
+	}
+	return l, nil
+}
+
+// unquoteSystemd returns the value of a key-value written as raw, without its trailing unquoted whitespace.
+func unquoteSystemd(raw string) (string, error) {
+	var b strings.Builder
+	quote := noQuote
+	// trimmed is the length of the value without its trailing unquoted whitespace
+	trimmed := 0
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case quote == singleQuote && c != singleQuote, quote == doubleQuote && c != doubleQuote && c != '\\':
+			b.WriteByte(c)
+			trimmed = b.Len()
+		case c == quote:
+			quote = noQuote
+		case c == '\\':
+			var err error
+			if i, err = unescapeSystemd(&b, raw, i, quote); err != nil {
+				return "", err
+			}
+			trimmed = b.Len()
+		case c == singleQuote || c == doubleQuote:
+			quote = c
+		default:
+			b.WriteByte(c)
+			if c != ' ' && c != '\t' {
+				trimmed = b.Len()
+			}
+		}
+	}
+	if quote != noQuote {
+		return "", ErrUnterminatedQuote
+	}
+	return b.String()[:trimmed], nil
+}
+
+// unescapeSystemd writes the character escaped by the backslash at raw[i], within quote, to b,
+// and returns the index of the character.
+// A backslash followed by a newline is removed, and one ending raw outside of quotes returns errContinued.
+func unescapeSystemd(b *strings.Builder, raw string, i int, quote byte) (int, error) {
+	if i++; i == len(raw) {
+		if quote == noQuote {
+			return i, errContinued
+		}
+		return i, nil
+	}
+	switch {
+	case raw[i] == '\n':
+	case quote == doubleQuote && !strings.ContainsRune(systemdEscaped, rune(raw[i])):
+		b.WriteByte('\\')
+		b.WriteByte(raw[i])
+	default:
+		b.WriteByte(raw[i])
+	}
+	return i, nil
+}
+
+// encodeSystemd writes d to buf as a systemd EnvironmentFile.
+// Lines that already hold the same key-value are written as they are, and others as KEY=VALUE,
+// quoted as they are read if possible. The export prefix is dropped, since systemd doesn't read it.
+func (d *Document) encodeSystemd(buf *bytes.Buffer) {
+	for _, l := range d.lines {
+		text := l.raw
+		if l.kind == entryLine {
+			if p, err := parseSystemdLine(l.raw); l.raw == "" || err != nil || p.key != l.key || p.value != l.value {
+				text = l.key + separator + systemdValue(l.value, l.quote)
+			}
+		}
+		buf.WriteString(text + "\n")
+	}
+}
+
+// systemdValue returns value quoted with quote, or double-quoted if it needs quotes that quote can't hold.
+func systemdValue(value string, quote byte) string {
+	plain := strings.TrimSpace(value) == value && !strings.ContainsAny(value, "\"'\\\n")
+	switch {
+	case quote == noQuote && plain:
+		return value
+	case quote == singleQuote && strings.IndexByte(value, singleQuote) < 0:
+		return "'" + value + "'"
+	}
+	var b strings.Builder
+	b.WriteByte(doubleQuote)
+	for i := 0; i < len(value); i++ {
+		if strings.IndexByte(systemdEscaped, value[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	b.WriteByte(doubleQuote)
+	return b.String()
+}
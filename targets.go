@@ -0,0 +1,40 @@
+package envsync
+
+import "context"
+
+// TargetResult is the outcome of synchronizing a source into one of several targets.
+// It is encoded in JSON with Err as an error message.
+type TargetResult struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+
+	// Result is nil if Err isn't.
+	Result *SyncResult `json:"result,omitempty"`
+	Err    error       `json:"-"`
+}
+
+// SyncTargets works like SyncContext, synchronizing source into each of targets in order.
+// source is read once. Each target is synchronized on its own:
+// an error on one target is reported in its TargetResult and doesn't stop the others.
+// The returned error is only about reading source or ctx being done.
+func (s *Syncer) SyncTargets(ctx context.Context, source string, targets []string) ([]TargetResult, error) {
+	sDoc, err := s.load(ctx, source, "source")
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TargetResult, 0, len(targets))
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		dst, err := s.open(target, "target")
+		var result *SyncResult
+		if err == nil {
+			result, err = s.syncTarget(ctx, sDoc, dst)
+		}
+		results = append(results, TargetResult{Source: source, Target: target, Result: result, Err: err})
+	}
+	return results, nil
+}
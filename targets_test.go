@@ -0,0 +1,49 @@
+package envsync_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncTargets(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "envsync")
+	defer os.RemoveAll(dir)
+
+	dev := filepath.Join(dir, ".env.dev")
+	prod := filepath.Join(dir, ".env.prod")
+	missing := filepath.Join(dir, ".env.staging")
+	ioutil.WriteFile(dev, []byte("HOME=dev\n"), 0644)
+	ioutil.WriteFile(prod, []byte("FOO=prod\n"), 0644)
+
+	syncer := &envsync.Syncer{}
+	results, err := syncer.SyncTargets(context.Background(), "testdata/env.success", []string{dev, missing, prod})
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+
+	assert.Equal(t, dev, results[0].Target)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, []string{"HOME"}, results[0].Result.Skipped)
+
+	assert.Equal(t, missing, results[1].Target)
+	assert.True(t, errors.Is(results[1].Err, envsync.ErrTargetNotFound))
+	assert.Nil(t, results[1].Result)
+
+	assert.Nil(t, results[2].Err)
+	assert.Equal(t, []string{"FOO"}, results[2].Result.Skipped)
+	assert.Equal(t, "prod", fileToMap(prod)["FOO"])
+	assert.Equal(t, "localhost", fileToMap(prod)["HOME"])
+}
+
+func TestSyncer_SyncTargets_ErrorSource(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	_, err := syncer.SyncTargets(context.Background(), "testdata/env.empty", []string{"testdata/env.success"})
+	assert.True(t, errors.Is(err, envsync.ErrSourceNotFound))
+}
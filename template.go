@@ -0,0 +1,83 @@
+package envsync
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// alphaNum holds the characters of randAlphaNum.
+const alphaNum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// defaultFuncs returns the functions available in templates.
+func defaultFuncs() template.FuncMap {
+	return template.FuncMap{
+		"uuid": func() (string, error) {
+			return generateValue("uuid")
+		},
+		"now": func() string {
+			return time.Now().UTC().Format(time.RFC3339)
+		},
+		"randHex": func(n int) (string, error) {
+			return generateValue(fmt.Sprintf("hex:%d", n))
+		},
+		"randAlphaNum": randAlphaNum,
+		"env":          os.Getenv,
+	}
+}
+
+// evaluate executes the values in added holding a template, recording them to edited.
+func (s *Syncer) evaluate(added, edited map[string]string) error {
+	if !s.Template {
+		return nil
+	}
+
+	funcs := defaultFuncs()
+	for name, fn := range s.Funcs {
+		funcs[name] = fn
+	}
+	for _, k := range sortedKeys(added) {
+		if !strings.Contains(added[k], "{{") {
+			continue
+		}
+
+		tmpl, err := template.New(k).Funcs(funcs).Option("missingkey=error").Parse(added[k])
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't parse template of %s", k))
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, nil); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't evaluate template of %s", k))
+		}
+		added[k] = b.String()
+		edited[k] = b.String()
+	}
+	return nil
+}
+
+// randAlphaNum returns n random letters and digits.
+func randAlphaNum(n int) (string, error) {
+	if n < 1 {
+		return "", fmt.Errorf("invalid length: %d", n)
+	}
+	b, err := randomBytes(n, 0)
+	if err != nil {
+		return "", err
+	}
+	for i := range b {
+		// 256 isn't a multiple of len(alphaNum), bytes in the last partial range are drawn again
+		for int(b[i]) >= 256-256%len(alphaNum) {
+			r, err := randomBytes(1, 0)
+			if err != nil {
+				return "", err
+			}
+			b[i] = r[0]
+		}
+		b[i] = alphaNum[int(b[i])%len(alphaNum)]
+	}
+	return string(b), nil
+}
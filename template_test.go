@@ -0,0 +1,65 @@
+package envsync_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_Template(t *testing.T) {
+	os.Setenv("ENVSYNC_TEST_REGION", "eu")
+	defer os.Unsetenv("ENVSYNC_TEST_REGION")
+
+	syncer := envsync.New(envsync.WithTemplate(template.FuncMap{
+		"upper": strings.ToUpper,
+	}))
+
+	src := strings.Join([]string{
+		"ID={{uuid}}",
+		"CREATED={{now}}",
+		"TOKEN={{randAlphaNum 24}}",
+		"SALT={{randHex 8}}",
+		`REGION={{env "ENVSYNC_TEST_REGION" | upper}}`,
+		"KEPT={{uuid}}",
+		"PLAIN=value",
+		"",
+	}, "\n")
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader("KEPT={{uuid}}\n"), &buf)
+	assert.Nil(t, err)
+	assert.Regexp(t, "^[0-9a-f-]{36}$", res.Env["ID"])
+	assert.Regexp(t, "^[A-Za-z0-9]{24}$", res.Env["TOKEN"])
+	assert.Regexp(t, "^[0-9a-f]{16}$", res.Env["SALT"])
+	assert.Equal(t, "EU", res.Env["REGION"])
+	assert.Equal(t, "{{uuid}}", res.Env["KEPT"])
+	assert.Equal(t, "value", res.Env["PLAIN"])
+
+	created, err := time.Parse(time.RFC3339, res.Env["CREATED"])
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), created, time.Minute)
+	assert.Contains(t, buf.String(), "REGION=EU\n")
+}
+
+func TestSyncer_SyncReaders_TemplateDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	res, err := envsync.New().SyncReaders(strings.NewReader("ID={{uuid}}\n"), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "{{uuid}}", res.Env["ID"])
+}
+
+func TestSyncer_SyncReaders_TemplateInvalid(t *testing.T) {
+	syncer := envsync.New(envsync.WithTemplate(nil))
+	for _, v := range []string{"{{uuid", "{{unknown}}", "{{randAlphaNum 0}}"} {
+		var buf bytes.Buffer
+		_, err := syncer.SyncReaders(strings.NewReader("ID="+v+"\n"), strings.NewReader(""), &buf)
+		assert.NotNil(t, err, v)
+		assert.Empty(t, buf.String(), v)
+	}
+}
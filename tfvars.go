@@ -0,0 +1,165 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// tfvarsName matches the names of Terraform variables.
+	tfvarsName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+	// tfvarsLiteral matches the numbers and booleans of Terraform, read as their text.
+	tfvarsLiteral = regexp.MustCompile(`^(-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?|true|false)$`)
+)
+
+// decodeTFVars reads the variables of a Terraform .tfvars file, its comments, and blank lines.
+// Only strings, numbers, booleans, and null, read as empty, are supported:
+// a list, a map, or an object is ErrComplexValue, and a heredoc is ErrUnsupportedSyntax.
+func decodeTFVars(r io.Reader) (*Document, error) {
+	doc := &Document{codec: CodecTFVars}
+	sc := bufio.NewScanner(r)
+	n, inComment := 0, false
+	for sc.Scan() {
+		n++
+		text := sc.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case inComment || strings.HasPrefix(trimmed, "/*"):
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+			inComment = !strings.HasSuffix(trimmed, "*/") || trimmed == "/*"
+		case trimmed == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+		case strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "//"):
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+		default:
+			l, err := parseTFVarsLine(text)
+			if err != nil {
+				return doc, &ParseError{Line: n, Text: text, Err: err}
+			}
+			doc.lines = append(doc.lines, l)
+		}
+	}
+	return doc, sc.Err()
+}
+
+// parseTFVarsLine parses a name = value line of a .tfvars file.
+func parseTFVarsLine(text string) (*line, error) {
+	i := strings.IndexByte(text, '=')
+	if i < 0 {
+		return nil, ErrMissingSeparator
+	}
+	name := strings.TrimSpace(text[:i])
+	if !tfvarsName.MatchString(name) {
+		return nil, ErrUnsupportedSyntax
+	}
+	rest := strings.TrimLeft(text[i+1:], " \t")
+
+	l := &line{kind: entryLine, key: name, raw: text}
+	switch {
+	case strings.HasPrefix(rest, "["), strings.HasPrefix(rest, "{"):
+		return nil, ErrComplexValue
+	case strings.HasPrefix(rest, "<<"):
+		return nil, ErrUnsupportedSyntax
+	case strings.HasPrefix(rest, `"`):
+		value, n, err := tfvarsString(rest)
+		if err != nil {
+			return nil, err
+		}
+		l.value, l.quote, rest = value, doubleQuote, rest[n:]
+	default:
+		end := strings.IndexAny(rest, " \t#/")
+		if end < 0 {
+			end = len(rest)
+		}
+		switch literal := rest[:end]; {
+		case literal == "null":
+		case tfvarsLiteral.MatchString(literal):
+			l.value = literal
+		default:
+			return nil, ErrUnsupportedSyntax
+		}
+		rest = rest[end:]
+	}
+
+	// a value may be followed by a # or // comment
+	trimmed := strings.TrimLeft(rest, " \t")
+	if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") {
+		return nil, ErrUnexpectedText
+	}
+	if trimmed != "" {
+		l.comment = rest
+	}
+	return l, nil
+}
+
+// tfvarsString unwraps the quoted string at the start of s, and returns the number of bytes it spans.
+// An escaped template sequence, $${ or %%{, is read as ${ or %{.
+func tfvarsString(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u', 'U':
+				size := 4
+				if e == 'U' {
+					size = 8
+				}
+				if i+size >= len(s) {
+					return "", 0, ErrInvalidEscape
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+				if err != nil {
+					return "", 0, ErrInvalidEscape
+				}
+				b.WriteRune(rune(r))
+				i += size
+			default:
+				b.WriteByte(e)
+			}
+		case (c == '$' || c == '%') && strings.HasPrefix(s[i+1:], string(c)+"{"):
+			b.WriteByte(c)
+			i++
+		case (c == '$' || c == '%') && strings.HasPrefix(s[i+1:], "{"):
+			// a template can't be evaluated in a .tfvars file
+			return "", 0, ErrUnsupportedSyntax
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, ErrUnterminatedQuote
+}
+
+// encodeTFVars writes d to buf as a .tfvars file.
+// Lines that already hold the same variable are written as they are, and others as name = "value".
+func (d *Document) encodeTFVars(buf *bytes.Buffer) {
+	for _, l := range d.lines {
+		text := l.raw
+		if l.kind == entryLine {
+			if p, err := parseTFVarsLine(l.raw); l.raw == "" || err != nil || p.key != l.key || p.value != l.value {
+				text = l.key + " = " + tfvarsQuote(l.value)
+			}
+		}
+		buf.WriteString(text + "\n")
+	}
+}
+
+// tfvarsQuote returns s as a Terraform string, escaping template sequences so they are taken literally.
+func tfvarsQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{")
+	return `"` + r.Replace(s) + `"`
+}
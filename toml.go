@@ -0,0 +1,176 @@
+package envsync
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tomlBareKey matches the keys written without quotes in TOML.
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// decodeTOML reads the key-values of a TOML file, its comments, and blank lines.
+// Only a flat table of strings, numbers, booleans, and dates is supported:
+// tables, arrays, inline tables, dotted keys, and multi-line strings are ErrUnsupportedSyntax.
+func decodeTOML(r io.Reader) (*Document, error) {
+	doc := &Document{codec: CodecTOML}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		text := sc.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+			doc.lines = append(doc.lines, &line{kind: blankLine, raw: text})
+		case strings.HasPrefix(trimmed, "#"):
+			doc.lines = append(doc.lines, &line{kind: commentLine, raw: text})
+		default:
+			l, err := parseTOMLLine(text)
+			if err != nil {
+				return doc, &ParseError{Line: n, Text: text, Err: err}
+			}
+			doc.lines = append(doc.lines, l)
+		}
+	}
+	return doc, sc.Err()
+}
+
+// parseTOMLLine parses a key = value line of a TOML file.
+// A number, a boolean, or a date is taken as it is written, keeping text as raw.
+func parseTOMLLine(text string) (*line, error) {
+	rest := strings.TrimLeft(text, " \t")
+	if strings.HasPrefix(rest, "[") {
+		return nil, ErrUnsupportedSyntax
+	}
+
+	var key string
+	var err error
+	if rest != "" && (rest[0] == doubleQuote || rest[0] == singleQuote) {
+		if key, rest, err = tomlQuoted(rest); err != nil {
+			return nil, err
+		}
+	} else {
+		i := strings.IndexAny(rest, " \t=")
+		if i < 0 {
+			return nil, ErrMissingSeparator
+		}
+		key, rest = rest[:i], rest[i:]
+		if !tomlBareKey.MatchString(key) {
+			return nil, ErrUnsupportedSyntax
+		}
+	}
+	rest = strings.TrimLeft(rest, " \t")
+	if !strings.HasPrefix(rest, separator) {
+		return nil, ErrMissingSeparator
+	}
+	rest = strings.TrimLeft(rest[len(separator):], " \t")
+
+	l := &line{kind: entryLine, key: key, raw: text}
+	switch {
+	case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"), strings.HasPrefix(rest, "["), strings.HasPrefix(rest, "{"):
+		return nil, ErrUnsupportedSyntax
+	case strings.HasPrefix(rest, `"`), strings.HasPrefix(rest, "'"):
+		l.quote = rest[0]
+		if l.value, rest, err = tomlQuoted(rest); err != nil {
+			return nil, err
+		}
+	default:
+		end := strings.IndexAny(rest, " \t#")
+		if end < 0 {
+			end = len(rest)
+		}
+		l.value, rest = rest[:end], rest[end:]
+		// a local date and time may be separated by a space, e.g. 1979-05-27 07:32:00
+		if tomlDate.MatchString(l.value) && tomlTime.MatchString(strings.TrimPrefix(rest, " ")) {
+			t := tomlTime.FindString(rest[1:])
+			l.value, rest = l.value+" "+t, rest[1+len(t):]
+		}
+		if l.value == "" {
+			return nil, ErrMissingSeparator
+		}
+	}
+	if l.comment, err = trailingComment(rest); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+var (
+	tomlDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlTime = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?`)
+)
+
+// tomlQuoted unwraps the basic or literal string at the start of s, and returns the text following it.
+func tomlQuoted(s string) (string, string, error) {
+	if s[0] == singleQuote {
+		end := strings.IndexByte(s[1:], singleQuote)
+		if end < 0 {
+			return "", "", ErrUnterminatedQuote
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case doubleQuote:
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", ErrUnexpectedText
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", ErrUnterminatedQuote
+}
+
+// encodeTOML writes d to buf as TOML.
+// Lines that are already valid TOML holding the same key-value are written as they are,
+// and others as key = "value".
+func (d *Document) encodeTOML(buf *bytes.Buffer) {
+	for _, l := range d.lines {
+		if l.kind != entryLine {
+			buf.WriteString(l.raw + "\n")
+			continue
+		}
+		if p, err := parseTOMLLine(l.raw); l.raw != "" && err == nil && p.key == l.key && p.value == l.value {
+			buf.WriteString(l.raw + "\n")
+			continue
+		}
+		key := l.key
+		if !tomlBareKey.MatchString(key) {
+			key = tomlString(key)
+		}
+		buf.WriteString(key + " = " + tomlString(l.value) + l.comment + "\n")
+	}
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package envsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// skippedDirs are never walked into by SyncTree.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// SyncTree walks the directory tree at root and synchronizes every file named sourceName
+// into the file named targetName in the same directory, e.g. every .env.example into its sibling .env.
+// The .git, node_modules, and vendor directories are skipped, and so are the files and directories
+// listed by Ignore, relative to root.
+//
+// Results are ordered by source path. Like in SyncTargets,
+// an error on one pair is reported in its TargetResult and doesn't stop the others.
+func (s *Syncer) SyncTree(ctx context.Context, root, sourceName, targetName string) ([]TargetResult, error) {
+	var sources []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() && path != root && (skippedDirs[info.Name()] || s.ignored(root, path, true)) {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() == sourceName {
+			sources = append(sources, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't walk directory")
+	}
+
+	return s.syncSiblings(ctx, root, sources, targetName)
+}
+
+// SyncGlob works like SyncTree for the source files matching pattern,
+// e.g. services/*/.env.example. The pattern syntax is the same as filepath.Match.
+// Files listed by Ignore are relative to the current directory.
+func (s *Syncer) SyncGlob(ctx context.Context, pattern, targetName string) ([]TargetResult, error) {
+	sources, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid glob pattern")
+	}
+	return s.syncSiblings(ctx, ".", sources, targetName)
+}
+
+// syncSiblings synchronizes each of sources into targetName in the same directory,
+// unless Ignore lists either file relative to root.
+func (s *Syncer) syncSiblings(ctx context.Context, root string, sources []string, targetName string) ([]TargetResult, error) {
+	sort.Strings(sources)
+
+	results := make([]TargetResult, 0, len(sources))
+	for _, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		target := filepath.Join(filepath.Dir(source), targetName)
+		if s.ignored(root, source, false) || s.ignored(root, target, false) {
+			continue
+		}
+		result, err := s.SyncContext(ctx, source, target)
+		results = append(results, TargetResult{Source: source, Target: target, Result: result, Err: err})
+	}
+	return results, nil
+}
+
+// ignored reports whether Ignore lists the file or directory at path, relative to root.
+func (s *Syncer) ignored(root, path string, dir bool) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return s.Ignore.Match(filepath.ToSlash(rel), dir)
+}
@@ -0,0 +1,76 @@
+package envsync_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "envsync")
+	assert.Nil(t, err)
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	return dir
+}
+
+func TestSyncer_SyncTree(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"api/.env.example":                "PORT=8080\n",
+		"api/.env":                        "",
+		"worker/.env.example":             "QUEUE=jobs\n",
+		"worker/.env":                     "QUEUE=default\n",
+		"web/.env.example":                "PORT=3000\n",
+		"node_modules/lib/.env.example":   "SKIPPED=true\n",
+		"node_modules/lib/.env":           "",
+		"api/docs/.env.example/README.md": "not a source file",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := &envsync.Syncer{}
+	results, err := syncer.SyncTree(context.Background(), dir, ".env.example", ".env")
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+
+	assert.Equal(t, filepath.Join(dir, "api", ".env"), results[0].Target)
+	assert.Equal(t, []string{"PORT"}, results[0].Result.Added)
+
+	assert.Equal(t, filepath.Join(dir, "web", ".env.example"), results[1].Source)
+	assert.True(t, errors.Is(results[1].Err, envsync.ErrTargetNotFound))
+
+	assert.Equal(t, []string{"QUEUE"}, results[2].Result.Skipped)
+
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "node_modules", "lib", ".env"))
+	assert.Empty(t, content)
+}
+
+func TestSyncer_SyncGlob(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"services/a/.env.sample": "A=1\n",
+		"services/a/.env":        "",
+		"services/b/.env.sample": "B=1\n",
+		"services/b/.env":        "",
+		"other/.env.sample":      "C=1\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := &envsync.Syncer{}
+	results, err := syncer.SyncGlob(context.Background(), filepath.Join(dir, "services", "*", ".env.sample"), ".env")
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, []string{"A"}, results[0].Result.Added)
+	assert.Equal(t, []string{"B"}, results[1].Result.Added)
+
+	_, err = syncer.SyncGlob(context.Background(), "[", ".env")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,68 @@
+package envsync
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// valueTypes maps the name of each value type to the function checking a value has the type.
+var valueTypes = map[string]func(string) error{
+	"string": func(string) error { return nil },
+	"int": func(v string) error {
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err
+	},
+	"float": func(v string) error {
+		_, err := strconv.ParseFloat(v, 64)
+		return err
+	},
+	"bool": func(v string) error {
+		_, err := strconv.ParseBool(v)
+		return err
+	},
+	"url": func(v string) error {
+		u, err := url.Parse(v)
+		if err == nil && (u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == "")) {
+			err = fmt.Errorf("missing scheme or host")
+		}
+		return err
+	},
+	"duration": func(v string) error {
+		_, err := time.ParseDuration(v)
+		return err
+	},
+}
+
+// typeAliases maps the JSON Schema names of types to their name in a schema.
+var typeAliases = map[string]string{
+	"integer": "int",
+	"number":  "float",
+	"boolean": "bool",
+	"uri":     "url",
+}
+
+// valueType returns the name of the type called name, or an error if there isn't any.
+func valueType(name string) (string, error) {
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := valueTypes[name]; !ok {
+		return "", fmt.Errorf("unknown type: %s", name)
+	}
+	return name, nil
+}
+
+// checkType returns a message describing why v doesn't have the type called name,
+// or an empty string if it has.
+func checkType(name, v string) string {
+	check, ok := valueTypes[name]
+	if !ok {
+		return ""
+	}
+	if err := check(v); err != nil {
+		return fmt.Sprintf("%q isn't a valid %s", v, name)
+	}
+	return ""
+}
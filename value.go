@@ -0,0 +1,118 @@
+package envsync
+
+import "strings"
+
+const (
+	noQuote     byte = 0
+	singleQuote byte = '\''
+	doubleQuote byte = '"'
+)
+
+// decodeValue unwraps raw from its quotes and reports which quote it is wrapped in.
+//
+// A single-quoted value is taken literally.
+// A double-quoted value may contain the escapes \n, \r, \t, \", and \\.
+// Any other escaped character is taken as it is.
+// A quoted value may contain newlines, e.g. a PEM key spanning multiple lines.
+// A quoted value may be followed by a comment, e.g. "bar" # the bar.
+//
+// An unquoted value is taken literally, including any whitespace.
+// If inlineComments is set, whitespace followed by '#' starts a comment instead.
+//
+// The comment is returned with the whitespace preceding it.
+func decodeValue(raw string, inlineComments bool) (value string, quote byte, comment string, err error) {
+	if raw == "" {
+		return "", noQuote, "", nil
+	}
+
+	switch raw[0] {
+	case singleQuote:
+		end := strings.IndexByte(raw[1:], singleQuote)
+		if end < 0 {
+			return "", singleQuote, "", ErrUnterminatedQuote
+		}
+		comment, err = trailingComment(raw[end+2:])
+		return raw[1 : end+1], singleQuote, comment, err
+	case doubleQuote:
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch c := raw[i]; {
+			case c == '\\' && i+1 < len(raw):
+				i++
+				b.WriteByte(unescape(raw[i]))
+			case c == doubleQuote:
+				comment, err = trailingComment(raw[i+1:])
+				return b.String(), doubleQuote, comment, err
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", doubleQuote, "", ErrUnterminatedQuote
+	}
+
+	if inlineComments {
+		if i := commentIndex(raw); i >= 0 {
+			value = strings.TrimRight(raw[:i], " \t")
+			return value, noQuote, raw[len(value):], nil
+		}
+	}
+	return raw, noQuote, "", nil
+}
+
+// commentIndex returns the index of the first '#' preceded by whitespace in raw, or -1 if there is none.
+func commentIndex(raw string) int {
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	}
+	return c
+}
+
+// trailingComment returns rest if it is blank or a comment.
+func trailingComment(rest string) (string, error) {
+	trimmed := strings.TrimLeft(rest, " \t")
+	if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+		return "", ErrUnexpectedText
+	}
+	if trimmed == "" {
+		return "", nil
+	}
+	return rest, nil
+}
+
+// encodeValue wraps value in quote, escaping it as needed.
+// A value is double-quoted if quote can't hold it,
+// or if it is unquoted and can't be read back as it is.
+func encodeValue(value string, quote byte) string {
+	if quote == singleQuote && !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	if quote == noQuote && !needsQuote(value) {
+		return value
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
+
+func needsQuote(value string) bool {
+	if value == "" {
+		return false
+	}
+	return strings.ContainsAny(value, "\n\r") || commentIndex(value) >= 0 ||
+		value[0] == singleQuote || value[0] == doubleQuote ||
+		strings.TrimSpace(value) != value
+}
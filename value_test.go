@@ -0,0 +1,145 @@
+package envsync_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_SyncReaders_QuotedValues(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	src := `DOUBLE="hello world"
+SINGLE='no \n escape'
+ESCAPED="say \"hi\"\n=bye"
+EQUALS="a=b=c"
+`
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", res.Env["DOUBLE"])
+	assert.Equal(t, `no \n escape`, res.Env["SINGLE"])
+	assert.Equal(t, "say \"hi\"\n=bye", res.Env["ESCAPED"])
+	assert.Equal(t, "a=b=c", res.Env["EQUALS"])
+}
+
+func TestSyncer_SyncReaders_KeepSourceQuotes(t *testing.T) {
+	syncer := &envsync.Syncer{Overwrite: true}
+
+	src := "NAME='John Doe'\nGREETING=\"hello\\tworld\"\n"
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader("NAME=\"Jane\"\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "NAME='John Doe'\nGREETING=\"hello\\tworld\"\n", buf.String())
+}
+
+func TestSyncer_Diff_IgnoreQuotes(t *testing.T) {
+	syncer := &envsync.Syncer{DryRun: true}
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(`FOO="bar"`), strings.NewReader("FOO='bar'\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FOO"}, res.Skipped)
+}
+
+func TestSyncer_SyncReaders_CorruptQuotedValue(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	for _, src := range []string{`FOO="bar`, `FOO='bar`, `FOO="bar" baz`} {
+		var buf bytes.Buffer
+		_, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+		assert.NotNil(t, err, src)
+	}
+}
+
+func TestSyncer_SyncReaders_MultilineValues(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	src := `PRIVATE_KEY="-----BEGIN KEY-----
+abc=
+def
+-----END KEY-----"
+CONFIG='{
+  "debug": true
+}'
+PORT=8080
+`
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader("# generated\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"CONFIG", "PORT", "PRIVATE_KEY"}, res.Added)
+	assert.Equal(t, "-----BEGIN KEY-----\nabc=\ndef\n-----END KEY-----", res.Env["PRIVATE_KEY"])
+	assert.Equal(t, "{\n  \"debug\": true\n}", res.Env["CONFIG"])
+
+	// the written target reads back to the same env
+	var again bytes.Buffer
+	res, err = syncer.SyncReaders(strings.NewReader(src), bytes.NewReader(buf.Bytes()), &again)
+	assert.Nil(t, err)
+	assert.Empty(t, res.Added)
+	assert.Equal(t, buf.String(), again.String())
+}
+
+func TestSyncer_SyncReaders_UnterminatedMultilineValue(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	var buf bytes.Buffer
+	_, err := syncer.SyncReaders(strings.NewReader("KEY=\"abc\ndef\n"), strings.NewReader(""), &buf)
+	assert.NotNil(t, err)
+}
+
+func TestSyncer_SyncReaders_ExportPrefix(t *testing.T) {
+	syncer := &envsync.Syncer{Overwrite: true}
+
+	src := "HOME=localhost\nexport PORT=8080\nDEBUG=true\n"
+	dst := "export HOME=production\nexport PORT=80\n"
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(dst), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DEBUG"}, res.Added)
+	assert.Equal(t, []string{"HOME", "PORT"}, res.Overwritten)
+	assert.Equal(t, "export HOME=localhost\nexport PORT=8080\nexport DEBUG=true\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_MixedExportPrefix(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader("export FOO=bar\nexport=1\n"), strings.NewReader("BAZ=qux\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FOO", "export"}, res.Added)
+	assert.Equal(t, "BAZ=qux\nexport FOO=bar\nexport=1\n", buf.String())
+}
+
+func TestSyncer_SyncReaders_InlineComments(t *testing.T) {
+	src := "PORT=8080 # http port\nHOST=\"localhost\" # bind address\nCOLOR=#fff\n"
+
+	syncer := &envsync.Syncer{InlineComments: true}
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "8080", res.Env["PORT"])
+	assert.Equal(t, "localhost", res.Env["HOST"])
+	assert.Equal(t, "#fff", res.Env["COLOR"])
+	assert.Equal(t, "COLOR=#fff\nHOST=\"localhost\" # bind address\nPORT=8080 # http port\n", buf.String())
+
+	syncer = &envsync.Syncer{}
+	buf.Reset()
+	res, err = syncer.SyncReaders(strings.NewReader(src), strings.NewReader(""), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "8080 # http port", res.Env["PORT"])
+	assert.Equal(t, "localhost", res.Env["HOST"])
+}
+
+func TestSyncer_SyncReaders_KeepTargetInlineComment(t *testing.T) {
+	syncer := &envsync.Syncer{InlineComments: true}
+
+	var buf bytes.Buffer
+	res, err := syncer.SyncReaders(strings.NewReader("PORT=8080 # sample\n"), strings.NewReader("PORT=80   # production\n"), &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT"}, res.Skipped)
+	assert.Equal(t, "PORT=80   # production\n", buf.String())
+}
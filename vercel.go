@@ -0,0 +1,217 @@
+package envsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const vercelAPI = "https://api.vercel.com"
+
+// vercelEnvironments are the environments of a Vercel project.
+var vercelEnvironments = map[string]bool{"production": true, "preview": true, "development": true}
+
+// vercelTarget is the environment variables of a Vercel project in one of its environments as a Target,
+// e.g. vercel://my-project/production, authenticated with the token in VERCEL_TOKEN.
+// The team query parameter sets the team owning the project, e.g. ?team=team_abc.
+//
+// A variable shared by several environments is split when it is changed or removed in this one,
+// so the others keep it as it is. Variables of a Git branch are left out.
+// Values of sensitive variables can't be read, so they are loaded empty.
+type vercelTarget struct {
+	name        string
+	project     string
+	environment string
+	team        string
+	client      *restClient
+
+	// vars are the variables of the environment as they are loaded, by key, and env their values.
+	vars map[string]vercelVar
+	env  Env
+}
+
+// vercelVar is an environment variable of a Vercel project.
+type vercelVar struct {
+	ID        string   `json:"id,omitempty"`
+	Key       string   `json:"key"`
+	Value     string   `json:"value"`
+	Type      string   `json:"type"`
+	Target    []string `json:"target"`
+	GitBranch string   `json:"gitBranch,omitempty"`
+}
+
+func openVercel(u *url.URL) (Target, error) {
+	environment := strings.Trim(u.Path, "/")
+	if u.Host == "" || !vercelEnvironments[environment] {
+		return nil, fmt.Errorf("expected vercel://project/production, preview, or development, got %s", u)
+	}
+	token := os.Getenv("VERCEL_TOKEN")
+	if token == "" {
+		return nil, errors.New("VERCEL_TOKEN isn't set")
+	}
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = vercelAPI
+	}
+	return &vercelTarget{
+		name:        u.String(),
+		project:     u.Host,
+		environment: environment,
+		team:        u.Query().Get("team"),
+		client: &restClient{
+			base: endpoint,
+			auth: bearer(func(context.Context) (string, error) {
+				return token, nil
+			}),
+			errorOf: func(status int, body []byte) error {
+				var e struct {
+					Error struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				}
+				if json.Unmarshal(body, &e) != nil || e.Error.Message == "" {
+					return nil
+				}
+				return &httpError{status: status, message: e.Error.Message}
+			},
+		},
+	}, nil
+}
+
+func (t *vercelTarget) Name() string {
+	return t.name
+}
+
+func (t *vercelTarget) Load(ctx context.Context) (*Document, error) {
+	q := t.query()
+	q.Set("decrypt", "true")
+	var out struct {
+		Envs []vercelVar `json:"envs"`
+	}
+	if err := t.client.do(ctx, http.MethodGet, t.path("/v9", "")+"?"+q.Encode(), nil, &out); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("couldn't read the environment variables of %s", t.project))
+	}
+
+	t.vars = make(map[string]vercelVar)
+	t.env = Env{}
+	for _, v := range out.Envs {
+		if v.GitBranch != "" || !v.targets(t.environment) {
+			continue
+		}
+		t.vars[v.Key] = v
+		t.env[v.Key] = v.Value
+	}
+	return envDocument(t.env), nil
+}
+
+// targets reports whether v is set in environment.
+func (v vercelVar) targets(environment string) bool {
+	for _, e := range v.Target {
+		if e == environment {
+			return true
+		}
+	}
+	return false
+}
+
+// Store creates or changes the variables whose value is added or changed since the target is loaded,
+// and removes the ones that aren't in doc anymore from the environment.
+func (t *vercelTarget) Store(ctx context.Context, doc *Document) error {
+	if t.env == nil {
+		if _, err := t.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	env := doc.Env()
+	for _, k := range env.Keys() {
+		if v, ok := t.env[k]; ok && v == env[k] {
+			continue
+		}
+		if err := t.set(ctx, k, env[k]); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't set %s in %s", k, t.project))
+		}
+		t.env[k] = env[k]
+	}
+	for _, k := range t.env.Keys() {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if err := t.remove(ctx, t.vars[k]); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("couldn't remove %s from %s", k, t.project))
+		}
+		delete(t.env, k)
+		delete(t.vars, k)
+	}
+	return nil
+}
+
+// set changes the value of the variable key in the environment, or creates it.
+func (t *vercelTarget) set(ctx context.Context, key, value string) error {
+	v, ok := t.vars[key]
+	if ok && len(v.Target) == 1 {
+		return t.client.do(ctx, http.MethodPatch, t.path("/v9", v.ID)+t.encodedQuery(), map[string]string{"value": value}, nil)
+	}
+	if ok {
+		if err := t.remove(ctx, v); err != nil {
+			return err
+		}
+	}
+
+	v = vercelVar{Key: key, Value: value, Type: "encrypted", Target: []string{t.environment}}
+	var out struct {
+		Created vercelVar `json:"created"`
+	}
+	if err := t.client.do(ctx, http.MethodPost, t.path("/v10", "")+t.encodedQuery(), v, &out); err != nil {
+		return err
+	}
+	v.ID = out.Created.ID
+	t.vars[key] = v
+	return nil
+}
+
+// remove deletes v, or takes the environment out of its targets if it is shared with others.
+func (t *vercelTarget) remove(ctx context.Context, v vercelVar) error {
+	if len(v.Target) == 1 {
+		return t.client.do(ctx, http.MethodDelete, t.path("/v9", v.ID)+t.encodedQuery(), nil, nil)
+	}
+	var others []string
+	for _, e := range v.Target {
+		if e != t.environment {
+			others = append(others, e)
+		}
+	}
+	return t.client.do(ctx, http.MethodPatch, t.path("/v9", v.ID)+t.encodedQuery(), map[string][]string{"target": others}, nil)
+}
+
+// path returns the path of the variable id of the project in version of the API, or of its variables if id is empty.
+func (t *vercelTarget) path(version, id string) string {
+	p := version + "/projects/" + url.PathEscape(t.project) + "/env"
+	if id != "" {
+		p += "/" + url.PathEscape(id)
+	}
+	return p
+}
+
+// query returns the team of the target as a query parameter, if it is set.
+func (t *vercelTarget) query() url.Values {
+	q := url.Values{}
+	if t.team != "" {
+		q.Set("teamId", t.team)
+	}
+	return q
+}
+
+// encodedQuery returns query encoded with its leading '?', or "" if it is empty.
+func (t *vercelTarget) encodedQuery() string {
+	if t.team == "" {
+		return ""
+	}
+	return "?" + t.query().Encode()
+}
@@ -0,0 +1,141 @@
+package envsync_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeVercelVar struct {
+	ID        string   `json:"id"`
+	Key       string   `json:"key"`
+	Value     string   `json:"value"`
+	Type      string   `json:"type"`
+	Target    []string `json:"target"`
+	GitBranch string   `json:"gitBranch,omitempty"`
+}
+
+// fakeVercel serves the environment variables of the project my-project of the team team_abc from memory.
+type fakeVercel struct {
+	mu   sync.Mutex
+	vars map[string]*fakeVercelVar
+	next int
+}
+
+func (f *fakeVercel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer vercel-token" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": "forbidden", "message": "Not authorized"}}`))
+		return
+	}
+	if r.URL.Query().Get("teamId") != "team_abc" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"code": "not_found", "message": "Project not found"}}`))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v9/projects/my-project/env":
+		envs := []*fakeVercelVar{}
+		for _, v := range f.vars {
+			envs = append(envs, v)
+		}
+		sort.Slice(envs, func(i, j int) bool { return envs[i].ID < envs[j].ID })
+		json.NewEncoder(w).Encode(map[string]interface{}{"envs": envs})
+	case r.Method == http.MethodPost && r.URL.Path == "/v10/projects/my-project/env":
+		var v fakeVercelVar
+		json.NewDecoder(r.Body).Decode(&v)
+		f.next++
+		v.ID = fmt.Sprintf("new%d", f.next)
+		f.vars[v.ID] = &v
+		json.NewEncoder(w).Encode(map[string]interface{}{"created": v})
+	case strings.HasPrefix(r.URL.Path, "/v9/projects/my-project/env/"):
+		v, ok := f.vars[strings.TrimPrefix(r.URL.Path, "/v9/projects/my-project/env/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.vars, v.ID)
+			return
+		}
+		var patch struct {
+			Value  *string  `json:"value"`
+			Target []string `json:"target"`
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		if patch.Value != nil {
+			v.Value = *patch.Value
+		}
+		if patch.Target != nil {
+			v.Target = patch.Target
+		}
+		json.NewEncoder(w).Encode(v)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// env returns the values of the variables of environment by key.
+func (f *fakeVercel) env(environment string) map[string]string {
+	env := make(map[string]string)
+	for _, v := range f.vars {
+		for _, e := range v.Target {
+			if e == environment && v.GitBranch == "" {
+				env[v.Key] = v.Value
+			}
+		}
+	}
+	return env
+}
+
+func TestSyncer_Sync_Vercel(t *testing.T) {
+	vercel := &fakeVercel{vars: map[string]*fakeVercelVar{
+		"a": {ID: "a", Key: "API_URL", Value: "https://api.example.com", Type: "encrypted", Target: []string{"production", "preview"}},
+		"b": {ID: "b", Key: "OLD", Value: "x", Type: "plain", Target: []string{"production", "preview"}},
+		"c": {ID: "c", Key: "FLAG", Value: "on", Type: "plain", Target: []string{"production"}},
+		"d": {ID: "d", Key: "API_URL", Value: "https://feature.example.com", Type: "encrypted", Target: []string{"preview"}, GitBranch: "feature"},
+	}}
+	server := httptest.NewServer(vercel)
+	defer server.Close()
+	defer setenv(map[string]string{"VERCEL_TOKEN": "vercel-token"})()
+	target := "vercel://my-project/production?team=team_abc&endpoint=" + server.URL
+
+	dir := makeTree(t, map[string]string{
+		"env.sample": "API_URL=http://localhost:3000\nFLAG=off\nANALYTICS_ID=\n",
+	})
+	defer os.RemoveAll(dir)
+
+	syncer := envsync.New(envsync.WithPrune())
+	syncer.Overwrite = true
+	res, err := syncer.SyncWithResult(filepath.Join(dir, "env.sample"), target)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ANALYTICS_ID"}, res.Added)
+	assert.Equal(t, []string{"OLD"}, res.Pruned)
+	assert.Equal(t, []string{"API_URL", "FLAG"}, res.Overwritten)
+	assert.Equal(t, map[string]string{"API_URL": "http://localhost:3000", "FLAG": "off", "ANALYTICS_ID": ""}, vercel.env("production"))
+	// preview keeps the variables it shared with production
+	assert.Equal(t, map[string]string{"API_URL": "https://api.example.com", "OLD": "x"}, vercel.env("preview"))
+	// a variable of production alone is changed in place
+	assert.Equal(t, "off", vercel.vars["c"].Value)
+
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "vercel://my-project/staging")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "expected vercel://project/production, preview, or development")
+
+	_, err = syncer.SyncWithResult(filepath.Join(dir, "env.sample"), "vercel://my-project/preview?endpoint="+server.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found: Project not found")
+}
@@ -0,0 +1,62 @@
+package envsync
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// Watch synchronizes source and target, then synchronizes them again every time source changes
+// until ctx is done.
+// Changes are debounced: a synchronization starts once source hasn't changed for the debounce duration,
+// so an editor saving a file in several writes triggers a single synchronization.
+//
+// fn receives the result of every synchronization. A failed synchronization doesn't stop Watch.
+// Watch returns ctx.Err() when ctx is done, or an error if source can't be watched.
+func (s *Syncer) Watch(ctx context.Context, source, target string, debounce time.Duration, fn func(*SyncResult, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "couldn't start watcher")
+	}
+	defer watcher.Close()
+
+	// editors often replace a file instead of writing to it,
+	// so the directory is watched to keep seeing source
+	if err := watcher.Add(filepath.Dir(source)); err != nil {
+		return errors.Wrap(err, "couldn't watch source file")
+	}
+
+	if fn == nil {
+		fn = func(*SyncResult, error) {}
+	}
+	fn(s.SyncContext(ctx, source, target))
+
+	name := filepath.Clean(source)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case event := <-watcher.Events:
+			if filepath.Clean(event.Name) != name || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(debounce)
+		case err := <-watcher.Errors:
+			return errors.Wrap(err, "couldn't watch source file")
+		case <-timer.C:
+			fn(s.SyncContext(ctx, source, target))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package envsync_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Watch(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		".env.example": "PORT=8080\n",
+		".env":         "",
+	})
+	defer os.RemoveAll(dir)
+	source, target := filepath.Join(dir, ".env.example"), filepath.Join(dir, ".env")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan *envsync.SyncResult, 10)
+	done := make(chan error)
+	go func() {
+		syncer := &envsync.Syncer{}
+		done <- syncer.Watch(ctx, source, target, 10*time.Millisecond, func(res *envsync.SyncResult, err error) {
+			assert.Nil(t, err)
+			results <- res
+		})
+	}()
+
+	assert.Equal(t, []string{"PORT"}, receive(t, results).Added)
+
+	// replace source the way most editors save a file
+	ioutil.WriteFile(source+".tmp", []byte("PORT=8080\nHOST=localhost\n"), 0644)
+	os.Rename(source+".tmp", source)
+	assert.Equal(t, []string{"HOST"}, receive(t, results).Added)
+
+	content, err := ioutil.ReadFile(target)
+	assert.Nil(t, err)
+	assert.Equal(t, "PORT=8080\nHOST=localhost\n", string(content))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestSyncer_Watch_MissingDir(t *testing.T) {
+	syncer := &envsync.Syncer{}
+	err := syncer.Watch(context.Background(), "testdata/missing/.env.example", "testdata/missing/.env", time.Millisecond, nil)
+	assert.NotNil(t, err)
+}
+
+func receive(t *testing.T, results <-chan *envsync.SyncResult) *envsync.SyncResult {
+	select {
+	case res := <-results:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("no synchronization after source has changed")
+		return nil
+	}
+}
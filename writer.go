@@ -0,0 +1,110 @@
+package envsync
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const backupSuffix = ".bak"
+
+// writeFile replaces the content of name with doc in a single step.
+// doc is written to a temporary file in the same directory, synced to disk,
+// and renamed over name, so name either has its old content or the new one.
+// If anything fails, name is left untouched.
+func writeFile(name string, doc *Document) error {
+	name, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(name, 0644, func(w io.Writer) error {
+		return doc.write(w)
+	})
+}
+
+// backupFile copies the content of name to a new backup in dir,
+// or in the backups directory next to name if dir is empty.
+// The backup is named after name and the current time, e.g. .env.20190221T100000.000000000.bak.
+// It has the mode of name, and the directories created for it are only accessible by the user,
+// so the backup of a file holding secrets isn't readable by anyone the file isn't.
+func backupFile(name, dir string) error {
+	name, err := resolve(name)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "couldn't open file to back up")
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return errors.Wrap(err, "couldn't open file to back up")
+	}
+
+	dir = backupDir(name, dir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err, "couldn't create backup directory")
+	}
+	stamp := time.Now().UTC().Format(backupTimeFormat)
+	backup := filepath.Join(dir, filepath.Base(name)+"."+stamp+backupSuffix)
+
+	return replaceFile(backup, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+// resolve follows symlinks, so renaming replaces the linked file instead of the link.
+func resolve(name string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(name)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrap(err, "couldn't resolve file")
+	}
+	if err != nil {
+		return name, nil
+	}
+	return resolved, nil
+}
+
+// replaceFile replaces the content of name with what write writes, keeping the mode of name,
+// or giving it mode if it doesn't exist yet.
+func replaceFile(name string, mode os.FileMode, write func(io.Writer) error) (err error) {
+	if info, statErr := os.Stat(name); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "couldn't create temporary file")
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return errors.Wrap(err, "couldn't write temporary file")
+	}
+	if err = tmp.Sync(); err != nil {
+		return errors.Wrap(err, "couldn't sync temporary file")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "couldn't close temporary file")
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return errors.Wrap(err, "couldn't set temporary file mode")
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return errors.Wrap(err, "couldn't replace file")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package envsync_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/bukalapak/envsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer_Sync_Backup(t *testing.T) {
+	syncer := &envsync.Syncer{Backup: true}
+
+	result := "testdata/env.result.backup"
+	ioutil.WriteFile(result, []byte("HOME=production\n"), 0600)
+	defer exec.Command("rm", "-rf", result, "testdata/.envsync").Run()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	backups, err := syncer.History(result)
+	assert.Nil(t, err)
+	assert.Len(t, backups, 1)
+	assert.Equal(t, "testdata/.envsync/backups", filepath.Dir(backups[0].Path))
+
+	backup, _ := ioutil.ReadFile(backups[0].Path)
+	assert.Equal(t, "HOME=production\n", string(backup))
+
+	info, _ := os.Stat(backups[0].Path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	info, _ = os.Stat(filepath.Dir(backups[0].Path))
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	info, _ = os.Stat(result)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestSyncer_Sync_NoBackupByDefault(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	result := "testdata/env.result.nobackup"
+	exec.Command("touch", result).Run()
+	defer exec.Command("rm", "-rf", result).Run()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	backups, err := syncer.History(result)
+	assert.Nil(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestSyncer_Sync_Symlink(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	result := "testdata/env.result.symlink"
+	link := "testdata/env.result.link"
+	exec.Command("touch", result).Run()
+	os.Symlink(filepath.Base(result), link)
+	defer exec.Command("rm", "-rf", result, link).Run()
+
+	err := syncer.Sync("testdata/env.success", link)
+	assert.Nil(t, err)
+
+	info, _ := os.Lstat(link)
+	assert.True(t, info.Mode()&os.ModeSymlink != 0)
+	assert.Equal(t, fileToMap("testdata/env.success"), fileToMap(result))
+}
+
+func TestSyncer_Sync_NoTemporaryFileLeft(t *testing.T) {
+	syncer := &envsync.Syncer{}
+
+	dir, _ := ioutil.TempDir("", "envsync")
+	defer os.RemoveAll(dir)
+
+	result := filepath.Join(dir, ".env")
+	exec.Command("touch", result).Run()
+
+	err := syncer.Sync("testdata/env.success", result)
+	assert.Nil(t, err)
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+}
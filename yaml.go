@@ -0,0 +1,148 @@
+package envsync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// yamlEnvironment is the key of the environment block of a docker-compose service.
+const yamlEnvironment = "environment"
+
+// yamlLayout tells how the key-values of a YAML env file are laid out, so they are written back the same way.
+type yamlLayout struct {
+	// wrapped tells whether the key-values are under an environment key.
+	wrapped bool
+
+	// list tells whether the key-values are a list of KEY=VALUE items rather than a mapping.
+	list bool
+
+	// manifest is the kind of the Kubernetes manifest holding the key-values in its data block, if any,
+	// and head and tail the text of the manifest before and after the block.
+	// blocks tells which of the data and stringData blocks the manifest has,
+	// and stringData which keys of a Secret are read from its stringData block.
+	manifest   string
+	head, tail string
+	blocks     map[string]bool
+	stringData map[string]bool
+}
+
+// yamlPlain matches the strings written as plain YAML scalars, without quotes.
+var yamlPlain = regexp.MustCompile(`^[A-Za-z0-9_./]([A-Za-z0-9_./:@+=,-]*[A-Za-z0-9_./@+=,-])?$`)
+
+var yamlNull = map[string]bool{"null": true, "Null": true, "NULL": true, "~": true}
+
+func decodeYAML(r io.Reader) (*Document, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// the mapping is decoded once for the order of keys, and again for the values as written, e.g. 1.0 rather than 1
+	var root yaml.MapSlice
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, errors.Wrap(err, "couldn't decode YAML")
+	}
+
+	if kind := manifestKind(root); kind != "" {
+		return decodeManifest(b, root, kind)
+	}
+
+	doc := &Document{codec: CodecYAML}
+	items := root
+	if len(root) == 1 && root[0].Key == yamlEnvironment {
+		switch v := root[0].Value.(type) {
+		case yaml.MapSlice:
+			doc.yaml.wrapped, items = true, v
+		case []interface{}:
+			doc.yaml.wrapped, doc.yaml.list = true, true
+		}
+	}
+
+	if doc.yaml.list {
+		var v struct {
+			Environment []string `yaml:"environment"`
+		}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, errors.Wrap(err, "couldn't decode YAML")
+		}
+		for _, item := range v.Environment {
+			// an item without a value, e.g. - KEY, is empty
+			sp := strings.SplitN(item, separator, splitNumber)
+			sp = append(sp, "")
+			doc.lines = append(doc.lines, &line{kind: entryLine, key: sp[0], value: sp[1]})
+		}
+		return doc, nil
+	}
+
+	env := make(map[string]string)
+	if doc.yaml.wrapped {
+		v := struct {
+			Environment map[string]string `yaml:"environment"`
+		}{Environment: env}
+		err = yaml.Unmarshal(b, &v)
+	} else {
+		err = yaml.Unmarshal(b, &env)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't decode YAML")
+	}
+	for _, item := range items {
+		k := fmt.Sprint(item.Key)
+		doc.lines = append(doc.lines, &line{kind: entryLine, key: k, value: env[k]})
+	}
+	return doc, nil
+}
+
+// encodeYAML writes d to buf as YAML, laid out as it is read.
+// Comments and blank lines are written as they are, and the export prefix is dropped.
+func (d *Document) encodeYAML(buf *bytes.Buffer) {
+	if d.yaml.manifest != "" {
+		d.encodeManifest(buf)
+		return
+	}
+	indent := ""
+	if d.yaml.wrapped {
+		empty := len(d.Keys()) == 0
+		switch {
+		case empty && d.yaml.list:
+			buf.WriteString(yamlEnvironment + ": []\n")
+		case empty:
+			buf.WriteString(yamlEnvironment + ": {}\n")
+		default:
+			buf.WriteString(yamlEnvironment + ":\n")
+		}
+		indent = "  "
+	}
+
+	for _, l := range d.lines {
+		switch l.kind {
+		case blankLine:
+			buf.WriteString("\n")
+		case commentLine:
+			buf.WriteString(indent + l.raw + "\n")
+		case entryLine:
+			if d.yaml.list {
+				buf.WriteString(indent + "- " + yamlScalar(l.key+separator+l.value))
+			} else {
+				buf.WriteString(indent + yamlScalar(l.key) + ": " + yamlScalar(l.value))
+			}
+			buf.WriteString(l.comment + "\n")
+		}
+	}
+}
+
+// yamlScalar returns s as a plain YAML scalar if it can be read back as the same string, or double-quoted otherwise.
+// Scalars are read as they are written, e.g. 1.0 and true, except null and ~ that are read as empty.
+func yamlScalar(s string) string {
+	if yamlPlain.MatchString(s) && !yamlNull[s] {
+		return s
+	}
+	return strconv.Quote(s)
+}